@@ -54,8 +54,10 @@ func DefaultConfig() *Config {
 // Client is the client to the Vault API. Create a client with
 // NewClient.
 type Client struct {
-	addr   *url.URL
-	config *Config
+	addr      *url.URL
+	config    *Config
+	wrapTTL   string
+	namespace string
 }
 
 // NewClient returns a new client for the given configuration.
@@ -143,6 +145,18 @@ func (c *Client) ClearToken() {
 	})
 }
 
+// SetWrapTTL sets the TTL with which the responses of future requests
+// are wrapped, such as "5m". An empty TTL disables response wrapping.
+func (c *Client) SetWrapTTL(ttl string) {
+	c.wrapTTL = ttl
+}
+
+// SetNamespace sets the namespace in which future requests are made,
+// such as "team1/". An empty namespace selects the root namespace.
+func (c *Client) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
 // NewRequest creates a new raw request object to query the Vault server
 // configured for this client. This is an advanced method and generally
 // doesn't need to be called externally.
@@ -154,7 +168,9 @@ func (c *Client) NewRequest(method, path string) *Request {
 			Host:   c.addr.Host,
 			Path:   path,
 		},
-		Params: make(map[string][]string),
+		Params:    make(map[string][]string),
+		WrapTTL:   c.wrapTTL,
+		Namespace: c.namespace,
 	}
 }
 
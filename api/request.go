@@ -17,6 +17,12 @@ type Request struct {
 	Obj      interface{}
 	Body     io.Reader
 	BodySize int64
+
+	// WrapTTL, if set, requests that the response is wrapped
+	WrapTTL string
+
+	// Namespace, if set, is the namespace the request is made in
+	Namespace string
 }
 
 // SetJSONBody is used to set a request body that is a JSON-encoded value.
@@ -57,5 +63,12 @@ func (r *Request) ToHTTP() (*http.Request, error) {
 	req.URL.Host = r.URL.Host
 	req.Host = r.URL.Host
 
+	if r.WrapTTL != "" {
+		req.Header.Set("X-Vault-Wrap-TTL", r.WrapTTL)
+	}
+	if r.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
 	return req, nil
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"io"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -61,3 +62,18 @@ func TestRequestResetJSONBody(t *testing.T) {
 		t.Fatalf("bad: %d", len(actual))
 	}
 }
+
+func TestRequestToHTTP_namespace(t *testing.T) {
+	r := &Request{
+		Method:    "GET",
+		URL:       &url.URL{Scheme: "http", Host: "127.0.0.1:8200", Path: "/v1/secret/foo"},
+		Namespace: "ns1/",
+	}
+	req, err := r.ToHTTP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := req.Header.Get("X-Vault-Namespace"); v != "ns1/" {
+		t.Fatalf("bad: %s", v)
+	}
+}
@@ -18,13 +18,25 @@ func (c *Sys) ListAuth() (map[string]*AuthMount, error) {
 }
 
 func (c *Sys) EnableAuth(path, authType, desc string) error {
-	if err := c.checkAuthPath(path); err != nil {
-		return err
-	}
-
-	body := map[string]string{
+	return c.enableAuth(path, map[string]string{
 		"type":        authType,
 		"description": desc,
+	})
+}
+
+// EnableAuthPlugin enables a plugin registered in the plugin catalog as a
+// credential backend
+func (c *Sys) EnableAuthPlugin(path, pluginName, desc string) error {
+	return c.enableAuth(path, map[string]string{
+		"type":        "plugin",
+		"description": desc,
+		"plugin_name": pluginName,
+	})
+}
+
+func (c *Sys) enableAuth(path string, body map[string]string) error {
+	if err := c.checkAuthPath(path); err != nil {
+		return err
 	}
 
 	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/auth/%s", path))
@@ -54,6 +66,65 @@ func (c *Sys) DisableAuth(path string) error {
 	return err
 }
 
+func (c *Sys) RemountAuth(from, to string) error {
+	if err := c.checkAuthPath(from); err != nil {
+		return err
+	}
+	if err := c.checkAuthPath(to); err != nil {
+		return err
+	}
+
+	body := map[string]string{
+		"from": from,
+		"to":   to,
+	}
+
+	r := c.c.NewRequest("POST", "/v1/sys/auth/remount")
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func (c *Sys) TuneAuth(path string, config MountConfigInput) error {
+	if err := c.checkAuthPath(path); err != nil {
+		return err
+	}
+
+	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/auth/%s/tune", path))
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func (c *Sys) AuthConfig(path string) (*MountConfigOutput, error) {
+	if err := c.checkAuthPath(path); err != nil {
+		return nil, err
+	}
+
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/auth/%s/tune", path))
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result MountConfigOutput
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
+
 func (c *Sys) checkAuthPath(path string) error {
 	if path[0] == '/' {
 		return fmt.Errorf("path must not start with /: %s", path)
@@ -69,4 +140,5 @@ func (c *Sys) checkAuthPath(path string) error {
 type AuthMount struct {
 	Type        string
 	Description string
+	PluginName  string `json:"plugin_name"`
 }
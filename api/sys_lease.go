@@ -34,3 +34,75 @@ func (c *Sys) RevokePrefix(id string) error {
 	}
 	return err
 }
+
+// RevokeForce deletes the leases under the given prefix without revoking
+// the secrets through their backends. The secrets may remain valid.
+func (c *Sys) RevokeForce(id string) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/revoke-force/"+id)
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// LookupLease returns the metadata of a lease, such as its expiration
+// and the accessor of the token owning it, but not its secret data.
+func (c *Sys) LookupLease(id string) (*Secret, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/leases/lookup")
+
+	body := map[string]interface{}{"lease_id": id}
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
+
+// ListLeases returns the lease IDs directly under the given prefix.
+// Nested prefixes are returned with a trailing slash.
+func (c *Sys) ListLeases(prefix string) ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/leases/lookup/"+prefix)
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	raw, _ := secret.Data["keys"].([]interface{})
+	keys := make([]string, 0, len(raw))
+	for _, k := range raw {
+		if key, ok := k.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// ListIrrevocableLeases returns the leases that could not be revoked
+// automatically, with the error of the last revocation attempt under
+// the "leases" key of the data.
+func (c *Sys) ListIrrevocableLeases() (*Secret, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/leases/irrevocable")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
@@ -18,13 +18,24 @@ func (c *Sys) ListMounts() (map[string]*Mount, error) {
 }
 
 func (c *Sys) Mount(path, mountType, description string) error {
-	if err := c.checkMountPath(path); err != nil {
-		return err
-	}
-
-	body := map[string]string{
+	return c.mount(path, map[string]string{
 		"type":        mountType,
 		"description": description,
+	})
+}
+
+// MountPlugin mounts a plugin registered in the plugin catalog
+func (c *Sys) MountPlugin(path, pluginName, description string) error {
+	return c.mount(path, map[string]string{
+		"type":        "plugin",
+		"description": description,
+		"plugin_name": pluginName,
+	})
+}
+
+func (c *Sys) mount(path string, body map[string]string) error {
+	if err := c.checkMountPath(path); err != nil {
+		return err
 	}
 
 	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/mounts/%s", path))
@@ -79,6 +90,40 @@ func (c *Sys) Remount(from, to string) error {
 	return err
 }
 
+func (c *Sys) TuneMount(path string, config MountConfigInput) error {
+	if err := c.checkMountPath(path); err != nil {
+		return err
+	}
+
+	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/mounts/%s/tune", path))
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func (c *Sys) MountConfig(path string) (*MountConfigOutput, error) {
+	if err := c.checkMountPath(path); err != nil {
+		return nil, err
+	}
+
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/mounts/%s/tune", path))
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result MountConfigOutput
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
+
 func (c *Sys) checkMountPath(path string) error {
 	if path[0] == '/' {
 		return fmt.Errorf("path must not start with /: %s", path)
@@ -90,4 +135,27 @@ func (c *Sys) checkMountPath(path string) error {
 type Mount struct {
 	Type        string
 	Description string
+	PluginName  string `json:"plugin_name"`
+}
+
+// MountConfigInput is used to tune a mount or a credential backend. The
+// TTLs are durations such as "1h", and omitted values are left unchanged.
+type MountConfigInput struct {
+	DefaultLeaseTTL          string            `json:"default_lease_ttl,omitempty"`
+	MaxLeaseTTL              string            `json:"max_lease_ttl,omitempty"`
+	Description              *string           `json:"description,omitempty"`
+	AuditNonHMACRequestKeys  []string          `json:"audit_non_hmac_request_keys,omitempty"`
+	AuditNonHMACResponseKeys []string          `json:"audit_non_hmac_response_keys,omitempty"`
+	Options                  map[string]string `json:"options,omitempty"`
+}
+
+// MountConfigOutput holds the settings of a mount or a credential backend.
+// The lease TTLs are the effective ones in seconds.
+type MountConfigOutput struct {
+	DefaultLeaseTTL          int               `json:"default_lease_ttl"`
+	MaxLeaseTTL              int               `json:"max_lease_ttl"`
+	Description              string            `json:"description"`
+	AuditNonHMACRequestKeys  []string          `json:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys []string          `json:"audit_non_hmac_response_keys"`
+	Options                  map[string]string `json:"options"`
 }
@@ -0,0 +1,77 @@
+package api
+
+import "fmt"
+
+// Namespace is a child namespace. AdminToken is only set when the
+// namespace is created.
+type Namespace struct {
+	ID         string `json:"id"`
+	Path       string `json:"path"`
+	AdminToken string `json:"admin_token"`
+}
+
+// ListNamespaces returns the names of the child namespaces
+func (c *Sys) ListNamespaces() ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/namespaces")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.Keys, err
+}
+
+// GetNamespace returns a child namespace, or nil if there is none
+func (c *Sys) GetNamespace(name string) (*Namespace, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data *Namespace `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+// CreateNamespace creates a child namespace. The returned namespace
+// holds the token to administer it.
+func (c *Sys) CreateNamespace(name string) (*Namespace, error) {
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *Namespace `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+// DeleteNamespace deletes a child namespace along with all its data
+func (c *Sys) DeleteNamespace(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plugin is a plugin registered in the plugin catalog. The command is
+// relative to the plugin directory of the server, and Sha256 is the
+// hex-encoded checksum of the command.
+type Plugin struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Sha256  string   `json:"sha256"`
+}
+
+// ListPlugins returns the names of the plugins in the catalog
+func (c *Sys) ListPlugins() ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/plugins/catalog")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.Keys, err
+}
+
+// GetPlugin returns a plugin of the catalog, or nil if there is none
+func (c *Sys) GetPlugin(name string) (*Plugin, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/plugins/catalog/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data *Plugin `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+// RegisterPlugin adds or updates a plugin in the catalog
+func (c *Sys) RegisterPlugin(plugin *Plugin) error {
+	body := map[string]string{
+		"command": plugin.Command,
+		"args":    strings.Join(plugin.Args, ","),
+		"sha256":  plugin.Sha256,
+	}
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/plugins/catalog/%s", plugin.Name))
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// DeregisterPlugin removes a plugin from the catalog
+func (c *Sys) DeregisterPlugin(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/plugins/catalog/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package api
+
+import "fmt"
+
+// LeaseCountQuota limits the number of leases under a path, or held by
+// each token if PerToken is set. An empty path applies to all paths.
+type LeaseCountQuota struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	MaxLeases int    `json:"max_leases"`
+	PerToken  bool   `json:"per_token"`
+}
+
+// ListLeaseCountQuotas returns the names of the lease count quotas
+func (c *Sys) ListLeaseCountQuotas() ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/quotas/lease-count")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.Keys, err
+}
+
+// GetLeaseCountQuota returns a lease count quota, or nil if there is none
+func (c *Sys) GetLeaseCountQuota(name string) (*LeaseCountQuota, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/quotas/lease-count/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data *LeaseCountQuota `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+// PutLeaseCountQuota creates or updates a lease count quota
+func (c *Sys) PutLeaseCountQuota(quota *LeaseCountQuota) error {
+	body := map[string]interface{}{
+		"path":       quota.Path,
+		"max_leases": quota.MaxLeases,
+		"per_token":  quota.PerToken,
+	}
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/quotas/lease-count/%s", quota.Name))
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// DeleteLeaseCountQuota deletes a lease count quota
+func (c *Sys) DeleteLeaseCountQuota(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/quotas/lease-count/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// RateLimitQuota limits the rate of the requests under a path, or from
+// each client address if PerClient is set. An empty path applies to all
+// paths. Rate is in requests per second, and Burst is the number of
+// requests allowed at once.
+type RateLimitQuota struct {
+	Name      string  `json:"name"`
+	Path      string  `json:"path"`
+	Rate      float64 `json:"rate"`
+	Burst     int     `json:"burst"`
+	PerClient bool    `json:"per_client"`
+
+	// BlockedRequests is the number of requests rejected by the quota
+	// since it was loaded by the server
+	BlockedRequests uint64 `json:"blocked_requests"`
+}
+
+// ListRateLimitQuotas returns the names of the rate limit quotas
+func (c *Sys) ListRateLimitQuotas() ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/quotas/rate-limit")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.Keys, err
+}
+
+// GetRateLimitQuota returns a rate limit quota, or nil if there is none
+func (c *Sys) GetRateLimitQuota(name string) (*RateLimitQuota, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/quotas/rate-limit/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data *RateLimitQuota `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data, err
+}
+
+// PutRateLimitQuota creates or updates a rate limit quota
+func (c *Sys) PutRateLimitQuota(quota *RateLimitQuota) error {
+	body := map[string]interface{}{
+		"path":       quota.Path,
+		"rate":       quota.Rate,
+		"burst":      quota.Burst,
+		"per_client": quota.PerClient,
+	}
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/quotas/rate-limit/%s", quota.Name))
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// DeleteRateLimitQuota deletes a rate limit quota
+func (c *Sys) DeleteRateLimitQuota(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/quotas/rate-limit/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package api
+
+// Unwrap returns the response wrapped by the given token. If the token
+// is empty, the token of the client is used.
+func (c *Sys) Unwrap(token string) (*Secret, error) {
+	return c.wrappingRequest("/v1/sys/wrapping/unwrap", token)
+}
+
+// WrapLookup returns the creation time and TTL of a wrapping token
+// without unwrapping its response.
+func (c *Sys) WrapLookup(token string) (*Secret, error) {
+	return c.wrappingRequest("/v1/sys/wrapping/lookup", token)
+}
+
+// Rewrap moves a wrapped response to a new wrapping token, revoking
+// the given one.
+func (c *Sys) Rewrap(token string) (*Secret, error) {
+	return c.wrappingRequest("/v1/sys/wrapping/rewrap", token)
+}
+
+func (c *Sys) wrappingRequest(path, token string) (*Secret, error) {
+	r := c.c.NewRequest("PUT", path)
+	if token != "" {
+		body := map[string]interface{}{"token": token}
+		if err := r.SetJSONBody(body); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
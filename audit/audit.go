@@ -11,13 +11,40 @@ type Backend interface {
 	// request is authorized but before the request is executed. The arguments
 	// MUST not be modified in anyway. They should be deep copied if this is
 	// a possibility.
-	LogRequest(*logical.Auth, *logical.Request, error) error
+	LogRequest(*logical.Auth, *logical.Request, error, *LogOptions) error
 
 	// LogResponse is used to syncronously log a response. This is done after
 	// the request is processed but before the response is sent. The arguments
 	// MUST not be modified in anyway. They should be deep copied if this is
 	// a possibility.
-	LogResponse(*logical.Auth, *logical.Request, *logical.Response, error) error
+	LogResponse(*logical.Auth, *logical.Request, *logical.Response, error, *LogOptions) error
+}
+
+// LogOptions are the settings of the mount of a request which apply to
+// its audit logs. A nil value has the default settings.
+type LogOptions struct {
+	// NonHMACRequestKeys are the keys of the request data whose values
+	// are logged as they are instead of hashed
+	NonHMACRequestKeys []string
+
+	// NonHMACResponseKeys are the same for the response data
+	NonHMACResponseKeys []string
+}
+
+// RequestKeys returns the request keys not to hash, if any
+func (o *LogOptions) RequestKeys() []string {
+	if o == nil {
+		return nil
+	}
+	return o.NonHMACRequestKeys
+}
+
+// ResponseKeys returns the response keys not to hash, if any
+func (o *LogOptions) ResponseKeys() []string {
+	if o == nil {
+		return nil
+	}
+	return o.NonHMACResponseKeys
 }
 
 // Factory is the factory function to create an audit backend.
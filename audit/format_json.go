@@ -3,6 +3,7 @@ package audit
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 )
@@ -33,6 +34,7 @@ func (f *FormatJSON) FormatRequest(
 		Error: errString,
 
 		Auth: JSONAuth{
+			Accessor:    auth.Accessor,
 			DisplayName: auth.DisplayName,
 			Policies:    auth.Policies,
 			Metadata:    auth.Metadata,
@@ -41,6 +43,7 @@ func (f *FormatJSON) FormatRequest(
 		Request: JSONRequest{
 			Operation:  req.Operation,
 			Path:       req.Path,
+			Namespace:  req.Namespace,
 			Data:       req.Data,
 			RemoteAddr: getRemoteAddr(req),
 		},
@@ -69,6 +72,7 @@ func (f *FormatJSON) FormatResponse(
 	if resp.Auth != nil {
 		respAuth = &JSONAuth{
 			ClientToken: resp.Auth.ClientToken,
+			Accessor:    resp.Auth.Accessor,
 			DisplayName: resp.Auth.DisplayName,
 			Policies:    resp.Auth.Policies,
 			Metadata:    resp.Auth.Metadata,
@@ -82,6 +86,16 @@ func (f *FormatJSON) FormatResponse(
 		}
 	}
 
+	var respWrapInfo *JSONWrapInfo
+	if resp.WrapInfo != nil {
+		respWrapInfo = &JSONWrapInfo{
+			Token:        resp.WrapInfo.Token,
+			Accessor:     resp.WrapInfo.Accessor,
+			TTL:          int(resp.WrapInfo.TTL.Seconds()),
+			CreationTime: resp.WrapInfo.CreationTime,
+		}
+	}
+
 	// Encode!
 	enc := json.NewEncoder(w)
 	return enc.Encode(&JSONResponseEntry{
@@ -89,6 +103,7 @@ func (f *FormatJSON) FormatResponse(
 		Error: errString,
 
 		Auth: JSONAuth{
+			Accessor: auth.Accessor,
 			Policies: auth.Policies,
 			Metadata: auth.Metadata,
 		},
@@ -96,6 +111,7 @@ func (f *FormatJSON) FormatResponse(
 		Request: JSONRequest{
 			Operation:  req.Operation,
 			Path:       req.Path,
+			Namespace:  req.Namespace,
 			Data:       req.Data,
 			RemoteAddr: getRemoteAddr(req),
 		},
@@ -105,6 +121,7 @@ func (f *FormatJSON) FormatResponse(
 			Secret:   respSecret,
 			Data:     resp.Data,
 			Redirect: resp.Redirect,
+			WrapInfo: respWrapInfo,
 		},
 	})
 }
@@ -129,6 +146,7 @@ type JSONResponseEntry struct {
 type JSONRequest struct {
 	Operation  logical.Operation      `json:"operation"`
 	Path       string                 `json:"path"`
+	Namespace  string                 `json:"namespace,omitempty"`
 	Data       map[string]interface{} `json:"data"`
 	RemoteAddr string                 `json:"remote_address"`
 }
@@ -138,10 +156,12 @@ type JSONResponse struct {
 	Secret   *JSONSecret            `json:"secret,emitempty"`
 	Data     map[string]interface{} `json:"data"`
 	Redirect string                 `json:"redirect"`
+	WrapInfo *JSONWrapInfo          `json:"wrap_info,omitempty"`
 }
 
 type JSONAuth struct {
 	ClientToken string            `json:"client_token,omitempty"`
+	Accessor    string            `json:"accessor,omitempty"`
 	DisplayName string            `json:"display_name"`
 	Policies    []string          `json:"policies"`
 	Metadata    map[string]string `json:"metadata"`
@@ -151,6 +171,13 @@ type JSONSecret struct {
 	LeaseID string `json:"lease_id"`
 }
 
+type JSONWrapInfo struct {
+	Token        string    `json:"token"`
+	Accessor     string    `json:"accessor"`
+	TTL          int       `json:"ttl"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
 // getRemoteAddr safely gets the remote address avoiding a nil pointer
 func getRemoteAddr(req *logical.Request) string {
 	if req != nil && req.Connection != nil {
@@ -14,10 +14,11 @@ import (
 
 // Hash will hash the given type. This has built-in support for auth,
 // requests, and responses. If it is a type that isn't recognized, then
-// it will be passed through.
+// it will be passed through. The values of the data keys listed in
+// nonHMACKeys are not hashed.
 //
 // The structure is modified in-place.
-func Hash(raw interface{}) error {
+func Hash(raw interface{}, nonHMACKeys ...string) error {
 	fn := HashSHA1("")
 
 	switch s := raw.(type) {
@@ -43,12 +44,12 @@ func Hash(raw interface{}) error {
 			}
 		}
 
-		data, err := HashStructure(s.Data, fn)
+		data, err := hashData(s.Data, fn, nonHMACKeys)
 		if err != nil {
 			return err
 		}
 
-		s.Data = data.(map[string]interface{})
+		s.Data = data
 	case *logical.Response:
 		if s == nil {
 			return nil
@@ -58,18 +59,43 @@ func Hash(raw interface{}) error {
 				return err
 			}
 		}
+		if s.WrapInfo != nil && s.WrapInfo.Token != "" {
+			token, err := fn(s.WrapInfo.Token)
+			if err != nil {
+				return err
+			}
 
-		data, err := HashStructure(s.Data, fn)
+			s.WrapInfo.Token = token
+		}
+
+		data, err := hashData(s.Data, fn, nonHMACKeys)
 		if err != nil {
 			return err
 		}
 
-		s.Data = data.(map[string]interface{})
+		s.Data = data
 	}
 
 	return nil
 }
 
+// hashData hashes the values of the data, except for the values of the
+// keys listed in nonHMACKeys
+func hashData(data map[string]interface{}, fn HashCallback, nonHMACKeys []string) (map[string]interface{}, error) {
+	raw, err := HashStructure(data, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := raw.(map[string]interface{})
+	for _, k := range nonHMACKeys {
+		if v, ok := data[k]; ok {
+			hashed[k] = v
+		}
+	}
+	return hashed, nil
+}
+
 // HashStructure takes an interface and hashes all the values within
 // the structure. Only _values_ are hashed: keys of objects are not.
 //
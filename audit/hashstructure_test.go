@@ -114,6 +114,24 @@ func TestHash(t *testing.T) {
 				},
 			},
 		},
+		{
+			&logical.Response{
+				WrapInfo: &logical.WrapInfo{
+					Token:        "foo",
+					Accessor:     "bar",
+					TTL:          1 * time.Minute,
+					CreationTime: now,
+				},
+			},
+			&logical.Response{
+				WrapInfo: &logical.WrapInfo{
+					Token:        "sha1:0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33",
+					Accessor:     "bar",
+					TTL:          1 * time.Minute,
+					CreationTime: now,
+				},
+			},
+		},
 		{
 			"foo",
 			"foo",
@@ -149,6 +167,26 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHash_nonHMACKeys(t *testing.T) {
+	req := &logical.Request{
+		Data: map[string]interface{}{
+			"foo": "bar",
+			"baz": "bar",
+		},
+	}
+	if err := Hash(req, "baz", "missing"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"foo": "sha1:62cdb7020ff920e5aa642c3d4066950dd1f01f4d",
+		"baz": "bar",
+	}
+	if !reflect.DeepEqual(req.Data, expected) {
+		t.Fatalf("bad: %#v", req.Data)
+	}
+}
+
 func TestHashWalker(t *testing.T) {
 	replaceText := "foo"
 
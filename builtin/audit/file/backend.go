@@ -48,7 +48,7 @@ type Backend struct {
 	f    *os.File
 }
 
-func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error) error {
+func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error, opts *audit.LogOptions) error {
 	if err := b.open(); err != nil {
 		return err
 	}
@@ -81,7 +81,7 @@ func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr
 		if err := audit.Hash(auth); err != nil {
 			return err
 		}
-		if err := audit.Hash(req); err != nil {
+		if err := audit.Hash(req, opts.RequestKeys()...); err != nil {
 			return err
 		}
 	}
@@ -94,7 +94,8 @@ func (b *Backend) LogResponse(
 	auth *logical.Auth,
 	req *logical.Request,
 	resp *logical.Response,
-	err error) error {
+	err error,
+	opts *audit.LogOptions) error {
 	if err := b.open(); err != nil {
 		return err
 	}
@@ -133,10 +134,10 @@ func (b *Backend) LogResponse(
 		if err := audit.Hash(auth); err != nil {
 			return err
 		}
-		if err := audit.Hash(req); err != nil {
+		if err := audit.Hash(req, opts.RequestKeys()...); err != nil {
 			return err
 		}
-		if err := audit.Hash(resp); err != nil {
+		if err := audit.Hash(resp, opts.ResponseKeys()...); err != nil {
 			return err
 		}
 	}
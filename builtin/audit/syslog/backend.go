@@ -52,7 +52,7 @@ type Backend struct {
 	logRaw bool
 }
 
-func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error) error {
+func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error, opts *audit.LogOptions) error {
 	if !b.logRaw {
 		// Before we copy the structure we must nil out some data
 		// otherwise we will cause reflection to panic and die
@@ -82,7 +82,7 @@ func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr
 		if err := audit.Hash(auth); err != nil {
 			return err
 		}
-		if err := audit.Hash(req); err != nil {
+		if err := audit.Hash(req, opts.RequestKeys()...); err != nil {
 			return err
 		}
 	}
@@ -100,7 +100,7 @@ func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr
 }
 
 func (b *Backend) LogResponse(auth *logical.Auth, req *logical.Request,
-	resp *logical.Response, err error) error {
+	resp *logical.Response, err error, opts *audit.LogOptions) error {
 	if !b.logRaw {
 		// Before we copy the structure we must nil out some data
 		// otherwise we will cause reflection to panic and die
@@ -136,10 +136,10 @@ func (b *Backend) LogResponse(auth *logical.Auth, req *logical.Request,
 		if err := audit.Hash(auth); err != nil {
 			return err
 		}
-		if err := audit.Hash(req); err != nil {
+		if err := audit.Hash(req, opts.RequestKeys()...); err != nil {
 			return err
 		}
-		if err := audit.Hash(resp); err != nil {
+		if err := audit.Hash(resp, opts.ResponseKeys()...); err != nil {
 			return err
 		}
 	}
@@ -1,6 +1,8 @@
 package appId
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -177,7 +179,16 @@ func testAccLogin(t *testing.T, display string) logicaltest.TestStep {
 func testAccLoginCidr(t *testing.T, ip string, err bool) logicaltest.TestStep {
 	check := logicaltest.TestCheckError()
 	if !err {
-		check = logicaltest.TestCheckAuth([]string{"bar", "foo"})
+		check = logicaltest.TestCheckMulti(
+			logicaltest.TestCheckAuth([]string{"bar", "foo"}),
+			func(resp *logical.Response) error {
+				// The token is bound to the CIDR block of the mapping
+				if !reflect.DeepEqual(resp.Auth.BoundCIDRs, []string{"192.168.0.0/16"}) {
+					return fmt.Errorf("bad: %#v", resp.Auth.BoundCIDRs)
+				}
+				return nil
+			},
+		)
 	}
 
 	return logicaltest.TestStep{
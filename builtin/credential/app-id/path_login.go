@@ -55,7 +55,9 @@ func (b *backend) pathLogin(
 		return logical.ErrorResponse("invalid user ID or app ID"), nil
 	}
 
-	// If there is a CIDR block restriction, check that
+	// If there is a CIDR block restriction, check that. The issued token
+	// is bound to the same block.
+	var boundCIDRs []string
 	if raw, ok := appsMap["cidr_block"]; ok {
 		_, cidr, err := net.ParseCIDR(raw.(string))
 		if err != nil {
@@ -69,6 +71,7 @@ func (b *backend) pathLogin(
 		if addr == "" || !cidr.Contains(net.ParseIP(addr)) {
 			return logical.ErrorResponse("unauthorized source address"), nil
 		}
+		boundCIDRs = []string{cidr.String()}
 	}
 
 	appsRaw, ok := appsMap["value"]
@@ -129,6 +132,7 @@ func (b *backend) pathLogin(
 			DisplayName: displayName,
 			Policies:    policies,
 			Metadata:    metadata,
+			BoundCIDRs:  boundCIDRs,
 		},
 	}, nil
 }
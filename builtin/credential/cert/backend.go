@@ -26,6 +26,7 @@ func Backend() *framework.Backend {
 
 		Paths: append([]*framework.Path{
 			pathLogin(&b),
+			pathCertsList(&b),
 			pathCerts(&b),
 		}),
 
@@ -8,6 +8,19 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathCertsList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathCertList,
+		},
+
+		HelpSynopsis:    pathCertsListHelpSyn,
+		HelpDescription: pathCertsListHelpDesc,
+	}
+}
+
 func pathCerts(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `certs/(?P<name>\w+)`,
@@ -148,6 +161,16 @@ type CertEntry struct {
 	Lease       time.Duration
 }
 
+func (b *backend) pathCertList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("cert/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathCertHelpSyn = `
 Manage trusted certificates used for authentication.
 `
@@ -160,3 +183,12 @@ Deleting a certificate will not revoke auth for prior authenticated connections.
 To do this, do a revoke on "login". If you don't need to revoke login immediately,
 then the next renew will cause the lease to expire.
 `
+
+const pathCertsListHelpSyn = `
+List the existing certs.
+`
+
+const pathCertsListHelpDesc = `
+This path lists the names of the trusted certificates that have been written
+to this backend. Details of each entry can be read at "certs/<name>".
+`
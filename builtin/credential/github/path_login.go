@@ -83,6 +83,12 @@ func (b *backend) pathLogin(
 		return nil, err
 	}
 
+	// Report the teams of the user for external identity groups
+	var groupAliases []*logical.Alias
+	for _, name := range teamNames {
+		groupAliases = append(groupAliases, &logical.Alias{Name: name})
+	}
+
 	return &logical.Response{
 		Auth: &logical.Auth{
 			Policies: policiesList,
@@ -91,6 +97,10 @@ func (b *backend) pathLogin(
 				"org":      *org.Login,
 			},
 			DisplayName: *user.Login,
+			Alias: &logical.Alias{
+				Name: *user.Login,
+			},
+			GroupAliases: groupAliases,
 		},
 	}, nil
 }
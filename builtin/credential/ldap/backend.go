@@ -31,6 +31,7 @@ func Backend() *framework.Backend {
 		Paths: append([]*framework.Path{
 			pathLogin(&b),
 			pathConfig(&b),
+			pathGroupsList(&b),
 			pathGroups(&b),
 		}),
 
@@ -78,25 +79,27 @@ func EscapeLDAPValue(input string) string {
 	return input
 }
 
-func (b *backend) Login(req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+// Login authenticates the user and returns the policies mapped to its
+// groups, along with the names of all groups the user is member of.
+func (b *backend) Login(req *logical.Request, username string, password string) ([]string, []string, *logical.Response, error) {
 
 	cfg, err := b.Config(req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if cfg == nil {
-		return nil, logical.ErrorResponse("ldap backend not configured"), nil
+		return nil, nil, logical.ErrorResponse("ldap backend not configured"), nil
 	}
 
 	c, err := cfg.DialLDAP()
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil
+		return nil, nil, logical.ErrorResponse(err.Error()), nil
 	}
 
 	// Try to authenticate to the server using the provided credentials
 	binddn := fmt.Sprintf("%s=%s,%s", cfg.UserAttr, EscapeLDAPValue(username), cfg.UserDN)
 	if err = c.Bind(binddn, password); err != nil {
-		return nil, logical.ErrorResponse(fmt.Sprintf("LDAP bind failed: %v", err)), nil
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("LDAP bind failed: %v", err)), nil
 	}
 
 	// Enumerate all groups the user is member of. The search filter should
@@ -107,7 +110,7 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 		Filter: fmt.Sprintf("(|(memberUid=%s)(member=%s)(uniqueMember=%s))", username, binddn, binddn),
 	})
 	if err != nil {
-		return nil, logical.ErrorResponse(fmt.Sprintf("LDAP search failed: %v", err)), nil
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("LDAP search failed: %v", err)), nil
 	}
 
 	var allgroups []string
@@ -126,10 +129,10 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 	}
 
 	if len(policies) == 0 {
-		return nil, logical.ErrorResponse("user is not member of any authorized group"), nil
+		return nil, nil, logical.ErrorResponse("user is not member of any authorized group"), nil
 	}
 
-	return policies, nil, nil
+	return policies, allgroups, nil, nil
 }
 
 const backendHelp = `
@@ -7,6 +7,19 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathGroupsList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "groups/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathGroupList,
+		},
+
+		HelpSynopsis:    pathGroupsListHelpSyn,
+		HelpDescription: pathGroupsListHelpDesc,
+	}
+}
+
 func pathGroups(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `groups/(?P<name>.+)`,
@@ -103,6 +116,16 @@ type GroupEntry struct {
 	Policies []string
 }
 
+func (b *backend) pathGroupList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("group/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathGroupHelpSyn = `
 Manage users allowed to authenticate.
 `
@@ -116,3 +139,12 @@ Deleting a group will not revoke auth for prior authenticated users in that
 group. To do this, do a revoke on "login/<username>" for
 the usernames you want revoked.
 `
+
+const pathGroupsListHelpSyn = `
+List the existing groups.
+`
+
+const pathGroupsListHelpDesc = `
+This path lists the names of the LDAP groups that have been written
+to this backend. Details of each entry can be read at "groups/<name>".
+`
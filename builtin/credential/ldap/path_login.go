@@ -38,13 +38,19 @@ func (b *backend) pathLogin(
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
 
-	policies, resp, err := b.Login(req, username, password)
+	policies, groupNames, resp, err := b.Login(req, username, password)
 	if len(policies) == 0 {
 		return resp, err
 	}
 
 	sort.Strings(policies)
 
+	// Report the groups of the user for external identity groups
+	var groupAliases []*logical.Alias
+	for _, name := range groupNames {
+		groupAliases = append(groupAliases, &logical.Alias{Name: name})
+	}
+
 	return &logical.Response{
 		Auth: &logical.Auth{
 			Policies: policies,
@@ -56,6 +62,10 @@ func (b *backend) pathLogin(
 				"password": password,
 			},
 			DisplayName: username,
+			Alias: &logical.Alias{
+				Name: username,
+			},
+			GroupAliases: groupAliases,
 		},
 	}, nil
 }
@@ -67,7 +77,7 @@ func (b *backend) pathLoginRenew(
 	password := req.Auth.InternalData["password"].(string)
 	prevpolicies := req.Auth.Metadata["policies"]
 
-	policies, resp, err := b.Login(req, username, password)
+	policies, _, resp, err := b.Login(req, username, password)
 	if len(policies) == 0 {
 		return resp, err
 	}
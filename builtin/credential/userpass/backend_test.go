@@ -2,6 +2,7 @@ package userpass
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -29,6 +30,7 @@ func TestBackend_userCrud(t *testing.T) {
 		Steps: []logicaltest.TestStep{
 			testAccStepUser(t, "web", "password", "foo"),
 			testAccStepReadUser(t, "web", "foo"),
+			testAccStepListUsers(t, []string{"web"}),
 			testAccStepDeleteUser(t, "web"),
 			testAccStepReadUser(t, "web", ""),
 		},
@@ -95,3 +97,17 @@ func testAccStepReadUser(t *testing.T, name string, policies string) logicaltest
 		},
 	}
 }
+
+func testAccStepListUsers(t *testing.T, users []string) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.ListOperation,
+		Path:      "users/",
+		Check: func(resp *logical.Response) error {
+			if !reflect.DeepEqual(resp.Data["keys"], users) {
+				return fmt.Errorf("bad: %#v", resp)
+			}
+
+			return nil
+		},
+	}
+}
@@ -68,6 +68,9 @@ func (b *backend) pathLogin(
 				"username": username,
 			},
 			DisplayName: username,
+			Alias: &logical.Alias{
+				Name: username,
+			},
 		},
 	}, nil
 }
@@ -8,6 +8,19 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+func pathUsersList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathUserList,
+		},
+
+		HelpSynopsis:    pathUsersListHelpSyn,
+		HelpDescription: pathUsersListHelpDesc,
+	}
+}
+
 func pathUsers(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `users/(?P<name>\w+)`,
@@ -125,6 +138,16 @@ type UserEntry struct {
 	Policies []string
 }
 
+func (b *backend) pathUserList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("user/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathUserHelpSyn = `
 Manage users allowed to authenticate.
 `
@@ -138,3 +161,12 @@ with that name. To do this, do a revoke on "login/<username>" for
 the username you want revoked. If you don't need to revoke login immediately,
 then the next renew will cause the lease to expire.
 `
+
+const pathUsersListHelpSyn = `
+List the existing users.
+`
+
+const pathUsersListHelpDesc = `
+This path lists the names of the users that have been written
+to this backend. Details of each entry can be read at "users/<name>".
+`
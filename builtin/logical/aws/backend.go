@@ -26,6 +26,7 @@ func Backend() *framework.Backend {
 		Paths: []*framework.Path{
 			pathConfigRoot(),
 			pathConfigLease(&b),
+			pathListRoles(),
 			pathRoles(),
 			pathUser(&b),
 		},
@@ -9,6 +9,19 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathListRoles() *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
 func pathRoles() *framework.Path {
 	return &framework.Path{
 		Pattern: `roles/(?P<name>\w+)`,
@@ -82,6 +95,16 @@ func pathRolesWrite(
 	return nil, nil
 }
 
+func pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("policy/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathRolesHelpSyn = `
 Read and write IAM policies that access keys can be made for.
 `
@@ -97,3 +120,12 @@ The policies written are normal IAM policies. Vault will not attempt to
 parse these except to validate that they're basic JSON. To validate the
 keys, attempt to read an access key after writing the policy.
 `
+
+const pathListRolesHelpSyn = `
+List the existing roles.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the IAM policies that have been written
+to this backend. Details of each entry can be read at "roles/<name>".
+`
@@ -29,6 +29,7 @@ func Backend() *framework.Backend {
 
 		Paths: []*framework.Path{
 			pathConfigConnection(&b),
+			pathListRoles(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
 		},
@@ -9,11 +9,34 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const (
 	defaultCreationCQL = `CREATE USER '{{username}}' WITH PASSWORD '{{password}}' NOSUPERUSER;`
 	defaultRollbackCQL = `DROP USER '{{username}}';`
 )
 
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
 func pathRoles(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "roles/(?P<name>\\w+)",
@@ -197,3 +220,12 @@ instance of Cassandra:
 "lease" and "lease_grace_period" control the lease time and the allowed grace
 period past lease expiration, respectively.
 `
+
+const pathListRolesHelpSyn = `
+List the existing roles.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the roles that have been written
+to this backend. Details of each entry can be read at "roles/<name>".
+`
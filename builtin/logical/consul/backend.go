@@ -20,6 +20,7 @@ func Backend() *framework.Backend {
 
 		Paths: []*framework.Path{
 			pathConfigAccess(),
+			pathListRoles(),
 			pathRoles(),
 			pathToken(&b),
 		},
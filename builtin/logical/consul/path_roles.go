@@ -9,6 +9,19 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathListRoles() *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
 func pathRoles() *framework.Path {
 	return &framework.Path{
 		Pattern: `roles/(?P<name>\w+)`,
@@ -101,7 +114,26 @@ func pathRolesDelete(
 	return nil, nil
 }
 
+func pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("policy/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
 type roleConfig struct {
 	Policy string        `json:"policy"`
 	Lease  time.Duration `json:"lease"`
 }
+
+const pathListRolesHelpSyn = `
+List the existing roles.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the roles that have been written to this
+backend, each holding a Consul ACL policy. Details of each entry can be
+read at "roles/<name>".
+`
@@ -36,6 +36,7 @@ func Backend() *framework.Backend {
 		},
 
 		Paths: []*framework.Path{
+			pathListRoles(&b),
 			pathRoles(&b),
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
@@ -9,6 +9,19 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
 func pathRoles(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `roles/(?P<name>\w[\w-]+\w)`,
@@ -267,6 +280,16 @@ type roleEntry struct {
 	KeyBits               int    `json:"key_bits" structs:"key_bits" mapstructure:"key_bits"`
 }
 
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathRoleHelpSyn = `
 Manage the roles that can be created with this backend.
 `
@@ -274,3 +297,12 @@ Manage the roles that can be created with this backend.
 const pathRoleHelpDesc = `
 This path lets you manage the roles that can be created with this backend.
 `
+
+const pathListRolesHelpSyn = `
+List the existing roles.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the roles that have been written
+to this backend. Details of each entry can be read at "roles/<name>".
+`
@@ -28,6 +28,7 @@ func Backend() *framework.Backend {
 		Paths: []*framework.Path{
 			pathConfigConnection(&b),
 			pathConfigLease(&b),
+			pathListRoles(&b),
 			pathRoles(&b),
 			pathRoleCreate(&b),
 		},
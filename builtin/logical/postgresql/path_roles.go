@@ -8,6 +8,19 @@ import (
 	_ "github.com/lib/pq"
 )
 
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
 func pathRoles(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "roles/(?P<name>\\w+)",
@@ -121,6 +134,16 @@ type roleEntry struct {
 	SQL string `json:"sql"`
 }
 
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const pathRoleHelpSyn = `
 Manage the roles that can be created with this backend.
 `
@@ -150,3 +173,12 @@ Example of a decent SQL query to use:
 Note the above user would be able to access everything. In schema dc1.
 For more complex GRANT clauses, see the PostgreSQL manuel.
 `
+
+const pathListRolesHelpSyn = `
+List the existing roles.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the roles that have been written
+to this backend. Details of each entry can be read at "roles/<name>".
+`
@@ -20,6 +20,7 @@ func Backend() *framework.Backend {
 		},
 
 		Paths: []*framework.Path{
+			pathListKeys(),
 			pathKeys(),
 			pathRaw(),
 			pathEncrypt(),
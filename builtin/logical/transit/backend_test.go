@@ -3,6 +3,7 @@ package transit
 import (
 	"encoding/base64"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -22,6 +23,7 @@ func TestBackend_basic(t *testing.T) {
 			testAccStepWritePolicy(t, "test", false),
 			testAccStepReadPolicy(t, "test", false, false),
 			testAccStepReadRaw(t, "test", false, false),
+			testAccStepListPolicy(t, []string{"test"}),
 			testAccStepEncrypt(t, "test", testPlaintext, decryptData),
 			testAccStepDecrypt(t, "test", testPlaintext, decryptData),
 			testAccStepDeletePolicy(t, "test"),
@@ -246,3 +248,16 @@ func testAccStepDecrypt(
 		},
 	}
 }
+
+func testAccStepListPolicy(t *testing.T, names []string) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.ListOperation,
+		Path:      "keys/",
+		Check: func(resp *logical.Response) error {
+			if !reflect.DeepEqual(resp.Data["keys"], names) {
+				return fmt.Errorf("bad: %#v", resp)
+			}
+			return nil
+		},
+	}
+}
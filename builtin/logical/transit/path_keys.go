@@ -10,6 +10,16 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+func pathPolicyList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("policy/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
 const (
 	// kdfMode is the only KDF mode currently supported
 	kdfMode = "hmac-sha256-counter"
@@ -121,6 +131,19 @@ func generatePolicy(storage logical.Storage, name string, derived bool) (*Policy
 	return p, nil
 }
 
+func pathListKeys() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: pathPolicyList,
+		},
+
+		HelpSynopsis:    pathListKeysHelpSyn,
+		HelpDescription: pathListKeysHelpDesc,
+	}
+}
+
 func pathKeys() *framework.Path {
 	return &framework.Path{
 		Pattern: `keys/(?P<name>\w+)`,
@@ -209,3 +232,12 @@ This path is used to manage the named keys that are available.
 Doing a write with no value against a new named key will create
 it using a randomly generated key.
 `
+
+const pathListKeysHelpSyn = `
+List the existing keys.
+`
+
+const pathListKeysHelpDesc = `
+This path lists the names of the named encryption keys that have been written
+to this backend. Details of each entry can be read at "keys/<name>".
+`
@@ -108,6 +108,12 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"auth-remount": func() (cli.Command, error) {
+			return &command.AuthRemountCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"audit-list": func() (cli.Command, error) {
 			return &command.AuditListCommand{
 				Meta: meta,
@@ -150,6 +156,12 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"list": func() (cli.Command, error) {
+			return &command.ListCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"read": func() (cli.Command, error) {
 			return &command.ReadCommand{
 				Meta: meta,
@@ -174,6 +186,18 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"lease-list": func() (cli.Command, error) {
+			return &command.LeaseListCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"lease-lookup": func() (cli.Command, error) {
+			return &command.LeaseLookupCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"renew": func() (cli.Command, error) {
 			return &command.RenewCommand{
 				Meta: meta,
@@ -11,10 +11,11 @@ type AuthEnableCommand struct {
 }
 
 func (c *AuthEnableCommand) Run(args []string) int {
-	var description, path string
+	var description, path, pluginName string
 	flags := c.Meta.FlagSet("auth-enable", FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
 	flags.StringVar(&path, "path", "", "")
+	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -30,9 +31,13 @@ func (c *AuthEnableCommand) Run(args []string) int {
 
 	authType := args[0]
 
-	// If no path is specified, we default the path to the backend type
+	// If no path is specified, we default the path to the backend type,
+	// or to the name of the plugin for plugin backends
 	if path == "" {
 		path = authType
+		if authType == "plugin" && pluginName != "" {
+			path = pluginName
+		}
 	}
 
 	client, err := c.Client()
@@ -42,7 +47,12 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		return 2
 	}
 
-	if err := client.Sys().EnableAuth(path, authType, description); err != nil {
+	if authType == "plugin" {
+		err = client.Sys().EnableAuthPlugin(path, pluginName, description)
+	} else {
+		err = client.Sys().EnableAuth(path, authType, description)
+	}
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error: %s", err))
 		return 2
@@ -80,7 +90,11 @@ Auth Enable Options:
 
   -path=<path>            Mount point for the auth provider. This defaults
                           to the type of the mount. This will make the auth
-                          provider available at "/auth/<path>"
+                          provider available at "/auth/<path>". For the
+                          "plugin" type, this defaults to the plugin name.
+
+  -plugin-name=<name>     Name of the plugin in the plugin catalog to enable,
+                          required for the "plugin" type.
 
 `
 	return strings.TrimSpace(helpText)
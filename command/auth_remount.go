@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthRemountCommand is a Command that remounts an enabled credential
+// backend to a new endpoint.
+type AuthRemountCommand struct {
+	Meta
+}
+
+func (c *AuthRemountCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("auth-remount", FlagSetDefault)
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 2 {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nauth-remount expects two arguments: the from and to path"))
+		return 1
+	}
+
+	from := args[0]
+	to := args[1]
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	if err := client.Sys().RemountAuth(from, to); err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Remount error: %s", err))
+		return 2
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		"Successfully remounted auth from '%s' to '%s'!", from, to))
+
+	return 0
+}
+
+func (c *AuthRemountCommand) Synopsis() string {
+	return "Remount an auth provider to a new path"
+}
+
+func (c *AuthRemountCommand) Help() string {
+	helpText := `
+Usage: vault auth-remount [options] from to
+
+  Remount an enabled auth provider to a new path.
+
+  This command remounts an auth provider that is already enabled to
+  a new path. The tokens issued by the provider and the Vault data
+  associated with it (such as configuration data) are preserved, and
+  the tokens can be revoked by the prefix of the new path.
+
+  Example: vault auth-remount github-old/ github/
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
@@ -22,6 +22,24 @@ func OutputSecret(ui cli.Ui, format string, secret *api.Secret) int {
 	}
 }
 
+// OutputList outputs the keys of a list response, one per line in the
+// table format
+func OutputList(ui cli.Ui, format string, secret *api.Secret) int {
+	switch format {
+	case "json":
+		return outputFormatJSON(ui, secret)
+	case "table":
+		fallthrough
+	default:
+		keys, _ := secret.Data["keys"].([]interface{})
+		ui.Output("Keys\n----")
+		for _, k := range keys {
+			ui.Output(fmt.Sprintf("%v", k))
+		}
+		return 0
+	}
+}
+
 func outputFormatJSON(ui cli.Ui, s *api.Secret) int {
 	b, err := json.Marshal(s)
 	if err != nil {
@@ -53,8 +71,15 @@ func outputFormatTable(ui cli.Ui, s *api.Secret, whitespace bool) int {
 			"lease_renewable %s %s", config.Delim, strconv.FormatBool(s.Renewable)))
 	}
 
+	if s.WrapInfo != nil {
+		input = append(input, fmt.Sprintf("wrapping_token %s %s", config.Delim, s.WrapInfo.Token))
+		input = append(input, fmt.Sprintf("wrapping_token_ttl %s %d", config.Delim, s.WrapInfo.TTL))
+		input = append(input, fmt.Sprintf("wrapping_token_creation_time %s %s", config.Delim, s.WrapInfo.CreationTime))
+	}
+
 	if s.Auth != nil {
 		input = append(input, fmt.Sprintf("token %s %s", config.Delim, s.Auth.ClientToken))
+		input = append(input, fmt.Sprintf("token_accessor %s %s", config.Delim, s.Auth.Accessor))
 		input = append(input, fmt.Sprintf("token_duration %s %d", config.Delim, s.Auth.LeaseDuration))
 		input = append(input, fmt.Sprintf("token_renewable %s %v", config.Delim, s.Auth.Renewable))
 		input = append(input, fmt.Sprintf("token_policies %s %v", config.Delim, s.Auth.Policies))
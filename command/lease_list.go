@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// LeaseListCommand is a Command that lists the lease IDs under a prefix.
+type LeaseListCommand struct {
+	Meta
+}
+
+func (c *LeaseListCommand) Run(args []string) int {
+	var irrevocable bool
+	flags := c.Meta.FlagSet("lease-list", FlagSetDefault)
+	flags.BoolVar(&irrevocable, "irrevocable", false, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) > 1 {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nlease-list expects at most one argument: the prefix to list"))
+		return 1
+	}
+
+	var prefix string
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+	if irrevocable && prefix != "" {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nA prefix cannot be given with -irrevocable"))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	if irrevocable {
+		return c.listIrrevocable(client)
+	}
+
+	keys, err := client.Sys().ListLeases(prefix)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error listing leases: %s", err))
+		return 1
+	}
+
+	for _, k := range keys {
+		c.Ui.Output(k)
+	}
+
+	return 0
+}
+
+// listIrrevocable outputs the leases that could not be revoked, along
+// with the error of the last revocation attempt
+func (c *LeaseListCommand) listIrrevocable(client *api.Client) int {
+	secret, err := client.Sys().ListIrrevocableLeases()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error listing irrevocable leases: %s", err))
+		return 1
+	}
+	if secret == nil || secret.Data == nil {
+		return 0
+	}
+
+	leases, _ := secret.Data["leases"].([]interface{})
+	for _, raw := range leases {
+		lease, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c.Ui.Output(fmt.Sprintf("%v\t%v", lease["id"], lease["last_revoke_error"]))
+	}
+	return 0
+}
+
+func (c *LeaseListCommand) Synopsis() string {
+	return "List the lease IDs under a prefix"
+}
+
+func (c *LeaseListCommand) Help() string {
+	helpText := `
+Usage: vault lease-list [options] [prefix]
+
+  List the lease IDs directly under a prefix, such as "aws/creds/deploy/".
+  Nested prefixes are listed with a trailing slash. Without a prefix, the
+  top level is listed.
+
+  This requires a token with sudo access to sys/leases/lookup/.
+
+  With the -irrevocable flag, the leases that could not be revoked
+  automatically are listed instead, along with the error of the last
+  revocation attempt. These can be deleted with "vault revoke -prefix
+  -force".
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Lease List Options:
+
+  -irrevocable=true       List the leases that could not be revoked. This
+                          defaults to false.
+`
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LeaseLookupCommand is a Command that shows the metadata of a lease.
+type LeaseLookupCommand struct {
+	Meta
+}
+
+func (c *LeaseLookupCommand) Run(args []string) int {
+	var format string
+	flags := c.Meta.FlagSet("lease-lookup", FlagSetDefault)
+	flags.StringVar(&format, "format", "table", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nlease-lookup expects one argument: the lease ID to look up"))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	secret, err := client.Sys().LookupLease(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error looking up lease: %s", err))
+		return 1
+	}
+
+	return OutputSecret(c.Ui, format, secret)
+}
+
+func (c *LeaseLookupCommand) Synopsis() string {
+	return "Show the metadata of a lease"
+}
+
+func (c *LeaseLookupCommand) Help() string {
+	helpText := `
+Usage: vault lease-lookup [options] id
+
+  Show the metadata of a lease: when it was issued and expires, whether
+  it is renewable, and the accessor of the token that owns it. The data
+  of the secret itself is not shown.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Lease Lookup Options:
+
+  -format=table           The format for output. By default it is a whitespace-
+                          delimited table. This can also be json.
+`
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListCommand is a Command that lists the keys under a path of the Vault.
+type ListCommand struct {
+	Meta
+}
+
+func (c *ListCommand) Run(args []string) int {
+	var format string
+	flags := c.Meta.FlagSet("list", FlagSetDefault)
+	flags.StringVar(&format, "format", "table", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("list expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	path := args[0]
+	if path[0] == '/' {
+		path = path[1:]
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	secret, err := client.Logical().List(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error listing %s: %s", path, err))
+		return 1
+	}
+	if secret == nil || secret.Data["keys"] == nil {
+		c.Ui.Error(fmt.Sprintf(
+			"No entries found at %s", path))
+		return 2
+	}
+
+	return OutputList(c.Ui, format, secret)
+}
+
+func (c *ListCommand) Synopsis() string {
+	return "List data or secrets in Vault"
+}
+
+func (c *ListCommand) Help() string {
+	helpText := `
+Usage: vault list [options] path
+
+  List data from Vault.
+
+  Lists the keys under the given path, such as the secrets of the generic
+  backend or the roles of a backend. The keys ending with a slash are
+  prefixes which can be listed in turn. Please reference the documentation
+  for the backends in use to determine what can be listed.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -format=table           The format for output. By default it is a list of
+                          keys. This can also be json.
+
+`
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func TestList(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &ListCommand{
+		Meta: Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+
+	for _, path := range []string{"secret/foo", "secret/bar/baz"} {
+		req := &logical.Request{
+			Operation:   logical.WriteOperation,
+			Path:        path,
+			Data:        map[string]interface{}{"value": "zip"},
+			ClientToken: token,
+		}
+		if _, err := core.HandleRequest(req); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	args := []string{
+		"-address", addr,
+		"secret",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "foo\n") || !strings.Contains(output, "bar/\n") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestList_notFound(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &ListCommand{
+		Meta: Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+
+	args := []string{
+		"-address", addr,
+		"secret/nope",
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+}
@@ -29,6 +29,7 @@ const EnvVaultCAPath = "VAULT_CAPATH"
 const EnvVaultClientCert = "VAULT_CLIENT_CERT"
 const EnvVaultClientKey = "VAULT_CLIENT_KEY"
 const EnvVaultInsecure = "VAULT_SKIP_VERIFY"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
 
 // FlagSetFlags is an enum to define what flags are present in the
 // default FlagSet returned by Meta.FlagSet.
@@ -57,6 +58,7 @@ type Meta struct {
 	flagClientCert string
 	flagClientKey  string
 	flagInsecure   bool
+	flagNamespace  string
 
 	// These are internal and shouldn't be modified or access by anyone
 	// except Meta.
@@ -144,6 +146,12 @@ func (m *Meta) Client() (*api.Client, error) {
 		return nil, err
 	}
 
+	// Make the requests within the namespace if one is selected
+	if v := os.Getenv(EnvVaultNamespace); v != "" && m.flagNamespace == "" {
+		m.flagNamespace = v
+	}
+	client.SetNamespace(m.flagNamespace)
+
 	// If we have a token directly, then set that
 	token := m.ClientToken
 
@@ -209,6 +217,7 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 		f.StringVar(&m.flagClientKey, "client-key", "", "")
 		f.BoolVar(&m.flagInsecure, "insecure", false, "")
 		f.BoolVar(&m.flagInsecure, "tls-skip-verify", false, "")
+		f.StringVar(&m.flagNamespace, "namespace", "", "")
 	}
 
 	// Create an io.Writer that writes to our Ui properly for errors.
@@ -332,6 +341,10 @@ func generalOptionsUsage() string {
 
   -tls-skip-verify        Do not verify TLS certificate. This is highly
                           not recommended.
+
+  -namespace=path         The namespace in which to make the requests. This
+                          can also be specified via the VAULT_NAMESPACE
+                          environment variable.
 	`
 	return strings.TrimSpace(general)
 }
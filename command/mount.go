@@ -11,10 +11,11 @@ type MountCommand struct {
 }
 
 func (c *MountCommand) Run(args []string) int {
-	var description, path string
+	var description, path, pluginName string
 	flags := c.Meta.FlagSet("mount", FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
 	flags.StringVar(&path, "path", "", "")
+	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -30,9 +31,13 @@ func (c *MountCommand) Run(args []string) int {
 
 	mountType := args[0]
 
-	// If no path is specified, we default the path to the backend type
+	// If no path is specified, we default the path to the backend type,
+	// or to the name of the plugin for plugin backends
 	if path == "" {
 		path = mountType
+		if mountType == "plugin" && pluginName != "" {
+			path = pluginName
+		}
 	}
 
 	client, err := c.Client()
@@ -42,7 +47,12 @@ func (c *MountCommand) Run(args []string) int {
 		return 2
 	}
 
-	if err := client.Sys().Mount(path, mountType, description); err != nil {
+	if mountType == "plugin" {
+		err = client.Sys().MountPlugin(path, pluginName, description)
+	} else {
+		err = client.Sys().Mount(path, mountType, description)
+	}
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Mount error: %s", err))
 		return 2
@@ -78,7 +88,11 @@ Mount Options:
                           mount. This shows up in the mounts command.
 
   -path=<path>            Mount point for the logical backend. This defaults
-                          to the type of the mount.
+                          to the type of the mount, or to the plugin name
+                          for the "plugin" type.
+
+  -plugin-name=<name>     Name of the plugin in the plugin catalog to mount,
+                          required for the "plugin" type.
 
 `
 	return strings.TrimSpace(helpText)
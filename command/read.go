@@ -12,10 +12,11 @@ type ReadCommand struct {
 
 func (c *ReadCommand) Run(args []string) int {
 	var format string
-	var field string
+	var field, wrapTTL string
 	flags := c.Meta.FlagSet("read", FlagSetDefault)
 	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&field, "field", "", "")
+	flags.StringVar(&wrapTTL, "wrap-ttl", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -39,6 +40,7 @@ func (c *ReadCommand) Run(args []string) int {
 			"Error initializing client: %s", err))
 		return 2
 	}
+	client.SetWrapTTL(wrapTTL)
 
 	secret, err := client.Logical().Read(path)
 	if err != nil {
@@ -94,6 +96,11 @@ Read Options:
   -field=field            If included, the raw value of the specified field
   						  will be output raw to stdout.
 
+  -wrap-ttl=""            If set, the response is wrapped in a single-use
+                          token with the given TTL, such as "5m". Only the
+                          wrapping token is returned; the original response
+                          can be retrieved through sys/wrapping/unwrap.
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -11,9 +11,10 @@ type RevokeCommand struct {
 }
 
 func (c *RevokeCommand) Run(args []string) int {
-	var prefix bool
+	var prefix, force bool
 	flags := c.Meta.FlagSet("revoke", FlagSetDefault)
 	flags.BoolVar(&prefix, "prefix", false, "")
+	flags.BoolVar(&force, "force", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -28,6 +29,13 @@ func (c *RevokeCommand) Run(args []string) int {
 	}
 	leaseId := args[0]
 
+	if force && !prefix {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nThe -force flag can only be used with -prefix"))
+		return 1
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -35,7 +43,9 @@ func (c *RevokeCommand) Run(args []string) int {
 		return 2
 	}
 
-	if prefix {
+	if force {
+		err = client.Sys().RevokeForce(leaseId)
+	} else if prefix {
 		err = client.Sys().RevokePrefix(leaseId)
 	} else {
 		err = client.Sys().Revoke(leaseId)
@@ -67,6 +77,10 @@ Usage: vault revoke [options] id
   with the given partial ID is revoked. Lease IDs are structured in such
   a way to make revocation of prefixes useful.
 
+  With the -force flag, the leases under the prefix are deleted without
+  revoking the secrets through their backends. This is meant to clean up
+  leases that can no longer be revoked, and the secrets may remain valid.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -76,6 +90,10 @@ Revoke Options:
   -prefix=true            Revoke all secrets with the matching prefix. This
                           defaults to false: an exact revocation.
 
+  -force=true             Delete the leases with the matching prefix without
+                          revoking them through their backends. This requires
+                          -prefix and defaults to false.
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -132,6 +132,9 @@ func (c *ServerCommand) Run(args []string) int {
 		LogicalBackends:    c.LogicalBackends,
 		Logger:             logger,
 		DisableMlock:       config.DisableMlock,
+		MaxLeaseTTL:        config.MaxLeaseTTL,
+		DefaultLeaseTTL:    config.DefaultLeaseTTL,
+		PluginDirectory:    config.PluginDirectory,
 	})
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error initializing core: %s", err))
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl"
 	hclobj "github.com/hashicorp/hcl/hcl"
@@ -20,6 +21,13 @@ type Config struct {
 	DisableMlock bool   `hcl:"disable_mlock"`
 	StatsiteAddr string `hcl:"statsite_addr"`
 	StatsdAddr   string `hcl:"statsd_addr"`
+
+	MaxLeaseTTL        time.Duration `hcl:"-"`
+	MaxLeaseTTLRaw     string        `hcl:"max_lease_ttl"`
+	DefaultLeaseTTL    time.Duration `hcl:"-"`
+	DefaultLeaseTTLRaw string        `hcl:"default_lease_ttl"`
+
+	PluginDirectory string `hcl:"plugin_directory"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -85,6 +93,20 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.StatsdAddr = c2.StatsdAddr
 	}
 
+	result.MaxLeaseTTL = c.MaxLeaseTTL
+	if c2.MaxLeaseTTL > 0 {
+		result.MaxLeaseTTL = c2.MaxLeaseTTL
+	}
+	result.DefaultLeaseTTL = c.DefaultLeaseTTL
+	if c2.DefaultLeaseTTL > 0 {
+		result.DefaultLeaseTTL = c2.DefaultLeaseTTL
+	}
+
+	result.PluginDirectory = c.PluginDirectory
+	if c2.PluginDirectory != "" {
+		result.PluginDirectory = c2.PluginDirectory
+	}
+
 	return result
 }
 
@@ -123,6 +145,17 @@ func LoadConfigFile(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if result.MaxLeaseTTLRaw != "" {
+		if result.MaxLeaseTTL, err = time.ParseDuration(result.MaxLeaseTTLRaw); err != nil {
+			return nil, fmt.Errorf("Error parsing max_lease_ttl: %s", err)
+		}
+	}
+	if result.DefaultLeaseTTLRaw != "" {
+		if result.DefaultLeaseTTL, err = time.ParseDuration(result.DefaultLeaseTTLRaw); err != nil {
+			return nil, fmt.Errorf("Error parsing default_lease_ttl: %s", err)
+		}
+	}
+
 	if objs := obj.Get("listener", false); objs != nil {
 		result.Listeners, err = loadListeners(objs)
 		if err != nil {
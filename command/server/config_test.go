@@ -3,6 +3,7 @@ package server
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadConfigFile(t *testing.T) {
@@ -32,6 +33,13 @@ func TestLoadConfigFile(t *testing.T) {
 		DisableMlock: true,
 		StatsiteAddr: "foo",
 		StatsdAddr:   "bar",
+
+		MaxLeaseTTL:        10 * time.Hour,
+		MaxLeaseTTLRaw:     "10h",
+		DefaultLeaseTTL:    10 * time.Hour,
+		DefaultLeaseTTLRaw: "10h",
+
+		PluginDirectory: "/etc/vault/plugins",
 	}
 	if !reflect.DeepEqual(config, expected) {
 		t.Fatalf("bad: %#v", config)
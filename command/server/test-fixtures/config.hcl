@@ -1,6 +1,9 @@
 disable_mlock = true
 statsd_addr = "bar"
 statsite_addr = "foo"
+max_lease_ttl = "10h"
+default_lease_ttl = "10h"
+plugin_directory = "/etc/vault/plugins"
 
 listener "tcp" {
     address = "127.0.0.1:443"
@@ -16,19 +16,26 @@ type TokenCreateCommand struct {
 
 func (c *TokenCreateCommand) Run(args []string) int {
 	var format string
-	var displayName, lease string
-	var orphan bool
+	var displayName, lease, period, explicitMaxTTL, role, tokenType, wrapTTL string
+	var orphan, noDefaultPolicy bool
 	var metadata map[string]string
 	var numUses int
-	var policies []string
+	var policies, boundCIDRs []string
 	flags := c.Meta.FlagSet("mount", FlagSetDefault)
 	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&displayName, "display-name", "", "")
 	flags.StringVar(&lease, "lease", "", "")
+	flags.StringVar(&period, "period", "", "")
+	flags.StringVar(&explicitMaxTTL, "explicit-max-ttl", "", "")
+	flags.StringVar(&role, "role", "", "")
+	flags.StringVar(&tokenType, "type", "", "")
+	flags.StringVar(&wrapTTL, "wrap-ttl", "", "")
 	flags.BoolVar(&orphan, "orphan", false, "")
+	flags.BoolVar(&noDefaultPolicy, "no-default-policy", false, "")
 	flags.IntVar(&numUses, "use-limit", 0, "")
 	flags.Var((*kvFlag.Flag)(&metadata), "metadata", "")
 	flags.Var((*sliceflag.StringFlag)(&policies), "policy", "")
+	flags.Var((*sliceflag.StringFlag)(&boundCIDRs), "bound-cidr", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -48,15 +55,28 @@ func (c *TokenCreateCommand) Run(args []string) int {
 			"Error initializing client: %s", err))
 		return 2
 	}
+	client.SetWrapTTL(wrapTTL)
+
+	tcr := &api.TokenCreateRequest{
+		Policies:        policies,
+		Metadata:        metadata,
+		Lease:           lease,
+		Period:          period,
+		ExplicitMaxTTL:  explicitMaxTTL,
+		NoParent:        orphan,
+		NoDefaultPolicy: noDefaultPolicy,
+		DisplayName:     displayName,
+		NumUses:         numUses,
+		BoundCIDRs:      boundCIDRs,
+		Type:            tokenType,
+	}
 
-	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
-		Policies:    policies,
-		Metadata:    metadata,
-		Lease:       lease,
-		NoParent:    orphan,
-		DisplayName: displayName,
-		NumUses:     numUses,
-	})
+	var secret *api.Secret
+	if role != "" {
+		secret, err = client.Auth().Token().CreateWithRole(tcr, role)
+	} else {
+		secret, err = client.Auth().Token().Create(tcr)
+	}
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error creating token: %s", err))
@@ -92,26 +112,56 @@ General Options:
 
 Token Options:
 
+  -bound-cidr="cidr"      If specified, the token can only be used by clients
+                          within the CIDR block. This can be specified
+                          multiple times.
+
   -display-name="name"    A display name to associate with this token. This
                           is a non-security sensitive value used to help
                           identify created secrets, i.e. prefixes.
 
+  -explicit-max-ttl="24h" If specified, the token can never be renewed past
+                          this lifetime, regardless of the max lease TTL
+                          of the token backend.
+
   -lease="1h"             Lease to associate with the token.
 
   -metadata="key=value"   Metadata to associate with the token. This shows
                           up in the audit log. This can be specified multiple
                           times.
 
+  -no-default-policy      If specified, the token will not have the "default"
+                          policy included in its policy set.
+
   -orphan                 If specified, the token will have no parent. Only
                           root tokens can create orphan tokens. This prevents
                           the new token from being revoked with your token.
 
+  -period="1h"            If specified, the token will be periodic. It will
+                          have no maximum lifetime, and every renewal will
+                          set its lease to exactly the period. Only root
+                          tokens can create periodic tokens without a role.
+
   -policy="name"          Policy to associate with this token. This can be
                           specified multiple times.
 
+  -role="name"            If set, the token will be created against the named
+                          role. The role may restrict or override the
+                          policies, orphan behavior and lease of the token.
+
+  -type="batch"           The type of the token, either "service" (the default)
+                          or "batch". Batch tokens are not persisted, cannot
+                          be renewed or revoked, and cannot create child
+                          tokens. They expire purely from their lease.
+
   -use-limit=5            The number of times this token can be used until
                           it is automatically revoked.
 
+  -wrap-ttl=""            If set, the response is wrapped in a single-use
+                          token with the given TTL, such as "5m". Only the
+                          wrapping token is returned; the original response
+                          can be retrieved through sys/wrapping/unwrap.
+
   -format=table           The format for output. By default it is a whitespace-
                           delimited table. This can also be json.
 
@@ -44,6 +44,8 @@ func (c *TokenRevokeCommand) Run(args []string) int {
 		fn = client.Auth().Token().RevokeOrphan
 	case "path":
 		fn = client.Auth().Token().RevokePrefix
+	case "accessor":
+		fn = client.Auth().Token().RevokeAccessor
 	default:
 		c.Ui.Error(fmt.Sprintf(
 			"Unknown revocation mode: %s", mode))
@@ -86,6 +88,10 @@ Usage: vault token-revoke [options] token
       prefix will be deleted, along with all their children. In this case
       the "token" arg above is actually a "path".
 
+    * With the "accessor" value, the token referenced by the given
+      accessor and all of its children will be revoked. In this case
+      the "token" arg above is actually an "accessor".
+
 General Options:
 
   ` + generalOptionsUsage() + `
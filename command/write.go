@@ -18,12 +18,13 @@ type WriteCommand struct {
 }
 
 func (c *WriteCommand) Run(args []string) int {
-	var format string
+	var format, wrapTTL string
 	var force bool
 	flags := c.Meta.FlagSet("write", FlagSetDefault)
 	flags.StringVar(&format, "format", "table", "")
 	flags.BoolVar(&force, "force", false, "")
 	flags.BoolVar(&force, "f", false, "")
+	flags.StringVar(&wrapTTL, "wrap-ttl", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -54,6 +55,7 @@ func (c *WriteCommand) Run(args []string) int {
 			"Error initializing client: %s", err))
 		return 2
 	}
+	client.SetWrapTTL(wrapTTL)
 
 	secret, err := client.Logical().Write(path, data)
 	if err != nil {
@@ -115,6 +117,11 @@ Write Options:
                           specified. This allows writing to keys that do not
                           need or expect any fields to be specified.
 
+  -wrap-ttl=""            If set, the response is wrapped in a single-use
+                          token with the given TTL, such as "5m". Only the
+                          wrapping token is returned; the original response
+                          can be retrieved through sys/wrapping/unwrap.
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
@@ -17,6 +19,15 @@ const AuthCookieName = "token"
 // AuthHeaderName is the name of the header containing the token.
 const AuthHeaderName = "X-Vault-Token"
 
+// WrapTTLHeaderName is the name of the header requesting that the
+// response is wrapped with the given TTL.
+const WrapTTLHeaderName = "X-Vault-Wrap-TTL"
+
+// NamespaceHeaderName is the name of the header selecting the namespace
+// of the request. It is the same as prefixing the path of the request
+// with the path of the namespace.
+const NamespaceHeaderName = "X-Vault-Namespace"
+
 // Handler returns an http.Handler for the API. This can be used on
 // its own to mount the Vault API within another web server.
 func Handler(core *vault.Core) http.Handler {
@@ -34,8 +45,21 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/renew/", handleSysRenew(core))
 	mux.Handle("/v1/sys/revoke/", handleSysRevoke(core))
 	mux.Handle("/v1/sys/revoke-prefix/", handleSysRevokePrefix(core))
+	mux.Handle("/v1/sys/revoke-force/", handleSysRevokeForce(core))
+	mux.Handle("/v1/sys/leases/lookup", handleSysLeaseLookup(core))
+	mux.Handle("/v1/sys/leases/lookup/", handleSysLeaseList(core))
+	mux.Handle("/v1/sys/leases/irrevocable", handleSysLeaseIrrevocable(core))
+	mux.Handle("/v1/sys/quotas/lease-count", handleSysListQuotas(core, "lease-count"))
+	mux.Handle("/v1/sys/quotas/lease-count/", handleSysQuota(core, "lease-count"))
+	mux.Handle("/v1/sys/quotas/rate-limit", handleSysListQuotas(core, "rate-limit"))
+	mux.Handle("/v1/sys/quotas/rate-limit/", handleSysQuota(core, "rate-limit"))
+	mux.Handle("/v1/sys/namespaces", handleSysListNamespaces(core))
+	mux.Handle("/v1/sys/namespaces/", handleSysNamespace(core))
+	mux.Handle("/v1/sys/plugins/catalog", handleSysListPlugins(core))
+	mux.Handle("/v1/sys/plugins/catalog/", handleSysPlugin(core))
 	mux.Handle("/v1/sys/auth", handleSysListAuth(core))
 	mux.Handle("/v1/sys/auth/", handleSysAuth(core))
+	mux.Handle("/v1/sys/auth/remount", handleSysAuthRemount(core))
 	mux.Handle("/v1/sys/audit", handleSysListAudit(core))
 	mux.Handle("/v1/sys/audit/", handleSysAudit(core))
 	mux.Handle("/v1/sys/leader", handleSysLeader(core))
@@ -44,11 +68,22 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/key-status", handleSysKeyStatus(core))
 	mux.Handle("/v1/sys/rekey/init", handleSysRekeyInit(core))
 	mux.Handle("/v1/sys/rekey/update", handleSysRekeyUpdate(core))
+	mux.Handle("/v1/sys/control-group/lookup", handleSysControlGroupLookup(core))
+	mux.Handle("/v1/sys/control-group/authorize", handleSysControlGroupAuthorize(core))
+	mux.Handle("/v1/sys/control-group/request", handleSysControlGroupRequest(core))
+	mux.Handle("/v1/sys/wrapping/unwrap", handleSysWrappingUnwrap(core))
+	mux.Handle("/v1/sys/wrapping/lookup", handleSysWrappingLookup(core))
+	mux.Handle("/v1/sys/wrapping/rewrap", handleSysWrappingRewrap(core))
+	mux.Handle("/v1/identity/oidc/.well-known/openid-configuration", handleIdentityOIDCDiscovery(core))
+	mux.Handle("/v1/identity/oidc/.well-known/keys", handleIdentityOIDCKeys(core))
 	mux.Handle("/v1/", handleLogical(core))
 
 	// Wrap the handler in another handler to trigger all help paths.
 	handler := handleHelpHandler(mux, core)
 
+	// Reject the requests exceeding the rate limit quotas first
+	handler = handleRateLimitHandler(handler, core)
+
 	return handler
 }
 
@@ -147,9 +182,56 @@ func requestAuth(r *http.Request, req *logical.Request) *logical.Request {
 		req.ClientToken = v
 	}
 
+	// Make the request within the namespace if one is selected
+	if ns := requestNamespace(r); ns != "" {
+		req.Path = ns + "/" + req.Path
+	}
+
 	return req
 }
 
+// requestNamespace returns the path of the namespace selected by the
+// header of the request, if any
+func requestNamespace(r *http.Request) string {
+	return strings.Trim(r.Header.Get(NamespaceHeaderName), "/")
+}
+
+// requestCore returns the core of the namespace selected by the request,
+// for the endpoints which are not handled by a logical request
+func requestCore(core *vault.Core, r *http.Request) (*vault.Core, error) {
+	ns := requestNamespace(r)
+	if ns == "" {
+		return core, nil
+	}
+	return core.NamespaceCore(ns)
+}
+
+// requestWrapTTL adds the wrap TTL to the logical.Request if the
+// response should be wrapped. The TTL is given as a duration such
+// as "5m" or as a number of seconds.
+func requestWrapTTL(r *http.Request, req *logical.Request) error {
+	raw := r.Header.Get(WrapTTLHeaderName)
+	if raw == "" {
+		return nil
+	}
+
+	var ttl time.Duration
+	if secs, err := strconv.Atoi(raw); err == nil {
+		ttl = time.Duration(secs) * time.Second
+	} else {
+		ttl, err = time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid wrap TTL '%s'", raw)
+		}
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("wrap TTL must be positive")
+	}
+
+	req.WrapTTL = ttl
+	return nil
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	// Adjust status code when sealed
 	if err == vault.ErrSealed {
@@ -185,6 +267,8 @@ func respondCommon(w http.ResponseWriter, resp *logical.Response, err error) boo
 			statusCode = http.StatusNotFound
 		case logical.ErrInvalidRequest:
 			statusCode = http.StatusBadRequest
+		case logical.ErrQuotaExceeded:
+			statusCode = http.StatusTooManyRequests
 		default:
 			statusCode = http.StatusBadRequest
 		}
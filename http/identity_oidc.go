@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleIdentityOIDCDiscovery serves the OpenID Connect discovery
+// document. Like the key set, it is served without a client token so
+// that services can verify identity tokens without access to Vault.
+func handleIdentityOIDCDiscovery(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		doc, err := core.OIDCDiscovery()
+		if err != nil {
+			respondOIDCError(core, w, r, err)
+			return
+		}
+		respondOk(w, doc)
+	})
+}
+
+// handleIdentityOIDCKeys serves the JSON web key set identity tokens
+// are verified with.
+func handleIdentityOIDCKeys(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		keys, err := core.OIDCKeys()
+		if err != nil {
+			respondOIDCError(core, w, r, err)
+			return
+		}
+		respondOk(w, keys)
+	})
+}
+
+func respondOIDCError(core *vault.Core, w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case vault.ErrStandby:
+		respondStandby(core, w, r.URL)
+	default:
+		respondError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestIdentityOIDC_WellKnown(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	req := logical.TestRequest(t, logical.WriteOperation, "identity/oidc/config")
+	req.ClientToken = token
+	req.Data["issuer"] = "https://vault.example.com:8200"
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/oidc/key/foo")
+	req.ClientToken = token
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Neither endpoint requires a client token
+	client := &http.Client{}
+	resp, err := client.Get(addr + "/v1/identity/oidc/.well-known/openid-configuration")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var discovery map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &discovery)
+	issuer := "https://vault.example.com:8200/v1/identity/oidc"
+	if discovery["issuer"] != issuer || discovery["jwks_uri"] != issuer+"/.well-known/keys" {
+		t.Fatalf("bad: %#v", discovery)
+	}
+
+	resp, err = client.Get(addr + "/v1/identity/oidc/.well-known/keys")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var jwks map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &jwks)
+	keys := jwks["keys"].([]interface{})
+	if len(keys) != 1 {
+		t.Fatalf("bad: %#v", jwks)
+	}
+	if key := keys[0].(map[string]interface{}); key["kty"] != "RSA" || key["alg"] != "RS256" {
+		t.Fatalf("bad: %#v", key)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +32,11 @@ func handleLogical(core *vault.Core) http.Handler {
 			op = logical.DeleteOperation
 		case "GET":
 			op = logical.ReadOperation
+			if listRequested(r) {
+				op = logical.ListOperation
+			}
+		case "LIST":
+			op = logical.ListOperation
 		case "POST":
 			fallthrough
 		case "PUT":
@@ -40,6 +46,11 @@ func handleLogical(core *vault.Core) http.Handler {
 			return
 		}
 
+		// A list is of the keys under a prefix
+		if op == logical.ListOperation && !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+
 		// Parse the request if we can
 		var req map[string]interface{}
 		if op == logical.WriteOperation {
@@ -57,12 +68,17 @@ func handleLogical(core *vault.Core) http.Handler {
 		// Make the internal request. We attach the connection info
 		// as well in case this is an authentication request that requires
 		// it. Vault core handles stripping this if we need to.
-		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+		lreq := requestAuth(r, &logical.Request{
 			Operation:  op,
 			Path:       path,
 			Data:       req,
 			Connection: getConnection(r),
-		}))
+		})
+		if err := requestWrapTTL(r, lreq); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		resp, ok := request(core, w, r, lreq)
 		if !ok {
 			return
 		}
@@ -70,12 +86,24 @@ func handleLogical(core *vault.Core) http.Handler {
 			respondError(w, http.StatusNotFound, nil)
 			return
 		}
+		if op == logical.ListOperation && !listHasKeys(resp) {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
 
 		// Build the proper response
 		respondLogical(w, r, path, resp)
 	})
 }
 
+// listRequested checks if a GET request asks for a list operation with
+// the "list" query parameter, for the clients which cannot send the
+// LIST method
+func listRequested(r *http.Request) bool {
+	list, _ := strconv.ParseBool(r.URL.Query().Get("list"))
+	return list
+}
+
 func respondLogical(w http.ResponseWriter, r *http.Request, path string, resp *logical.Response) {
 	var httpResp interface{}
 	if resp != nil {
@@ -92,6 +120,19 @@ func respondLogical(w http.ResponseWriter, r *http.Request, path string, resp *l
 			return
 		}
 
+		// A wrapped response only contains the wrapping information
+		if resp.WrapInfo != nil {
+			respondOk(w, &LogicalResponse{
+				WrapInfo: &WrapInfo{
+					Token:        resp.WrapInfo.Token,
+					Accessor:     resp.WrapInfo.Accessor,
+					TTL:          int(resp.WrapInfo.TTL.Seconds()),
+					CreationTime: resp.WrapInfo.CreationTime,
+				},
+			})
+			return
+		}
+
 		logicalResp := &LogicalResponse{Data: resp.Data}
 		if resp.Secret != nil {
 			logicalResp.LeaseID = resp.Secret.LeaseID
@@ -111,7 +152,8 @@ func respondLogical(w http.ResponseWriter, r *http.Request, path string, resp *l
 			// Do not set the token as the auth cookie if the endpoint
 			// is the token store. Otherwise, attempting to create a token
 			// will cause the client to be authenticated as that token.
-			if !strings.HasPrefix(path, "auth/token/") {
+			// The same applies to unwrapping a response.
+			if !strings.HasPrefix(path, "auth/token/") && !strings.HasPrefix(path, "sys/wrapping/") {
 				http.SetCookie(w, &http.Cookie{
 					Name:    AuthCookieName,
 					Value:   resp.Auth.ClientToken,
@@ -122,6 +164,7 @@ func respondLogical(w http.ResponseWriter, r *http.Request, path string, resp *l
 
 			logicalResp.Auth = &Auth{
 				ClientToken:   resp.Auth.ClientToken,
+				Accessor:      resp.Auth.Accessor,
 				Policies:      resp.Auth.Policies,
 				Metadata:      resp.Auth.Metadata,
 				LeaseDuration: int(resp.Auth.Lease.Seconds()),
@@ -211,12 +254,30 @@ type LogicalResponse struct {
 	LeaseDuration int                    `json:"lease_duration"`
 	Data          map[string]interface{} `json:"data"`
 	Auth          *Auth                  `json:"auth"`
+	WrapInfo      *WrapInfo              `json:"wrap_info,omitempty"`
+}
+
+type WrapInfo struct {
+	Token        string    `json:"token"`
+	Accessor     string    `json:"accessor"`
+	TTL          int       `json:"ttl"`
+	CreationTime time.Time `json:"creation_time"`
 }
 
 type Auth struct {
 	ClientToken   string            `json:"client_token"`
+	Accessor      string            `json:"accessor"`
 	Policies      []string          `json:"policies"`
 	Metadata      map[string]string `json:"metadata"`
 	LeaseDuration int               `json:"lease_duration"`
 	Renewable     bool              `json:"renewable"`
 }
+
+// listHasKeys returns whether the response to a list contains any keys
+func listHasKeys(resp *logical.Response) bool {
+	if resp == nil {
+		return false
+	}
+	keys, ok := resp.Data["keys"].([]string)
+	return ok && len(keys) > 0
+}
@@ -57,6 +57,43 @@ func TestLogical(t *testing.T) {
 	testResponseStatus(t, resp, 404)
 }
 
+func TestLogical_List(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPut(t, addr+"/v1/secret/bar/baz", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	// Both the LIST method and the list parameter list the keys, with
+	// or without the trailing slash
+	for _, r := range []struct{ method, url string }{
+		{"LIST", addr + "/v1/secret/"},
+		{"LIST", addr + "/v1/secret"},
+		{"GET", addr + "/v1/secret/?list=true"},
+	} {
+		resp = testHttpData(t, r.method, r.url, nil)
+		var actual map[string]interface{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+		keys := actual["data"].(map[string]interface{})["keys"]
+		if !reflect.DeepEqual(keys, []interface{}{"bar/", "foo"}) {
+			t.Fatalf("%s %s: bad: %#v", r.method, r.url, actual)
+		}
+	}
+
+	// There is nothing under an empty prefix
+	resp = testHttpData(t, "LIST", addr+"/v1/secret/empty/", nil)
+	testResponseStatus(t, resp, 404)
+}
+
 func TestLogical_noExist(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -127,12 +164,16 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 		"renewable":      false,
 		"lease_duration": float64(0),
 		"data": map[string]interface{}{
-			"meta":         nil,
-			"num_uses":     float64(0),
-			"path":         "auth/token/root",
-			"policies":     []interface{}{"root"},
-			"display_name": "root",
-			"id":           root,
+			"meta":             nil,
+			"num_uses":         float64(0),
+			"period":           float64(0),
+			"explicit_max_ttl": float64(0),
+			"bound_cidrs":      nil,
+			"type":             "service",
+			"path":             "auth/token/root",
+			"policies":         []interface{}{"root"},
+			"display_name":     "root",
+			"id":               root,
 		},
 		"auth": nil,
 	}
@@ -175,6 +216,10 @@ func TestLogical_CreateToken(t *testing.T) {
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
 	delete(actual["auth"].(map[string]interface{}), "client_token")
+	if actual["auth"].(map[string]interface{})["accessor"] == "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+	delete(actual["auth"].(map[string]interface{}), "accessor")
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: %#v %#v", actual, expected)
 	}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleRateLimitHandler wraps a handler to reject the requests exceeding
+// the rate limit quotas before they reach the core
+func handleRateLimitHandler(h http.Handler, core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path, ok := stripPrefix("/v1/", req.URL.Path)
+		if !ok {
+			h.ServeHTTP(w, req)
+			return
+		}
+
+		// The quotas apply to the full path of the namespace, whether
+		// it is selected with the header or with the path
+		if ns := requestNamespace(req); ns != "" {
+			path = ns + "/" + path
+		}
+
+		allowed, wait := core.AllowRequest(path, getConnection(req).RemoteAddr)
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			respondError(w, http.StatusTooManyRequests,
+				fmt.Errorf("rate limit quota exceeded, retry after %d seconds", retryAfter))
+			return
+		}
+
+		h.ServeHTTP(w, req)
+	})
+}
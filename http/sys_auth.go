@@ -10,6 +10,11 @@ import (
 
 func handleSysAuth(core *vault.Core) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tune") {
+			handleSysTune(core, w, r, "sys/auth/")
+			return
+		}
+
 		switch r.Method {
 		case "GET":
 			handleSysListAuth(core).ServeHTTP(w, r)
@@ -22,6 +27,39 @@ func handleSysAuth(core *vault.Core) http.Handler {
 	})
 }
 
+func handleSysAuthRemount(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request if we can
+		var req RemountRequest
+		if err := parseRequest(r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		_, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       "sys/auth/remount",
+			Connection: getConnection(r),
+			Data: map[string]interface{}{
+				"from": req.From,
+				"to":   req.To,
+			},
+		}))
+		if !ok {
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
 func handleSysListAuth(core *vault.Core) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -85,6 +123,7 @@ func handleSysEnableAuth(
 		Data: map[string]interface{}{
 			"type":        req.Type,
 			"description": req.Description,
+			"plugin_name": req.PluginName,
 		},
 	}))
 	if err != nil {
@@ -116,4 +155,5 @@ func handleSysDisableAuth(
 type EnableAuthRequest struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
+	PluginName  string `json:"plugin_name"`
 }
@@ -101,3 +101,68 @@ func TestSysDisableAuth(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestSysTuneAuth(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, addr+"/v1/sys/auth/token/tune", map[string]interface{}{
+		"default_lease_ttl": "1h",
+		"description":       "tokens",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/auth/token/tune")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if actual["default_lease_ttl"] != float64(3600) || actual["description"] != "tokens" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestSysRemountAuth(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, addr+"/v1/sys/auth/foo", map[string]interface{}{
+		"type": "noop",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPost(t, addr+"/v1/sys/auth/remount", map[string]interface{}{
+		"from": "foo",
+		"to":   "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/auth")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if _, ok := actual["foo/"]; ok {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if _, ok := actual["bar/"]; !ok {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The token backend cannot be remounted
+	resp = testHttpPost(t, addr+"/v1/sys/auth/remount", map[string]interface{}{
+		"from": "token",
+		"to":   "baz",
+	})
+	testResponseStatus(t, resp, 400)
+}
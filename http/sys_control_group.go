@@ -0,0 +1,93 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleSysControlGroupLookup(core *vault.Core) http.Handler {
+	return handleSysControlGroupStatus(core, "sys/control-group/lookup")
+}
+
+func handleSysControlGroupAuthorize(core *vault.Core) http.Handler {
+	return handleSysControlGroupStatus(core, "sys/control-group/authorize")
+}
+
+// handleSysControlGroupStatus handles the endpoints that respond with
+// the status of a parked request
+func handleSysControlGroupStatus(core *vault.Core, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request
+		var req ControlGroupRequest
+		if err := parseControlGroupRequest(r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       path,
+			Connection: getConnection(r),
+			Data: map[string]interface{}{
+				"request_id": req.RequestID,
+			},
+		}))
+		if !ok {
+			return
+		}
+		respondOk(w, resp.Data)
+	})
+}
+
+func handleSysControlGroupRequest(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request
+		var req ControlGroupRequest
+		if err := parseControlGroupRequest(r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Redeem the request, responding with the original response
+		path := "sys/control-group/request"
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       path,
+			Connection: getConnection(r),
+			Data: map[string]interface{}{
+				"request_id": req.RequestID,
+			},
+		}))
+		if !ok {
+			return
+		}
+		respondLogical(w, r, path, resp)
+	})
+}
+
+func parseControlGroupRequest(r *http.Request, req *ControlGroupRequest) error {
+	if err := parseRequest(r, req); err != nil {
+		return err
+	}
+	if req.RequestID == "" {
+		return errors.New("'request_id' must be specified in request body as JSON")
+	}
+	return nil
+}
+
+type ControlGroupRequest struct {
+	RequestID string `json:"request_id"`
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysControlGroup(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/sys/policy/dba", map[string]interface{}{
+		"rules": `path "secret/*" { policy = "write" required_approvals = 1 approver_policies = ["security"] }`,
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPut(t, addr+"/v1/sys/policy/security", map[string]interface{}{
+		"rules": `path "secret/*" { policy = "read" } path "sys/control-group/authorize" { policy = "write" }`,
+	})
+	testResponseStatus(t, resp, 204)
+
+	for id, policy := range map[string]string{"requester": "dba", "approver": "security"} {
+		resp = testHttpPut(t, addr+"/v1/auth/token/create", map[string]interface{}{
+			"id":       id,
+			"policies": []string{policy},
+		})
+		testResponseStatus(t, resp, 200)
+	}
+
+	// The write is parked
+	TestServerAuth(t, addr, "requester")
+	resp = testHttpPut(t, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	var parked map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &parked)
+	id := parked["data"].(map[string]interface{})["control_group_request_id"].(string)
+
+	// Redeeming without approval fails
+	resp = testHttpPut(t, addr+"/v1/sys/control-group/request", map[string]interface{}{
+		"request_id": id,
+	})
+	testResponseStatus(t, resp, 400)
+
+	// The approver authorizes the request
+	TestServerAuth(t, addr, "approver")
+	resp = testHttpPut(t, addr+"/v1/sys/control-group/authorize", map[string]interface{}{
+		"request_id": id,
+	})
+	var status map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &status)
+	if status["approved"] != true || status["path"] != "secret/foo" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// The approver cannot redeem the request
+	resp = testHttpPut(t, addr+"/v1/sys/control-group/request", map[string]interface{}{
+		"request_id": id,
+	})
+	testResponseStatus(t, resp, 403)
+
+	// The requester redeems the request
+	TestServerAuth(t, addr, "requester")
+	resp = testHttpPut(t, addr+"/v1/sys/control-group/request", map[string]interface{}{
+		"request_id": id,
+	})
+	testResponseStatus(t, resp, 204)
+
+	// The write was performed
+	TestServerAuth(t, addr, "approver")
+	resp, err := http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["data"] != "bar" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -119,6 +119,123 @@ func handleSysRevokePrefix(core *vault.Core) http.Handler {
 	})
 }
 
+func handleSysRevokeForce(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Determine the path...
+		prefix := "/v1/sys/revoke-force/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+		path := r.URL.Path[len(prefix):]
+		if path == "" {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+
+		_, err := core.HandleRequest(requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       "sys/revoke-force/" + path,
+			Connection: getConnection(r),
+		}))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+func handleSysLeaseLookup(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" && r.Method != "POST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request if we can
+		var req LeaseLookupRequest
+		if err := parseRequest(r, &req); err != nil {
+			if err != io.EOF {
+				respondError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       "sys/leases/lookup",
+			Connection: getConnection(r),
+			Data: map[string]interface{}{
+				"lease_id": req.LeaseID,
+			},
+		}))
+		if !ok {
+			return
+		}
+
+		respondLogical(w, r, "sys/leases/lookup", resp)
+	})
+}
+
+func handleSysLeaseList(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "LIST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Determine the prefix, which may be empty to list from the root
+		prefix := "/v1/sys/leases/lookup/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+		path := "sys/leases/lookup/" + r.URL.Path[len(prefix):]
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.ListOperation,
+			Path:       path,
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+
+		respondLogical(w, r, path, resp)
+	})
+}
+
+func handleSysLeaseIrrevocable(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.ReadOperation,
+			Path:       "sys/leases/irrevocable",
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+
+		respondLogical(w, r, "sys/leases/irrevocable", resp)
+	})
+}
+
+type LeaseLookupRequest struct {
+	LeaseID string `json:"lease_id"`
+}
+
 type RenewRequest struct {
 	Increment int `json:"increment"`
 }
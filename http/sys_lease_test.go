@@ -58,3 +58,83 @@ func TestSysRevokePrefix(t *testing.T) {
 	resp := testHttpPut(t, addr+"/v1/sys/revoke-prefix/secret/foo/1234", nil)
 	testResponseStatus(t, resp, 204)
 }
+
+func TestSysRevokeForce(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/sys/revoke-force/secret/foo/1234", nil)
+	testResponseStatus(t, resp, 204)
+}
+
+func TestSysLeaseIrrevocable(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp, err := http.Get(addr + "/v1/sys/leases/irrevocable")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var result map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &result)
+	leases := result["data"].(map[string]interface{})["leases"].([]interface{})
+	if len(leases) != 0 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestSysLeaseLookup(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// write secret
+	resp := testHttpPut(t, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":  "bar",
+		"lease": "1h",
+	})
+	testResponseStatus(t, resp, 204)
+
+	// read secret
+	resp, err := http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var result struct {
+		LeaseId string `json:"lease_id"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(&result); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	resp = testHttpPut(t, addr+"/v1/sys/leases/lookup", map[string]interface{}{
+		"lease_id": result.LeaseId,
+	})
+	var lookup map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &lookup)
+	data := lookup["data"].(map[string]interface{})
+	if data["id"] != result.LeaseId || data["renewable"] != true {
+		t.Fatalf("bad: %#v", lookup)
+	}
+
+	resp, err = http.Get(addr + "/v1/sys/leases/lookup/secret/foo/")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var list map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &list)
+	keys := list["data"].(map[string]interface{})["keys"].([]interface{})
+	if len(keys) != 1 || "secret/foo/"+keys[0].(string) != result.LeaseId {
+		t.Fatalf("bad: %#v", list)
+	}
+}
@@ -10,6 +10,11 @@ import (
 
 func handleSysMounts(core *vault.Core) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tune") {
+			handleSysTune(core, w, r, "sys/mounts/")
+			return
+		}
+
 		switch r.Method {
 		case "GET":
 			handleSysListMounts(core).ServeHTTP(w, r)
@@ -129,6 +134,7 @@ func handleSysMount(
 		Data: map[string]interface{}{
 			"type":        req.Type,
 			"description": req.Description,
+			"plugin_name": req.PluginName,
 		},
 	}))
 	if err != nil {
@@ -157,9 +163,89 @@ func handleSysUnmount(
 	respondOk(w, nil)
 }
 
+// handleSysTune reads or sets the settings of the mount or credential
+// backend at the path of the request, under the given sys prefix
+func handleSysTune(core *vault.Core, w http.ResponseWriter, r *http.Request, sysPrefix string) {
+	// Determine the path...
+	prefix := "/v1/" + sysPrefix
+	path := strings.TrimSuffix(r.URL.Path[len(prefix):], "/tune")
+	if path == "" {
+		respondError(w, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.ReadOperation,
+			Path:       sysPrefix + path + "/tune",
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+
+		respondOk(w, resp.Data)
+	case "PUT", "POST":
+		// Parse the request if we can
+		var req MountTuneRequest
+		if err := parseRequest(r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data := make(map[string]interface{})
+		if req.DefaultLeaseTTL != nil {
+			data["default_lease_ttl"] = req.DefaultLeaseTTL
+		}
+		if req.MaxLeaseTTL != nil {
+			data["max_lease_ttl"] = req.MaxLeaseTTL
+		}
+		if req.Description != nil {
+			data["description"] = *req.Description
+		}
+		if req.AuditNonHMACRequestKeys != nil {
+			data["audit_non_hmac_request_keys"] = strings.Join(req.AuditNonHMACRequestKeys, ",")
+		}
+		if req.AuditNonHMACResponseKeys != nil {
+			data["audit_non_hmac_response_keys"] = strings.Join(req.AuditNonHMACResponseKeys, ",")
+		}
+		if req.Options != nil {
+			data["options"] = req.Options
+		}
+
+		_, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Path:       sysPrefix + path + "/tune",
+			Connection: getConnection(r),
+			Data:       data,
+		}))
+		if !ok {
+			return
+		}
+
+		respondOk(w, nil)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
 type MountRequest struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
+	PluginName  string `json:"plugin_name"`
+}
+
+// MountTuneRequest holds the settings to set. The lease TTLs are either a
+// number of seconds or a duration string such as "1h". Omitted values are
+// unchanged.
+type MountTuneRequest struct {
+	DefaultLeaseTTL          interface{}       `json:"default_lease_ttl"`
+	MaxLeaseTTL              interface{}       `json:"max_lease_ttl"`
+	Description              *string           `json:"description"`
+	AuditNonHMACRequestKeys  []string          `json:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys []string          `json:"audit_non_hmac_response_keys"`
+	Options                  map[string]string `json:"options"`
 }
 
 type RemountRequest struct {
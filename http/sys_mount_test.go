@@ -25,6 +25,14 @@ func TestSysMounts(t *testing.T) {
 			"description": "generic secret storage",
 			"type":        "generic",
 		},
+		"cubbyhole/": map[string]interface{}{
+			"description": "per-token private secret storage",
+			"type":        "cubbyhole",
+		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
 			"type":        "system",
@@ -64,6 +72,14 @@ func TestSysMount(t *testing.T) {
 			"description": "generic secret storage",
 			"type":        "generic",
 		},
+		"cubbyhole/": map[string]interface{}{
+			"description": "per-token private secret storage",
+			"type":        "cubbyhole",
+		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
 			"type":        "system",
@@ -125,6 +141,14 @@ func TestSysRemount(t *testing.T) {
 			"description": "generic secret storage",
 			"type":        "generic",
 		},
+		"cubbyhole/": map[string]interface{}{
+			"description": "per-token private secret storage",
+			"type":        "cubbyhole",
+		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
 			"type":        "system",
@@ -163,6 +187,14 @@ func TestSysUnmount(t *testing.T) {
 			"description": "generic secret storage",
 			"type":        "generic",
 		},
+		"cubbyhole/": map[string]interface{}{
+			"description": "per-token private secret storage",
+			"type":        "cubbyhole",
+		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
 			"type":        "system",
@@ -174,3 +206,45 @@ func TestSysUnmount(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestSysTuneMount(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"default_lease_ttl":            "1h",
+		"max_lease_ttl":                7200,
+		"description":                  "tuned",
+		"audit_non_hmac_response_keys": []string{"foo"},
+		"options":                      map[string]string{"foo": "bar"},
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/mounts/secret/tune")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual map[string]interface{}
+	expected := map[string]interface{}{
+		"default_lease_ttl":            float64(3600),
+		"max_lease_ttl":                float64(7200),
+		"description":                  "tuned",
+		"audit_non_hmac_request_keys":  []interface{}{},
+		"audit_non_hmac_response_keys": []interface{}{"foo"},
+		"options":                      map[string]interface{}{"foo": "bar"},
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The default cannot exceed the max
+	resp = testHttpPost(t, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"default_lease_ttl": "3h",
+	})
+	testResponseStatus(t, resp, 400)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleSysListNamespaces lists the child namespaces
+func handleSysListNamespaces(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "LIST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.ListOperation,
+			Path:       "sys/namespaces",
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+
+		respondLogical(w, r, "sys/namespaces", resp)
+	})
+}
+
+// handleSysNamespace creates, reads and deletes a child namespace
+func handleSysNamespace(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Determine the path...
+		prefix := "/v1/sys/namespaces/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+		name := r.URL.Path[len(prefix):]
+		if name == "" {
+			handleSysListNamespaces(core).ServeHTTP(w, r)
+			return
+		}
+		path := "sys/namespaces/" + name
+
+		var op logical.Operation
+		switch r.Method {
+		case "GET":
+			op = logical.ReadOperation
+		case "PUT", "POST":
+			op = logical.WriteOperation
+		case "DELETE":
+			op = logical.DeleteOperation
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  op,
+			Path:       path,
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+		if op == logical.ReadOperation && resp == nil {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+
+		respondLogical(w, r, path, resp)
+	})
+}
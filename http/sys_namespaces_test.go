@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysNamespaces(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/sys/namespaces/ns1", nil)
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	data := actual["data"].(map[string]interface{})
+	if data["path"] != "ns1/" {
+		t.Fatalf("bad: %#v", actual)
+	}
+	admin := data["admin_token"].(string)
+
+	resp, err := http.Get(addr + "/v1/sys/namespaces")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual = nil
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	keys := actual["data"].(map[string]interface{})["keys"]
+	if !reflect.DeepEqual(keys, []interface{}{"ns1"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Select the namespace with the header, which is the same as
+	// prefixing the path
+	req, err := http.NewRequest("GET", addr+"/v1/sys/mounts", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, admin)
+	req.Header.Set(NamespaceHeaderName, "ns1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 200)
+
+	req, err = http.NewRequest("GET", addr+"/v1/ns1/sys/mounts", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, admin)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 200)
+
+	// The admin token is not valid in the root namespace
+	req, err = http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, admin)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 403)
+
+	resp = testHttpDelete(t, addr+"/v1/sys/namespaces/ns1")
+	testResponseStatus(t, resp, 204)
+
+	resp, err = http.Get(addr + "/v1/sys/namespaces/ns1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 404)
+}
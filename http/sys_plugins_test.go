@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysPlugin(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// The test core has no plugin directory
+	resp := testHttpPut(t, addr+"/v1/sys/plugins/catalog/foo", map[string]interface{}{
+		"command": "foo",
+		"sha256":  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpPut(t, addr+"/v1/sys/plugins/catalog/foo", map[string]interface{}{
+		"command": "foo",
+		"sha256":  "bar",
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp, err := http.Get(addr + "/v1/sys/plugins/catalog/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 404)
+
+}
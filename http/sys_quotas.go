@@ -0,0 +1,88 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleSysListQuotas lists the quotas of a type, such as "lease-count"
+func handleSysListQuotas(core *vault.Core, quotaType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "LIST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		path := "sys/quotas/" + quotaType
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  logical.ListOperation,
+			Path:       path,
+			Connection: getConnection(r),
+		}))
+		if !ok {
+			return
+		}
+
+		respondLogical(w, r, path, resp)
+	})
+}
+
+// handleSysQuota manages a quota of a type, such as "lease-count"
+func handleSysQuota(core *vault.Core, quotaType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Determine the path...
+		prefix := "/v1/sys/quotas/" + quotaType + "/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+		name := r.URL.Path[len(prefix):]
+		if name == "" {
+			handleSysListQuotas(core, quotaType).ServeHTTP(w, r)
+			return
+		}
+		path := "sys/quotas/" + quotaType + "/" + name
+
+		var op logical.Operation
+		switch r.Method {
+		case "GET":
+			op = logical.ReadOperation
+		case "PUT", "POST":
+			op = logical.WriteOperation
+		case "DELETE":
+			op = logical.DeleteOperation
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request if we can
+		var data map[string]interface{}
+		if op == logical.WriteOperation {
+			if err := parseRequest(r, &data); err != nil && err != io.EOF {
+				respondError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		resp, ok := request(core, w, r, requestAuth(r, &logical.Request{
+			Operation:  op,
+			Path:       path,
+			Connection: getConnection(r),
+			Data:       data,
+		}))
+		if !ok {
+			return
+		}
+		if op == logical.ReadOperation && resp == nil {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+
+		respondLogical(w, r, path, resp)
+	})
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysLeaseCountQuota(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":  "bar",
+		"lease": "1h",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, addr+"/v1/sys/quotas/lease-count/secret", map[string]interface{}{
+		"path":       "secret/",
+		"max_leases": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/quotas/lease-count/secret")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var actual map[string]interface{}
+	expected := map[string]interface{}{
+		"name":       "secret",
+		"path":       "secret/",
+		"max_leases": float64(1),
+		"per_token":  false,
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual["data"], expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	resp, err = http.Get(addr + "/v1/sys/quotas/lease-count")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual = nil
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	keys := actual["data"].(map[string]interface{})["keys"]
+	if !reflect.DeepEqual(keys, []interface{}{"secret"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The second lease exceeds the quota
+	resp, err = http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 200)
+	resp, err = http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 429)
+
+	resp = testHttpDelete(t, addr+"/v1/sys/quotas/lease-count/secret")
+	testResponseStatus(t, resp, 204)
+
+	resp, err = http.Get(addr + "/v1/sys/quotas/lease-count/secret")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 404)
+}
+
+func TestSysRateLimitQuota(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/sys/quotas/rate-limit/secret", map[string]interface{}{
+		"path":  "secret",
+		"rate":  0.01,
+		"burst": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 404)
+
+	// The second request waits for the bucket to refill
+	resp, err = http.Get(addr + "/v1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 429)
+	if retry := resp.Header.Get("Retry-After"); retry != "100" {
+		t.Fatalf("bad: %s", retry)
+	}
+
+	resp, err = http.Get(addr + "/v1/sys/quotas/rate-limit/secret")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var actual map[string]interface{}
+	expected := map[string]interface{}{
+		"name":             "secret",
+		"path":             "secret/",
+		"rate":             0.01,
+		"burst":            float64(1),
+		"per_client":       false,
+		"blocked_requests": float64(1),
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual["data"], expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestSysRateLimitQuota_Namespace(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/sys/namespaces/ns1", nil)
+	testResponseStatus(t, resp, 200)
+
+	resp = testHttpPut(t, addr+"/v1/sys/quotas/rate-limit/ns1", map[string]interface{}{
+		"path":  "ns1/secret",
+		"rate":  0.01,
+		"burst": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	// The secret mount of the root is not limited
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(addr + "/v1/secret/foo")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		testResponseStatus(t, resp, 404)
+	}
+
+	// Selecting the namespace with the header or with the path uses
+	// the same bucket
+	req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(NamespaceHeaderName, "ns1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.StatusCode == 429 {
+		t.Fatalf("rejected")
+	}
+
+	resp, err = http.Get(addr + "/v1/ns1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 429)
+
+	// And the other way around
+	resp = testHttpPut(t, addr+"/v1/sys/quotas/rate-limit/ns1", map[string]interface{}{
+		"path":  "ns1/secret",
+		"rate":  0.01,
+		"burst": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err = http.Get(addr + "/v1/ns1/secret/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.StatusCode == 429 {
+		t.Fatalf("rejected")
+	}
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 429)
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleSysWrappingUnwrap(core *vault.Core) http.Handler {
+	return handleSysWrapping(core, "sys/wrapping/unwrap")
+}
+
+func handleSysWrappingLookup(core *vault.Core) http.Handler {
+	return handleSysWrapping(core, "sys/wrapping/lookup")
+}
+
+func handleSysWrappingRewrap(core *vault.Core) http.Handler {
+	return handleSysWrapping(core, "sys/wrapping/rewrap")
+}
+
+// handleSysWrapping handles the endpoints consuming a response wrapping
+// token. The token is taken from the body if given, otherwise the client
+// token is used.
+func handleSysWrapping(core *vault.Core, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" && r.Method != "POST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request
+		var req WrappingRequest
+		if err := parseRequest(r, &req); err != nil && err != io.EOF {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// Wrapping tokens belong to the root namespace, so the namespace
+		// selected by the request is ignored
+		lreq := requestAuth(r, &logical.Request{
+			Operation:  logical.WriteOperation,
+			Connection: getConnection(r),
+			Data: map[string]interface{}{
+				"token": req.Token,
+			},
+		})
+		lreq.Path = path
+
+		resp, ok := request(core, w, r, lreq)
+		if !ok {
+			return
+		}
+		respondLogical(w, r, path, resp)
+	})
+}
+
+type WrappingRequest struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysWrapping(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	// Read the secret wrapped
+	req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(WrapTTLHeaderName, "5m")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var wrapped map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &wrapped)
+	if wrapped["data"] != nil {
+		t.Fatalf("bad: %#v", wrapped)
+	}
+	info := wrapped["wrap_info"].(map[string]interface{})
+	if info["ttl"] != float64(300) {
+		t.Fatalf("bad: %#v", info)
+	}
+	wrapToken := info["token"].(string)
+
+	resp = testHttpPut(t, addr+"/v1/sys/wrapping/lookup", map[string]interface{}{
+		"token": wrapToken,
+	})
+	var lookup map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &lookup)
+	if lookup["data"].(map[string]interface{})["creation_ttl"] != float64(300) {
+		t.Fatalf("bad: %#v", lookup)
+	}
+
+	resp = testHttpPut(t, addr+"/v1/sys/wrapping/rewrap", map[string]interface{}{
+		"token": wrapToken,
+	})
+	var rewrapped map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &rewrapped)
+	newToken := rewrapped["wrap_info"].(map[string]interface{})["token"].(string)
+
+	// The old token no longer unwraps
+	resp = testHttpPut(t, addr+"/v1/sys/wrapping/unwrap", map[string]interface{}{
+		"token": wrapToken,
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpPut(t, addr+"/v1/sys/wrapping/unwrap", map[string]interface{}{
+		"token": newToken,
+	})
+	var unwrapped map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &unwrapped)
+	if unwrapped["data"].(map[string]interface{})["data"] != "bar" {
+		t.Fatalf("bad: %#v", unwrapped)
+	}
+
+	// An invalid TTL is rejected
+	req, err = http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(WrapTTLHeaderName, "soon")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 400)
+}
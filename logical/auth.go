@@ -1,6 +1,9 @@
 package logical
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Auth is the resulting authentication information that is part of
 // Response for credential backends.
@@ -29,10 +32,38 @@ type Auth struct {
 	// audit log.
 	Metadata map[string]string
 
+	// Period, if set, makes the token periodic. A periodic token has no
+	// maximum lifetime, and every renewal sets its lease to exactly the
+	// period. The lease given is ignored.
+	Period time.Duration
+
+	// BoundCIDRs, if set, restricts the use of the token to clients whose
+	// address is within one of the given CIDR blocks. This is enforced on
+	// every use of the token, not just at login.
+	BoundCIDRs []string
+
+	// Alias, if set, identifies the authenticated user within the auth
+	// backend. Vault core maps it to an entity of the identity store.
+	Alias *Alias
+
+	// GroupAliases, if set, are the names of the groups the user is a
+	// member of within the auth backend, such as LDAP groups or GitHub
+	// teams. They determine membership of external identity groups.
+	GroupAliases []*Alias
+
+	// EntityID is the ID of the entity the token belongs to, if any.
+	// This will be filled in by Vault core.
+	EntityID string
+
 	// ClientToken is the token that is generated for the authentication.
 	// This will be filled in by Vault core when an auth structure is
 	// returned. Setting this manually will have no effect.
 	ClientToken string
+
+	// Accessor is the non-secret identifier of the client token. It can
+	// be used to look up or revoke the token without knowing it. This is
+	// filled in by Vault core alongside the client token.
+	Accessor string
 }
 
 func (a *Auth) GoString() string {
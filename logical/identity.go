@@ -0,0 +1,19 @@
+package logical
+
+import "fmt"
+
+// Alias identifies the user of an auth backend within the identity
+// store. Credential backends set it on the Auth of a login so that the
+// login can be mapped to an entity.
+type Alias struct {
+	// Name is the unique name of the user within the backend, such as
+	// the GitHub or LDAP user name.
+	Name string
+
+	// Metadata is attached to the alias and kept current on every login
+	Metadata map[string]string
+}
+
+func (a *Alias) GoString() string {
+	return fmt.Sprintf("*%#v", *a)
+}
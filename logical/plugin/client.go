@@ -0,0 +1,342 @@
+package plugin
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// startTimeout is how long a plugin may take to start listening
+const startTimeout = 10 * time.Second
+
+// Runner describes how to launch a plugin
+type Runner struct {
+	Name    string
+	Command string
+	Args    []string
+
+	// Sha256 is the checksum the command must match to be run
+	Sha256 []byte
+}
+
+// BackendClient is a logical.Backend running in a plugin process
+type BackendClient struct {
+	name    string
+	cmd     *exec.Cmd
+	client  *rpc.Client
+	conn    net.Conn
+	storage *storageServer
+	paths   *logical.Paths
+
+	cleanupOnce sync.Once
+}
+
+// NewBackend launches the plugin and creates the backend in it. The
+// plugin process runs until Cleanup is called.
+func NewBackend(runner *Runner, conf *logical.BackendConfig) (*BackendClient, error) {
+	if err := verifyChecksum(runner.Command, runner.Sha256); err != nil {
+		return nil, err
+	}
+
+	// Generate the certificates of both ends for this launch
+	serverCert, serverKey, err := generateCert(x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return nil, err
+	}
+	clientCert, clientKey, err := generateCert(x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	config, err := tlsConfig(clientCert, clientKey, serverCert)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := conf.Logger
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	cmd := exec.Command(runner.Command, runner.Args...)
+	cmd.Env = append(os.Environ(),
+		envServerCert+"="+string(serverCert),
+		envServerKey+"="+string(serverKey),
+		envClientCert+"="+string(clientCert))
+	cmd.Stderr = &logWriter{
+		logger: logger,
+		prefix: fmt.Sprintf("[DEBUG] plugin.%s: ", runner.Name),
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	b := &BackendClient{
+		name:    runner.Name,
+		cmd:     cmd,
+		storage: newStorageServer(),
+	}
+	if err := b.connect(stdout, config); err != nil {
+		b.Cleanup()
+		return nil, fmt.Errorf("plugin %s: %v", runner.Name, err)
+	}
+	if err := b.setup(conf); err != nil {
+		b.Cleanup()
+		return nil, fmt.Errorf("plugin %s: %v", runner.Name, err)
+	}
+	return b, nil
+}
+
+// connect reads the address of the plugin and opens the connections for
+// the requests and for the storage calls
+func (b *BackendClient) connect(stdout io.Reader, config *tls.Config) error {
+	addrCh := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(stdout).ReadString('\n')
+		addrCh <- strings.TrimSpace(line)
+
+		// Drain the output so the plugin never blocks on it
+		io.Copy(ioutil.Discard, stdout)
+	}()
+
+	var line string
+	select {
+	case line = <-addrCh:
+	case <-time.After(startTimeout):
+		return fmt.Errorf("timed out waiting for the plugin to start")
+	}
+
+	parts := strings.SplitN(line, "|", 2)
+	if len(parts) != 2 || parts[0] != handshakeVersion {
+		return fmt.Errorf("unexpected handshake: %q", line)
+	}
+	addr := parts[1]
+
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	b.client = jsonrpc.NewClient(conn)
+
+	b.conn, err = tls.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Storage", b.storage); err != nil {
+		return err
+	}
+	go server.ServeCodec(jsonrpc.NewServerCodec(b.conn))
+	return nil
+}
+
+// setup creates the backend in the plugin. The view of the configuration
+// is only available during the call.
+func (b *BackendClient) setup(conf *logical.BackendConfig) error {
+	id := b.storage.add(conf.View)
+	defer b.storage.remove(id)
+
+	var reply SetupReply
+	if err := b.client.Call("Plugin.Setup", &SetupArgs{
+		StorageID: id,
+		Config:    conf.Config,
+	}, &reply); err != nil {
+		return err
+	}
+	if err := unwrapError(reply.Error); err != nil {
+		return err
+	}
+	b.paths = reply.Paths
+	return nil
+}
+
+// HandleRequest sends the request to the plugin. The storage of the
+// request is available to the plugin until it responds.
+func (b *BackendClient) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	id := b.storage.add(req.Storage)
+	defer b.storage.remove(id)
+
+	args := &HandleRequestArgs{StorageID: id}
+	wire := *req
+	wire.Storage = nil
+	if req.Connection != nil {
+		wire.Connection = &logical.Connection{RemoteAddr: req.Connection.RemoteAddr}
+	}
+	if req.Secret != nil {
+		args.SecretLease = leaseTimes(&req.Secret.LeaseOptions)
+	}
+	if req.Auth != nil {
+		args.AuthLease = leaseTimes(&req.Auth.LeaseOptions)
+	}
+	args.Request = &wire
+
+	var reply HandleRequestReply
+	if err := b.client.Call("Plugin.HandleRequest", args, &reply); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", b.name, err)
+	}
+	if req.Operation == logical.ListOperation && reply.Response != nil {
+		listKeys(reply.Response)
+	}
+	return reply.Response, unwrapError(reply.Error)
+}
+
+// listKeys restores the keys of a list response, which are decoded as a
+// []interface{}, to the []string of logical.ListResponse
+func listKeys(resp *logical.Response) {
+	raw, ok := resp.Data["keys"].([]interface{})
+	if !ok {
+		return
+	}
+	keys := make([]string, 0, len(raw))
+	for _, v := range raw {
+		key, ok := v.(string)
+		if !ok {
+			return
+		}
+		keys = append(keys, key)
+	}
+	resp.Data["keys"] = keys
+}
+
+// SpecialPaths returns the special paths of the backend in the plugin
+func (b *BackendClient) SpecialPaths() *logical.Paths {
+	return b.paths
+}
+
+// Cleanup closes the connections and stops the plugin process
+func (b *BackendClient) Cleanup() {
+	b.cleanupOnce.Do(func() {
+		if b.client != nil {
+			b.client.Close()
+		}
+		if b.conn != nil {
+			b.conn.Close()
+		}
+		b.cmd.Process.Kill()
+		b.cmd.Wait()
+	})
+}
+
+// verifyChecksum checks the SHA-256 checksum of the command
+func verifyChecksum(command string, sum []byte) error {
+	f, err := os.Open(command)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(h.Sum(nil), sum) != 1 {
+		return fmt.Errorf("checksum of %s does not match", command)
+	}
+	return nil
+}
+
+// storageServer serves the storage calls of the plugin. The storage of
+// each call in progress is registered under an ID sent to the plugin.
+type storageServer struct {
+	l     sync.RWMutex
+	next  uint64
+	views map[uint64]logical.Storage
+}
+
+func newStorageServer() *storageServer {
+	return &storageServer{
+		views: make(map[uint64]logical.Storage),
+	}
+}
+
+func (s *storageServer) add(view logical.Storage) uint64 {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.next++
+	s.views[s.next] = view
+	return s.next
+}
+
+func (s *storageServer) remove(id uint64) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	delete(s.views, id)
+}
+
+func (s *storageServer) view(id uint64) (logical.Storage, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	view := s.views[id]
+	if view == nil {
+		return nil, fmt.Errorf("storage is not available outside of a request")
+	}
+	return view, nil
+}
+
+func (s *storageServer) List(args *StorageArgs, reply *StorageReply) error {
+	view, err := s.view(args.StorageID)
+	if err == nil {
+		reply.Keys, err = view.List(args.Key)
+	}
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (s *storageServer) Get(args *StorageArgs, reply *StorageReply) error {
+	view, err := s.view(args.StorageID)
+	if err == nil {
+		reply.Entry, err = view.Get(args.Key)
+	}
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (s *storageServer) Put(args *StorageArgs, reply *StorageReply) error {
+	view, err := s.view(args.StorageID)
+	if err == nil {
+		err = view.Put(args.Entry)
+	}
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (s *storageServer) Delete(args *StorageArgs, reply *StorageReply) error {
+	view, err := s.view(args.StorageID)
+	if err == nil {
+		err = view.Delete(args.Key)
+	}
+	reply.Error = wrapError(err)
+	return nil
+}
+
+// logWriter writes the standard error of the plugin to the logger
+type logWriter struct {
+	logger *log.Logger
+	prefix string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.logger.Printf("%s%s", w.prefix, line)
+	}
+	return len(p), nil
+}
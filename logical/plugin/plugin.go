@@ -0,0 +1,130 @@
+// Package plugin runs logical backends as separate processes.
+//
+// The core launches the plugin command and connects to it over TLS on the
+// loopback interface. Both ends are authenticated with certificates that
+// are generated for each launch and passed to the plugin in its
+// environment. Requests are sent to the plugin with net/rpc, and the
+// plugin accesses its storage with calls back to the core, so all of its
+// data goes through the barrier view of its mount.
+package plugin
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// handshakeVersion is the version of the protocol, written by the
+	// plugin before the address it listens on
+	handshakeVersion = "1"
+
+	// The environment variables passing the TLS material to the plugin
+	envServerCert = "VAULT_PLUGIN_SERVER_CERT"
+	envServerKey  = "VAULT_PLUGIN_SERVER_KEY"
+	envClientCert = "VAULT_PLUGIN_CLIENT_CERT"
+)
+
+// SetupArgs is sent to create the backend in the plugin
+type SetupArgs struct {
+	StorageID uint64
+	Config    map[string]string
+}
+
+// SetupReply returns the special paths of the backend
+type SetupReply struct {
+	Paths *logical.Paths
+	Error *Error
+}
+
+// HandleRequestArgs is sent to handle a request in the plugin
+type HandleRequestArgs struct {
+	StorageID uint64
+	Request   *logical.Request
+
+	// The lease fields which are not encoded with the request
+	SecretLease *LeaseTimes
+	AuthLease   *LeaseTimes
+}
+
+// HandleRequestReply returns the response of the backend
+type HandleRequestReply struct {
+	Response *logical.Response
+	Error    *Error
+}
+
+// LeaseTimes carries the fields of logical.LeaseOptions which are not
+// encoded to JSON, so that the plugin can extend renewed leases
+type LeaseTimes struct {
+	Increment time.Duration
+	Issue     time.Time
+}
+
+// StorageArgs is sent by the plugin to access the storage of a request
+type StorageArgs struct {
+	StorageID uint64
+	Key       string
+	Entry     *logical.StorageEntry
+}
+
+// StorageReply returns the result of a storage call
+type StorageReply struct {
+	Keys  []string
+	Entry *logical.StorageEntry
+	Error *Error
+}
+
+// Error is an error sent over RPC
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// knownErrors are restored when they are received, so that they can still
+// be compared by the caller
+var knownErrors = []error{
+	logical.ErrUnsupportedOperation,
+	logical.ErrUnsupportedPath,
+	logical.ErrInvalidRequest,
+	logical.ErrPermissionDenied,
+	logical.ErrQuotaExceeded,
+}
+
+func wrapError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Message: err.Error()}
+}
+
+func unwrapError(e *Error) error {
+	if e == nil {
+		return nil
+	}
+	for _, err := range knownErrors {
+		if e.Message == err.Error() {
+			return err
+		}
+	}
+	return e
+}
+
+// leaseTimes returns the lease fields of a request not encoded with it
+func leaseTimes(opts *logical.LeaseOptions) *LeaseTimes {
+	return &LeaseTimes{
+		Increment: opts.LeaseIncrement,
+		Issue:     opts.LeaseIssue,
+	}
+}
+
+// restore sets the lease fields of a decoded request
+func (t *LeaseTimes) restore(opts *logical.LeaseOptions) {
+	if t == nil {
+		return
+	}
+	opts.LeaseIncrement = t.Increment
+	opts.LeaseIssue = t.Issue
+}
@@ -0,0 +1,262 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// testHelperArg makes the test binary run as a plugin
+const testHelperArg = "plugin-helper"
+
+func testRunner(t *testing.T) *Runner {
+	f, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	return &Runner{
+		Name:    "test",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestBackendPlugin_helper", "--", testHelperArg},
+		Sha256:  h.Sum(nil),
+	}
+}
+
+func testBackendFactory(conf *logical.BackendConfig) (logical.Backend, error) {
+	return &framework.Backend{
+		PathsSpecial: &logical.Paths{
+			Root: []string{"config"},
+		},
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "config",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+						return &logical.Response{
+							Data: map[string]interface{}{"value": conf.Config["value"]},
+						}, nil
+					},
+				},
+			},
+			&framework.Path{
+				Pattern: "kv/?$",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+						keys, err := req.Storage.List("kv/")
+						if err != nil {
+							return nil, err
+						}
+						return logical.ListResponse(keys), nil
+					},
+				},
+			},
+			&framework.Path{
+				Pattern: "kv/(?P<key>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"key":   &framework.FieldSchema{Type: framework.TypeString},
+					"value": &framework.FieldSchema{Type: framework.TypeString},
+				},
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+						entry, err := req.Storage.Get("kv/" + data.Get("key").(string))
+						if err != nil || entry == nil {
+							return nil, err
+						}
+						return &logical.Response{
+							Data: map[string]interface{}{"value": string(entry.Value)},
+						}, nil
+					},
+					logical.WriteOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+						value := data.Get("value").(string)
+						if value == "" {
+							return logical.ErrorResponse("missing value"), logical.ErrInvalidRequest
+						}
+						return nil, req.Storage.Put(&logical.StorageEntry{
+							Key:   "kv/" + data.Get("key").(string),
+							Value: []byte(value),
+						})
+					},
+				},
+			},
+		},
+		Secrets: []*framework.Secret{
+			&framework.Secret{
+				Type: "kv",
+				Renew: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+					return &logical.Response{
+						Data: map[string]interface{}{
+							"increment": req.Secret.LeaseIncrement.String(),
+						},
+					}, nil
+				},
+			},
+		},
+	}, nil
+}
+
+func TestBackendPlugin(t *testing.T) {
+	storage := new(logical.InmemStorage)
+	b, err := NewBackend(testRunner(t), &logical.BackendConfig{
+		View:   storage,
+		Config: map[string]string{"value": "bar"},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer b.Cleanup()
+
+	if paths := b.SpecialPaths(); len(paths.Root) != 1 || paths.Root[0] != "config" {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	// The configuration is passed to the factory
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The storage calls go to the storage of the request
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.WriteOperation,
+		Path:      "kv/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"value": "baz"},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entry, err := storage.Get("kv/foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "kv/foo",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The keys of a list are returned as they are by the backend
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "kv/",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected, err := storage.List("kv/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys, ok := resp.Data["keys"].([]string); !ok || len(keys) != 1 || !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The errors of the logical package are restored
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.WriteOperation,
+		Path:      "kv/foo",
+		Storage:   storage,
+	})
+	if err != logical.ErrInvalidRequest || resp.Data["error"] != "missing value" {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "unknown",
+		Storage:   storage,
+	})
+	if err != logical.ErrUnsupportedPath {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The lease fields not encoded with the request are passed
+	secret := &logical.Secret{
+		InternalData: map[string]interface{}{"secret_type": "kv"},
+	}
+	secret.LeaseIncrement = time.Hour
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RenewOperation,
+		Path:      "kv/foo",
+		Storage:   storage,
+		Secret:    secret,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["increment"] != "1h0m0s" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Requests fail once the plugin is stopped
+	b.Cleanup()
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	if err == nil {
+		t.Fatalf("should fail")
+	}
+}
+
+func TestBackendPlugin_checksum(t *testing.T) {
+	runner := testRunner(t)
+	runner.Sha256[0]++
+
+	_, err := NewBackend(runner, &logical.BackendConfig{
+		View: new(logical.InmemStorage),
+	})
+	if err == nil {
+		t.Fatalf("should fail")
+	}
+}
+
+func TestBackendPlugin_tls(t *testing.T) {
+	// A plugin can't be served without the certificates of a launch
+	if err := Serve(testBackendFactory); err == nil {
+		t.Fatalf("should fail")
+	}
+}
+
+// TestBackendPlugin_helper is run as the plugin by the tests above
+func TestBackendPlugin_helper(t *testing.T) {
+	if os.Args[len(os.Args)-1] != testHelperArg {
+		return
+	}
+
+	if err := Serve(testBackendFactory); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// acceptTimeout bounds the TLS handshake of an incoming connection, so
+// that a connection which never completes it can't block the core
+const acceptTimeout = 10 * time.Second
+
+// Serve runs the backend created by the factory as a plugin. It is called
+// from the main function of the plugin command, and returns once the core
+// closes its connection. Nothing else may be written to standard output,
+// which is used to tell the core where to connect.
+func Serve(factory logical.Factory) error {
+	config, err := tlsConfig(
+		[]byte(os.Getenv(envServerCert)),
+		[]byte(os.Getenv(envServerKey)),
+		[]byte(os.Getenv(envClientCert)))
+	if err != nil {
+		return fmt.Errorf("plugin must be launched by vault: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	fmt.Printf("%s|%s\n", handshakeVersion, ln.Addr())
+
+	// The core connects once for its requests and once for the storage
+	// calls of the plugin, in that order
+	backendConn, err := accept(ln)
+	if err != nil {
+		return err
+	}
+	storageConn, err := accept(ln)
+	if err != nil {
+		backendConn.Close()
+		return err
+	}
+	ln.Close()
+
+	storage := jsonrpc.NewClient(storageConn)
+	defer storage.Close()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &backendServer{
+		factory: factory,
+		storage: storage,
+	}); err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(backendConn))
+	return nil
+}
+
+// accept returns the next connection to complete the TLS handshake, which
+// requires the client certificate of the core
+func accept(ln net.Listener) (net.Conn, error) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		conn.SetDeadline(time.Now().Add(acceptTimeout))
+		if err := conn.(*tls.Conn).Handshake(); err != nil {
+			conn.Close()
+			continue
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+}
+
+// backendServer serves the RPC calls of the core in the plugin
+type backendServer struct {
+	factory logical.Factory
+	storage *rpc.Client
+	backend logical.Backend
+}
+
+// Setup creates the backend
+func (s *backendServer) Setup(args *SetupArgs, reply *SetupReply) error {
+	backend, err := s.factory(&logical.BackendConfig{
+		View:   &storageClient{client: s.storage, id: args.StorageID},
+		Logger: log.New(os.Stderr, "", 0),
+		Config: args.Config,
+	})
+	if err != nil {
+		reply.Error = wrapError(err)
+		return nil
+	}
+
+	s.backend = backend
+	reply.Paths = backend.SpecialPaths()
+	return nil
+}
+
+// HandleRequest passes a request to the backend
+func (s *backendServer) HandleRequest(args *HandleRequestArgs, reply *HandleRequestReply) error {
+	if s.backend == nil {
+		return errors.New("backend is not set up")
+	}
+
+	req := args.Request
+	req.Storage = &storageClient{client: s.storage, id: args.StorageID}
+	if req.Secret != nil {
+		args.SecretLease.restore(&req.Secret.LeaseOptions)
+	}
+	if req.Auth != nil {
+		args.AuthLease.restore(&req.Auth.LeaseOptions)
+	}
+
+	resp, err := s.backend.HandleRequest(req)
+	reply.Response = resp
+	reply.Error = wrapError(err)
+	return nil
+}
+
+// storageClient implements logical.Storage in the plugin with calls to
+// the storage of a request in the core
+type storageClient struct {
+	client *rpc.Client
+	id     uint64
+}
+
+func (s *storageClient) List(prefix string) ([]string, error) {
+	var reply StorageReply
+	if err := s.client.Call("Storage.List", &StorageArgs{StorageID: s.id, Key: prefix}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Keys, unwrapError(reply.Error)
+}
+
+func (s *storageClient) Get(key string) (*logical.StorageEntry, error) {
+	var reply StorageReply
+	if err := s.client.Call("Storage.Get", &StorageArgs{StorageID: s.id, Key: key}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Entry, unwrapError(reply.Error)
+}
+
+func (s *storageClient) Put(entry *logical.StorageEntry) error {
+	var reply StorageReply
+	if err := s.client.Call("Storage.Put", &StorageArgs{StorageID: s.id, Entry: entry}, &reply); err != nil {
+		return err
+	}
+	return unwrapError(reply.Error)
+}
+
+func (s *storageClient) Delete(key string) error {
+	var reply StorageReply
+	if err := s.client.Call("Storage.Delete", &StorageArgs{StorageID: s.id, Key: key}, &reply); err != nil {
+		return err
+	}
+	return unwrapError(reply.Error)
+}
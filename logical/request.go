@@ -3,6 +3,7 @@ package logical
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Request is a struct that stores the parameters and context
@@ -48,10 +49,25 @@ type Request struct {
 	// name, but is useful for operators.
 	DisplayName string
 
+	// EntityID is the ID of the identity entity the client token belongs
+	// to, if any. It is set by the core after the token is validated.
+	EntityID string
+
+	// Namespace is the path of the namespace the request is made in, if
+	// it is not the root namespace. It is set by the core, which removes
+	// it from the path of the request.
+	Namespace string
+
 	// MountPoint is provided so that a logical backend can generate
 	// paths relative to itself. The `Path` is effectively the client
 	// request path with the MountPoint trimmed off.
 	MountPoint string
+
+	// WrapTTL, if set, requests that the response is wrapped: instead of
+	// the response, the client receives a single-use token that can be
+	// used to unwrap it within the given TTL. This is handled by the core
+	// and is not visible to backends.
+	WrapTTL time.Duration
 }
 
 // Get returns a data field and guards for nil Data
@@ -147,4 +163,7 @@ var (
 
 	// ErrPermissionDeneid is returned if the client is not authorized
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrQuotaExceeded is returned if the request is rejected by a quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
 )
@@ -1,5 +1,7 @@
 package logical
 
+import "time"
+
 const (
 	// HTTPContentType can be specified in the Data field of a Response
 	// so that the HTTP front end can specify a custom Content-Type associated
@@ -40,6 +42,27 @@ type Response struct {
 	// This is only valid for credential backends. This will be blanked
 	// for any logical backend and ignored.
 	Redirect string
+
+	// WrapInfo, if not nil, means the response has been wrapped and only
+	// contains the information needed to unwrap it. This is set by the
+	// core and is ignored if returned by a backend.
+	WrapInfo *WrapInfo
+}
+
+// WrapInfo is the information returned in place of a wrapped response
+type WrapInfo struct {
+	// Token is the single-use token that unwraps the response
+	Token string
+
+	// Accessor is the accessor of the token, which can be logged to
+	// identify the token without being able to use it
+	Accessor string
+
+	// TTL is how long the token is valid for after its creation
+	TTL time.Duration
+
+	// CreationTime is when the response was wrapped
+	CreationTime time.Time
 }
 
 // IsError returns true if this response seems to indicate an error.
@@ -1,6 +1,9 @@
 package vault
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/vault/logical"
 )
@@ -38,6 +41,13 @@ type ACL struct {
 	root bool
 }
 
+// aclEntry holds the path policies of every policy that has a rule
+// for the same path. The rules are kept apart rather than merged into
+// one, since each carries its own conditions.
+type aclEntry struct {
+	policies []*PathPolicy
+}
+
 // New is used to construct a policy based ACL from a set of policies.
 func NewACL(policies []*Policy) (*ACL, error) {
 	// Initialize
@@ -64,88 +74,201 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				tree = a.globRules
 			}
 
-			// Check for an existing policy
+			// Add to an existing entry
 			raw, ok := tree.Get(pp.Prefix)
 			if !ok {
-				tree.Insert(pp.Prefix, pp)
+				tree.Insert(pp.Prefix, &aclEntry{policies: []*PathPolicy{pp}})
 				continue
 			}
-			existing := raw.(*PathPolicy)
-
-			// Check if this policy is takes precedence
-			if pp.TakesPrecedence(existing) {
-				tree.Insert(pp.Prefix, pp)
-			}
+			entry := raw.(*aclEntry)
+			entry.policies = append(entry.policies, pp)
 		}
 	}
 	return a, nil
 }
 
-// AllowOperation is used to check if the given operation is permitted
-func (a *ACL) AllowOperation(op logical.Operation, path string) bool {
+// ConditionError is returned when the policy level for a path would
+// permit an operation, but the request does not satisfy the conditions
+// attached to the path policy.
+type ConditionError struct {
+	Prefix string
+	Reason string
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("permission denied: condition on path policy '%s' not met: %s",
+		e.Prefix, e.Reason)
+}
+
+// AllowOperation is used to check if the given operation is permitted.
+// The connection is used to evaluate any conditions attached to the
+// matching path policies. The operation is permitted if any of them grants
+// a sufficient level and has its conditions met, unless one of them denies
+// the path. If the operation is denied only because conditions were not
+// met, a *ConditionError describing the reason is returned.
+func (a *ACL) AllowOperation(op logical.Operation, path string,
+	conn *logical.Connection) (bool, error) {
 	// Fast-path root
 	if a.root {
-		return true
+		return true, nil
 	}
 
 	// Check if any policy level allows this operation
 	permitted := permittedPolicyLevels[op]
 	if permitted[0] == PathPolicyDeny {
-		return true
+		return true, nil
 	}
 
-	// Find an exact matching rule, look for glob if no match
-	var policy *PathPolicy
-	raw, ok := a.exactRules.Get(path)
-	if ok {
-		policy = raw.(*PathPolicy)
-		goto CHECK
+	// Find the matching rule, default deny if no match
+	entry := a.entry(path)
+	if entry == nil {
+		return false, nil
 	}
+	return entry.allow(permitted, conn, time.Now())
+}
 
-	// Find a glob rule, default deny if no match
-	_, raw, ok = a.globRules.LongestPrefix(path)
-	if !ok {
-		return false
-	} else {
-		policy = raw.(*PathPolicy)
+// ControlGroup returns a path policy describing the control group that
+// the path is placed behind, or nil if there is none. If several policies
+// place the path behind a control group, the strictest applies: the highest
+// number of approvals is required, from holders of approver policies common
+// to all of them. If they have none in common, nobody can approve. It
+// should only be consulted once AllowOperation has permitted
+// the operation. Root and help operations are never subject to a control
+// group.
+func (a *ACL) ControlGroup(op logical.Operation, path string) *PathPolicy {
+	if a.root || op == logical.HelpOperation {
+		return nil
 	}
 
-CHECK:
-	// Check if the minimum permissions are met
-	for _, allowed := range permitted {
-		if allowed == policy.Policy {
-			return true
+	entry := a.entry(path)
+	if entry == nil {
+		return nil
+	}
+
+	var merged *PathPolicy
+	for _, pp := range entry.policies {
+		if !pp.HasControlGroup() {
+			continue
 		}
+		if merged == nil {
+			merged = &PathPolicy{
+				Prefix:            pp.Prefix,
+				Policy:            pp.Policy,
+				Glob:              pp.Glob,
+				RequiredApprovals: pp.RequiredApprovals,
+				ApproverPolicies:  append([]string{}, pp.ApproverPolicies...),
+			}
+			continue
+		}
+		if pp.RequiredApprovals > merged.RequiredApprovals {
+			merged.RequiredApprovals = pp.RequiredApprovals
+		}
+
+		// Only the approvers satisfying every control group may approve
+		approvers := make([]string, 0, len(merged.ApproverPolicies))
+		for _, name := range merged.ApproverPolicies {
+			if strListContains(pp.ApproverPolicies, name) {
+				approvers = append(approvers, name)
+			}
+		}
+		merged.ApproverPolicies = approvers
 	}
-	return false
+	return merged
 }
 
 // RootPrivilege checks if the user has root level permission
 // to given path. This requires that the user be root, or that
-// sudo privilege is available on that path.
-func (a *ACL) RootPrivilege(path string) bool {
+// sudo privilege is available on that path. The connection is used
+// to evaluate any conditions attached to the sudo privilege.
+func (a *ACL) RootPrivilege(path string, conn *logical.Connection) bool {
 	// Fast-path root
 	if a.root {
 		return true
 	}
 
-	// Find an exact matching rule, look for glob if no match
-	var policy *PathPolicy
-	raw, ok := a.exactRules.Get(path)
-	if ok {
-		policy = raw.(*PathPolicy)
-		goto CHECK
-	}
-
 	// Check the rules for a match, default deny if no match
-	_, raw, ok = a.globRules.LongestPrefix(path)
-	if !ok {
+	entry := a.entry(path)
+	if entry == nil {
 		return false
-	} else {
-		policy = raw.(*PathPolicy)
 	}
 
-CHECK:
 	// Check the policy level
-	return policy.Policy == PathPolicySudo
+	allowed, _ := entry.allow([]string{PathPolicySudo}, conn, time.Now())
+	return allowed
+}
+
+// Capabilities returns the policy levels that are granted on the given
+// path, taking into account the conditions of each grant. If none are,
+// the path is denied.
+func (a *ACL) Capabilities(path string, conn *logical.Connection) []string {
+	// Fast-path root
+	if a.root {
+		return []string{"root"}
+	}
+
+	entry := a.entry(path)
+	if entry == nil {
+		return []string{PathPolicyDeny}
+	}
+
+	var levels []string
+	for _, level := range []string{PathPolicyRead, PathPolicyWrite, PathPolicySudo} {
+		if allowed, _ := entry.allow([]string{level}, conn, time.Now()); allowed {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return []string{PathPolicyDeny}
+	}
+	return levels
+}
+
+// entry returns the rules for the given path. An exact matching rule
+// is used if present, otherwise the longest matching glob rule.
+func (a *ACL) entry(path string) *aclEntry {
+	if raw, ok := a.exactRules.Get(path); ok {
+		return raw.(*aclEntry)
+	}
+	if _, raw, ok := a.globRules.LongestPrefix(path); ok {
+		return raw.(*aclEntry)
+	}
+	return nil
+}
+
+// allow checks if any of the path policies grants one of the permitted
+// levels and has its conditions met at the given time. A deny policy
+// always takes precedence, regardless of its conditions.
+func (e *aclEntry) allow(permitted []string, conn *logical.Connection,
+	now time.Time) (bool, error) {
+	for _, pp := range e.policies {
+		if pp.Policy == PathPolicyDeny {
+			return false, nil
+		}
+	}
+
+	var condErr *ConditionError
+	for _, pp := range e.policies {
+		if !strListContains(permitted, pp.Policy) {
+			continue
+		}
+
+		// Check any conditions on the request context
+		if pp.HasConditions() {
+			if reason := pp.checkConditions(conn, now); reason != "" {
+				if condErr == nil {
+					prefix := pp.Prefix
+					if pp.Glob {
+						prefix += "*"
+					}
+					condErr = &ConditionError{Prefix: prefix, Reason: reason}
+				}
+				continue
+			}
+		}
+		return true, nil
+	}
+	if condErr != nil {
+		return false, condErr
+	}
+	return false, nil
 }
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -14,10 +15,10 @@ func TestACL_Root(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if !acl.RootPrivilege("sys/mount/foo") {
+	if !acl.RootPrivilege("sys/mount/foo", nil) {
 		t.Fatalf("expected root")
 	}
-	if !acl.AllowOperation(logical.WriteOperation, "sys/mount/foo") {
+	if allowed, _ := acl.AllowOperation(logical.WriteOperation, "sys/mount/foo", nil); !allowed {
 		t.Fatalf("expected permission")
 	}
 }
@@ -33,7 +34,7 @@ func TestACL_Single(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if acl.RootPrivilege("sys/mount/foo") {
+	if acl.RootPrivilege("sys/mount/foo", nil) {
 		t.Fatalf("unexpected root")
 	}
 
@@ -61,7 +62,7 @@ func TestACL_Single(t *testing.T) {
 	}
 
 	for _, tc := range tcases {
-		out := acl.AllowOperation(tc.op, tc.path)
+		out, _ := acl.AllowOperation(tc.op, tc.path, nil)
 		if out != tc.expect {
 			t.Fatalf("bad: case %#v: %v", tc, out)
 		}
@@ -87,7 +88,7 @@ func TestACL_Layered(t *testing.T) {
 }
 
 func testLayeredACL(t *testing.T, acl *ACL) {
-	if acl.RootPrivilege("sys/mount/foo") {
+	if acl.RootPrivilege("sys/mount/foo", nil) {
 		t.Fatalf("unexpected root")
 	}
 
@@ -118,7 +119,7 @@ func testLayeredACL(t *testing.T, acl *ACL) {
 	}
 
 	for _, tc := range tcases {
-		out := acl.AllowOperation(tc.op, tc.path)
+		out, _ := acl.AllowOperation(tc.op, tc.path, nil)
 		if out != tc.expect {
 			t.Fatalf("bad: case %#v: %v", tc, out)
 		}
@@ -165,3 +166,208 @@ path "sys/seal" {
 	policy = "write"
 }
 `
+
+func TestACL_Conditions(t *testing.T) {
+	policy, err := Parse(aclConditionalPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Unconditional paths are unaffected by the connection
+	allowed, err := acl.AllowOperation(logical.ReadOperation, "secret/foo", nil)
+	if !allowed || err != nil {
+		t.Fatalf("bad: %v %v", allowed, err)
+	}
+
+	// Matching connection is permitted
+	conn := &logical.Connection{RemoteAddr: "10.1.2.3"}
+	allowed, err = acl.AllowOperation(logical.WriteOperation, "sys/raw/foo", conn)
+	if !allowed || err != nil {
+		t.Fatalf("bad: %v %v", allowed, err)
+	}
+
+	// Non-matching connection is denied with a condition error
+	conn = &logical.Connection{RemoteAddr: "127.0.0.1"}
+	allowed, err = acl.AllowOperation(logical.WriteOperation, "sys/raw/foo", conn)
+	if allowed {
+		t.Fatalf("expected denial")
+	}
+	if _, ok := err.(*ConditionError); !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	// A denial by policy level is not a condition error
+	allowed, err = acl.AllowOperation(logical.WriteOperation, "secret/foo", conn)
+	if allowed || err != nil {
+		t.Fatalf("bad: %v %v", allowed, err)
+	}
+}
+
+var aclConditionalPolicy = `
+name = "bastion"
+path "sys/raw/*" {
+	policy = "write"
+	allowed_cidrs = ["10.0.0.0/8"]
+}
+path "secret/*" {
+	policy = "read"
+}
+`
+
+func TestACL_Conditions_Layered(t *testing.T) {
+	conditional, err := Parse(aclConditionalPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	unconditional, err := Parse(`
+name = "raw-read"
+path "sys/raw/*" {
+	policy = "read"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The conditions of one policy must not take away the access granted
+	// by another, regardless of the order of the policies
+	for _, policies := range [][]*Policy{
+		{conditional, unconditional},
+		{unconditional, conditional},
+	} {
+		acl, err := NewACL(policies)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		conn := &logical.Connection{RemoteAddr: "127.0.0.1"}
+		allowed, err := acl.AllowOperation(logical.ReadOperation, "sys/raw/foo", conn)
+		if !allowed || err != nil {
+			t.Fatalf("bad: %v %v", allowed, err)
+		}
+		allowed, err = acl.AllowOperation(logical.WriteOperation, "sys/raw/foo", conn)
+		if allowed {
+			t.Fatalf("expected denial")
+		}
+		if _, ok := err.(*ConditionError); !ok {
+			t.Fatalf("bad: %#v", err)
+		}
+
+		conn = &logical.Connection{RemoteAddr: "10.1.2.3"}
+		allowed, err = acl.AllowOperation(logical.WriteOperation, "sys/raw/foo", conn)
+		if !allowed || err != nil {
+			t.Fatalf("bad: %v %v", allowed, err)
+		}
+	}
+}
+
+func TestACL_ControlGroup(t *testing.T) {
+	policy, err := Parse(aclControlGroupPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	pp := acl.ControlGroup(logical.WriteOperation, "sys/raw/foo")
+	if pp == nil || pp.RequiredApprovals != 2 {
+		t.Fatalf("bad: %#v", pp)
+	}
+	if pp := acl.ControlGroup(logical.HelpOperation, "sys/raw/foo"); pp != nil {
+		t.Fatalf("bad: %#v", pp)
+	}
+	if pp := acl.ControlGroup(logical.ReadOperation, "secret/foo"); pp != nil {
+		t.Fatalf("bad: %#v", pp)
+	}
+	if pp := acl.ControlGroup(logical.ReadOperation, "unknown"); pp != nil {
+		t.Fatalf("bad: %#v", pp)
+	}
+}
+
+var aclControlGroupPolicy = `
+name = "dba"
+path "sys/raw/*" {
+	policy = "write"
+	required_approvals = 2
+	approver_policies = ["security"]
+}
+path "secret/*" {
+	policy = "read"
+}
+`
+
+func TestACL_ControlGroup_Merge(t *testing.T) {
+	dba, err := Parse(aclControlGroupPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ops, err := Parse(`
+name = "ops"
+path "sys/raw/*" {
+	policy = "sudo"
+	required_approvals = 1
+	approver_policies = ["admins", "security"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	plain, err := Parse(`
+name = "raw"
+path "sys/raw/*" {
+	policy = "sudo"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The strictest control group applies, regardless of the order of
+	// the policies or of another policy granting the same level. Only the
+	// approver policies common to the control groups may approve.
+	for _, policies := range [][]*Policy{
+		{dba, ops, plain},
+		{plain, ops, dba},
+	} {
+		acl, err := NewACL(policies)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		pp := acl.ControlGroup(logical.WriteOperation, "sys/raw/foo")
+		if pp == nil || pp.RequiredApprovals != 2 {
+			t.Fatalf("bad: %#v", pp)
+		}
+		if !reflect.DeepEqual(pp.ApproverPolicies, []string{"security"}) {
+			t.Fatalf("bad: %#v", pp.ApproverPolicies)
+		}
+	}
+
+	// Without common approver policies nobody can approve
+	admins, err := Parse(`
+name = "admins-only"
+path "sys/raw/*" {
+	policy = "sudo"
+	required_approvals = 1
+	approver_policies = ["admins"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL([]*Policy{dba, admins})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pp := acl.ControlGroup(logical.WriteOperation, "sys/raw/foo")
+	if pp == nil || pp.RequiredApprovals != 2 || len(pp.ApproverPolicies) != 0 {
+		t.Fatalf("bad: %#v", pp)
+	}
+}
@@ -262,7 +262,8 @@ func (a *AuditBroker) IsRegistered(name string) bool {
 
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
-func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error) (reterr error) {
+func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request,
+	outerErr error, opts *audit.LogOptions) (reterr error) {
 	defer metrics.MeasureSince([]string{"audit", "log_request"}, time.Now())
 	a.l.RLock()
 	defer a.l.RUnlock()
@@ -277,7 +278,7 @@ func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, outer
 	anyLogged := false
 	for name, be := range a.backends {
 		start := time.Now()
-		err := be.backend.LogRequest(auth, req, outerErr)
+		err := be.backend.LogRequest(auth, req, outerErr, opts)
 		metrics.MeasureSince([]string{"audit", name, "log_request"}, start)
 		if err != nil {
 			a.logger.Printf("[ERR] audit: backend '%s' failed to log request: %v", name, err)
@@ -294,7 +295,7 @@ func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, outer
 // LogResponse is used to ensure all the audit backends have an opportunity to
 // log the given response and that *at least one* succeeds.
 func (a *AuditBroker) LogResponse(auth *logical.Auth, req *logical.Request,
-	resp *logical.Response, err error) (reterr error) {
+	resp *logical.Response, err error, opts *audit.LogOptions) (reterr error) {
 	defer metrics.MeasureSince([]string{"audit", "log_response"}, time.Now())
 	a.l.RLock()
 	defer a.l.RUnlock()
@@ -309,7 +310,7 @@ func (a *AuditBroker) LogResponse(auth *logical.Auth, req *logical.Request,
 	anyLogged := false
 	for name, be := range a.backends {
 		start := time.Now()
-		err := be.backend.LogResponse(auth, req, resp, err)
+		err := be.backend.LogResponse(auth, req, resp, err, opts)
 		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
 		if err != nil {
 			a.logger.Printf("[ERR] audit: backend '%s' failed to log response: %v", name, err)
@@ -18,6 +18,7 @@ type NoopAudit struct {
 	ReqAuth []*logical.Auth
 	Req     []*logical.Request
 	ReqErrs []error
+	ReqOpts []*audit.LogOptions
 
 	RespErr  error
 	RespAuth []*logical.Auth
@@ -26,14 +27,15 @@ type NoopAudit struct {
 	RespErrs []error
 }
 
-func (n *NoopAudit) LogRequest(a *logical.Auth, r *logical.Request, err error) error {
+func (n *NoopAudit) LogRequest(a *logical.Auth, r *logical.Request, err error, o *audit.LogOptions) error {
 	n.ReqAuth = append(n.ReqAuth, a)
 	n.Req = append(n.Req, r)
 	n.ReqErrs = append(n.ReqErrs, err)
+	n.ReqOpts = append(n.ReqOpts, o)
 	return n.ReqErr
 }
 
-func (n *NoopAudit) LogResponse(a *logical.Auth, r *logical.Request, re *logical.Response, err error) error {
+func (n *NoopAudit) LogResponse(a *logical.Auth, r *logical.Request, re *logical.Response, err error, o *audit.LogOptions) error {
 	n.RespAuth = append(n.RespAuth, a)
 	n.RespReq = append(n.RespReq, r)
 	n.Resp = append(n.Resp, re)
@@ -208,7 +210,7 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	}
 	reqErrs := errors.New("errs")
 
-	err := b.LogRequest(auth, req, reqErrs)
+	err := b.LogRequest(auth, req, reqErrs, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -227,13 +229,13 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 
 	// Should still work with one failing backend
 	a1.ReqErr = fmt.Errorf("failed")
-	if err := b.LogRequest(auth, req, nil); err != nil {
+	if err := b.LogRequest(auth, req, nil, nil); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
 	// Should FAIL work with both failing backends
 	a2.ReqErr = fmt.Errorf("failed")
-	if err := b.LogRequest(auth, req, nil); err.Error() != "no audit backend succeeded in logging the request" {
+	if err := b.LogRequest(auth, req, nil, nil); err.Error() != "no audit backend succeeded in logging the request" {
 		t.Fatalf("err: %v", err)
 	}
 }
@@ -271,7 +273,7 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	}
 	respErr := fmt.Errorf("permission denied")
 
-	err := b.LogResponse(auth, req, resp, respErr)
+	err := b.LogResponse(auth, req, resp, respErr, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -293,14 +295,14 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 
 	// Should still work with one failing backend
 	a1.RespErr = fmt.Errorf("failed")
-	err = b.LogResponse(auth, req, resp, respErr)
+	err = b.LogResponse(auth, req, resp, respErr, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
 	// Should FAIL work with both failing backends
 	a2.RespErr = fmt.Errorf("failed")
-	err = b.LogResponse(auth, req, resp, respErr)
+	err = b.LogResponse(auth, req, resp, respErr, nil)
 	if err.Error() != "no audit backend succeeded in logging the response" {
 		t.Fatalf("err: %v", err)
 	}
@@ -66,7 +66,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	view := NewBarrierView(c.barrier, credentialBarrierPrefix+entry.UUID+"/")
 
 	// Create the new backend
-	backend, err := c.newCredentialBackend(entry.Type, view, nil)
+	backend, err := c.newCredentialBackend(entry.Type, view, entry.backendConfig())
 	if err != nil {
 		return err
 	}
@@ -75,6 +75,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	newTable := c.auth.Clone()
 	newTable.Entries = append(newTable.Entries, entry)
 	if err := c.persistAuth(newTable); err != nil {
+		cleanupBackend(backend)
 		return errors.New("failed to update auth table")
 	}
 	c.auth = newTable
@@ -82,6 +83,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	// Mount the backend
 	path := credentialRoutePrefix + entry.Path
 	if err := c.router.Mount(backend, path, entry.UUID, view); err != nil {
+		cleanupBackend(backend)
 		return err
 	}
 	c.logger.Printf("[INFO] core: enabled credential backend '%s' type: %s",
@@ -138,6 +140,13 @@ func (c *Core) disableCredential(path string) error {
 		}
 	}
 
+	// Remove the aliases of the backend from the identity store
+	if entry := c.auth.Find(path); entry != nil {
+		if err := c.identityStore.DeleteMountAliases(entry.UUID); err != nil {
+			return err
+		}
+	}
+
 	// Remove the mount table entry
 	if err := c.removeCredEntry(path); err != nil {
 		return err
@@ -146,6 +155,108 @@ func (c *Core) disableCredential(path string) error {
 	return nil
 }
 
+// remountCredential is used to move a credential backend to a new path.
+// The storage of the backend is kept, and the leases and tokens issued by
+// it are moved along so they remain valid.
+func (c *Core) remountCredential(src, dst string) error {
+	c.auth.Lock()
+	defer c.auth.Unlock()
+
+	// Ensure we end the path in a slash
+	if !strings.HasSuffix(src, "/") {
+		src += "/"
+	}
+	if !strings.HasSuffix(dst, "/") {
+		dst += "/"
+	}
+
+	// Ensure the token backend is not affected
+	if src == "token/" || dst == "token/" {
+		return fmt.Errorf("token credential backend cannot be remounted")
+	}
+
+	// Verify exact match of the route
+	srcPath := credentialRoutePrefix + src
+	dstPath := credentialRoutePrefix + dst
+	if match := c.router.MatchingMount(srcPath); match == "" || match != srcPath {
+		return fmt.Errorf("no matching backend at '%s'", src)
+	}
+
+	// Verify there is no conflicting backend
+	if match := c.router.MatchingMount(dstPath); match != "" {
+		return fmt.Errorf("existing backend at '%s'", strings.TrimPrefix(match, credentialRoutePrefix))
+	}
+
+	// Mark the entry as tainted
+	if err := c.taintCredEntry(src); err != nil {
+		return err
+	}
+
+	// Taint the router path to prevent routing
+	if err := c.router.Taint(srcPath); err != nil {
+		return err
+	}
+
+	// Move the leases and the tokens of the backend
+	if err := c.expiration.MovePrefix(srcPath, dstPath); err != nil {
+		return err
+	}
+
+	// Update the entry in the auth table. The storage view of the backend
+	// is keyed by its UUID, so its data moves along with the entry.
+	newTable := c.auth.Clone()
+	for _, ent := range newTable.Entries {
+		if ent.Path == src {
+			ent.Path = dst
+			ent.Tainted = false
+			break
+		}
+	}
+
+	// Update the auth table
+	if err := c.persistAuth(newTable); err != nil {
+		return errors.New("failed to update auth table")
+	}
+	c.auth = newTable
+
+	// Remount the backend
+	if err := c.router.Remount(srcPath, dstPath); err != nil {
+		return err
+	}
+
+	// Un-taint the path
+	if err := c.router.Untaint(dstPath); err != nil {
+		return err
+	}
+
+	c.logger.Printf("[INFO] core: remounted credential backend '%s' to '%s'", src, dst)
+	return nil
+}
+
+// tuneCredential is used to update the settings of a credential backend
+func (c *Core) tuneCredential(path string, tuned *MountEntry) error {
+	c.auth.Lock()
+	defer c.auth.Unlock()
+
+	// Ensure we end the path in a slash
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	// Verify exact match of the route
+	entry := c.auth.Find(path)
+	if entry == nil {
+		return fmt.Errorf("no matching backend at '%s'", path)
+	}
+
+	if err := c.tuneEntry(c.auth, entry, tuned, credentialRoutePrefix+path,
+		c.persistAuth, c.newCredentialBackend); err != nil {
+		return err
+	}
+	c.logger.Printf("[INFO] core: tuned credential backend '%s'", path)
+	return nil
+}
+
 // removeCredEntry is used to remove an entry in the auth table
 func (c *Core) removeCredEntry(path string) error {
 	// Taint the entry from the auth table
@@ -242,7 +353,7 @@ func (c *Core) setupCredentials() error {
 		view = NewBarrierView(c.barrier, credentialBarrierPrefix+entry.UUID+"/")
 
 		// Initialize the backend
-		backend, err = c.newCredentialBackend(entry.Type, view, nil)
+		backend, err = c.newCredentialBackend(entry.Type, view, entry.backendConfig())
 		if err != nil {
 			c.logger.Printf(
 				"[ERR] core: failed to create credential entry %#v: %v",
@@ -3,6 +3,7 @@ package vault
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 )
@@ -221,6 +222,119 @@ func TestCore_DisableCredential_Cleanup(t *testing.T) {
 	}
 }
 
+func TestCore_RemountCredential(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	me := &MountEntry{
+		Path: "foo",
+		Type: "noop",
+	}
+	if err := c.enableCredential(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Inject data
+	se := &logical.StorageEntry{
+		Key:   "keep",
+		Value: []byte("test"),
+	}
+	if err := c.router.MatchingView("auth/foo/").Put(se); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Generate a new token auth
+	noop.Response = &logical.Response{
+		Auth: &logical.Auth{
+			Policies: []string{"foo"},
+			LeaseOptions: logical.LeaseOptions{
+				Lease:     time.Hour,
+				Renewable: true,
+			},
+		},
+	}
+	r := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "auth/foo/login",
+	}
+	resp, err := c.HandleRequest(r)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := resp.Auth.ClientToken
+
+	if err := c.remountCredential("foo", "bar"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if c.auth.Find("foo/") != nil || c.auth.Find("bar/") == nil {
+		t.Fatalf("bad: %#v", c.auth.Entries)
+	}
+
+	// The data of the backend is kept
+	out, err := c.router.MatchingView("auth/bar/").Get("keep")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("missing entry")
+	}
+
+	// The token is moved along with its lease
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.Path != "auth/bar/login" {
+		t.Fatalf("bad: %#v", te)
+	}
+	leases, err := c.expiration.List("auth/bar/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(leases) != 1 {
+		t.Fatalf("bad: %v", leases)
+	}
+	if leases, _ := c.expiration.List("auth/foo/"); len(leases) != 0 {
+		t.Fatalf("bad: %v", leases)
+	}
+
+	// The token can still be renewed
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The token is revoked by the prefix of the new path
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/revoke-prefix/auth/bar")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te, err = c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te != nil {
+		t.Fatalf("bad: %#v", te)
+	}
+}
+
+func TestCore_RemountCredential_Protected(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	if err := c.remountCredential("token", "foo"); err == nil {
+		t.Fatalf("should fail")
+	}
+	if err := c.remountCredential("nope", "foo"); err == nil {
+		t.Fatalf("should fail")
+	}
+}
+
 func TestDefaultAuthTable(t *testing.T) {
 	table := defaultAuthTable()
 	verifyDefaultAuthTable(t, table)
@@ -116,6 +116,11 @@ type SecurityBarrier interface {
 	// ActiveKeyInfo is used to inform details about the active key
 	ActiveKeyInfo() (*KeyInfo, error)
 
+	// DeriveKey is used to derive a key for the given context from the
+	// encryption key of the given term. This allows values that are never
+	// persisted to be protected by the keyring.
+	DeriveKey(term uint32, context []byte) ([]byte, error)
+
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey([]byte) error
 
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/binary"
 	"encoding/json"
@@ -549,6 +551,27 @@ func (b *AESGCMBarrier) ActiveKeyInfo() (*KeyInfo, error) {
 	return info, nil
 }
 
+// DeriveKey is used to derive a key for the given context from the
+// encryption key of the given term
+func (b *AESGCMBarrier) DeriveKey(term uint32, context []byte) ([]byte, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return nil, ErrBarrierSealed
+	}
+
+	// Read the underlying key
+	key := b.keyring.TermKey(term)
+	if key == nil {
+		return nil, fmt.Errorf("no key for term %d", term)
+	}
+
+	// Derive the key using HMAC-SHA256 keyed by the term key
+	mac := hmac.New(sha256.New, key.Value)
+	mac.Write(context)
+	return mac.Sum(nil), nil
+}
+
 // Rekey is used to change the master key used to protect the keyring
 func (b *AESGCMBarrier) Rekey(key []byte) error {
 	b.l.Lock()
@@ -284,3 +284,50 @@ func TestInitialize_KeyLength(t *testing.T) {
 		t.Fatalf("key length protection failed")
 	}
 }
+
+func TestAESGCMBarrier_DeriveKey(t *testing.T) {
+	_, b, _ := mockBarrier(t)
+
+	info, err := b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	term := uint32(info.Term)
+
+	first, err := b.DeriveKey(term, []byte("foo"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(first) != 32 {
+		t.Fatalf("bad key length: %d", len(first))
+	}
+
+	// Same context should derive the same key
+	second, err := b.DeriveKey(term, []byte("foo"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("keys should match")
+	}
+
+	// A different context should derive a different key
+	other, err := b.DeriveKey(term, []byte("bar"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bytes.Equal(first, other) {
+		t.Fatalf("keys should differ")
+	}
+
+	// Unknown terms should fail
+	if _, err := b.DeriveKey(term+1, []byte("foo")); err == nil {
+		t.Fatalf("should fail")
+	}
+
+	// A sealed barrier should fail
+	b.Seal()
+	if _, err := b.DeriveKey(term, []byte("foo")); err != ErrBarrierSealed {
+		t.Fatalf("err: %v", err)
+	}
+}
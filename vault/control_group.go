@@ -0,0 +1,370 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/uuid"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupSubPath is the sub-path used for the control group
+	// store view. This is nested under the system view.
+	controlGroupSubPath = "control-group/"
+
+	// controlGroupRedeemPath is the path of the system backend that
+	// redeems an approved request by replaying it
+	controlGroupRedeemPath = "sys/control-group/request"
+
+	// controlGroupRequestTTL is how long a parked request may wait
+	// for approval and redemption before it is discarded.
+	controlGroupRequestTTL = 24 * time.Hour
+)
+
+// ControlGroupRequest is a request that was permitted by the ACL but
+// is parked until enough approvers have authorized it. It is stored in
+// the barrier so that it survives a leader failover.
+type ControlGroupRequest struct {
+	ID                string                  `json:"id"`
+	Operation         logical.Operation       `json:"operation"`
+	Path              string                  `json:"path"`
+	Data              map[string]interface{}  `json:"data"`
+	RequesterID       string                  `json:"requester_id"`
+	RequesterIdentity string                  `json:"requester_identity"`
+	RequesterName     string                  `json:"requester_name"`
+	RequiredApprovals int                     `json:"required_approvals"`
+	ApproverPolicies  []string                `json:"approver_policies"`
+	Approvals         []*ControlGroupApproval `json:"approvals"`
+	CreationTime      time.Time               `json:"creation_time"`
+}
+
+// ControlGroupApproval records a single authorization of a parked request.
+// The approver is identified by controlGroupIdentity, so that approvals
+// made with several tokens of the same person are only counted once.
+type ControlGroupApproval struct {
+	ApproverID   string    `json:"approver_id"`
+	ApproverName string    `json:"approver_name"`
+	Time         time.Time `json:"time"`
+}
+
+// Approved checks if the request has collected enough approvals
+func (r *ControlGroupRequest) Approved() bool {
+	return len(r.Approvals) >= r.RequiredApprovals
+}
+
+// ControlGroupStore is used to durably store parked control group requests
+type ControlGroupStore struct {
+	view *BarrierView
+
+	// l serializes the read-modify-write cycles on requests
+	l sync.Mutex
+}
+
+// NewControlGroupStore creates a new ControlGroupStore backed by the given view
+func NewControlGroupStore(view *BarrierView) *ControlGroupStore {
+	return &ControlGroupStore{
+		view: view,
+	}
+}
+
+// setupControlGroups is used to initialize the control group store
+// when the vault is being unsealed.
+func (c *Core) setupControlGroups() error {
+	view := c.systemView.SubView(controlGroupSubPath)
+	c.controlGroups = NewControlGroupStore(view)
+	return nil
+}
+
+// teardownControlGroups is used to reverse setupControlGroups
+// when the vault is being sealed.
+func (c *Core) teardownControlGroups() error {
+	c.controlGroups = nil
+	return nil
+}
+
+// put is used to persist a request
+func (cs *ControlGroupStore) put(r *ControlGroupRequest) error {
+	entry, err := logical.StorageEntryJSON(r.ID, r)
+	if err != nil {
+		return fmt.Errorf("failed to create entry: %v", err)
+	}
+	if err := cs.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist control group request: %v", err)
+	}
+	return nil
+}
+
+// get is used to load a request. Requests that have outlived
+// controlGroupRequestTTL are removed and treated as missing.
+func (cs *ControlGroupStore) get(id string) (*ControlGroupRequest, error) {
+	out, err := cs.view.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read control group request: %v", err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	r := new(ControlGroupRequest)
+	if err := out.DecodeJSON(r); err != nil {
+		return nil, fmt.Errorf("failed to decode control group request: %v", err)
+	}
+
+	if time.Now().UTC().Sub(r.CreationTime) > controlGroupRequestTTL {
+		if err := cs.view.Delete(id); err != nil {
+			return nil, fmt.Errorf("failed to delete control group request: %v", err)
+		}
+		return nil, nil
+	}
+	return r, nil
+}
+
+// parkControlGroupRequest stores a request that requires approval by the
+// control group of the given path policy, and returns the response that
+// tells the requester how to follow up.
+func (c *Core) parkControlGroupRequest(req *logical.Request, auth *logical.Auth,
+	pp *PathPolicy) (*logical.Response, error) {
+	defer metrics.MeasureSince([]string{"control_group", "park"}, time.Now())
+
+	te, err := c.tokenStore.Lookup(auth.ClientToken)
+	if err != nil || te == nil {
+		c.logger.Printf("[ERR] core: failed to lookup token: %v", err)
+		return nil, ErrInternalError
+	}
+	identity, err := c.controlGroupIdentity(te)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to resolve control group identity: %v", err)
+		return nil, ErrInternalError
+	}
+
+	r := &ControlGroupRequest{
+		ID:                uuid.GenerateUUID(),
+		Operation:         req.Operation,
+		Path:              req.Path,
+		Data:              req.Data,
+		RequesterID:       c.tokenStore.SaltID(auth.ClientToken),
+		RequesterIdentity: identity,
+		RequesterName:     auth.DisplayName,
+		RequiredApprovals: pp.RequiredApprovals,
+		ApproverPolicies:  pp.ApproverPolicies,
+		CreationTime:      time.Now().UTC(),
+	}
+	if err := c.controlGroups.put(r); err != nil {
+		c.logger.Printf("[ERR] core: failed to park control group request: %v", err)
+		return nil, ErrInternalError
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"control_group_request_id": r.ID,
+			"required_approvals":       r.RequiredApprovals,
+		},
+	}
+	return resp, nil
+}
+
+// controlGroupLookup is used to fetch a parked request. It may be
+// called by the requester or by any holder of an approver policy.
+func (c *Core) controlGroupLookup(token, id string) (*ControlGroupRequest, error) {
+	r, te, err := c.lookupControlGroupRequest(token, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.tokenStore.SaltID(te.ID) == r.RequesterID {
+		return r, nil
+	}
+	approver, err := c.controlGroupApprover(r, te)
+	if err != nil {
+		c.logger.Printf("[ERR] core: %v", err)
+		return nil, ErrInternalError
+	}
+	if !approver {
+		return nil, logical.ErrPermissionDenied
+	}
+	return r, nil
+}
+
+// controlGroupAuthorize is used to approve a parked request. The token
+// must hold one of the approver policies and may not belong to the
+// requester. Each identity is only counted once.
+func (c *Core) controlGroupAuthorize(token, id string) (*ControlGroupRequest, error) {
+	defer metrics.MeasureSince([]string{"control_group", "authorize"}, time.Now())
+	c.controlGroups.l.Lock()
+	defer c.controlGroups.l.Unlock()
+
+	r, te, err := c.lookupControlGroupRequest(token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the approver
+	approverID, err := c.controlGroupIdentity(te)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to resolve control group identity: %v", err)
+		return nil, ErrInternalError
+	}
+	if c.tokenStore.SaltID(te.ID) == r.RequesterID || approverID == r.RequesterIdentity {
+		return nil, fmt.Errorf("requester cannot authorize their own request")
+	}
+	approver, err := c.controlGroupApprover(r, te)
+	if err != nil {
+		c.logger.Printf("[ERR] core: %v", err)
+		return nil, ErrInternalError
+	}
+	if !approver {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	// Record the approval unless this identity already approved
+	for _, a := range r.Approvals {
+		if a.ApproverID == approverID {
+			return r, nil
+		}
+	}
+	r.Approvals = append(r.Approvals, &ControlGroupApproval{
+		ApproverID:   approverID,
+		ApproverName: te.DisplayName,
+		Time:         time.Now().UTC(),
+	})
+	if err := c.controlGroups.put(r); err != nil {
+		c.logger.Printf("[ERR] core: failed to authorize control group request: %v", err)
+		return nil, ErrInternalError
+	}
+	c.logger.Printf("[INFO] core: control group request %s on '%s' authorized by %s (%d/%d)",
+		r.ID, r.Path, te.DisplayName, len(r.Approvals), r.RequiredApprovals)
+	return r, nil
+}
+
+// controlGroupRedeem is used by the requester to execute an approved
+// request. The request is removed and replayed with the given token and
+// connection, so the token must still be valid and permitted to perform it.
+// The replay is handled and audited as a request of its own. The redeemed
+// request is returned along with the response of the replay.
+func (c *Core) controlGroupRedeem(token, id string,
+	conn *logical.Connection) (*ControlGroupRequest, *logical.Response, error) {
+	defer metrics.MeasureSince([]string{"control_group", "redeem"}, time.Now())
+	c.controlGroups.l.Lock()
+	r, te, err := c.lookupControlGroupRequest(token, id)
+	if err == nil {
+		switch {
+		case c.tokenStore.SaltID(te.ID) != r.RequesterID:
+			err = logical.ErrPermissionDenied
+		case !r.Approved():
+			err = fmt.Errorf("request has %d of %d required approvals",
+				len(r.Approvals), r.RequiredApprovals)
+		default:
+			if derr := c.controlGroups.view.Delete(r.ID); derr != nil {
+				c.logger.Printf("[ERR] core: failed to delete control group request: %v", derr)
+				err = ErrInternalError
+			}
+		}
+	}
+	c.controlGroups.l.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Replay the original request, bypassing the control group
+	req := &logical.Request{
+		Operation:   r.Operation,
+		Path:        r.Path,
+		Data:        r.Data,
+		ClientToken: token,
+		Connection:  conn,
+	}
+	if c.namespace != nil {
+		req.Namespace = c.namespace.Path
+	}
+	resp, auth, err := c.handleRequest(req, true)
+	resp, err = c.completeRequest(req, resp, auth, err)
+	return r, resp, err
+}
+
+// controlGroupIdentity returns the identity that requests and approvals
+// made with the token are attributed to, so that a person cannot act as
+// several approvers, or approve their own request, by using several tokens.
+// This is the entity of the token if it has one. Otherwise it is the topmost
+// ancestor of the token below a root token, since tokens created from a
+// token are held by the same person as it.
+func (c *Core) controlGroupIdentity(te *TokenEntry) (string, error) {
+	if te.EntityID != "" {
+		return "entity:" + te.EntityID, nil
+	}
+	for te.Parent != "" {
+		parent, err := c.tokenStore.Lookup(te.Parent)
+		if err != nil {
+			return "", err
+		}
+		if parent == nil || strListContains(parent.Policies, "root") {
+			break
+		}
+		te = parent
+	}
+	return "token:" + c.tokenStore.SaltID(te.ID), nil
+}
+
+// controlGroupStatus formats the status of a parked request
+func controlGroupStatus(r *ControlGroupRequest) map[string]interface{} {
+	approvers := make([]string, 0, len(r.Approvals))
+	for _, a := range r.Approvals {
+		approvers = append(approvers, a.ApproverName)
+	}
+	return map[string]interface{}{
+		"request_id":         r.ID,
+		"operation":          string(r.Operation),
+		"path":               r.Path,
+		"requester":          r.RequesterName,
+		"required_approvals": r.RequiredApprovals,
+		"approvers":          approvers,
+		"approved":           r.Approved(),
+		"creation_time":      r.CreationTime,
+	}
+}
+
+// lookupControlGroupRequest resolves both the token and the parked request
+func (c *Core) lookupControlGroupRequest(token, id string) (*ControlGroupRequest, *TokenEntry, error) {
+	if token == "" {
+		return nil, nil, fmt.Errorf("missing client token")
+	}
+	if id == "" {
+		return nil, nil, fmt.Errorf("missing request id")
+	}
+
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to lookup token: %v", err)
+		return nil, nil, ErrInternalError
+	}
+	if te == nil {
+		return nil, nil, logical.ErrPermissionDenied
+	}
+
+	r, err := c.controlGroups.get(id)
+	if err != nil {
+		c.logger.Printf("[ERR] core: %v", err)
+		return nil, nil, ErrInternalError
+	}
+	if r == nil {
+		return nil, nil, fmt.Errorf("control group request not found")
+	}
+	return r, te, nil
+}
+
+// controlGroupApprover checks if the token holds an approver policy of the
+// request, either directly or through its entity, like the policies its ACL
+// is built from
+func (c *Core) controlGroupApprover(r *ControlGroupRequest, te *TokenEntry) (bool, error) {
+	policies, err := c.tokenPolicies(te)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range r.ApproverPolicies {
+		if strListContains(policies, p) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,285 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func testCoreControlGroup(t *testing.T) (*Core, []byte, string) {
+	c, key, root := TestCoreUnsealed(t)
+
+	policies := map[string]string{
+		"dba": `
+path "secret/*" {
+	policy = "write"
+	required_approvals = 1
+	approver_policies = ["security"]
+}
+`,
+		"security": `
+path "secret/*" {
+	policy = "read"
+}
+path "sys/control-group/authorize" {
+	policy = "write"
+}
+`,
+	}
+	for name, rules := range policies {
+		req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/"+name)
+		req.Data["rules"] = rules
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	testCoreMakeToken(t, c, root, "requester", []string{"dba"})
+	testCoreMakeToken(t, c, root, "other", []string{"dba"})
+	testCoreMakeToken(t, c, root, "approver", []string{"security"})
+	return c, key, root
+}
+
+func TestCore_ControlGroup(t *testing.T) {
+	c, key, root := testCoreControlGroup(t)
+
+	// The write is parked instead of being performed
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: "requester",
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id, _ := resp.Data["control_group_request_id"].(string)
+	if id == "" || resp.Data["required_approvals"] != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Parked requests survive a seal
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if unseal, err := c.Unseal(key); err != nil || !unseal {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the requester and approvers may look up the request
+	resp, err = testControlGroupRequest(c, "lookup", "requester", id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["path"] != "secret/foo" || resp.Data["requester"] != "token" ||
+		resp.Data["approved"] != false {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if _, err := testControlGroupRequest(c, "lookup", "approver", id); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testControlGroupRequest(c, "lookup", "other", id); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Redeeming without approval fails
+	if _, err := testControlGroupRequest(c, "request", "requester", id); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// The requester and non-approvers cannot authorize
+	if _, err := testControlGroupRequest(c, "authorize", "requester", id); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := testControlGroupRequest(c, "authorize", "other", id); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Approvals from the same token are only counted once
+	for i := 0; i < 2; i++ {
+		resp, err = testControlGroupRequest(c, "authorize", "approver", id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(resp.Data["approvers"].([]string)) != 1 || resp.Data["approved"] != true {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	// Only the requester can redeem the request
+	if _, err := testControlGroupRequest(c, "request", "other", id); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err = testControlGroupRequest(c, "request", "requester", id)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The original request was performed
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// A request can only be redeemed once
+	if _, err := testControlGroupRequest(c, "request", "requester", id); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestCore_ControlGroup_Identity(t *testing.T) {
+	c, _, root := testCoreControlGroup(t)
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/dba-strict")
+	req.Data["rules"] = `
+path "secret/*" {
+	policy = "write"
+	required_approvals = 2
+	approver_policies = ["security"]
+}
+`
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testCoreMakeToken(t, c, root, "strict", []string{"dba-strict", "security"})
+	testCoreMakeToken(t, c, root, "approver2", []string{"security"})
+
+	req = &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: "strict",
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id := resp.Data["control_group_request_id"].(string)
+
+	// The requester cannot approve with a child token
+	child := testControlGroupChildToken(t, c, "strict", []string{"security"})
+	if _, err := testControlGroupRequest(c, "authorize", child, id); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Approvals from children of the same parent are counted once
+	for _, token := range []string{
+		testControlGroupChildToken(t, c, "approver", []string{"security"}),
+		testControlGroupChildToken(t, c, "approver", []string{"security"}),
+		"approver",
+	} {
+		resp, err = testControlGroupRequest(c, "authorize", token, id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(resp.Data["approvers"].([]string)) != 1 || resp.Data["approved"] != false {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	// A distinct approver completes the approval
+	resp, err = testControlGroupRequest(c, "authorize", "approver2", id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["approved"] != true {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_ControlGroup_GroupApprover(t *testing.T) {
+	c, _, root := testCoreControlGroup(t)
+	login := testIdentityLogin(t, c, root, "armon")
+	auth := login()
+
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: "requester",
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id := resp.Data["control_group_request_id"].(string)
+
+	if _, err := testControlGroupRequest(c, "authorize", auth.ClientToken, id); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The approver policy is granted through a group of the entity
+	resp, err = testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"name":              "security",
+		"policies":          "security",
+		"member_entity_ids": auth.EntityID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	resp, err = testControlGroupRequest(c, "authorize", auth.ClientToken, id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["approved"] != true {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_ControlGroup_Root(t *testing.T) {
+	c, _, root := testCoreControlGroup(t)
+
+	// Root tokens are not subject to control groups
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: root,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+// testControlGroupRequest performs a request on a control group endpoint
+func testControlGroupRequest(c *Core, endpoint, token, id string) (*logical.Response, error) {
+	return c.HandleRequest(&logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "sys/control-group/" + endpoint,
+		Data:        map[string]interface{}{"request_id": id},
+		ClientToken: token,
+	})
+}
+
+// testControlGroupChildToken creates a child of the given token
+func testControlGroupChildToken(t *testing.T, c *Core, parent string, policies []string) string {
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = parent
+	req.Data["policies"] = policies
+	resp, err := c.tokenStore.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	return resp.Auth.ClientToken
+}
@@ -210,9 +210,42 @@ type Core struct {
 	// token store is used to manage authentication tokens
 	tokenStore *TokenStore
 
+	// identityStore is used to map logins to entities
+	identityStore *IdentityStore
+
+	// controlGroups is used to park requests awaiting approval
+	controlGroups *ControlGroupStore
+
+	// quotas is used to limit the use of the vault
+	quotas *QuotaStore
+
+	// pluginCatalog records the plugins which can be mounted
+	pluginCatalog *PluginCatalog
+
+	// namespace is the namespace served by this core, it is nil for
+	// the root namespace
+	namespace *Namespace
+
+	// parent is the core of the parent namespace, it is nil for the
+	// root namespace
+	parent *Core
+
+	// namespaces are the cores of the child namespaces by name. They are
+	// loaded after unseal since they are a protected configuration.
+	namespaces    map[string]*Core
+	namespaceLock sync.RWMutex
+
+	// wrappingLock serializes the consumption of response wrapping tokens
+	wrappingLock sync.Mutex
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
+	// defaultLeaseTTL and maxLeaseTTL are the system-wide lease limits,
+	// which may be overridden per mount
+	defaultLeaseTTL time.Duration
+	maxLeaseTTL     time.Duration
+
 	logger *log.Logger
 }
 
@@ -227,6 +260,9 @@ type CoreConfig struct {
 	DisableMlock       bool   // Disables mlock syscall
 	CacheSize          int    // Custom cache size of zero for default
 	AdvertiseAddr      string // Set as the leader address for HA
+	DefaultLeaseTTL    time.Duration
+	MaxLeaseTTL        time.Duration
+	PluginDirectory    string // Directory of the plugin commands
 }
 
 // NewCore isk used to construct a new core
@@ -252,6 +288,17 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		}
 	}
 
+	// Apply the default lease limits
+	if conf.MaxLeaseTTL == 0 {
+		conf.MaxLeaseTTL = maxLeaseDuration
+	}
+	if conf.DefaultLeaseTTL == 0 {
+		conf.DefaultLeaseTTL = defaultLeaseDuration
+	}
+	if conf.DefaultLeaseTTL > conf.MaxLeaseTTL {
+		return nil, fmt.Errorf("cannot have DefaultLeaseTTL larger than MaxLeaseTTL")
+	}
+
 	// Wrap the backend in a cache unless disabled
 	if !conf.DisableCache {
 		_, isCache := conf.Physical.(*physical.Cache)
@@ -299,27 +346,23 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		sealed:        true,
 		standby:       true,
 		logger:        conf.Logger,
+
+		defaultLeaseTTL: conf.DefaultLeaseTTL,
+		maxLeaseTTL:     conf.MaxLeaseTTL,
 	}
+	c.pluginCatalog = NewPluginCatalog(
+		NewBarrierView(barrier, pluginCatalogPath), conf.PluginDirectory)
 
 	// Setup the backends
-	logicalBackends := make(map[string]logical.Factory)
+	c.logicalBackends = make(map[string]logical.Factory)
 	for k, f := range conf.LogicalBackends {
-		logicalBackends[k] = f
+		c.logicalBackends[k] = f
 	}
-	logicalBackends["generic"] = PassthroughBackendFactory
-	logicalBackends["system"] = func(*logical.BackendConfig) (logical.Backend, error) {
-		return NewSystemBackend(c), nil
-	}
-	c.logicalBackends = logicalBackends
-
-	credentialBackends := make(map[string]logical.Factory)
+	c.credentialBackends = make(map[string]logical.Factory)
 	for k, f := range conf.CredentialBackends {
-		credentialBackends[k] = f
-	}
-	credentialBackends["token"] = func(*logical.BackendConfig) (logical.Backend, error) {
-		return NewTokenStore(c)
+		c.credentialBackends[k] = f
 	}
-	c.credentialBackends = credentialBackends
+	c.setupBuiltinBackends()
 
 	auditBackends := make(map[string]audit.Factory)
 	for k, f := range conf.AuditBackends {
@@ -329,6 +372,27 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	return c, nil
 }
 
+// setupBuiltinBackends adds the builtin backends, which are bound to
+// the core, to the backends of the core
+func (c *Core) setupBuiltinBackends() {
+	c.logicalBackends["generic"] = PassthroughBackendFactory
+	c.logicalBackends["cubbyhole"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return NewCubbyholeBackend(c), nil
+	}
+	c.logicalBackends["identity"] = func(config *logical.BackendConfig) (logical.Backend, error) {
+		return NewIdentityStore(c, config), nil
+	}
+	c.logicalBackends["system"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return NewSystemBackend(c), nil
+	}
+	c.logicalBackends["plugin"] = c.newPluginBackend
+
+	c.credentialBackends["token"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return NewTokenStore(c)
+	}
+	c.credentialBackends["plugin"] = c.newPluginBackend
+}
+
 // Shutdown is invoked when the Vault instance is about to be terminated. It
 // should not be accessible as part of an API call as it will cause an availability
 // problem. It is only used to gracefully quit in the case of HA so that failover
@@ -345,7 +409,7 @@ func (c *Core) Shutdown() error {
 }
 
 // HandleRequest is used to handle a new incoming request
-func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err error) {
+func (c *Core) HandleRequest(req *logical.Request) (*logical.Response, error) {
 	c.stateLock.RLock()
 	defer c.stateLock.RUnlock()
 	if c.sealed {
@@ -355,13 +419,56 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		return nil, ErrStandby
 	}
 
+	// Requests within a namespace are handled by the core of the
+	// namespace, with the path of the namespace removed
+	nc, path := c.requestNamespace(req.Path)
+	if nc != c {
+		nc.stateLock.RLock()
+		defer nc.stateLock.RUnlock()
+		if nc.sealed {
+			return logical.ErrorResponse("namespace has been deleted"), logical.ErrInvalidRequest
+		}
+		req.Path = path
+		req.Namespace = nc.namespace.Path
+	}
+
+	var resp *logical.Response
 	var auth *logical.Auth
-	if c.router.LoginPath(req.Path) {
-		resp, auth, err = c.handleLoginRequest(req)
+	var err error
+	if nc.router.LoginPath(req.Path) {
+		resp, auth, err = nc.handleLoginRequest(req)
 	} else {
-		resp, auth, err = c.handleRequest(req)
+		resp, auth, err = nc.handleRequest(req, false)
+	}
+	if resp != nil && req.Path != wrappingRewrapPath {
+		resp.WrapInfo = nil
+	}
+
+	// Wrap the response if requested, before it is audited so that the
+	// wrapping token is recorded along with the response. The wrapping
+	// tokens of all namespaces belong to the root namespace.
+	var wrapInfo *logical.WrapInfo
+	if err == nil && req.WrapTTL > 0 {
+		wrapInfo, err = c.wrapResponse(req, resp)
+		if err != nil {
+			resp = nil
+		} else if wrapInfo != nil {
+			resp.WrapInfo = wrapInfo
+		}
 	}
+	resp, err = nc.completeRequest(req, resp, auth, err)
 
+	// Only the information to unwrap a wrapped response is returned
+	if err == nil && wrapInfo != nil {
+		resp = &logical.Response{WrapInfo: wrapInfo}
+	}
+	return resp, err
+}
+
+// completeRequest is used to scrub internal data from the response of
+// a handled request and to create an audit trail of the response.
+func (c *Core) completeRequest(req *logical.Request, resp *logical.Response,
+	auth *logical.Auth, err error) (*logical.Response, error) {
 	// Ensure we don't leak internal data
 	if resp != nil {
 		if resp.Secret != nil {
@@ -373,20 +480,24 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	}
 
 	// Create an audit trail of the response
-	if err := c.auditBroker.LogResponse(auth, req, resp, err); err != nil {
+	if err := c.auditBroker.LogResponse(auth, req, resp, err, c.auditOptions(req.Path)); err != nil {
 		c.logger.Printf("[ERR] core: failed to audit response (request: %#v, response: %#v): %v",
 			req, resp, err)
 		return nil, ErrInternalError
 	}
 
-	return
+	return resp, err
 }
 
-func (c *Core) handleRequest(req *logical.Request) (*logical.Response, *logical.Auth, error) {
+// handleRequest is used to handle an authenticated request. Unless
+// controlGroupApproved is set, requests on paths protected by a control
+// group are parked until they have been approved.
+func (c *Core) handleRequest(req *logical.Request,
+	controlGroupApproved bool) (*logical.Response, *logical.Auth, error) {
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
 	// Validate the token
-	auth, err := c.checkToken(req.Operation, req.Path, req.ClientToken)
+	acl, auth, err := c.checkToken(req.Operation, req.Path, req.ClientToken, req.Connection)
 	if err != nil {
 		// If it is an internal error we return that, otherwise we
 		// return invalid request so that the status codes can be correct
@@ -398,38 +509,88 @@ func (c *Core) handleRequest(req *logical.Request) (*logical.Response, *logical.
 			errType = logical.ErrInvalidRequest
 		}
 
-		if err := c.auditBroker.LogRequest(auth, req, err); err != nil {
+		// A failed policy condition is a permission denied. The reason
+		// is recorded in the audit log but not returned to the client.
+		respErr := err
+		if _, ok := err.(*ConditionError); ok {
+			errType = logical.ErrPermissionDenied
+			respErr = logical.ErrPermissionDenied
+		}
+
+		if err := c.auditBroker.LogRequest(auth, req, err, c.auditOptions(req.Path)); err != nil {
 			c.logger.Printf("[ERR] core: failed to audit request (%#v): %v",
 				req, err)
 		}
 
-		return logical.ErrorResponse(err.Error()), nil, errType
+		return logical.ErrorResponse(respErr.Error()), nil, errType
 	}
 
-	// Attach the display name
+	// Attach the display name and entity
 	req.DisplayName = auth.DisplayName
+	req.EntityID = auth.EntityID
 
 	// Create an audit trail of the request
-	if err := c.auditBroker.LogRequest(auth, req, nil); err != nil {
+	if err := c.auditBroker.LogRequest(auth, req, nil, c.auditOptions(req.Path)); err != nil {
 		c.logger.Printf("[ERR] core: failed to audit request (%#v): %v",
 			req, err)
 		return nil, auth, ErrInternalError
 	}
 
+	// Reject the request if it may create a lease beyond a quota, and
+	// otherwise hold a slot for the lease until it has been registered
+	reservation, err := c.checkLeaseCountQuotas(req)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), auth, logical.ErrQuotaExceeded
+	}
+	if reservation != nil {
+		defer reservation.release()
+	}
+
+	// Park the request if the path is protected by a control group
+	if !controlGroupApproved {
+		if pp := acl.ControlGroup(req.Operation, req.Path); pp != nil {
+			resp, err := c.parkControlGroupRequest(req, auth, pp)
+			return resp, auth, err
+		}
+	}
+
 	// Route the request
 	resp, err := c.router.Route(req)
 
+	// A redeemed control group request responds with the response of its
+	// replay, which has already been handled as a request of its own. The
+	// same goes for an unwrapped response.
+	if req.Path == controlGroupRedeemPath || req.Path == wrappingUnwrapPath {
+		return resp, auth, err
+	}
+
 	// If there is a secret, we must register it with the expiration manager.
 	// We exclude renewal of a lease, since it does not need to be re-registered
 	if resp != nil && resp.Secret != nil && !strings.HasPrefix(req.Path, "sys/renew/") {
-		// Apply the default lease if none given
+		// Apply the default lease of the mount if none given
+		defaultTTL, maxTTL := c.leaseTTLs(req.Path)
 		if resp.Secret.Lease == 0 {
-			resp.Secret.Lease = defaultLeaseDuration
+			resp.Secret.Lease = defaultTTL
 		}
 
 		// Limit the lease duration
-		if resp.Secret.Lease > maxLeaseDuration {
-			resp.Secret.Lease = maxLeaseDuration
+		if resp.Secret.Lease > maxTTL {
+			resp.Secret.Lease = maxTTL
+		}
+
+		// A lease cannot outlive the batch token it was issued to, since
+		// nothing revokes it when the token expires
+		if strings.HasPrefix(req.ClientToken, batchTokenPrefix) {
+			te, err := c.tokenStore.Lookup(req.ClientToken)
+			if err != nil || te == nil {
+				c.logger.Printf("[ERR] core: failed to lookup batch token "+
+					"(request: %#v, response: %#v): %v", req, resp, err)
+				return nil, auth, ErrInternalError
+			}
+			remaining := te.ExpireTime.Sub(time.Now().UTC())
+			if resp.Secret.Lease == 0 || resp.Secret.Lease > remaining {
+				resp.Secret.Lease = remaining
+			}
 		}
 
 		// Register the lease
@@ -446,7 +607,7 @@ func (c *Core) handleRequest(req *logical.Request) (*logical.Response, *logical.
 	// Only the token store is allowed to return an auth block, for any
 	// other request this is an internal error. We exclude renewal of a token,
 	// since it does not need to be re-registered
-	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew/") {
+	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
 		if !strings.HasPrefix(req.Path, "auth/token/") {
 			c.logger.Printf(
 				"[ERR] core: unexpected Auth response for non-token backend "+
@@ -455,21 +616,34 @@ func (c *Core) handleRequest(req *logical.Request) (*logical.Response, *logical.
 		}
 
 		// Set the default lease if non-provided, root tokens are exempt
+		defaultTTL, maxTTL := c.leaseTTLs(req.Path)
 		if resp.Auth.Lease == 0 && !strListContains(resp.Auth.Policies, "root") {
-			resp.Auth.Lease = defaultLeaseDuration
+			resp.Auth.Lease = defaultTTL
 		}
 
-		// Limit the lease duration
-		if resp.Auth.Lease > maxLeaseDuration {
-			resp.Auth.Lease = maxLeaseDuration
+		// Limit the lease duration. Periodic tokens are exempt from the
+		// mount limit, as they are when renewed.
+		if resp.Auth.Lease > maxTTL && resp.Auth.Period == 0 {
+			resp.Auth.Lease = maxTTL
 		}
 
-		// Register with the expiration manager
-		if err := c.expiration.RegisterAuth(req.Path, resp.Auth); err != nil {
-			c.logger.Printf("[ERR] core: failed to register token lease "+
+		// Register with the expiration manager under the path of the token,
+		// which differs from the request path for tokens created against a role
+		te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+		if err != nil || te == nil {
+			c.logger.Printf("[ERR] core: failed to lookup created token "+
 				"(request: %#v, response: %#v): %v", req, resp, err)
 			return nil, auth, ErrInternalError
 		}
+
+		// Batch tokens expire from their embedded TTL and have no lease
+		if !te.IsBatch() {
+			if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
+				c.logger.Printf("[ERR] core: failed to register token lease "+
+					"(request: %#v, response: %#v): %v", req, resp, err)
+				return nil, auth, ErrInternalError
+			}
+		}
 	}
 
 	// Return the response and error
@@ -482,7 +656,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 	defer metrics.MeasureSince([]string{"core", "handle_login_request"}, time.Now())
 
 	// Create an audit trail of the request, auth is not available on login requests
-	if err := c.auditBroker.LogRequest(nil, req, nil); err != nil {
+	if err := c.auditBroker.LogRequest(nil, req, nil, c.auditOptions(req.Path)); err != nil {
 		c.logger.Printf("[ERR] core: failed to audit request (%#v): %v",
 			req, err)
 		return nil, nil, ErrInternalError
@@ -511,29 +685,69 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		// Prepend the source to the display name
 		auth.DisplayName = strings.TrimSuffix(source+auth.DisplayName, "-")
 
+		// Attach the default policy
+		auth.Policies = addDefaultPolicy(auth.Policies)
+
+		// The lease of a periodic token is always its period
+		if auth.Period > 0 {
+			auth.Lease = auth.Period
+			auth.Renewable = true
+		}
+
+		// Validate the CIDR blocks the token is bound to
+		boundCIDRs, err := parseCIDRList(auth.BoundCIDRs)
+		if err != nil {
+			c.logger.Printf("[ERR] core: invalid bound CIDR blocks for token: %v", err)
+			return nil, auth, ErrInternalError
+		}
+
+		// Map the login to an entity if the backend identified the user
+		if auth.Alias != nil {
+			mountUUID := c.credentialMountUUID(c.router.MatchingMount(req.Path))
+			entity, err := c.identityStore.CreateOrFetchEntity(mountUUID, auth.Alias)
+			if err != nil {
+				c.logger.Printf("[ERR] core: failed to map login to entity: %v", err)
+				return nil, auth, ErrInternalError
+			}
+			auth.EntityID = entity.ID
+
+			// Sync the external groups the backend reported the user in
+			err = c.identityStore.UpdateExternalGroups(entity.ID, mountUUID, auth.GroupAliases)
+			if err != nil {
+				c.logger.Printf("[ERR] core: failed to update external groups of entity: %v", err)
+				return nil, auth, ErrInternalError
+			}
+		}
+
 		// Generate a token
 		te := TokenEntry{
 			Path:        req.Path,
 			Policies:    auth.Policies,
 			Meta:        auth.Metadata,
 			DisplayName: auth.DisplayName,
+			Period:      auth.Period,
+			BoundCIDRs:  boundCIDRs,
+			EntityID:    auth.EntityID,
 		}
 		if err := c.tokenStore.Create(&te); err != nil {
 			c.logger.Printf("[ERR] core: failed to create token: %v", err)
 			return nil, auth, ErrInternalError
 		}
 
-		// Populate the client token
+		// Populate the client token and accessor
 		resp.Auth.ClientToken = te.ID
+		resp.Auth.Accessor = te.Accessor
 
 		// Set the default lease if non-provided, root tokens are exempt
+		defaultTTL, maxTTL := c.leaseTTLs(req.Path)
 		if auth.Lease == 0 && !strListContains(auth.Policies, "root") {
-			auth.Lease = defaultLeaseDuration
+			auth.Lease = defaultTTL
 		}
 
-		// Limit the lease duration
-		if resp.Auth.Lease > maxLeaseDuration {
-			resp.Auth.Lease = maxLeaseDuration
+		// Limit the lease duration. Periodic tokens are exempt from the
+		// mount limit, as they are when renewed.
+		if auth.Lease > maxTTL && auth.Period == 0 {
+			auth.Lease = maxTTL
 		}
 
 		// Register with the expiration manager
@@ -550,58 +764,107 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 	return resp, auth, err
 }
 
-func (c *Core) checkToken(
-	op logical.Operation, path string, token string) (*logical.Auth, error) {
+func (c *Core) checkToken(op logical.Operation, path string, token string,
+	conn *logical.Connection) (*ACL, *logical.Auth, error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
 	// Ensure there is a client token
 	if token == "" {
-		return nil, fmt.Errorf("missing client token")
+		return nil, nil, fmt.Errorf("missing client token")
 	}
 
 	// Resolve the token policy
 	te, err := c.tokenStore.Lookup(token)
 	if err != nil {
 		c.logger.Printf("[ERR] core: failed to lookup token: %v", err)
-		return nil, ErrInternalError
+		return nil, nil, ErrInternalError
 	}
 
 	// Ensure the token is valid
 	if te == nil {
-		return nil, logical.ErrPermissionDenied
+		return nil, nil, logical.ErrPermissionDenied
 	}
 
-	// Attempt to use the token
-	if err := c.tokenStore.UseToken(te); err != nil {
-		c.logger.Printf("[ERR] core: failed to use token: %v", err)
-		return nil, ErrInternalError
+	// Ensure the token is used from an address it is bound to
+	if len(te.BoundCIDRs) > 0 {
+		var addr string
+		if conn != nil {
+			addr = conn.RemoteAddr
+		}
+		if !cidrListContains(te.BoundCIDRs, addr) {
+			return nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
+	// Attempt to use the token. Response wrapping tokens are consumed by
+	// the wrapping endpoints themselves.
+	if te.Path != wrappingTokenPath || wrappingConsumes(path) {
+		if err := c.tokenStore.UseToken(te); err != nil {
+			c.logger.Printf("[ERR] core: failed to use token: %v", err)
+			return nil, nil, ErrInternalError
+		}
 	}
 
 	// Construct the corresponding ACL object
-	acl, err := c.policy.ACL(te.Policies...)
+	acl, err := c.tokenACL(te)
 	if err != nil {
 		c.logger.Printf("[ERR] core: failed to construct ACL: %v", err)
-		return nil, ErrInternalError
+		return nil, nil, ErrInternalError
 	}
 
 	// Check if this is a root protected path
-	if c.router.RootPath(path) && !acl.RootPrivilege(path) {
-		return nil, logical.ErrPermissionDenied
+	if c.router.RootPath(path) && !acl.RootPrivilege(path, conn) {
+		return nil, nil, logical.ErrPermissionDenied
 	}
 
 	// Check the standard non-root ACLs
-	if !acl.AllowOperation(op, path) {
-		return nil, logical.ErrPermissionDenied
+	allowed, err := acl.AllowOperation(op, path, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, nil, logical.ErrPermissionDenied
 	}
 
 	// Create the auth response
 	auth := &logical.Auth{
 		ClientToken: token,
+		Accessor:    te.Accessor,
 		Policies:    te.Policies,
 		Metadata:    te.Meta,
 		DisplayName: te.DisplayName,
+		EntityID:    te.EntityID,
+	}
+	return acl, auth, nil
+}
+
+// tokenACL is used to construct the ACL of a token from its policies
+// and the policies of the entity it belongs to
+func (c *Core) tokenACL(te *TokenEntry) (*ACL, error) {
+	// Response wrapping tokens have a built-in policy
+	if te.Path == wrappingTokenPath {
+		return wrappingACL()
+	}
+
+	policies, err := c.tokenPolicies(te)
+	if err != nil {
+		return nil, err
+	}
+	return c.policy.ACL(policies...)
+}
+
+// tokenPolicies returns the effective policies of a token, which are its
+// own policies and those of the entity it belongs to
+func (c *Core) tokenPolicies(te *TokenEntry) ([]string, error) {
+	policies := te.Policies
+	if te.EntityID != "" {
+		entityPolicies, err := c.identityStore.EntityPolicies(te.EntityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup entity policies: %v", err)
+		}
+		policies = append(append([]string{}, policies...), entityPolicies...)
 	}
-	return auth, nil
+	return policies, nil
 }
 
 // Initialized checks if the Vault is already initialized
@@ -942,7 +1205,7 @@ func (c *Core) Seal(token string) error {
 	}
 
 	// Validate the token is a root token
-	_, err := c.checkToken(logical.WriteOperation, "sys/seal", token)
+	_, _, err := c.checkToken(logical.WriteOperation, "sys/seal", token, nil)
 	if err != nil {
 		return err
 	}
@@ -1231,7 +1494,13 @@ func (c *Core) postUnseal() error {
 		return err
 	}
 	if err := c.setupPolicyStore(); err != nil {
-		return nil
+		return err
+	}
+	if err := c.setupControlGroups(); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(); err != nil {
+		return err
 	}
 	if err := c.loadCredentials(); err != nil {
 		return nil
@@ -1248,6 +1517,9 @@ func (c *Core) postUnseal() error {
 	if err := c.setupAudits(); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(); err != nil {
+		return err
+	}
 	c.metricsCh = make(chan struct{})
 	go c.emitMetrics(c.metricsCh)
 	c.logger.Printf("[INFO] core: post-unseal setup complete")
@@ -1268,6 +1540,9 @@ func (c *Core) preSeal() error {
 		close(c.metricsCh)
 		c.metricsCh = nil
 	}
+	if err := c.unloadNamespaces(); err != nil {
+		return err
+	}
 	if err := c.teardownAudits(); err != nil {
 		return err
 	}
@@ -1277,6 +1552,12 @@ func (c *Core) preSeal() error {
 	if err := c.teardownCredentials(); err != nil {
 		return err
 	}
+	if err := c.teardownQuotas(); err != nil {
+		return err
+	}
+	if err := c.teardownControlGroups(); err != nil {
+		return err
+	}
 	if err := c.teardownPolicyStore(); err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -816,8 +817,9 @@ func TestCore_HandleLogin_Token(t *testing.T) {
 	}
 	expect := &TokenEntry{
 		ID:       clientToken,
+		Accessor: lresp.Auth.Accessor,
 		Parent:   "",
-		Policies: []string{"foo", "bar"},
+		Policies: []string{"foo", "bar", "default"},
 		Path:     "auth/foo/login",
 		Meta: map[string]string{
 			"user": "armon",
@@ -969,7 +971,8 @@ func TestCore_HandleLogin_AuditTrail(t *testing.T) {
 	if auth.ClientToken != clientToken {
 		t.Fatalf("bad client token: %#v", auth)
 	}
-	if len(auth.Policies) != 2 || auth.Policies[0] != "foo" || auth.Policies[1] != "bar" {
+	if len(auth.Policies) != 3 || auth.Policies[0] != "foo" || auth.Policies[1] != "bar" ||
+		auth.Policies[2] != "default" {
 		t.Fatalf("bad: %#v", auth)
 	}
 	if len(noop.RespReq) != 2 || !reflect.DeepEqual(noop.RespReq[1], lreq) {
@@ -1006,8 +1009,9 @@ func TestCore_HandleRequest_CreateToken_Lease(t *testing.T) {
 	}
 	expect := &TokenEntry{
 		ID:          clientToken,
+		Accessor:    resp.Auth.Accessor,
 		Parent:      root,
-		Policies:    []string{"foo"},
+		Policies:    []string{"foo", "default"},
 		Path:        "auth/token/create",
 		DisplayName: "token",
 	}
@@ -1945,3 +1949,134 @@ func TestCore_Standby_Rekey(t *testing.T) {
 		t.Fatalf("rekey failed")
 	}
 }
+
+// Check that a failed policy condition is denied and audited
+func TestCore_HandleRequest_PolicyCondition(t *testing.T) {
+	noop := &NoopAudit{}
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(map[string]string) (audit.Backend, error) {
+		return noop, nil
+	}
+	testCoreMakeToken(t, c, root, "child", []string{"test"})
+
+	// Enable the audit backend
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/audit/noop")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Set the 'test' policy to only permit access from a subnet
+	req = &logical.Request{
+		Operation: logical.WriteOperation,
+		Path:      "sys/policy/test",
+		Data: map[string]interface{}{
+			"rules": `path "secret/*" { policy = "write" allowed_cidrs = ["10.0.0.0/8"] }`,
+		},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Request from outside the subnet is denied
+	req = &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "secret/test",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: "child",
+		Connection:  &logical.Connection{RemoteAddr: "127.0.0.1"},
+	}
+	resp, err := c.HandleRequest(req)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v, resp: %v", err, resp)
+	}
+
+	// The reason is recorded in the audit log
+	last := noop.ReqErrs[len(noop.ReqErrs)-1]
+	if _, ok := last.(*ConditionError); !ok {
+		t.Fatalf("bad: %#v", last)
+	}
+
+	// Request from inside the subnet is permitted
+	req.Connection = &logical.Connection{RemoteAddr: "10.0.0.5"}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_HandleRequest_RenewSelf(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["lease"] = "1h"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	client := resp.Auth.ClientToken
+
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = client
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth == nil || resp.Auth.Lease != time.Hour {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The renewal must not register a second lease for the token
+	keys, err := CollectKeys(c.expiration.idView)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "auth/token/renew") {
+			t.Fatalf("bad: %v", keys)
+		}
+	}
+}
+
+func TestCore_MountTune_LeaseTTL(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/mounts/secret/tune")
+	req.Data["default_lease_ttl"] = "1h"
+	req.Data["max_lease_ttl"] = "2h"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Leases of the mount are limited to its max
+	req = logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.Data["lease"] = "10h"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Secret.Lease != 2*time.Hour {
+		t.Fatalf("bad: %#v", resp.Secret)
+	}
+}
+
+func TestNewCore_LeaseTTL_Invalid(t *testing.T) {
+	conf := &CoreConfig{
+		Physical:        physical.NewInmem(),
+		DisableMlock:    true,
+		DefaultLeaseTTL: 2 * time.Hour,
+		MaxLeaseTTL:     time.Hour,
+	}
+	if _, err := NewCore(conf); err == nil {
+		t.Fatalf("expected error")
+	}
+}
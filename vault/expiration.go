@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,7 +28,33 @@ const (
 	// tokenViewPrefix is the prefix used for the token based lookup of leases.
 	tokenViewPrefix = "token/"
 
-	// maxRevokeAttempts limits how many revoke attempts are made
+	// expiryViewPrefix is the prefix used for the time based lookup of
+	// leases. Leases are indexed in buckets by their expiration time, so
+	// that only the leases expiring soon need to be held in memory.
+	expiryViewPrefix = "expiry/"
+
+	// irrevocableViewPrefix is the prefix used for the lookup of leases
+	// that could not be revoked automatically.
+	irrevocableViewPrefix = "irrevocable/"
+
+	// expiryIndexedKey marks that all leases have been indexed by their
+	// expiration time, which is not the case for leases written before
+	// the index existed.
+	expiryIndexedKey = "expiry-indexed"
+
+	// expiryBucketWidth is the time span of a bucket of the expiry index
+	expiryBucketWidth = time.Minute
+
+	// expiryLoadWindow is how far ahead leases are loaded from the expiry
+	// index into the scheduler. The window is advanced every half window.
+	expiryLoadWindow = time.Hour
+
+	// maxConcurrentRevokes limits the number of automatic revocations
+	// that are in progress at the same time
+	maxConcurrentRevokes = 64
+
+	// maxRevokeAttempts limits how many automatic revoke attempts are
+	// made before a lease is marked irrevocable
 	maxRevokeAttempts = 6
 
 	// revokeRetryBase is a baseline retry time
@@ -35,10 +63,12 @@ const (
 	// minRevokeDelay is used to prevent an instant revoke on restore
 	minRevokeDelay = 5 * time.Second
 
-	// maxLeaseDuration is the maximum lease duration
+	// maxLeaseDuration is the maximum lease duration, unless
+	// configured otherwise
 	maxLeaseDuration = 30 * 24 * time.Hour
 
-	// defaultLeaseDuration is the lease duration used when no lease is specified
+	// defaultLeaseDuration is the lease duration used when no lease is
+	// specified, unless configured otherwise
 	defaultLeaseDuration = maxLeaseDuration
 )
 
@@ -48,13 +78,43 @@ const (
 // the ExpirationManager will handle doing automatic revocation.
 type ExpirationManager struct {
 	router     *Router
+	view       *BarrierView
 	idView     *BarrierView
 	tokenView  *BarrierView
+	expiryView *BarrierView
+	irrevView  *BarrierView
 	tokenStore *TokenStore
 	logger     *log.Logger
 
-	pending     map[string]*time.Timer
+	// leaseTTLs returns the default and maximum lease TTLs of the
+	// mount serving a path
+	leaseTTLs func(path string) (time.Duration, time.Duration)
+
+	// pending is the queue of leases to expire. It is served by a single
+	// scheduler goroutine, which is woken up through wakeCh when the
+	// queue changes. Leases expiring after the horizon are left to be
+	// loaded from the expiry index later on. A zero horizon means that
+	// all leases are scheduled.
+	pending     *expiryQueue
+	horizon     time.Time
+	loadWindow  time.Duration
+	stopCh      chan struct{}
 	pendingLock sync.Mutex
+
+	wakeCh    chan struct{}
+	revokeSem chan struct{}
+	wg        sync.WaitGroup
+
+	// counts tracks the number of leases for the lease count quotas.
+	// The leases and the entries of the index by token are counted under
+	// countPrefix, the path of the namespace of the manager. deleteLock
+	// serializes the removal of lease entries and of entries of the
+	// index by token, so that they are only counted out once.
+	counts      *leaseCounts
+	countPrefix string
+	leaseKeys   *keyCounts
+	indexKeys   *keyCounts
+	deleteLock  sync.Mutex
 }
 
 // NewExpirationManager creates a new ExpirationManager that is backed
@@ -65,15 +125,34 @@ func NewExpirationManager(router *Router, view *BarrierView, ts *TokenStore, log
 	}
 	exp := &ExpirationManager{
 		router:     router,
+		view:       view,
 		idView:     view.SubView(leaseViewPrefix),
 		tokenView:  view.SubView(tokenViewPrefix),
+		expiryView: view.SubView(expiryViewPrefix),
+		irrevView:  view.SubView(irrevocableViewPrefix),
 		tokenStore: ts,
 		logger:     logger,
-		pending:    make(map[string]*time.Timer),
+		leaseTTLs:  defaultLeaseTTLs,
+		pending:    newExpiryQueue(),
+		loadWindow: expiryLoadWindow,
+		wakeCh:     make(chan struct{}, 1),
+		revokeSem:  make(chan struct{}, maxConcurrentRevokes),
 	}
+	exp.shareCounts(newLeaseCounts(), "")
+	exp.start()
 	return exp
 }
 
+// shareCounts makes the manager count its leases in the given counts,
+// under the path of the namespace of the manager. This must be done
+// before the manager is restored.
+func (m *ExpirationManager) shareCounts(counts *leaseCounts, prefix string) {
+	m.counts = counts
+	m.countPrefix = prefix
+	m.leaseKeys = counts.leaseKeys(prefix)
+	m.indexKeys = counts.indexKeys(prefix)
+}
+
 // setupExpiration is invoked after we've loaded the mount table to
 // initialize the expiration manager
 func (c *Core) setupExpiration() error {
@@ -82,6 +161,12 @@ func (c *Core) setupExpiration() error {
 
 	// Create the manager
 	mgr := NewExpirationManager(c.router, view, c.tokenStore, c.logger)
+	mgr.leaseTTLs = c.leaseTTLs
+
+	// The leases of a namespace count toward the quotas of its parents
+	if c.parent != nil {
+		mgr.shareCounts(c.parent.expiration.counts, c.namespace.Path)
+	}
 	c.expiration = mgr
 
 	// Link the token store to this
@@ -107,65 +192,68 @@ func (c *Core) stopExpiration() error {
 }
 
 // Restore is used to recover the lease states when starting.
-// This is used after starting the vault.
+// This is used after starting the vault. The leases are counted for the
+// lease count quotas and loaded from the expiry index in the background.
+// Leases that are not loaded yet are still read from storage when they
+// are requested, and the quotas are not enforced until they are counted.
 func (m *ExpirationManager) Restore() error {
+	m.leaseKeys.startBuild()
+	m.indexKeys.startBuild()
+
 	m.pendingLock.Lock()
 	defer m.pendingLock.Unlock()
 
-	// Accumulate existing leases
-	existing, err := CollectKeys(m.idView)
-	if err != nil {
-		return fmt.Errorf("failed to scan for leases: %v", err)
+	if m.stopCh == nil {
+		m.startLocked()
 	}
 
-	// Restore each key
-	for _, leaseID := range existing {
-		// Load the entry
-		le, err := m.loadEntry(leaseID)
-		if err != nil {
-			return err
-		}
-
-		// If there is no entry, nothing to restore
-		if le == nil {
-			continue
-		}
-
-		// If there is no expiry time, don't do anything
-		if le.ExpireTime.IsZero() {
-			continue
-		}
-
-		// Determine the remaining time to expiration
-		expires := le.ExpireTime.Sub(time.Now().UTC())
-		if expires <= 0 {
-			expires = minRevokeDelay
-		}
+	// Only the leases expiring within the load window are scheduled
+	// from now on, the loader schedules the rest as the window advances
+	m.horizon = time.Now().UTC().Add(m.loadWindow)
 
-		// Setup revocation timer
-		m.pending[le.LeaseID] = time.AfterFunc(expires, func() {
-			m.expireID(le.LeaseID)
-		})
-	}
-	if len(m.pending) > 0 {
-		m.logger.Printf("[INFO] expire: restored %d leases", len(m.pending))
-	}
+	m.wg.Add(1)
+	go m.runLoader(m.stopCh)
 	return nil
 }
 
 // Stop is used to prevent further automatic revocations.
 // This must be called before sealing the view.
 func (m *ExpirationManager) Stop() error {
-	// Stop all the pending expiration timers
 	m.pendingLock.Lock()
-	for _, timer := range m.pending {
-		timer.Stop()
+	if m.stopCh != nil {
+		close(m.stopCh)
+		m.stopCh = nil
 	}
-	m.pending = make(map[string]*time.Timer)
+	m.pending = newExpiryQueue()
+	m.horizon = time.Time{}
 	m.pendingLock.Unlock()
+
+	// Wait for the scheduler and the loader to exit. Revocations that
+	// are already in progress are not waited on.
+	m.wg.Wait()
+
+	// If the leases were not counted yet, only the changes since the
+	// restore are kept, so that the counts shared with the other
+	// namespaces are built
+	m.leaseKeys.build(nil)
+	m.indexKeys.build(nil)
 	return nil
 }
 
+// start is used to start the scheduler
+func (m *ExpirationManager) start() {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	m.startLocked()
+}
+
+// startLocked starts the scheduler, the pendingLock must be held
+func (m *ExpirationManager) startLocked() {
+	m.stopCh = make(chan struct{})
+	m.wg.Add(1)
+	go m.runScheduler(m.stopCh)
+}
+
 // Revoke is used to revoke a secret named by the given LeaseID
 func (m *ExpirationManager) Revoke(leaseID string) error {
 	defer metrics.MeasureSince([]string{"expire", "revoke"}, time.Now())
@@ -184,23 +272,77 @@ func (m *ExpirationManager) Revoke(leaseID string) error {
 	if err := m.revokeEntry(le); err != nil {
 		return err
 	}
+	return m.removeEntry(le)
+}
+
+// RevokeForce is used to delete all leases with a given prefix without
+// revoking the secrets through their backends. This is meant to clean
+// up leases that cannot be revoked, such as when the system the
+// secrets were generated on is gone. The secrets may remain valid.
+// Tokens are still revoked, since that does not involve a backend.
+func (m *ExpirationManager) RevokeForce(prefix string) error {
+	defer metrics.MeasureSince([]string{"expire", "revoke-force"}, time.Now())
+	// Ensure there is a trailing slash
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
 
+	// Accumulate existing leases
+	sub := m.idView.SubView(prefix)
+	existing, err := CollectKeys(sub)
+	if err != nil {
+		return fmt.Errorf("failed to scan for leases: %v", err)
+	}
+
+	// Delete all the keys
+	for idx, suffix := range existing {
+		leaseID := prefix + suffix
+		le, err := m.loadEntry(leaseID)
+		if err != nil {
+			return err
+		}
+		if le == nil {
+			continue
+		}
+		if le.Auth != nil {
+			if err := m.revokeEntry(le); err != nil {
+				return fmt.Errorf("failed to revoke '%s' (%d / %d): %v",
+					leaseID, idx+1, len(existing), err)
+			}
+		}
+		if err := m.removeEntry(le); err != nil {
+			return fmt.Errorf("failed to delete '%s' (%d / %d): %v",
+				leaseID, idx+1, len(existing), err)
+		}
+		m.logger.Printf("[WARN] expire: force revoked '%s'", leaseID)
+	}
+	return nil
+}
+
+// removeEntry is used to delete a lease entry along with its secondary
+// indexes and any pending expiration, once it has been revoked
+func (m *ExpirationManager) removeEntry(le *leaseEntry) error {
 	// Delete the entry
-	if err := m.deleteEntry(leaseID); err != nil {
+	if err := m.deleteEntry(le); err != nil {
 		return err
 	}
 
-	// Delete the secondary index
-	if err := m.indexByToken(le.ClientToken, le.LeaseID); err != nil {
+	// Delete the secondary indexes
+	if err := m.removeIndexByToken(le.ClientToken, le.LeaseID); err != nil {
+		return err
+	}
+	if err := m.removeExpiryIndex(le.LeaseID, le.revokeTime(), time.Time{}); err != nil {
 		return err
 	}
+	if le.Irrevocable {
+		if err := m.irrevView.Delete(m.tokenStore.SaltID(le.LeaseID)); err != nil {
+			return fmt.Errorf("failed to delete irrevocable lease index entry: %v", err)
+		}
+	}
 
 	// Clear the expiration handler
 	m.pendingLock.Lock()
-	if timer, ok := m.pending[leaseID]; ok {
-		timer.Stop()
-		delete(m.pending, leaseID)
-	}
+	m.pending.Remove(le.LeaseID)
 	m.pendingLock.Unlock()
 	return nil
 }
@@ -233,6 +375,87 @@ func (m *ExpirationManager) RevokePrefix(prefix string) error {
 	return nil
 }
 
+// MovePrefix is used to move all the leases with a given prefix to
+// another prefix, when the backend that issued them is remounted. The
+// lease IDs and paths are rewritten, and so are the paths of the tokens
+// of the auth leases, so that the tokens can still be renewed and be
+// revoked by prefix.
+func (m *ExpirationManager) MovePrefix(src, dst string) error {
+	defer metrics.MeasureSince([]string{"expire", "move-prefix"}, time.Now())
+	// Ensure there is a trailing slash
+	if !strings.HasSuffix(src, "/") {
+		src = src + "/"
+	}
+	if !strings.HasSuffix(dst, "/") {
+		dst = dst + "/"
+	}
+
+	// Accumulate existing leases
+	sub := m.idView.SubView(src)
+	existing, err := CollectKeys(sub)
+	if err != nil {
+		return fmt.Errorf("failed to scan for leases: %v", err)
+	}
+
+	for idx, suffix := range existing {
+		leaseID := src + suffix
+		le, err := m.loadEntry(leaseID)
+		if err != nil {
+			return err
+		}
+		if le == nil {
+			continue
+		}
+		if err := m.moveEntry(le, dst+suffix, dst+strings.TrimPrefix(le.Path, src)); err != nil {
+			return fmt.Errorf("failed to move '%s' (%d / %d): %v",
+				leaseID, idx+1, len(existing), err)
+		}
+	}
+	return nil
+}
+
+// moveEntry is used to give a lease entry a new ID and path. The entry
+// is written under the new ID before the old one is removed, so that an
+// interrupted move never loses the lease.
+func (m *ExpirationManager) moveEntry(le *leaseEntry, leaseID, path string) error {
+	moved := *le
+	moved.LeaseID = leaseID
+	moved.Path = path
+
+	// Persist the moved entry along with its secondary indexes
+	if err := m.createEntry(&moved); err != nil {
+		return err
+	}
+	if moved.Auth == nil {
+		if err := m.indexByToken(moved.ClientToken, moved.LeaseID); err != nil {
+			return err
+		}
+	}
+	if moved.Irrevocable {
+		ent := &logical.StorageEntry{
+			Key:   m.tokenStore.SaltID(moved.LeaseID),
+			Value: []byte(moved.LeaseID),
+		}
+		if err := m.irrevView.Put(ent); err != nil {
+			return fmt.Errorf("failed to persist irrevocable lease index entry: %v", err)
+		}
+	}
+
+	// The token of a login is renewed through the path it was issued at
+	if moved.Auth != nil {
+		if err := m.tokenStore.setPath(moved.Auth.ClientToken, moved.Path); err != nil {
+			return err
+		}
+	}
+
+	// Remove the old entry and schedule the moved one
+	if err := m.removeEntry(le); err != nil {
+		return err
+	}
+	m.updatePending(&moved)
+	return nil
+}
+
 // RevokeByToken is used to revoke all the secrets issued with
 // a given token. This is done by using the secondary index.
 func (m *ExpirationManager) RevokeByToken(token string) error {
@@ -253,6 +476,219 @@ func (m *ExpirationManager) RevokeByToken(token string) error {
 	return nil
 }
 
+// TidyTokenIndex is used to clean up the secondary index by token. Index
+// entries of leases that no longer exist are removed, and leases issued
+// to a token that no longer exists are revoked. It returns the number of
+// removed index entries and of revoked leases.
+func (m *ExpirationManager) TidyTokenIndex() (int, int, error) {
+	defer metrics.MeasureSince([]string{"expire", "tidy-token-index"}, time.Now())
+	tokens, err := m.tokenView.List("")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan for tokens: %v", err)
+	}
+
+	var removed, revoked int
+	for _, prefix := range tokens {
+		subKeys, err := m.tokenView.List(prefix)
+		if err != nil {
+			return removed, revoked, fmt.Errorf("failed to list leases: %v", err)
+		}
+
+		for _, sub := range subKeys {
+			out, err := m.tokenView.Get(prefix + sub)
+			if err != nil {
+				return removed, revoked, fmt.Errorf("failed to read lease index: %v", err)
+			}
+			if out == nil {
+				continue
+			}
+			leaseID := string(out.Value)
+
+			le, err := m.loadEntry(leaseID)
+			if err != nil {
+				return removed, revoked, err
+			}
+			if le == nil {
+				if err := m.deleteIndexEntry(prefix + sub); err != nil {
+					return removed, revoked, err
+				}
+				removed++
+				continue
+			}
+
+			if le.ClientToken == "" {
+				continue
+			}
+			te, err := m.tokenStore.Lookup(le.ClientToken)
+			if err != nil {
+				return removed, revoked, err
+			}
+			if te != nil {
+				continue
+			}
+
+			// The token is gone, so the lease should have been revoked
+			// along with it. Revoking also removes the index entry.
+			if err := m.Revoke(leaseID); err != nil {
+				return removed, revoked, fmt.Errorf("failed to revoke '%s': %v", leaseID, err)
+			}
+			revoked++
+		}
+	}
+	return removed, revoked, nil
+}
+
+// LeaseInfo is the metadata of a lease returned by Lookup. It does not
+// include the data of the secret.
+type LeaseInfo struct {
+	LeaseID       string
+	Path          string
+	IssueTime     time.Time
+	ExpireTime    time.Time
+	Renewable     bool
+	TokenAccessor string
+
+	// Irrevocable is set once the automatic revocation has failed too
+	// many times, with the error of the last attempt
+	RevokeAttempts  int
+	LastRevokeError string
+	Irrevocable     bool
+}
+
+// Lookup is used to inspect a lease without revealing its secret data.
+// Nil is returned if there is no such lease.
+func (m *ExpirationManager) Lookup(leaseID string) (*LeaseInfo, error) {
+	le, err := m.loadEntry(leaseID)
+	if err != nil {
+		return nil, err
+	}
+	if le == nil {
+		return nil, nil
+	}
+
+	return m.leaseInfo(le)
+}
+
+// ListIrrevocable is used to list the leases that could not be revoked
+// automatically after the maximum number of attempts
+func (m *ExpirationManager) ListIrrevocable() ([]*LeaseInfo, error) {
+	keys, err := m.irrevView.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list irrevocable leases: %v", err)
+	}
+
+	infos := make([]*LeaseInfo, 0, len(keys))
+	for _, key := range keys {
+		out, err := m.irrevView.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read irrevocable lease index: %v", err)
+		}
+		if out == nil {
+			continue
+		}
+		le, err := m.loadEntry(string(out.Value))
+		if err != nil {
+			return nil, err
+		}
+		if le == nil {
+			continue
+		}
+		info, err := m.leaseInfo(le)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// leaseInfo returns the metadata of a lease entry
+func (m *ExpirationManager) leaseInfo(le *leaseEntry) (*LeaseInfo, error) {
+	info := &LeaseInfo{
+		LeaseID:         le.LeaseID,
+		Path:            le.Path,
+		IssueTime:       le.IssueTime,
+		ExpireTime:      le.ExpireTime,
+		Renewable:       le.renewable() == nil,
+		RevokeAttempts:  le.RevokeAttempts,
+		LastRevokeError: le.LastRevokeError,
+		Irrevocable:     le.Irrevocable,
+	}
+
+	// Identify the owning token by its accessor, never the token itself
+	if le.ClientToken != "" {
+		te, err := m.tokenStore.Lookup(le.ClientToken)
+		if err != nil {
+			return nil, err
+		}
+		if te != nil {
+			info.TokenAccessor = te.Accessor
+		}
+	}
+	return info, nil
+}
+
+// List is used to list the lease IDs directly under a prefix. Like
+// storage listings, nested prefixes are returned with a trailing slash.
+func (m *ExpirationManager) List(prefix string) ([]string, error) {
+	return m.idView.List(prefix)
+}
+
+// Count returns the number of leases under a prefix, which is either
+// empty to count all leases or ends with a slash. The leases of nested
+// namespaces are included. The leases are counted in memory.
+func (m *ExpirationManager) Count(prefix string) int {
+	return m.counts.prefix(m.countPrefix + prefix)
+}
+
+// CountByToken returns the number of leases issued with a given token.
+// The leases are counted in memory.
+func (m *ExpirationManager) CountByToken(token string) int {
+	if token == "" {
+		return 0
+	}
+	return m.counts.token(m.countPrefix + m.tokenStore.SaltID(token))
+}
+
+// reserveLease reserves a slot for a lease a request with the given token
+// may create, under each of the given lease count quotas. If one of them
+// is reached, the quota is returned instead.
+func (m *ExpirationManager) reserveLease(quotas []*LeaseCountQuota, token string) (*leaseReservation, *LeaseCountQuota) {
+	var salted string
+	if token != "" {
+		salted = m.countPrefix + m.tokenStore.SaltID(token)
+	}
+	return m.counts.reserve(quotas, salted)
+}
+
+// countLeases builds the counts of the leases from storage. Only the keys
+// of the lease entries and of the index by token are listed. Leases
+// registered and removed in the meantime are counted as they are.
+func (m *ExpirationManager) countLeases() error {
+	existing, err := CollectKeys(m.idView)
+	if err != nil {
+		return fmt.Errorf("failed to scan for leases: %v", err)
+	}
+	m.leaseKeys.build(existing)
+
+	tokens, err := m.tokenView.List("")
+	if err != nil {
+		return fmt.Errorf("failed to scan for tokens: %v", err)
+	}
+	var indexed []string
+	for _, prefix := range tokens {
+		subKeys, err := m.tokenView.List(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list leases: %v", err)
+		}
+		for _, sub := range subKeys {
+			indexed = append(indexed, prefix+sub)
+		}
+	}
+	m.indexKeys.build(indexed)
+	return nil
+}
+
 // Renew is used to renew a secret using the given leaseID
 // and a renew interval. The increment may be ignored.
 func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*logical.Response, error) {
@@ -268,6 +704,12 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 		return nil, err
 	}
 
+	// Check if the lease has reached the maximum TTL of the mount
+	_, maxTTL := m.leaseTTLs(le.Path)
+	if err := le.checkMaxTTL(maxTTL); err != nil {
+		return nil, err
+	}
+
 	// Attempt to renew the entry
 	resp, err := m.renewEntry(le, increment)
 	if err != nil {
@@ -284,19 +726,26 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 		return nil, err
 	}
 
+	// Limit the lease to the maximum TTL
+	le.capLease(&resp.Secret.LeaseOptions, maxTTL)
+
 	// Attach the LeaseID
 	resp.Secret.LeaseID = leaseID
 
 	// Update the lease entry
+	oldExpire := le.revokeTime()
 	le.Data = resp.Data
 	le.Secret = resp.Secret
 	le.ExpireTime = resp.Secret.ExpirationTime()
 	if err := m.persistEntry(le); err != nil {
 		return nil, err
 	}
+	if err := m.removeExpiryIndex(le.LeaseID, oldExpire, le.revokeTime()); err != nil {
+		return nil, err
+	}
 
 	// Update the expiration time
-	m.updatePending(le, resp.Secret.LeaseTotal())
+	m.updatePending(le)
 
 	// Return the response
 	return resp, nil
@@ -321,6 +770,23 @@ func (m *ExpirationManager) RenewToken(source string, token string,
 		return nil, err
 	}
 
+	// Determine the maximum TTL, which is the most restrictive of the
+	// mount and the token. Periodic tokens are exempt from the mount limit.
+	var maxTTL time.Duration
+	if le.Auth.Period == 0 {
+		_, maxTTL = m.leaseTTLs(le.Path)
+	}
+	te, err := m.tokenStore.Lookup(token)
+	if err != nil {
+		return nil, err
+	}
+	if te != nil && te.ExplicitMaxTTL > 0 && (maxTTL == 0 || te.ExplicitMaxTTL < maxTTL) {
+		maxTTL = te.ExplicitMaxTTL
+	}
+	if err := le.checkMaxTTL(maxTTL); err != nil {
+		return nil, err
+	}
+
 	// Attempt to renew the auth entry
 	resp, err := m.renewAuthEntry(le, increment)
 	if err != nil {
@@ -331,23 +797,38 @@ func (m *ExpirationManager) RenewToken(source string, token string,
 	if resp == nil {
 		return nil, nil
 	}
+
+	// Periodic tokens are always renewed for exactly their period,
+	// regardless of the lease returned by the backend
+	if resp.Auth != nil && le.Auth.Period > 0 {
+		resp.Auth.Period = le.Auth.Period
+		resp.Auth.Lease = le.Auth.Period
+	}
+
 	if resp.Auth == nil || !resp.Auth.LeaseEnabled() {
 		return resp.Auth, nil
 	}
 
+	// Limit the lease to the maximum TTL
+	le.capLease(&resp.Auth.LeaseOptions, maxTTL)
+
 	// Attach the ClientToken
 	resp.Auth.ClientToken = token
 	resp.Auth.LeaseIncrement = 0
 
 	// Update the lease entry
+	oldExpire := le.revokeTime()
 	le.Auth = resp.Auth
 	le.ExpireTime = resp.Auth.ExpirationTime()
 	if err := m.persistEntry(le); err != nil {
 		return nil, err
 	}
+	if err := m.removeExpiryIndex(le.LeaseID, oldExpire, le.revokeTime()); err != nil {
+		return nil, err
+	}
 
 	// Update the expiration time
-	m.updatePending(le, resp.Auth.LeaseTotal())
+	m.updatePending(le)
 	return resp.Auth, nil
 }
 
@@ -378,7 +859,7 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 	}
 
 	// Encode the entry
-	if err := m.persistEntry(&le); err != nil {
+	if err := m.createEntry(&le); err != nil {
 		return "", err
 	}
 
@@ -387,8 +868,8 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 		return "", err
 	}
 
-	// Setup revocation timer if there is a lease
-	m.updatePending(&le, resp.Secret.LeaseTotal())
+	// Schedule the revocation if there is a lease
+	m.updatePending(&le)
 
 	// Done
 	return le.LeaseID, nil
@@ -411,62 +892,351 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 	}
 
 	// Encode the entry
-	if err := m.persistEntry(&le); err != nil {
+	if err := m.createEntry(&le); err != nil {
 		return err
 	}
 
-	// Setup revocation timer
-	m.updatePending(&le, auth.LeaseTotal())
+	// Schedule the revocation
+	m.updatePending(&le)
 	return nil
 }
 
 // updatePending is used to update a pending invocation for a lease
-func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Duration) {
+func (m *ExpirationManager) updatePending(le *leaseEntry) {
 	m.pendingLock.Lock()
 	defer m.pendingLock.Unlock()
 
-	// Check for an existing timer
-	timer, ok := m.pending[le.LeaseID]
-
-	// Create entry if it does not exist
-	if !ok && leaseTotal > 0 {
-		timer := time.AfterFunc(leaseTotal, func() {
-			m.expireID(le.LeaseID)
-		})
-		m.pending[le.LeaseID] = timer
+	// Leases that are not revoked automatically, or that are due beyond
+	// the horizon, are not held in the queue. The latter are loaded from
+	// the expiry index when the horizon advances.
+	revokeTime := le.revokeTime()
+	if revokeTime.IsZero() || (!m.horizon.IsZero() && revokeTime.After(m.horizon)) {
+		m.pending.Remove(le.LeaseID)
 		return
 	}
+	m.scheduleLocked(le.LeaseID, revokeTime)
+}
 
-	// Delete the timer if the expiration time is zero
-	if ok && leaseTotal == 0 {
-		timer.Stop()
-		delete(m.pending, le.LeaseID)
-		return
+// scheduleLocked schedules the expiration of a lease and wakes up the
+// scheduler. The pendingLock must be held.
+func (m *ExpirationManager) scheduleLocked(leaseID string, expireTime time.Time) {
+	m.pending.Schedule(leaseID, expireTime)
+	select {
+	case m.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// runScheduler is a long running routine that expires the leases in
+// the queue as they become due, until the stop channel is closed
+func (m *ExpirationManager) runScheduler(stopCh chan struct{}) {
+	defer m.wg.Done()
+	for {
+		// Collect the due leases and determine when the next one is due
+		var due []*expiryItem
+		var timer *time.Timer
+		var timerCh <-chan time.Time
+		m.pendingLock.Lock()
+		now := time.Now().UTC()
+		for item := m.pending.PopDue(now); item != nil; item = m.pending.PopDue(now) {
+			due = append(due, item)
+		}
+		if next := m.pending.Next(); next != nil {
+			timer = time.NewTimer(next.expireTime.Sub(now))
+			timerCh = timer.C
+		}
+		m.pendingLock.Unlock()
+
+		// Expire the due leases, limiting the concurrent revocations
+		for _, item := range due {
+			select {
+			case m.revokeSem <- struct{}{}:
+			case <-stopCh:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			go func(leaseID string) {
+				defer func() { <-m.revokeSem }()
+				m.expireID(leaseID)
+			}(item.leaseID)
+		}
+
+		select {
+		case <-timerCh:
+		case <-m.wakeCh:
+		case <-stopCh:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// runLoader is a long running routine that loads the leases expiring
+// within the load window from the expiry index into the queue, and
+// advances the window until the stop channel is closed
+func (m *ExpirationManager) runLoader(stopCh chan struct{}) {
+	defer m.wg.Done()
+
+	// Index the leases written before the expiry index existed
+	if err := m.indexExistingLeases(stopCh); err != nil {
+		m.logger.Printf("[ERR] expire: failed to index leases: %v", err)
+	}
+
+	// Buckets before the loaded time have been loaded already
+	var loaded time.Time
+	restored, counted := false, false
+	start := time.Now()
+	for {
+		m.pendingLock.Lock()
+		horizon := m.horizon
+		m.pendingLock.Unlock()
+
+		num, err := m.loadExpiring(stopCh, loaded, horizon)
+		if err != nil {
+			m.logger.Printf("[ERR] expire: failed to load leases: %v", err)
+		} else {
+			loaded = horizon
+			if !restored {
+				restored = true
+				metrics.MeasureSince([]string{"expire", "restore"}, start)
+				if num > 0 {
+					m.logger.Printf("[INFO] expire: restored %d leases", num)
+				}
+			}
+		}
+
+		// Count the leases once the first ones are scheduled, retrying
+		// along with the loading until it succeeds
+		if !counted {
+			if err := m.countLeases(); err != nil {
+				m.logger.Printf("[ERR] expire: failed to count leases: %v", err)
+			} else {
+				counted = true
+			}
+		}
+
+		select {
+		case <-time.After(m.loadWindow / 2):
+		case <-stopCh:
+			return
+		}
+
+		// Advance the horizon before loading, so that leases registered
+		// in the meantime are scheduled directly
+		m.pendingLock.Lock()
+		m.horizon = time.Now().UTC().Add(m.loadWindow)
+		m.pendingLock.Unlock()
+	}
+}
+
+// loadExpiring schedules the leases in the expiry buckets that overlap
+// the time range from the given start to the given end. Leases that are
+// already scheduled are left alone. It returns the number of leases
+// scheduled.
+func (m *ExpirationManager) loadExpiring(stopCh chan struct{}, from, to time.Time) (int, error) {
+	buckets, err := m.expiryBuckets()
+	if err != nil {
+		return 0, err
+	}
+
+	var num int
+	for _, bucket := range buckets {
+		if !from.IsZero() && !bucket.Add(expiryBucketWidth).After(from) {
+			continue
+		}
+		if bucket.After(to) {
+			break
+		}
+
+		// Bail out early when stopping
+		select {
+		case <-stopCh:
+			return num, nil
+		default:
+		}
+
+		prefix := fmt.Sprintf("%d/", bucket.Unix())
+		keys, err := m.expiryView.List(prefix)
+		if err != nil {
+			return num, fmt.Errorf("failed to list expiry index: %v", err)
+		}
+		for _, key := range keys {
+			out, err := m.expiryView.Get(prefix + key)
+			if err != nil {
+				return num, fmt.Errorf("failed to read expiry index: %v", err)
+			}
+			if out == nil {
+				continue
+			}
+			var ent expiryIndexEntry
+			if err := out.DecodeJSON(&ent); err != nil {
+				return num, fmt.Errorf("failed to decode expiry index: %v", err)
+			}
+
+			// Prevent an instant revoke of expired leases
+			expires := ent.ExpireTime
+			if now := time.Now().UTC(); !expires.After(now) {
+				expires = now.Add(minRevokeDelay)
+			}
+
+			m.pendingLock.Lock()
+			if m.pending.Contains(ent.LeaseID) {
+				m.pendingLock.Unlock()
+				continue
+			}
+			m.scheduleLocked(ent.LeaseID, expires)
+			m.pendingLock.Unlock()
+			num++
+		}
+	}
+	return num, nil
+}
+
+// expiryBuckets returns the start times of the buckets of the expiry
+// index in chronological order
+func (m *ExpirationManager) expiryBuckets() ([]time.Time, error) {
+	keys, err := m.expiryView.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiry index: %v", err)
+	}
+
+	unix := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		sec, err := strconv.ParseInt(strings.TrimSuffix(key, "/"), 10, 64)
+		if err != nil {
+			continue
+		}
+		unix = append(unix, sec)
+	}
+	sort.Sort(int64Slice(unix))
+
+	buckets := make([]time.Time, len(unix))
+	for i, sec := range unix {
+		buckets[i] = time.Unix(sec, 0).UTC()
+	}
+	return buckets, nil
+}
+
+// indexExistingLeases indexes all leases by their expiration time,
+// unless this has been done before
+func (m *ExpirationManager) indexExistingLeases(stopCh chan struct{}) error {
+	out, err := m.view.Get(expiryIndexedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read expiry index marker: %v", err)
+	}
+	if out != nil {
+		return nil
+	}
+
+	existing, err := CollectKeys(m.idView)
+	if err != nil {
+		return fmt.Errorf("failed to scan for leases: %v", err)
+	}
+	for _, leaseID := range existing {
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+
+		le, err := m.loadEntry(leaseID)
+		if err != nil {
+			return err
+		}
+		if le == nil || le.revokeTime().IsZero() {
+			continue
+		}
+		if err := m.indexExpiry(le); err != nil {
+			return err
+		}
 	}
 
-	// Extend the timer by the lease total
-	if ok && leaseTotal > 0 {
-		timer.Reset(leaseTotal)
+	if err := m.view.Put(&logical.StorageEntry{Key: expiryIndexedKey}); err != nil {
+		return fmt.Errorf("failed to persist expiry index marker: %v", err)
+	}
+	if len(existing) > 0 {
+		m.logger.Printf("[INFO] expire: indexed %d leases", len(existing))
 	}
+	return nil
 }
 
 // expireID is invoked when a given ID is expired
 func (m *ExpirationManager) expireID(leaseID string) {
-	// Clear from the pending expiration
-	m.pendingLock.Lock()
-	delete(m.pending, leaseID)
-	m.pendingLock.Unlock()
-
-	for attempt := uint(0); attempt < maxRevokeAttempts; attempt++ {
-		err := m.Revoke(leaseID)
-		if err == nil {
-			m.logger.Printf("[INFO] expire: revoked '%s'", leaseID)
+	// The lease may have been renewed since it was scheduled, or it may
+	// have been loaded from a stale index entry
+	le, err := m.loadEntry(leaseID)
+	if err == nil {
+		if le == nil || le.revokeTime().IsZero() {
+			return
+		}
+		if le.revokeTime().After(time.Now().UTC()) {
+			m.updatePending(le)
 			return
 		}
-		m.logger.Printf("[ERR] expire: failed to revoke '%s': %v", leaseID, err)
-		time.Sleep((1 << attempt) * revokeRetryBase)
 	}
-	m.logger.Printf("[ERR] expire: maximum revoke attempts for '%s' reached", leaseID)
+
+	err = m.Revoke(leaseID)
+	if err == nil {
+		m.logger.Printf("[INFO] expire: revoked '%s'", leaseID)
+		return
+	}
+	m.logger.Printf("[ERR] expire: failed to revoke '%s': %v", leaseID, err)
+
+	if err := m.revokeFailed(leaseID, err); err != nil {
+		m.logger.Printf("[ERR] expire: failed to record revoke attempt for '%s': %v", leaseID, err)
+	}
+}
+
+// revokeFailed records a failed automatic revocation in the lease entry.
+// The next attempt is scheduled with an exponential backoff through the
+// expiry index, so that it survives a restart or a change of leader.
+// Once the maximum number of attempts is reached, the lease is marked
+// irrevocable and is no longer revoked automatically.
+func (m *ExpirationManager) revokeFailed(leaseID string, revokeErr error) error {
+	le, err := m.loadEntry(leaseID)
+	if err != nil {
+		return err
+	}
+	if le == nil {
+		return nil
+	}
+
+	oldRevoke := le.revokeTime()
+	le.RevokeAttempts++
+	le.LastRevokeError = revokeErr.Error()
+	if le.RevokeAttempts >= maxRevokeAttempts {
+		le.Irrevocable = true
+		le.RevokeAfter = time.Time{}
+	} else {
+		backoff := (1 << uint(le.RevokeAttempts-1)) * revokeRetryBase
+		le.RevokeAfter = time.Now().UTC().Add(backoff)
+	}
+
+	if err := m.persistEntry(le); err != nil {
+		return err
+	}
+	if err := m.removeExpiryIndex(le.LeaseID, oldRevoke, le.revokeTime()); err != nil {
+		return err
+	}
+	if le.Irrevocable {
+		m.logger.Printf("[ERR] expire: maximum revoke attempts for '%s' reached, marking it irrevocable", leaseID)
+		ent := &logical.StorageEntry{
+			Key:   m.tokenStore.SaltID(le.LeaseID),
+			Value: []byte(le.LeaseID),
+		}
+		if err := m.irrevView.Put(ent); err != nil {
+			return fmt.Errorf("failed to persist irrevocable lease index entry: %v", err)
+		}
+	}
+	m.updatePending(le)
+	return nil
 }
 
 // revokeEntry is used to attempt revocation of an internal entry
@@ -535,6 +1305,16 @@ func (m *ExpirationManager) loadEntry(leaseID string) (*leaseEntry, error) {
 	return le, nil
 }
 
+// createEntry is used to persist a new lease entry and count it
+func (m *ExpirationManager) createEntry(le *leaseEntry) error {
+	m.leaseKeys.creating(le.LeaseID)
+	if err := m.persistEntry(le); err != nil {
+		return err
+	}
+	m.leaseKeys.add(le.LeaseID, 1)
+	return nil
+}
+
 // persistEntry is used to persist a lease entry
 func (m *ExpirationManager) persistEntry(le *leaseEntry) error {
 	// Encode the entry
@@ -551,14 +1331,35 @@ func (m *ExpirationManager) persistEntry(le *leaseEntry) error {
 	if err := m.idView.Put(&ent); err != nil {
 		return fmt.Errorf("failed to persist lease entry: %v", err)
 	}
+
+	// Maintain the index by expiration time
+	if !le.revokeTime().IsZero() {
+		if err := m.indexExpiry(le); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// deleteEntry is used to delete a lease entry
-func (m *ExpirationManager) deleteEntry(leaseID string) error {
-	if err := m.idView.Delete(leaseID); err != nil {
+// deleteEntry is used to delete a lease entry. The lease is only counted
+// out if the entry still exists, since it may be removed concurrently.
+func (m *ExpirationManager) deleteEntry(le *leaseEntry) error {
+	m.deleteLock.Lock()
+	defer m.deleteLock.Unlock()
+
+	out, err := m.idView.Get(le.LeaseID)
+	if err != nil {
+		return fmt.Errorf("failed to read lease entry: %v", err)
+	}
+	if out != nil {
+		m.leaseKeys.deleting(le.LeaseID)
+	}
+	if err := m.idView.Delete(le.LeaseID); err != nil {
 		return fmt.Errorf("failed to delete lease entry: %v", err)
 	}
+	if out != nil {
+		m.leaseKeys.add(le.LeaseID, -1)
+	}
 	return nil
 }
 
@@ -568,18 +1369,77 @@ func (m *ExpirationManager) indexByToken(token, leaseID string) error {
 		Key:   m.tokenStore.SaltID(token) + "/" + m.tokenStore.SaltID(leaseID),
 		Value: []byte(leaseID),
 	}
+	m.indexKeys.creating(ent.Key)
 	if err := m.tokenView.Put(&ent); err != nil {
 		return fmt.Errorf("failed to persist lease index entry: %v", err)
 	}
+	m.indexKeys.add(ent.Key, 1)
 	return nil
 }
 
 // removeIndexByToken removes the secondary index from the token to a lease entry
 func (m *ExpirationManager) removeIndexByToken(token, leaseID string) error {
-	key := m.tokenStore.SaltID(token) + "/" + m.tokenStore.SaltID(leaseID)
+	return m.deleteIndexEntry(m.tokenStore.SaltID(token) + "/" + m.tokenStore.SaltID(leaseID))
+}
+
+// deleteIndexEntry is used to delete an entry of the index by token. Like
+// a lease entry, it is only counted out if it still exists.
+func (m *ExpirationManager) deleteIndexEntry(key string) error {
+	m.deleteLock.Lock()
+	defer m.deleteLock.Unlock()
+
+	out, err := m.tokenView.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read lease index entry: %v", err)
+	}
+	if out == nil {
+		return nil
+	}
+	m.indexKeys.deleting(key)
 	if err := m.tokenView.Delete(key); err != nil {
 		return fmt.Errorf("failed to delete lease index entry: %v", err)
 	}
+	m.indexKeys.add(key, -1)
+	return nil
+}
+
+// expiryKey returns the key of a lease in the expiry index
+func (m *ExpirationManager) expiryKey(leaseID string, expireTime time.Time) string {
+	bucket := expireTime.Truncate(expiryBucketWidth).Unix()
+	return fmt.Sprintf("%d/%s", bucket, m.tokenStore.SaltID(leaseID))
+}
+
+// indexExpiry creates a secondary index from the expiration time to a
+// lease entry
+func (m *ExpirationManager) indexExpiry(le *leaseEntry) error {
+	revokeTime := le.revokeTime()
+	ent, err := logical.StorageEntryJSON(m.expiryKey(le.LeaseID, revokeTime), &expiryIndexEntry{
+		LeaseID:    le.LeaseID,
+		ExpireTime: revokeTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode expiry index entry: %v", err)
+	}
+	if err := m.expiryView.Put(ent); err != nil {
+		return fmt.Errorf("failed to persist expiry index entry: %v", err)
+	}
+	return nil
+}
+
+// removeExpiryIndex removes the secondary index from an expiration time
+// to a lease entry, unless the lease is indexed in the same bucket for
+// the time it is kept at
+func (m *ExpirationManager) removeExpiryIndex(leaseID string, expireTime, keep time.Time) error {
+	if expireTime.IsZero() {
+		return nil
+	}
+	key := m.expiryKey(leaseID, expireTime)
+	if !keep.IsZero() && key == m.expiryKey(leaseID, keep) {
+		return nil
+	}
+	if err := m.expiryView.Delete(key); err != nil {
+		return fmt.Errorf("failed to delete expiry index entry: %v", err)
+	}
 	return nil
 }
 
@@ -610,7 +1470,7 @@ func (m *ExpirationManager) lookupByToken(token string) ([]string, error) {
 // emitMetrics is invoked periodically to emit statistics
 func (m *ExpirationManager) emitMetrics() {
 	m.pendingLock.Lock()
-	num := len(m.pending)
+	num := m.pending.Len()
 	m.pendingLock.Unlock()
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
 }
@@ -626,13 +1486,47 @@ type leaseEntry struct {
 	Auth        *logical.Auth          `json:"auth"`
 	IssueTime   time.Time              `json:"issue_time"`
 	ExpireTime  time.Time              `json:"expire_time"`
+
+	// RevokeAttempts counts the failed automatic revocations, and
+	// RevokeAfter is when the next one is due. Irrevocable is set once
+	// the attempts are exhausted.
+	RevokeAttempts  int       `json:"revoke_attempts,omitempty"`
+	RevokeAfter     time.Time `json:"revoke_after"`
+	LastRevokeError string    `json:"last_revoke_error,omitempty"`
+	Irrevocable     bool      `json:"irrevocable,omitempty"`
+}
+
+// expiryIndexEntry is the value of a lease in the expiry index
+type expiryIndexEntry struct {
+	LeaseID    string    `json:"lease_id"`
+	ExpireTime time.Time `json:"expire_time"`
 }
 
+// int64Slice sorts a slice of int64 in increasing order
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 // encode is used to JSON encode the lease entry
 func (l *leaseEntry) encode() ([]byte, error) {
 	return json.Marshal(l)
 }
 
+// revokeTime returns when the lease is due to be revoked automatically,
+// which is after a backoff if a revocation has failed before. It is zero
+// if the lease is not revoked automatically.
+func (le *leaseEntry) revokeTime() time.Time {
+	if le.Irrevocable {
+		return time.Time{}
+	}
+	if !le.RevokeAfter.IsZero() {
+		return le.RevokeAfter
+	}
+	return le.ExpireTime
+}
+
 func (le *leaseEntry) renewable() error {
 	// If there is no entry, cannot review
 	if le == nil || le.ExpireTime.IsZero() {
@@ -654,6 +1548,34 @@ func (le *leaseEntry) renewable() error {
 	return nil
 }
 
+// checkMaxTTL returns an error if the lease has outlived the given
+// maximum TTL, measured from its issue time. A zero TTL is unlimited.
+func (le *leaseEntry) checkMaxTTL(maxTTL time.Duration) error {
+	if maxTTL > 0 && !time.Now().UTC().Before(le.IssueTime.Add(maxTTL)) {
+		return fmt.Errorf("lease has reached its maximum TTL")
+	}
+	return nil
+}
+
+// capLease limits the renewed lease so that the lease does not outlive
+// the given maximum TTL, measured from its issue time. A zero TTL is
+// unlimited.
+func (le *leaseEntry) capLease(lease *logical.LeaseOptions, maxTTL time.Duration) {
+	if maxTTL == 0 {
+		return
+	}
+	remaining := le.IssueTime.Add(maxTTL).Sub(time.Now().UTC())
+	if lease.LeaseTotal() > remaining {
+		lease.Lease = remaining
+		lease.LeaseGracePeriod = 0
+	}
+}
+
+// defaultLeaseTTLs returns the default lease limits regardless of the path
+func defaultLeaseTTLs(string) (time.Duration, time.Duration) {
+	return defaultLeaseDuration, maxLeaseDuration
+}
+
 // decodeLeaseEntry is used to reverse encode and return a new entry
 func decodeLeaseEntry(buf []byte) (*leaseEntry, error) {
 	out := new(leaseEntry)
@@ -0,0 +1,293 @@
+package vault
+
+import (
+	"strings"
+	"sync"
+)
+
+// leaseCounts keeps in memory the number of leases under every prefix and
+// held by every token, along with the slots reserved by the requests in
+// progress, so that the lease count quotas are checked without scanning
+// the leases. The counts are shared by the expiration managers of all the
+// namespaces, which count their leases under the path of their namespace.
+// They are built in the background when the leases are restored, and are
+// maintained as leases are registered and removed. The quotas are not
+// enforced while counts are being built.
+type leaseCounts struct {
+	// prefixes counts the leases under the empty prefix and under every
+	// directory of their ID. tokens counts the leases indexed by token,
+	// keyed by the salted token.
+	prefixes map[string]int
+	tokens   map[string]int
+
+	reservedPrefixes map[string]int
+	reservedTokens   map[string]int
+
+	// building is the number of key counts being built
+	building int
+
+	l sync.Mutex
+}
+
+// keyCounts counts the keys of a view of an expiration manager under the
+// groups each key belongs to. The keys are prefixed with the path of the
+// namespace of the manager. While the counts are built from a listing of
+// the view, the keys created and deleted concurrently are recorded, so
+// that the listing can be reconciled with the changes already counted.
+type keyCounts struct {
+	lc      *leaseCounts
+	counts  map[string]int
+	groups  func(key string) []string
+	prefix  string
+	changes map[string]*keyChange
+}
+
+// keyChange records a key created or deleted while the counts are built
+type keyChange struct {
+	// created is set if the key was created while the counts are built,
+	// so that its creation is already counted. Otherwise the key existed
+	// before and its deletion is counted.
+	created bool
+
+	// listed is set if the listing the counts are built from has the key
+	listed bool
+}
+
+// leaseReservation is a slot reserved under the lease count quotas for
+// the leases a request may create
+type leaseReservation struct {
+	counts   *leaseCounts
+	prefixes []string
+	token    string
+}
+
+func newLeaseCounts() *leaseCounts {
+	return &leaseCounts{
+		prefixes:         make(map[string]int),
+		tokens:           make(map[string]int),
+		reservedPrefixes: make(map[string]int),
+		reservedTokens:   make(map[string]int),
+	}
+}
+
+// leaseKeys returns the counts of the lease IDs of the namespace with the
+// given path, which are counted under every prefix
+func (lc *leaseCounts) leaseKeys(prefix string) *keyCounts {
+	return &keyCounts{lc: lc, counts: lc.prefixes, groups: leasePrefixes, prefix: prefix}
+}
+
+// indexKeys returns the counts of the keys of the index by token of the
+// namespace with the given path, which are counted under their token
+func (lc *leaseCounts) indexKeys(prefix string) *keyCounts {
+	return &keyCounts{lc: lc, counts: lc.tokens, groups: indexToken, prefix: prefix}
+}
+
+// leasePrefixes returns the prefixes a lease is counted under, which are
+// the empty prefix and every directory of its ID
+func leasePrefixes(leaseID string) []string {
+	prefixes := []string{""}
+	for i := 0; i < len(leaseID); i++ {
+		if leaseID[i] == '/' {
+			prefixes = append(prefixes, leaseID[:i+1])
+		}
+	}
+	return prefixes
+}
+
+// indexToken returns the token a key of the index by token is counted
+// under, which is the salted token prefixed with the namespace path
+func indexToken(key string) []string {
+	if idx := strings.LastIndex(key, "/"); idx != -1 {
+		return []string{key[:idx]}
+	}
+	return nil
+}
+
+// built returns whether no counts are being built
+func (lc *leaseCounts) built() bool {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	return lc.building == 0
+}
+
+// startBuild starts building the counts of the keys from storage. The
+// counts of the keys must be empty, as they are for a new expiration
+// manager.
+func (kc *keyCounts) startBuild() {
+	kc.lc.l.Lock()
+	defer kc.lc.l.Unlock()
+
+	if kc.changes == nil {
+		kc.changes = make(map[string]*keyChange)
+		kc.lc.building++
+	}
+}
+
+// creating must be called before a key is written, and the creation then
+// counted with add once the key has been written
+func (kc *keyCounts) creating(key string) {
+	kc.lc.l.Lock()
+	defer kc.lc.l.Unlock()
+
+	key = kc.prefix + key
+	if kc.changes != nil && kc.changes[key] == nil {
+		kc.changes[key] = &keyChange{created: true}
+	}
+}
+
+// deleting must be called before an existing key is deleted, and the
+// deletion then counted with add once the key has been deleted
+func (kc *keyCounts) deleting(key string) {
+	kc.lc.l.Lock()
+	defer kc.lc.l.Unlock()
+
+	key = kc.prefix + key
+	if kc.changes != nil && kc.changes[key] == nil {
+		kc.changes[key] = &keyChange{}
+	}
+}
+
+// add adjusts the counts of a key by delta. While the counts are built,
+// they may drop below zero until the listing is counted.
+func (kc *keyCounts) add(key string, delta int) {
+	kc.lc.l.Lock()
+	defer kc.lc.l.Unlock()
+
+	for _, group := range kc.groups(kc.prefix + key) {
+		if kc.lc.building > 0 {
+			kc.counts[group] += delta
+		} else {
+			adjustCount(kc.counts, group, delta)
+		}
+	}
+}
+
+// build counts the keys listed from storage, on top of the changes that
+// have been counted since the build started, and ends the build. The keys
+// created since then are already counted, whether the listing has them or
+// not, and the keys that existed before are counted whether they were
+// deleted since or not. Nothing is done if the counts are not being built.
+func (kc *keyCounts) build(keys []string) {
+	kc.lc.l.Lock()
+	defer kc.lc.l.Unlock()
+
+	if kc.changes == nil {
+		return
+	}
+	for _, key := range keys {
+		key = kc.prefix + key
+		change := kc.changes[key]
+		if change != nil {
+			if change.created {
+				continue
+			}
+			change.listed = true
+		}
+		for _, group := range kc.groups(key) {
+			kc.counts[group]++
+		}
+	}
+
+	// The keys deleted before they could be listed are counted out
+	// already, so they are counted in
+	for key, change := range kc.changes {
+		if change.created || change.listed {
+			continue
+		}
+		for _, group := range kc.groups(key) {
+			kc.counts[group]++
+		}
+	}
+	kc.changes = nil
+
+	kc.lc.building--
+	if kc.lc.building == 0 {
+		for _, counts := range []map[string]int{kc.lc.prefixes, kc.lc.tokens} {
+			for group, n := range counts {
+				if n <= 0 {
+					delete(counts, group)
+				}
+			}
+		}
+	}
+}
+
+// prefix returns the number of leases under a prefix, which is either
+// empty or ends with a slash
+func (lc *leaseCounts) prefix(prefix string) int {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	return lc.prefixes[prefix]
+}
+
+// token returns the number of leases indexed by a token
+func (lc *leaseCounts) token(token string) int {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+	return lc.tokens[token]
+}
+
+// reserve reserves a slot for a new lease under each of the given quotas.
+// If one of them is reached, nothing is reserved and the quota is returned
+// instead. The token is the token of the request as counted, which the
+// per token quotas apply to. The reservation must be released once the
+// request has registered its leases. While counts are being built, the
+// quotas are not enforced and nothing is reserved.
+func (lc *leaseCounts) reserve(quotas []*LeaseCountQuota, token string) (*leaseReservation, *LeaseCountQuota) {
+	lc.l.Lock()
+	defer lc.l.Unlock()
+
+	r := &leaseReservation{counts: lc}
+	if lc.building > 0 {
+		return r, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, q := range quotas {
+		if q.PerToken {
+			if lc.tokens[token]+lc.reservedTokens[token] >= q.MaxLeases {
+				return nil, q
+			}
+			r.token = token
+			continue
+		}
+		if lc.prefixes[q.Path]+lc.reservedPrefixes[q.Path] >= q.MaxLeases {
+			return nil, q
+		}
+		if !seen[q.Path] {
+			seen[q.Path] = true
+			r.prefixes = append(r.prefixes, q.Path)
+		}
+	}
+
+	for _, prefix := range r.prefixes {
+		adjustCount(lc.reservedPrefixes, prefix, 1)
+	}
+	if r.token != "" {
+		adjustCount(lc.reservedTokens, r.token, 1)
+	}
+	return r, nil
+}
+
+// release gives back the slots of a reservation
+func (r *leaseReservation) release() {
+	lc := r.counts
+	lc.l.Lock()
+	defer lc.l.Unlock()
+
+	for _, prefix := range r.prefixes {
+		adjustCount(lc.reservedPrefixes, prefix, -1)
+	}
+	if r.token != "" {
+		adjustCount(lc.reservedTokens, r.token, -1)
+	}
+}
+
+// adjustCount adds delta to a count, dropping the count once it is zero
+func adjustCount(counts map[string]int, key string, delta int) {
+	if n := counts[key] + delta; n > 0 {
+		counts[key] = n
+	} else {
+		delete(counts, key)
+	}
+}
@@ -0,0 +1,157 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLeasePrefixes(t *testing.T) {
+	out := leasePrefixes("secret/foo/bar")
+	expect := []string{"", "secret/", "secret/foo/"}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestIndexToken(t *testing.T) {
+	out := indexToken("ns1/ns2/tok/lease")
+	expect := []string{"ns1/ns2/tok"}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestLeaseCounts(t *testing.T) {
+	lc := newLeaseCounts()
+	leases, index := lc.leaseKeys(""), lc.indexKeys("")
+	leases.add("secret/foo/1", 1)
+	leases.add("secret/bar/1", 1)
+	index.add("tok/1", 1)
+	index.add("tok/2", 1)
+	if lc.prefix("") != 2 || lc.prefix("secret/") != 2 || lc.prefix("secret/foo/") != 1 {
+		t.Fatalf("bad: %#v", lc.prefixes)
+	}
+	if lc.token("tok") != 2 {
+		t.Fatalf("bad: %#v", lc.tokens)
+	}
+
+	// A reservation holds a slot until it is released
+	quotas := []*LeaseCountQuota{
+		&LeaseCountQuota{Name: "secret", Path: "secret/", MaxLeases: 3},
+		&LeaseCountQuota{Name: "token", MaxLeases: 3, PerToken: true},
+	}
+	r, q := lc.reserve(quotas, "tok")
+	if q != nil {
+		t.Fatalf("bad: %#v", q)
+	}
+	if _, q := lc.reserve(quotas, "other"); q != quotas[0] {
+		t.Fatalf("bad: %#v", q)
+	}
+	r.release()
+	r, q = lc.reserve(quotas, "other")
+	if q != nil {
+		t.Fatalf("bad: %#v", q)
+	}
+	r.release()
+
+	// A rejected reservation holds nothing
+	index.add("tok/3", 1)
+	if _, q := lc.reserve(quotas, "tok"); q != quotas[1] {
+		t.Fatalf("bad: %#v", q)
+	}
+	if len(lc.reservedPrefixes) != 0 || len(lc.reservedTokens) != 0 {
+		t.Fatalf("bad: %#v %#v", lc.reservedPrefixes, lc.reservedTokens)
+	}
+
+	leases.add("secret/foo/1", -1)
+	if _, ok := lc.prefixes["secret/foo/"]; ok {
+		t.Fatalf("bad: %#v", lc.prefixes)
+	}
+}
+
+func TestLeaseCounts_Namespace(t *testing.T) {
+	lc := newLeaseCounts()
+	lc.leaseKeys("").add("secret/1", 1)
+	lc.leaseKeys("ns1/").add("secret/1", 1)
+	lc.indexKeys("ns1/").add("tok/1", 1)
+	if lc.prefix("") != 2 || lc.prefix("secret/") != 1 || lc.prefix("ns1/secret/") != 1 {
+		t.Fatalf("bad: %#v", lc.prefixes)
+	}
+	if lc.token("tok") != 0 || lc.token("ns1/tok") != 1 {
+		t.Fatalf("bad: %#v", lc.tokens)
+	}
+
+	// The quotas of the root apply to the leases of the namespaces
+	quotas := []*LeaseCountQuota{&LeaseCountQuota{Path: "", MaxLeases: 2}}
+	if _, q := lc.reserve(quotas, ""); q != quotas[0] {
+		t.Fatalf("bad: %#v", q)
+	}
+}
+
+func TestLeaseCounts_Build(t *testing.T) {
+	lc := newLeaseCounts()
+	kc, index := lc.leaseKeys(""), lc.indexKeys("")
+	kc.startBuild()
+	index.startBuild()
+
+	// The quotas are not enforced while the counts are built
+	quotas := []*LeaseCountQuota{&LeaseCountQuota{Path: "", MaxLeases: 1}}
+	r, q := lc.reserve(quotas, "")
+	if q != nil || lc.built() {
+		t.Fatalf("bad: %#v", q)
+	}
+	r.release()
+
+	// Created before the listing, and listed
+	kc.creating("a/1")
+	kc.add("a/1", 1)
+
+	// Created after the listing, and not listed
+	kc.creating("a/2")
+	kc.add("a/2", 1)
+
+	// Existing, deleted before the listing, and not listed
+	kc.deleting("a/3")
+	kc.add("a/3", -1)
+
+	// Existing, listed, and deleted before the listing is counted
+	kc.deleting("a/4")
+	kc.add("a/4", -1)
+
+	// Created and deleted
+	kc.creating("a/5")
+	kc.add("a/5", 1)
+	kc.deleting("a/5")
+	kc.add("a/5", -1)
+
+	kc.build([]string{"a/1", "a/4", "a/6", "b/1"})
+	if lc.prefix("") != 4 || lc.prefix("a/") != 3 || lc.prefix("b/") != 1 {
+		t.Fatalf("bad: %#v", lc.prefixes)
+	}
+
+	// Existing, listed, and deleted once the counts are built
+	kc.deleting("a/6")
+	kc.add("a/6", -1)
+	if lc.prefix("a/") != 2 {
+		t.Fatalf("bad: %#v", lc.prefixes)
+	}
+
+	index.build(nil)
+	if !lc.built() {
+		t.Fatalf("expected built counts")
+	}
+	if _, q := lc.reserve(quotas, ""); q != quotas[0] {
+		t.Fatalf("bad: %#v", q)
+	}
+}
+
+// testWaitLeaseCounts waits for the leases to be counted after the
+// expiration manager has been restored
+func testWaitLeaseCounts(t *testing.T, m *ExpirationManager) {
+	for start := time.Now(); !m.counts.built(); time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("leases not counted")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package vault
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryItem is a lease scheduled for expiration
+type expiryItem struct {
+	leaseID    string
+	expireTime time.Time
+
+	// index is the position in the heap, maintained by the queue
+	index int
+}
+
+// expiryQueue is a priority queue of leases ordered by expiration time.
+// Leases can be looked up by ID to be rescheduled or removed. It is not
+// safe for concurrent use.
+type expiryQueue struct {
+	items []*expiryItem
+	byID  map[string]*expiryItem
+}
+
+func newExpiryQueue() *expiryQueue {
+	return &expiryQueue{
+		byID: make(map[string]*expiryItem),
+	}
+}
+
+// Len returns the number of scheduled leases
+func (q *expiryQueue) Len() int {
+	return len(q.items)
+}
+
+// Schedule adds a lease to the queue or moves it to a new time
+func (q *expiryQueue) Schedule(leaseID string, expireTime time.Time) {
+	if item, ok := q.byID[leaseID]; ok {
+		item.expireTime = expireTime
+		heap.Fix((*expiryHeap)(q), item.index)
+		return
+	}
+
+	item := &expiryItem{
+		leaseID:    leaseID,
+		expireTime: expireTime,
+	}
+	q.byID[leaseID] = item
+	heap.Push((*expiryHeap)(q), item)
+}
+
+// Contains checks if a lease is scheduled
+func (q *expiryQueue) Contains(leaseID string) bool {
+	_, ok := q.byID[leaseID]
+	return ok
+}
+
+// Remove unschedules a lease, if it is scheduled
+func (q *expiryQueue) Remove(leaseID string) {
+	item, ok := q.byID[leaseID]
+	if !ok {
+		return
+	}
+	heap.Remove((*expiryHeap)(q), item.index)
+	delete(q.byID, leaseID)
+}
+
+// Next returns the earliest scheduled lease, or nil if there is none
+func (q *expiryQueue) Next() *expiryItem {
+	if len(q.items) == 0 {
+		return nil
+	}
+	return q.items[0]
+}
+
+// PopDue removes and returns the earliest lease if it is due by now
+func (q *expiryQueue) PopDue(now time.Time) *expiryItem {
+	next := q.Next()
+	if next == nil || next.expireTime.After(now) {
+		return nil
+	}
+	item := heap.Pop((*expiryHeap)(q)).(*expiryItem)
+	delete(q.byID, item.leaseID)
+	return item
+}
+
+// expiryHeap implements heap.Interface over the items of the queue
+type expiryHeap expiryQueue
+
+func (h *expiryHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *expiryHeap) Less(i, j int) bool {
+	return h.items[i].expireTime.Before(h.items[j].expireTime)
+}
+
+func (h *expiryHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	item := x.(*expiryItem)
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	item.index = -1
+	return item
+}
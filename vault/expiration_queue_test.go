@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryQueue(t *testing.T) {
+	q := newExpiryQueue()
+	now := time.Now()
+	q.Schedule("c", now.Add(3*time.Second))
+	q.Schedule("a", now.Add(time.Second))
+	q.Schedule("b", now.Add(2*time.Second))
+	q.Schedule("d", now.Add(4*time.Second))
+
+	// Rescheduling moves the lease
+	q.Schedule("d", now.Add(-time.Second))
+	q.Remove("b")
+	q.Remove("nope")
+
+	if q.Len() != 3 || !q.Contains("a") || q.Contains("b") {
+		t.Fatalf("bad: %#v", q.items)
+	}
+	if item := q.PopDue(now.Add(-2 * time.Second)); item != nil {
+		t.Fatalf("bad: %#v", item)
+	}
+
+	var order []string
+	for item := q.PopDue(now.Add(time.Hour)); item != nil; item = q.PopDue(now.Add(time.Hour)) {
+		order = append(order, item.leaseID)
+	}
+	if len(order) != 3 || order[0] != "d" || order[1] != "a" || order[2] != "c" {
+		t.Fatalf("bad: %v", order)
+	}
+	if q.Len() != 0 || q.Contains("a") || q.Next() != nil {
+		t.Fatalf("bad: %#v", q.items)
+	}
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
@@ -317,6 +318,65 @@ func TestExpiration_RevokePrefix(t *testing.T) {
 	}
 }
 
+func TestExpiration_MovePrefix(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	exp.router.Mount(noop, "prod/aws/", uuid.GenerateUUID(), view)
+
+	var leaseIDs []string
+	for _, path := range []string{"prod/aws/foo", "prod/aws/sub/bar"} {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: "foobarbaz",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					Lease: time.Hour,
+				},
+			},
+		}
+		leaseID, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+
+	exp.router.Remount("prod/aws/", "prod/gcp/")
+	if err := exp.MovePrefix("prod/aws", "prod/gcp"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The leases are moved to the new prefix
+	for _, leaseID := range leaseIDs {
+		if le, _ := exp.loadEntry(leaseID); le != nil {
+			t.Fatalf("bad: %#v", le)
+		}
+		moved := "prod/gcp/" + strings.TrimPrefix(leaseID, "prod/aws/")
+		le, err := exp.loadEntry(moved)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if le == nil || !strings.HasPrefix(le.Path, "prod/gcp/") {
+			t.Fatalf("bad: %#v", le)
+		}
+	}
+
+	// The moved leases are still indexed by their token
+	if err := exp.RevokeByToken("foobarbaz"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expect := []string{"foo", "sub/bar"}
+	sort.Strings(noop.Paths)
+	if !reflect.DeepEqual(noop.Paths, expect) {
+		t.Fatalf("bad: %v", noop.Paths)
+	}
+}
+
 func TestExpiration_RevokeByToken(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -376,6 +436,99 @@ func TestExpiration_RevokeByToken(t *testing.T) {
 	if !reflect.DeepEqual(noop.Paths, expect) {
 		t.Fatalf("bad: %v", noop.Paths)
 	}
+
+	// The index should be cleared
+	out, err := exp.lookupByToken("foobarbaz")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("bad: %v", out)
+	}
+}
+
+func TestExpiration_TidyTokenIndex(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	exp.router.Mount(noop, "prod/aws/", uuid.GenerateUUID(), view)
+
+	root, err := exp.tokenStore.RootToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Register a lease for a live token and one for a missing token
+	var ids []string
+	for _, token := range []string{root.ID, "foobarbaz"} {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "prod/aws/foo",
+			ClientToken: token,
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					Lease: time.Hour,
+				},
+			},
+		}
+		id, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Index a lease that does not exist
+	if err := exp.indexByToken(root.ID, "prod/aws/missing"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	removed, revoked, err := exp.TidyTokenIndex()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if removed != 1 || revoked != 1 {
+		t.Fatalf("bad: %d %d", removed, revoked)
+	}
+
+	// Only the lease of the missing token is revoked
+	if len(noop.Requests) != 1 || noop.Requests[0].Operation != logical.RevokeOperation {
+		t.Fatalf("bad: %v", noop.Requests)
+	}
+	le, err := exp.loadEntry(ids[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le == nil {
+		t.Fatalf("lease should exist")
+	}
+	le, err = exp.loadEntry(ids[1])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le != nil {
+		t.Fatalf("bad: %#v", le)
+	}
+
+	out, err := exp.lookupByToken(root.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, []string{ids[0]}) {
+		t.Fatalf("bad: %v", out)
+	}
+
+	// A second pass finds nothing to do
+	removed, revoked, err = exp.TidyTokenIndex()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if removed != 0 || revoked != 0 {
+		t.Fatalf("bad: %d %d", removed, revoked)
+	}
 }
 
 func TestExpiration_RenewToken(t *testing.T) {
@@ -498,6 +651,61 @@ func TestExpiration_Renew(t *testing.T) {
 	}
 }
 
+func TestExpiration_Renew_MaxTTL(t *testing.T) {
+	exp := mockExpiration(t)
+	exp.leaseTTLs = func(string) (time.Duration, time.Duration) {
+		return time.Hour, time.Hour
+	}
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	exp.router.Mount(noop, "prod/aws/", uuid.GenerateUUID(), view)
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "prod/aws/foo",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				Lease:     time.Hour,
+				Renewable: true,
+			},
+		},
+	}
+	id, err := exp.Register(req, resp)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The renewed lease is capped to the max TTL since the issue time
+	noop.Response = &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				Lease:            2 * time.Hour,
+				LeaseGracePeriod: time.Minute,
+				Renewable:        true,
+			},
+		},
+	}
+	out, err := exp.Renew(id, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Secret.Lease > time.Hour || out.Secret.Lease < 59*time.Minute ||
+		out.Secret.LeaseGracePeriod != 0 {
+		t.Fatalf("bad: %#v", out.Secret)
+	}
+
+	// A lease past its max TTL cannot be renewed
+	exp.leaseTTLs = func(string) (time.Duration, time.Duration) {
+		return time.Nanosecond, time.Nanosecond
+	}
+	if _, err := exp.Renew(id, 0); err == nil || err.Error() != "lease has reached its maximum TTL" {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestExpiration_Renew_NotRenewable(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -840,7 +1048,7 @@ func TestExpiration_PersistLoadDelete(t *testing.T) {
 		t.Fatalf("out: %#v expect: %#v", out, le)
 	}
 
-	err = exp.deleteEntry("foo/bar/1234")
+	err = exp.deleteEntry(le)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -884,3 +1092,314 @@ func TestLeaseEntry(t *testing.T) {
 		t.Fatalf("got: %#v, expect %#v", out, le)
 	}
 }
+
+func TestExpiration_Restore_Lazy(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	exp.router.Mount(noop, "prod/aws/", uuid.GenerateUUID(), view)
+
+	register := func(lease time.Duration) string {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "prod/aws/foo",
+			ClientToken: "foobar",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					Lease: lease,
+				},
+			},
+		}
+		leaseID, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return leaseID
+	}
+	soon := register(50 * time.Millisecond)
+	later := register(400 * time.Millisecond)
+
+	if err := exp.Stop(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the leases expiring within the window are scheduled, the
+	// window advancing every 100ms
+	exp.loadWindow = 200 * time.Millisecond
+	if err := exp.Restore(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	pending := func(leaseID string) bool {
+		exp.pendingLock.Lock()
+		defer exp.pendingLock.Unlock()
+		return exp.pending.Contains(leaseID)
+	}
+	exists := func(leaseID string) bool {
+		le, err := exp.loadEntry(leaseID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return le != nil
+	}
+
+	// Leases that are not loaded are read from storage on demand
+	info, err := exp.Lookup(later)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info == nil || info.LeaseID != later {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	start := time.Now()
+	for exists(soon) {
+		if time.Now().Sub(start) > 2*time.Second {
+			t.Fatalf("lease not revoked")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !exists(later) {
+		t.Fatalf("lease revoked early")
+	}
+	if pending(later) && time.Now().Sub(start) < 50*time.Millisecond {
+		t.Fatalf("lease loaded beyond the window")
+	}
+
+	for exists(later) {
+		if time.Now().Sub(start) > 2*time.Second {
+			t.Fatalf("lease not revoked")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The expiry index is cleaned up along with the leases
+	buckets, err := exp.expiryView.List("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Fatalf("bad: %v", buckets)
+	}
+}
+
+func TestExpiration_indexExistingLeases(t *testing.T) {
+	exp := mockExpiration(t)
+	le := &leaseEntry{
+		LeaseID:    "foo/bar/1234",
+		Path:       "foo/bar",
+		IssueTime:  time.Now().UTC(),
+		ExpireTime: time.Now().UTC().Add(time.Hour),
+	}
+	if err := exp.persistEntry(le); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Simulate a lease written before the expiry index existed
+	key := exp.expiryKey(le.LeaseID, le.ExpireTime)
+	if err := exp.expiryView.Delete(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := exp.view.Delete(expiryIndexedKey); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	if err := exp.indexExistingLeases(stopCh); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := exp.expiryView.Get(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("missing index entry")
+	}
+
+	// Indexing is only done once
+	if err := exp.expiryView.Delete(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := exp.indexExistingLeases(stopCh); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = exp.expiryView.Get(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	num, err := exp.loadExpiring(stopCh, time.Time{}, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if num != 0 {
+		t.Fatalf("bad: %d", num)
+	}
+}
+
+func TestExpiration_Irrevocable(t *testing.T) {
+	exp := mockExpiration(t)
+
+	// Nothing is mounted at the path, so revocations fail
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/foo",
+		ClientToken: "foobar",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				Lease: 10 * time.Millisecond,
+			},
+		},
+	}
+	leaseID, err := exp.Register(req, resp)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The failed attempt is recorded with the retry due after a backoff
+	var le *leaseEntry
+	start := time.Now()
+	for {
+		le, err = exp.loadEntry(leaseID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if le.RevokeAttempts > 0 {
+			break
+		}
+		if time.Now().Sub(start) > time.Second {
+			t.Fatalf("revoke not attempted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if le.LastRevokeError == "" || le.Irrevocable {
+		t.Fatalf("bad: %#v", le)
+	}
+	if retry := le.RevokeAfter.Sub(time.Now()); retry < revokeRetryBase/2 || retry > revokeRetryBase {
+		t.Fatalf("bad: %v", retry)
+	}
+	out, err := exp.expiryView.Get(exp.expiryKey(leaseID, le.RevokeAfter))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("retry not persisted")
+	}
+
+	// Exhausting the attempts marks the lease irrevocable
+	for le.RevokeAttempts < maxRevokeAttempts {
+		if err := exp.revokeFailed(leaseID, fmt.Errorf("backend unreachable")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if le, err = exp.loadEntry(leaseID); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	exp.pendingLock.Lock()
+	pending := exp.pending.Contains(leaseID)
+	exp.pendingLock.Unlock()
+	if !le.Irrevocable || le.LastRevokeError != "backend unreachable" || pending {
+		t.Fatalf("bad: %#v", le)
+	}
+
+	infos, err := exp.ListIrrevocable()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(infos) != 1 || infos[0].LeaseID != leaseID || !infos[0].Irrevocable {
+		t.Fatalf("bad: %#v", infos)
+	}
+
+	// Forcing the revocation deletes the lease and its indexes
+	if err := exp.RevokeForce("prod/aws/"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le, err = exp.loadEntry(leaseID); err != nil || le != nil {
+		t.Fatalf("bad: %#v %v", le, err)
+	}
+	if infos, err = exp.ListIrrevocable(); err != nil || len(infos) != 0 {
+		t.Fatalf("bad: %#v %v", infos, err)
+	}
+	buckets, err := exp.expiryView.List("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Fatalf("bad: %v", buckets)
+	}
+}
+
+func TestExpiration_Count(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	exp.router.Mount(noop, "prod/", uuid.GenerateUUID(), view)
+
+	root, err := exp.tokenStore.RootToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var ids []string
+	for _, path := range []string{"prod/aws/foo", "prod/aws/sub/bar", "prod/db/zip"} {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: root.ID,
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					Lease: time.Hour,
+				},
+			},
+		}
+		id, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	check := func(all, aws, sub, token int) {
+		if n := exp.Count(""); n != all {
+			t.Fatalf("bad: %d", n)
+		}
+		if n := exp.Count("prod/aws/"); n != aws {
+			t.Fatalf("bad: %d", n)
+		}
+		if n := exp.Count("prod/aws/sub/"); n != sub {
+			t.Fatalf("bad: %d", n)
+		}
+		if n := exp.CountByToken(root.ID); n != token {
+			t.Fatalf("bad: %d", n)
+		}
+	}
+	check(3, 2, 1, 3)
+
+	// The counts are rebuilt from the stored leases
+	exp.shareCounts(newLeaseCounts(), "")
+	exp.leaseKeys.startBuild()
+	exp.indexKeys.startBuild()
+	if err := exp.countLeases(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	check(3, 2, 1, 3)
+
+	// Revoked leases are no longer counted, even if revoked twice
+	for i := 0; i < 2; i++ {
+		if err := exp.Revoke(ids[1]); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	check(2, 1, 0, 2)
+}
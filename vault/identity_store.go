@@ -0,0 +1,1001 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// identityMountPath is the path the identity store is mounted at
+	identityMountPath = "identity/"
+
+	// entityPrefix is the storage prefix of entities by ID
+	entityPrefix = "entity/"
+
+	// entityNamePrefix indexes entity IDs by entity name
+	entityNamePrefix = "entity-name/"
+
+	// entityAliasesPrefix indexes the aliases of an entity, stored as
+	// entity-aliases/<entity id>/<alias id>
+	entityAliasesPrefix = "entity-aliases/"
+
+	// aliasPrefix is the storage prefix of aliases by ID
+	aliasPrefix = "alias/"
+
+	// aliasIndexPrefix indexes alias IDs by mount and name, stored as
+	// alias-index/<mount uuid>/<hashed alias name>
+	aliasIndexPrefix = "alias-index/"
+)
+
+// Entity represents a single person or service known to Vault. It may
+// log in through any number of auth backends, each login being mapped
+// to it by an alias.
+type Entity struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Policies       []string          `json:"policies"`
+	Metadata       map[string]string `json:"metadata"`
+	CreationTime   time.Time         `json:"creation_time"`
+	LastUpdateTime time.Time         `json:"last_update_time"`
+}
+
+// EntityAlias maps a user name of an auth backend to an entity. The
+// auth backend is referenced by the UUID of its mount entry, so that
+// aliases survive the backend being remounted.
+type EntityAlias struct {
+	ID             string            `json:"id"`
+	EntityID       string            `json:"entity_id"`
+	MountUUID      string            `json:"mount_uuid"`
+	Name           string            `json:"name"`
+	Metadata       map[string]string `json:"metadata"`
+	CreationTime   time.Time         `json:"creation_time"`
+	LastUpdateTime time.Time         `json:"last_update_time"`
+}
+
+// IdentityStore is the backend mounted at identity/ that manages
+// entities and their aliases. Logins are mapped to entities by the
+// core, and entity policies are added to those of the token.
+type IdentityStore struct {
+	*framework.Backend
+
+	core *Core
+	view logical.Storage
+
+	// lock serializes changes to entities and aliases so that the
+	// indexes stay consistent
+	lock sync.RWMutex
+}
+
+// NewIdentityStore returns the backend managing entities and aliases
+func NewIdentityStore(core *Core, conf *logical.BackendConfig) *IdentityStore {
+	i := &IdentityStore{
+		core: core,
+		view: conf.View,
+	}
+
+	i.Backend = &framework.Backend{
+		Help: strings.TrimSpace(identityHelp),
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "entity$",
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the entity. Generated if not given.",
+					},
+					"policies": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Comma-separated list of policies of the entity.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Metadata of the entity, as string keys and values.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: i.handleEntityCreate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityEntityHelp),
+				HelpDescription: strings.TrimSpace(identityEntityHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity/id/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: i.handleEntityList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityEntityListHelp),
+				HelpDescription: strings.TrimSpace(identityEntityListHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity/id/(?P<id>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the entity.",
+					},
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the entity.",
+					},
+					"policies": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Comma-separated list of policies of the entity.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Metadata of the entity, as string keys and values.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.handleEntityRead,
+					logical.WriteOperation:  i.handleEntityUpdate,
+					logical.DeleteOperation: i.handleEntityDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityEntityIDHelp),
+				HelpDescription: strings.TrimSpace(identityEntityIDHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity/name/(?P<name>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the entity.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: i.handleEntityReadName,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityEntityNameHelp),
+				HelpDescription: strings.TrimSpace(identityEntityNameHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias$",
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "User name of the alias in the auth backend.",
+					},
+					"mount_path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Path of the auth backend, such as auth/github/.",
+					},
+					"entity_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the entity the alias belongs to.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Metadata of the alias, as string keys and values.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: i.handleAliasCreate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityAliasHelp),
+				HelpDescription: strings.TrimSpace(identityAliasHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias/id/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: i.handleAliasList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityAliasListHelp),
+				HelpDescription: strings.TrimSpace(identityAliasListHelp),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias/id/(?P<id>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the alias.",
+					},
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "User name of the alias in the auth backend.",
+					},
+					"entity_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the entity the alias belongs to.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Metadata of the alias, as string keys and values.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.handleAliasRead,
+					logical.WriteOperation:  i.handleAliasUpdate,
+					logical.DeleteOperation: i.handleAliasDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityAliasIDHelp),
+				HelpDescription: strings.TrimSpace(identityAliasIDHelp),
+			},
+		},
+	}
+	i.Backend.Paths = append(i.Backend.Paths, groupPaths(i)...)
+	i.Backend.Paths = append(i.Backend.Paths, oidcPaths(i)...)
+
+	return i
+}
+
+// HandleRequest handles the requests to the identity store. The periodic
+// rollback operation rotates the OIDC keys that are due, so that they are
+// rotated even when no token is signed with them.
+func (i *IdentityStore) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.RollbackOperation {
+		return nil, i.rotateOIDCKeys()
+	}
+	return i.Backend.HandleRequest(req)
+}
+
+// EntityPolicies returns the policies granted to a token through the
+// entity it belongs to, including those of the groups of the entity.
+func (i *IdentityStore) EntityPolicies(entityID string) ([]string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, err := i.entity(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	groupPolicies, err := i.groupPolicies(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, entity.Policies...), groupPolicies...), nil
+}
+
+// CreateOrFetchEntity returns the entity an alias of the given auth
+// backend maps to. If the alias is unknown, a new entity is created for
+// it. The metadata of the alias is updated to the given one.
+func (i *IdentityStore) CreateOrFetchEntity(mountUUID string, alias *logical.Alias) (*Entity, error) {
+	if alias == nil || alias.Name == "" {
+		return nil, fmt.Errorf("missing alias name")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	existing, err := i.aliasByName(mountUUID, alias.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		entity, err := i.entity(existing.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return nil, fmt.Errorf("entity %q of alias %q not found",
+				existing.EntityID, existing.ID)
+		}
+
+		// Keep the metadata reported by the backend current
+		if !strMapEqual(existing.Metadata, alias.Metadata) {
+			existing.Metadata = alias.Metadata
+			existing.LastUpdateTime = time.Now().UTC()
+			if err := i.putAlias(existing); err != nil {
+				return nil, err
+			}
+		}
+		return entity, nil
+	}
+
+	// Create a new entity for the unknown alias
+	now := time.Now().UTC()
+	entity := &Entity{
+		ID:             uuid.GenerateUUID(),
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}
+	entity.Name = "entity_" + entity.ID[:8]
+	if err := i.putEntity(entity, ""); err != nil {
+		return nil, err
+	}
+
+	newAlias := &EntityAlias{
+		ID:             uuid.GenerateUUID(),
+		EntityID:       entity.ID,
+		MountUUID:      mountUUID,
+		Name:           alias.Name,
+		Metadata:       alias.Metadata,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}
+	if err := i.putAlias(newAlias); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// DeleteMountAliases removes all entity and group aliases of an auth
+// backend. It is invoked when the backend is disabled.
+func (i *IdentityStore) DeleteMountAliases(mountUUID string) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	prefix := aliasIndexPrefix + mountUUID + "/"
+	keys, err := i.view.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		raw, err := i.view.Get(prefix + key)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+		alias, err := i.alias(string(raw.Value))
+		if err != nil {
+			return err
+		}
+		if alias == nil {
+			if err := i.view.Delete(prefix + key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := i.deleteAlias(alias); err != nil {
+			return err
+		}
+	}
+	return i.deleteMountGroupAliases(mountUUID)
+}
+
+func (i *IdentityStore) handleEntityCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policies, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	now := time.Now().UTC()
+	entity := &Entity{
+		ID:             uuid.GenerateUUID(),
+		Name:           data.Get("name").(string),
+		Policies:       policies,
+		Metadata:       metadata,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}
+	if entity.Name == "" {
+		entity.Name = "entity_" + entity.ID[:8]
+	}
+	if resp, err := i.checkEntityName(entity.Name, ""); resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := i.putEntity(entity, ""); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   entity.ID,
+			"name": entity.Name,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleEntityList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	keys, err := i.view.List(entityPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) handleEntityRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, err := i.entity(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	return i.entityResponse(entity)
+}
+
+func (i *IdentityStore) handleEntityReadName(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, err := i.entityByName(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	return i.entityResponse(entity)
+}
+
+func (i *IdentityStore) handleEntityUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policies, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.entity(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+
+	// Only update the fields that are given
+	oldName := entity.Name
+	if name, ok := data.GetOk("name"); ok && name.(string) != "" {
+		entity.Name = name.(string)
+		if resp, err := i.checkEntityName(entity.Name, entity.ID); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+	if _, ok := data.GetOk("policies"); ok {
+		entity.Policies = policies
+	}
+	if _, ok := data.GetOk("metadata"); ok {
+		entity.Metadata = metadata
+	}
+	entity.LastUpdateTime = time.Now().UTC()
+
+	if err := i.putEntity(entity, oldName); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleEntityDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.entity(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	// Delete the aliases of the entity first
+	aliasIDs, err := i.view.List(entityAliasesPrefix + entity.ID + "/")
+	if err != nil {
+		return nil, err
+	}
+	for _, aliasID := range aliasIDs {
+		alias, err := i.alias(aliasID)
+		if err != nil {
+			return nil, err
+		}
+		if alias == nil {
+			continue
+		}
+		if err := i.deleteAlias(alias); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.removeEntityFromGroups(entity.ID); err != nil {
+		return nil, err
+	}
+
+	if err := i.view.Delete(entityNamePrefix + entity.Name); err != nil {
+		return nil, err
+	}
+	if err := i.view.Delete(entityPrefix + entity.ID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleAliasCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing name"), logical.ErrInvalidRequest
+	}
+	_, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	// Resolve the auth backend of the alias
+	mountUUID := i.core.credentialMountUUID(data.Get("mount_path").(string))
+	if mountUUID == "" {
+		return logical.ErrorResponse("no auth backend mounted at mount_path"),
+			logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, err := i.entity(data.Get("entity_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+	}
+
+	existing, err := i.aliasByName(mountUUID, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse("an alias with this name already exists for the auth backend"),
+			logical.ErrInvalidRequest
+	}
+
+	now := time.Now().UTC()
+	alias := &EntityAlias{
+		ID:             uuid.GenerateUUID(),
+		EntityID:       entity.ID,
+		MountUUID:      mountUUID,
+		Name:           name,
+		Metadata:       metadata,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}
+	if err := i.putAlias(alias); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":        alias.ID,
+			"entity_id": alias.EntityID,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleAliasList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	keys, err := i.view.List(aliasPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) handleAliasRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	alias, err := i.alias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: i.aliasData(alias),
+	}
+	resp.Data["entity_id"] = alias.EntityID
+	resp.Data["metadata"] = alias.Metadata
+	return resp, nil
+}
+
+func (i *IdentityStore) handleAliasUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	_, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	alias, err := i.alias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return logical.ErrorResponse("alias not found"), logical.ErrInvalidRequest
+	}
+
+	// Only update the fields that are given. Changing the name or the
+	// entity requires moving the index entries.
+	updated := *alias
+	if name, ok := data.GetOk("name"); ok && name.(string) != "" {
+		updated.Name = name.(string)
+		if updated.Name != alias.Name {
+			existing, err := i.aliasByName(alias.MountUUID, updated.Name)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				return logical.ErrorResponse("an alias with this name already exists for the auth backend"),
+					logical.ErrInvalidRequest
+			}
+		}
+	}
+	if entityID, ok := data.GetOk("entity_id"); ok && entityID.(string) != "" {
+		entity, err := i.entity(entityID.(string))
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return logical.ErrorResponse("entity not found"), logical.ErrInvalidRequest
+		}
+		updated.EntityID = entity.ID
+	}
+	if _, ok := data.GetOk("metadata"); ok {
+		updated.Metadata = metadata
+	}
+	updated.LastUpdateTime = time.Now().UTC()
+
+	if err := i.deleteAlias(alias); err != nil {
+		return nil, err
+	}
+	if err := i.putAlias(&updated); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleAliasDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	alias, err := i.alias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, nil
+	}
+	if err := i.deleteAlias(alias); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// entityResponse formats an entity along with its aliases
+func (i *IdentityStore) entityResponse(entity *Entity) (*logical.Response, error) {
+	if entity == nil {
+		return nil, nil
+	}
+
+	aliasIDs, err := i.view.List(entityAliasesPrefix + entity.ID + "/")
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]map[string]interface{}, 0, len(aliasIDs))
+	for _, aliasID := range aliasIDs {
+		alias, err := i.alias(aliasID)
+		if err != nil {
+			return nil, err
+		}
+		if alias != nil {
+			aliases = append(aliases, i.aliasData(alias))
+		}
+	}
+
+	groupIDs, err := i.view.List(entityGroupsPrefix + entity.ID + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":               entity.ID,
+			"name":             entity.Name,
+			"policies":         entity.Policies,
+			"metadata":         entity.Metadata,
+			"aliases":          aliases,
+			"group_ids":        groupIDs,
+			"creation_time":    entity.CreationTime,
+			"last_update_time": entity.LastUpdateTime,
+		},
+	}, nil
+}
+
+// aliasData returns the fields of an alias shown when reading it
+func (i *IdentityStore) aliasData(alias *EntityAlias) map[string]interface{} {
+	mountPath, mountType := i.core.credentialMountByUUID(alias.MountUUID)
+	return map[string]interface{}{
+		"id":               alias.ID,
+		"name":             alias.Name,
+		"mount_path":       mountPath,
+		"mount_type":       mountType,
+		"creation_time":    alias.CreationTime,
+		"last_update_time": alias.LastUpdateTime,
+	}
+}
+
+// checkEntityName returns an error response if the name is taken by an
+// entity other than the given one.
+func (i *IdentityStore) checkEntityName(name, entityID string) (*logical.Response, error) {
+	existing, err := i.entityByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != entityID {
+		return logical.ErrorResponse("an entity with this name already exists"),
+			logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// entity returns the entity with the given ID, or nil if not found
+func (i *IdentityStore) entity(id string) (*Entity, error) {
+	if id == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(entityPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entity Entity
+	if err := raw.DecodeJSON(&entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// entityByName returns the entity with the given name, or nil if not found
+func (i *IdentityStore) entityByName(name string) (*Entity, error) {
+	if name == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(entityNamePrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return i.entity(string(raw.Value))
+}
+
+// putEntity persists an entity and its name index. If the entity was
+// renamed, the index of the old name is removed.
+func (i *IdentityStore) putEntity(entity *Entity, oldName string) error {
+	entry, err := logical.StorageEntryJSON(entityPrefix+entity.ID, entity)
+	if err != nil {
+		return err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return err
+	}
+
+	if oldName != "" && oldName != entity.Name {
+		if err := i.view.Delete(entityNamePrefix + oldName); err != nil {
+			return err
+		}
+	}
+	return i.view.Put(&logical.StorageEntry{
+		Key:   entityNamePrefix + entity.Name,
+		Value: []byte(entity.ID),
+	})
+}
+
+// alias returns the alias with the given ID, or nil if not found
+func (i *IdentityStore) alias(id string) (*EntityAlias, error) {
+	if id == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(aliasPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var alias EntityAlias
+	if err := raw.DecodeJSON(&alias); err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// aliasByName returns the alias of an auth backend with the given
+// name, or nil if not found
+func (i *IdentityStore) aliasByName(mountUUID, name string) (*EntityAlias, error) {
+	raw, err := i.view.Get(aliasIndexKey(mountUUID, name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return i.alias(string(raw.Value))
+}
+
+// putAlias persists an alias along with its indexes
+func (i *IdentityStore) putAlias(alias *EntityAlias) error {
+	entry, err := logical.StorageEntryJSON(aliasPrefix+alias.ID, alias)
+	if err != nil {
+		return err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return err
+	}
+
+	if err := i.view.Put(&logical.StorageEntry{
+		Key:   aliasIndexKey(alias.MountUUID, alias.Name),
+		Value: []byte(alias.ID),
+	}); err != nil {
+		return err
+	}
+	return i.view.Put(&logical.StorageEntry{
+		Key: entityAliasesPrefix + alias.EntityID + "/" + alias.ID,
+	})
+}
+
+// deleteAlias removes an alias along with its indexes
+func (i *IdentityStore) deleteAlias(alias *EntityAlias) error {
+	if err := i.view.Delete(entityAliasesPrefix + alias.EntityID + "/" + alias.ID); err != nil {
+		return err
+	}
+	if err := i.view.Delete(aliasIndexKey(alias.MountUUID, alias.Name)); err != nil {
+		return err
+	}
+	return i.view.Delete(aliasPrefix + alias.ID)
+}
+
+// aliasIndexKey returns the storage key indexing an alias by the auth
+// backend and name. The name is hashed as it is chosen by the backend.
+func aliasIndexKey(mountUUID, name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return aliasIndexPrefix + mountUUID + "/" + hex.EncodeToString(hash[:])
+}
+
+// parseIdentityFields parses the policies and metadata fields shared by
+// entities, aliases and groups. An error response is returned if invalid.
+func parseIdentityFields(data *framework.FieldData) ([]string, map[string]string, *logical.Response) {
+	var policies []string
+	if raw, ok := data.GetOk("policies"); ok {
+		policies = parseCommaList(raw.(string))
+	}
+	if strListContains(policies, "root") {
+		return nil, nil, logical.ErrorResponse("the root policy cannot be granted through identity")
+	}
+
+	var metadata map[string]string
+	raw, ok, err := data.GetOkErr("metadata")
+	if err != nil {
+		return nil, nil, logical.ErrorResponse(err.Error())
+	}
+	if ok {
+		metadata = make(map[string]string)
+		for k, v := range raw.(map[string]interface{}) {
+			vStr, ok := v.(string)
+			if !ok {
+				return nil, nil, logical.ErrorResponse("metadata must be string valued")
+			}
+			metadata[k] = vStr
+		}
+	}
+	return policies, metadata, nil
+}
+
+// credentialMountUUID returns the UUID of the auth backend mounted at
+// the given path, such as "auth/github/". An empty string is returned if
+// there is no such backend.
+func (c *Core) credentialMountUUID(path string) string {
+	path = strings.TrimPrefix(path, credentialRoutePrefix)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	c.auth.RLock()
+	defer c.auth.RUnlock()
+	entry := c.auth.Find(path)
+	if entry == nil {
+		return ""
+	}
+	return entry.UUID
+}
+
+// credentialMountByUUID returns the path and type of the auth backend
+// with the given mount UUID. Empty strings are returned if there is no
+// such backend.
+func (c *Core) credentialMountByUUID(uuid string) (string, string) {
+	c.auth.RLock()
+	defer c.auth.RUnlock()
+	for _, entry := range c.auth.Entries {
+		if entry.UUID == uuid {
+			return credentialRoutePrefix + entry.Path, entry.Type
+		}
+	}
+	return "", ""
+}
+
+const identityHelp = `
+The identity backend maps the logins of the various auth backends to
+entities. An entity represents a single person or service, and carries
+policies and metadata that apply to every token it logs in with.
+`
+
+const identityEntityHelp = `
+Create an entity. The policies of the entity are granted to all tokens
+issued to it, in addition to the policies of the tokens themselves.
+`
+
+const identityEntityListHelp = `
+List the IDs of all entities.
+`
+
+const identityEntityIDHelp = `
+Read, update or delete an entity by ID. Deleting an entity deletes its
+aliases as well.
+`
+
+const identityEntityNameHelp = `
+Read an entity by name.
+`
+
+const identityAliasHelp = `
+Create an alias mapping a user name of an auth backend to an entity.
+Logins through the backend with that user name are issued tokens of the
+entity. Aliases for unknown user names are created automatically at
+login, each with a new entity.
+`
+
+const identityAliasListHelp = `
+List the IDs of all aliases.
+`
+
+const identityAliasIDHelp = `
+Read, update or delete an alias by ID.
+`
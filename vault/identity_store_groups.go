@@ -0,0 +1,1103 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// groupPrefix is the storage prefix of groups by ID
+	groupPrefix = "group/"
+
+	// groupNamePrefix indexes group IDs by group name
+	groupNamePrefix = "group-name/"
+
+	// groupAliasPrefix is the storage prefix of group aliases by ID
+	groupAliasPrefix = "group-alias/"
+
+	// groupAliasIndexPrefix indexes group alias IDs by mount and name,
+	// stored as group-alias-index/<mount uuid>/<hashed alias name>
+	groupAliasIndexPrefix = "group-alias-index/"
+
+	// entityGroupsPrefix indexes the groups an entity is a direct member
+	// of, stored as entity-groups/<entity id>/<group id>
+	entityGroupsPrefix = "entity-groups/"
+
+	// groupParentsPrefix indexes the groups a group is a member of,
+	// stored as group-parents/<group id>/<parent group id>
+	groupParentsPrefix = "group-parents/"
+
+	// groupTypeInternal groups have explicitly managed members
+	groupTypeInternal = "internal"
+
+	// groupTypeExternal groups have their members determined by the
+	// groups reported by an auth backend at login
+	groupTypeExternal = "external"
+)
+
+// Group is a set of entities and other groups sharing policies. The
+// members of a group, including the members of its member groups, are
+// granted the policies of the group.
+type Group struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Policies        []string          `json:"policies"`
+	Metadata        map[string]string `json:"metadata"`
+	MemberEntityIDs []string          `json:"member_entity_ids"`
+	MemberGroupIDs  []string          `json:"member_group_ids"`
+	AliasID         string            `json:"alias_id"`
+	CreationTime    time.Time         `json:"creation_time"`
+	LastUpdateTime  time.Time         `json:"last_update_time"`
+}
+
+// GroupAlias maps a group name reported by an auth backend to an
+// external group.
+type GroupAlias struct {
+	ID             string    `json:"id"`
+	GroupID        string    `json:"group_id"`
+	MountUUID      string    `json:"mount_uuid"`
+	Name           string    `json:"name"`
+	CreationTime   time.Time `json:"creation_time"`
+	LastUpdateTime time.Time `json:"last_update_time"`
+}
+
+// groupPaths returns the paths of the identity store managing groups
+func groupPaths(i *IdentityStore) []*framework.Path {
+	groupFields := map[string]*framework.FieldSchema{
+		"name": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the group.",
+		},
+		"type": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Default:     groupTypeInternal,
+			Description: `Type of the group, "internal" or "external".`,
+		},
+		"policies": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of policies of the group.",
+		},
+		"metadata": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: "Metadata of the group, as string keys and values.",
+		},
+		"member_entity_ids": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of member entity IDs. Internal groups only.",
+		},
+		"member_group_ids": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of member group IDs. Internal groups only.",
+		},
+	}
+	groupIDFields := map[string]*framework.FieldSchema{
+		"id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "ID of the group.",
+		},
+	}
+	for k, v := range groupFields {
+		groupIDFields[k] = v
+	}
+
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "group$",
+			Fields:  groupFields,
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.WriteOperation: i.handleGroupCreate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupHelp),
+			HelpDescription: strings.TrimSpace(identityGroupHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group/id/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.handleGroupList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupListHelp),
+			HelpDescription: strings.TrimSpace(identityGroupListHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group/id/(?P<id>.+)",
+			Fields:  groupIDFields,
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.handleGroupRead,
+				logical.WriteOperation:  i.handleGroupUpdate,
+				logical.DeleteOperation: i.handleGroupDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupIDHelp),
+			HelpDescription: strings.TrimSpace(identityGroupIDHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group/name/(?P<name>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the group.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.handleGroupReadName,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupNameHelp),
+			HelpDescription: strings.TrimSpace(identityGroupNameHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group-alias$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the group in the auth backend.",
+				},
+				"mount_path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Path of the auth backend, such as auth/ldap/.",
+				},
+				"canonical_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "ID of the external group the alias belongs to.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.WriteOperation: i.handleGroupAliasCreate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupAliasHelp),
+			HelpDescription: strings.TrimSpace(identityGroupAliasHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group-alias/id/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.handleGroupAliasList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupAliasListHelp),
+			HelpDescription: strings.TrimSpace(identityGroupAliasListHelp),
+		},
+
+		&framework.Path{
+			Pattern: "group-alias/id/(?P<id>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "ID of the group alias.",
+				},
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the group in the auth backend.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.handleGroupAliasRead,
+				logical.WriteOperation:  i.handleGroupAliasUpdate,
+				logical.DeleteOperation: i.handleGroupAliasDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityGroupAliasIDHelp),
+			HelpDescription: strings.TrimSpace(identityGroupAliasIDHelp),
+		},
+	}
+}
+
+// UpdateExternalGroups sets the membership of an entity in the external
+// groups of an auth backend to the groups reported at login.
+func (i *IdentityStore) UpdateExternalGroups(entityID, mountUUID string, aliases []*logical.Alias) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	// Resolve the reported group names to external groups
+	desired := make(map[string]bool)
+	for _, alias := range aliases {
+		if alias == nil || alias.Name == "" {
+			continue
+		}
+		groupAlias, err := i.groupAliasByName(mountUUID, alias.Name)
+		if err != nil {
+			return err
+		}
+		if groupAlias != nil {
+			desired[groupAlias.GroupID] = true
+		}
+	}
+
+	// Leave the external groups of the backend that were not reported
+	groupIDs, err := i.view.List(entityGroupsPrefix + entityID + "/")
+	if err != nil {
+		return err
+	}
+	for _, groupID := range groupIDs {
+		if desired[groupID] {
+			delete(desired, groupID)
+			continue
+		}
+		group, err := i.group(groupID)
+		if err != nil {
+			return err
+		}
+		if group == nil || group.Type != groupTypeExternal {
+			continue
+		}
+		groupAlias, err := i.groupAlias(group.AliasID)
+		if err != nil {
+			return err
+		}
+		if groupAlias == nil || groupAlias.MountUUID != mountUUID {
+			continue
+		}
+
+		updated := *group
+		updated.MemberEntityIDs = strListRemove(group.MemberEntityIDs, entityID)
+		updated.LastUpdateTime = time.Now().UTC()
+		if err := i.putGroup(&updated, group); err != nil {
+			return err
+		}
+	}
+
+	// Join the reported groups the entity is not a member of yet
+	for groupID := range desired {
+		group, err := i.group(groupID)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			continue
+		}
+
+		updated := *group
+		updated.MemberEntityIDs = append(append([]string{}, group.MemberEntityIDs...), entityID)
+		updated.LastUpdateTime = time.Now().UTC()
+		if err := i.putGroup(&updated, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupPolicies returns the policies of all groups the entity is a
+// member of, directly or through member groups. The lock must be held.
+func (i *IdentityStore) groupPolicies(entityID string) ([]string, error) {
+	groups, err := i.entityGroups(entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []string
+	for _, group := range groups {
+		policies = append(policies, group.Policies...)
+	}
+	return policies, nil
+}
+
+// entityGroups returns all groups the entity is a member of, directly or
+// through member groups. The lock must be held.
+func (i *IdentityStore) entityGroups(entityID string) ([]*Group, error) {
+	groupIDs, err := i.view.List(entityGroupsPrefix + entityID + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*Group
+	visited := make(map[string]bool)
+	for len(groupIDs) > 0 {
+		groupID := groupIDs[0]
+		groupIDs = groupIDs[1:]
+		if visited[groupID] {
+			continue
+		}
+		visited[groupID] = true
+
+		group, err := i.group(groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			continue
+		}
+		groups = append(groups, group)
+
+		parents, err := i.view.List(groupParentsPrefix + groupID + "/")
+		if err != nil {
+			return nil, err
+		}
+		groupIDs = append(groupIDs, parents...)
+	}
+	return groups, nil
+}
+
+func (i *IdentityStore) handleGroupCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policies, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	now := time.Now().UTC()
+	group := &Group{
+		ID:              uuid.GenerateUUID(),
+		Name:            data.Get("name").(string),
+		Type:            data.Get("type").(string),
+		Policies:        policies,
+		Metadata:        metadata,
+		MemberEntityIDs: parseCommaList(data.Get("member_entity_ids").(string)),
+		MemberGroupIDs:  parseCommaList(data.Get("member_group_ids").(string)),
+		CreationTime:    now,
+		LastUpdateTime:  now,
+	}
+	if group.Name == "" {
+		group.Name = "group_" + group.ID[:8]
+	}
+	if group.Type == groupTypeExternal &&
+		(len(group.MemberEntityIDs) > 0 || len(group.MemberGroupIDs) > 0) {
+		return logical.ErrorResponse("members of external groups cannot be set"),
+			logical.ErrInvalidRequest
+	}
+	if resp, err := i.validateGroup(group); resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := i.putGroup(group, nil); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   group.ID,
+			"name": group.Name,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleGroupList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	keys, err := i.view.List(groupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) handleGroupRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	group, err := i.group(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	return i.groupResponse(group)
+}
+
+func (i *IdentityStore) handleGroupReadName(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	group, err := i.groupByName(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	return i.groupResponse(group)
+}
+
+func (i *IdentityStore) handleGroupUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policies, metadata, resp := parseIdentityFields(data)
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	group, err := i.group(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return logical.ErrorResponse("group not found"), logical.ErrInvalidRequest
+	}
+
+	// Only update the fields that are given
+	updated := *group
+	if name, ok := data.GetOk("name"); ok && name.(string) != "" {
+		updated.Name = name.(string)
+	}
+	if groupType, ok := data.GetOk("type"); ok && groupType.(string) != group.Type {
+		return logical.ErrorResponse("the type of a group cannot be changed"),
+			logical.ErrInvalidRequest
+	}
+	if _, ok := data.GetOk("policies"); ok {
+		updated.Policies = policies
+	}
+	if _, ok := data.GetOk("metadata"); ok {
+		updated.Metadata = metadata
+	}
+	if raw, ok := data.GetOk("member_entity_ids"); ok {
+		updated.MemberEntityIDs = parseCommaList(raw.(string))
+	}
+	if raw, ok := data.GetOk("member_group_ids"); ok {
+		updated.MemberGroupIDs = parseCommaList(raw.(string))
+	}
+	updated.LastUpdateTime = time.Now().UTC()
+
+	// Members of external groups are only managed at login
+	if group.Type == groupTypeExternal {
+		_, entitiesOk := data.GetOk("member_entity_ids")
+		_, groupsOk := data.GetOk("member_group_ids")
+		if entitiesOk || groupsOk {
+			return logical.ErrorResponse("members of external groups cannot be set"),
+				logical.ErrInvalidRequest
+		}
+	}
+	if resp, err := i.validateGroup(&updated); resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := i.putGroup(&updated, group); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleGroupDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	group, err := i.group(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, nil
+	}
+
+	// Remove the group from the groups it is a member of
+	parentIDs, err := i.view.List(groupParentsPrefix + group.ID + "/")
+	if err != nil {
+		return nil, err
+	}
+	for _, parentID := range parentIDs {
+		parent, err := i.group(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			continue
+		}
+		updated := *parent
+		updated.MemberGroupIDs = strListRemove(parent.MemberGroupIDs, group.ID)
+		updated.LastUpdateTime = time.Now().UTC()
+		if err := i.putGroup(&updated, parent); err != nil {
+			return nil, err
+		}
+	}
+
+	// Drop the membership indexes by emptying the group first
+	empty := *group
+	empty.MemberEntityIDs = nil
+	empty.MemberGroupIDs = nil
+	if err := i.putGroup(&empty, group); err != nil {
+		return nil, err
+	}
+
+	if group.AliasID != "" {
+		groupAlias, err := i.groupAlias(group.AliasID)
+		if err != nil {
+			return nil, err
+		}
+		if groupAlias != nil {
+			if err := i.deleteGroupAlias(groupAlias); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := i.view.Delete(groupNamePrefix + group.Name); err != nil {
+		return nil, err
+	}
+	if err := i.view.Delete(groupPrefix + group.ID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleGroupAliasCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing name"), logical.ErrInvalidRequest
+	}
+
+	// Resolve the auth backend of the alias
+	mountUUID := i.core.credentialMountUUID(data.Get("mount_path").(string))
+	if mountUUID == "" {
+		return logical.ErrorResponse("no auth backend mounted at mount_path"),
+			logical.ErrInvalidRequest
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	group, err := i.group(data.Get("canonical_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case group == nil:
+		return logical.ErrorResponse("group not found"), logical.ErrInvalidRequest
+	case group.Type != groupTypeExternal:
+		return logical.ErrorResponse("aliases can only be created for external groups"),
+			logical.ErrInvalidRequest
+	case group.AliasID != "":
+		return logical.ErrorResponse("the group already has an alias"),
+			logical.ErrInvalidRequest
+	}
+
+	existing, err := i.groupAliasByName(mountUUID, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse("a group alias with this name already exists for the auth backend"),
+			logical.ErrInvalidRequest
+	}
+
+	now := time.Now().UTC()
+	groupAlias := &GroupAlias{
+		ID:             uuid.GenerateUUID(),
+		GroupID:        group.ID,
+		MountUUID:      mountUUID,
+		Name:           name,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}
+	if err := i.putGroupAlias(groupAlias); err != nil {
+		return nil, err
+	}
+
+	updated := *group
+	updated.AliasID = groupAlias.ID
+	updated.LastUpdateTime = now
+	if err := i.putGroup(&updated, group); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":           groupAlias.ID,
+			"canonical_id": group.ID,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleGroupAliasList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	keys, err := i.view.List(groupAliasPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) handleGroupAliasRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	groupAlias, err := i.groupAlias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if groupAlias == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: i.groupAliasData(groupAlias),
+	}, nil
+}
+
+func (i *IdentityStore) handleGroupAliasUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	groupAlias, err := i.groupAlias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if groupAlias == nil {
+		return logical.ErrorResponse("group alias not found"), logical.ErrInvalidRequest
+	}
+
+	name := data.Get("name").(string)
+	if name == "" || name == groupAlias.Name {
+		return nil, nil
+	}
+	existing, err := i.groupAliasByName(groupAlias.MountUUID, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse("a group alias with this name already exists for the auth backend"),
+			logical.ErrInvalidRequest
+	}
+
+	if err := i.view.Delete(groupAliasIndexKey(groupAlias.MountUUID, groupAlias.Name)); err != nil {
+		return nil, err
+	}
+	groupAlias.Name = name
+	groupAlias.LastUpdateTime = time.Now().UTC()
+	if err := i.putGroupAlias(groupAlias); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleGroupAliasDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	groupAlias, err := i.groupAlias(data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if groupAlias == nil {
+		return nil, nil
+	}
+	if err := i.deleteGroupAlias(groupAlias); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validateGroup returns an error response if the group is invalid. The
+// name must be unique, members must exist and membership cannot be
+// cyclic.
+func (i *IdentityStore) validateGroup(group *Group) (*logical.Response, error) {
+	switch group.Type {
+	case groupTypeInternal, groupTypeExternal:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid group type '%s'", group.Type)),
+			logical.ErrInvalidRequest
+	}
+
+	existing, err := i.groupByName(group.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != group.ID {
+		return logical.ErrorResponse("a group with this name already exists"),
+			logical.ErrInvalidRequest
+	}
+
+	// The entity members of external groups are added at login
+	if group.Type == groupTypeInternal {
+		for _, entityID := range group.MemberEntityIDs {
+			entity, err := i.entity(entityID)
+			if err != nil {
+				return nil, err
+			}
+			if entity == nil {
+				return logical.ErrorResponse(fmt.Sprintf("entity '%s' not found", entityID)),
+					logical.ErrInvalidRequest
+			}
+		}
+	}
+
+	for _, memberID := range group.MemberGroupIDs {
+		member, err := i.group(memberID)
+		if err != nil {
+			return nil, err
+		}
+		if member == nil {
+			return logical.ErrorResponse(fmt.Sprintf("group '%s' not found", memberID)),
+				logical.ErrInvalidRequest
+		}
+
+		// A member group may not contain this group, directly or
+		// indirectly, which is the case if the member is reachable
+		// through the parents of this group
+		cyclic, err := i.groupReachable(group.ID, memberID)
+		if err != nil {
+			return nil, err
+		}
+		if cyclic {
+			return logical.ErrorResponse(fmt.Sprintf("group '%s' cannot be a member, as it would create a cycle", memberID)),
+				logical.ErrInvalidRequest
+		}
+	}
+	return nil, nil
+}
+
+// groupReachable checks if the target group is the given group or one
+// of the groups it is a member of, directly or indirectly.
+func (i *IdentityStore) groupReachable(groupID, target string) (bool, error) {
+	pending := []string{groupID}
+	visited := make(map[string]bool)
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if current == target {
+			return true, nil
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		parents, err := i.view.List(groupParentsPrefix + current + "/")
+		if err != nil {
+			return false, err
+		}
+		pending = append(pending, parents...)
+	}
+	return false, nil
+}
+
+// groupResponse formats a group along with its alias
+func (i *IdentityStore) groupResponse(group *Group) (*logical.Response, error) {
+	if group == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"id":                group.ID,
+			"name":              group.Name,
+			"type":              group.Type,
+			"policies":          group.Policies,
+			"metadata":          group.Metadata,
+			"member_entity_ids": group.MemberEntityIDs,
+			"member_group_ids":  group.MemberGroupIDs,
+			"creation_time":     group.CreationTime,
+			"last_update_time":  group.LastUpdateTime,
+		},
+	}
+
+	if group.AliasID != "" {
+		groupAlias, err := i.groupAlias(group.AliasID)
+		if err != nil {
+			return nil, err
+		}
+		if groupAlias != nil {
+			resp.Data["alias"] = i.groupAliasData(groupAlias)
+		}
+	}
+	return resp, nil
+}
+
+// groupAliasData returns the fields of a group alias shown when reading it
+func (i *IdentityStore) groupAliasData(groupAlias *GroupAlias) map[string]interface{} {
+	mountPath, mountType := i.core.credentialMountByUUID(groupAlias.MountUUID)
+	return map[string]interface{}{
+		"id":               groupAlias.ID,
+		"canonical_id":     groupAlias.GroupID,
+		"name":             groupAlias.Name,
+		"mount_path":       mountPath,
+		"mount_type":       mountType,
+		"creation_time":    groupAlias.CreationTime,
+		"last_update_time": groupAlias.LastUpdateTime,
+	}
+}
+
+// group returns the group with the given ID, or nil if not found
+func (i *IdentityStore) group(id string) (*Group, error) {
+	if id == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(groupPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var group Group
+	if err := raw.DecodeJSON(&group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// groupByName returns the group with the given name, or nil if not found
+func (i *IdentityStore) groupByName(name string) (*Group, error) {
+	if name == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(groupNamePrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return i.group(string(raw.Value))
+}
+
+// putGroup persists a group along with its name and membership indexes.
+// The previous version of the group, if any, is used to remove the
+// indexes that no longer apply.
+func (i *IdentityStore) putGroup(group, old *Group) error {
+	entry, err := logical.StorageEntryJSON(groupPrefix+group.ID, group)
+	if err != nil {
+		return err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return err
+	}
+
+	var oldEntityIDs, oldGroupIDs []string
+	if old != nil {
+		oldEntityIDs, oldGroupIDs = old.MemberEntityIDs, old.MemberGroupIDs
+		if old.Name != group.Name {
+			if err := i.view.Delete(groupNamePrefix + old.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := i.view.Put(&logical.StorageEntry{
+		Key:   groupNamePrefix + group.Name,
+		Value: []byte(group.ID),
+	}); err != nil {
+		return err
+	}
+
+	// Update the membership indexes
+	for _, entityID := range oldEntityIDs {
+		if !strListContains(group.MemberEntityIDs, entityID) {
+			if err := i.view.Delete(entityGroupsPrefix + entityID + "/" + group.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entityID := range group.MemberEntityIDs {
+		if err := i.view.Put(&logical.StorageEntry{
+			Key: entityGroupsPrefix + entityID + "/" + group.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, memberID := range oldGroupIDs {
+		if !strListContains(group.MemberGroupIDs, memberID) {
+			if err := i.view.Delete(groupParentsPrefix + memberID + "/" + group.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, memberID := range group.MemberGroupIDs {
+		if err := i.view.Put(&logical.StorageEntry{
+			Key: groupParentsPrefix + memberID + "/" + group.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeEntityFromGroups removes an entity from all groups it is a
+// direct member of. It is invoked when the entity is deleted.
+func (i *IdentityStore) removeEntityFromGroups(entityID string) error {
+	groupIDs, err := i.view.List(entityGroupsPrefix + entityID + "/")
+	if err != nil {
+		return err
+	}
+	for _, groupID := range groupIDs {
+		group, err := i.group(groupID)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			if err := i.view.Delete(entityGroupsPrefix + entityID + "/" + groupID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		updated := *group
+		updated.MemberEntityIDs = strListRemove(group.MemberEntityIDs, entityID)
+		updated.LastUpdateTime = time.Now().UTC()
+		if err := i.putGroup(&updated, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupAlias returns the group alias with the given ID, or nil if not found
+func (i *IdentityStore) groupAlias(id string) (*GroupAlias, error) {
+	if id == "" {
+		return nil, nil
+	}
+	raw, err := i.view.Get(groupAliasPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var groupAlias GroupAlias
+	if err := raw.DecodeJSON(&groupAlias); err != nil {
+		return nil, err
+	}
+	return &groupAlias, nil
+}
+
+// groupAliasByName returns the group alias of an auth backend with the
+// given name, or nil if not found
+func (i *IdentityStore) groupAliasByName(mountUUID, name string) (*GroupAlias, error) {
+	raw, err := i.view.Get(groupAliasIndexKey(mountUUID, name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return i.groupAlias(string(raw.Value))
+}
+
+// putGroupAlias persists a group alias along with its index
+func (i *IdentityStore) putGroupAlias(groupAlias *GroupAlias) error {
+	entry, err := logical.StorageEntryJSON(groupAliasPrefix+groupAlias.ID, groupAlias)
+	if err != nil {
+		return err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return err
+	}
+	return i.view.Put(&logical.StorageEntry{
+		Key:   groupAliasIndexKey(groupAlias.MountUUID, groupAlias.Name),
+		Value: []byte(groupAlias.ID),
+	})
+}
+
+// deleteGroupAlias removes a group alias. As the membership of the
+// external group was driven by the alias, its members are removed too.
+func (i *IdentityStore) deleteGroupAlias(groupAlias *GroupAlias) error {
+	group, err := i.group(groupAlias.GroupID)
+	if err != nil {
+		return err
+	}
+	if group != nil && group.AliasID == groupAlias.ID {
+		updated := *group
+		updated.AliasID = ""
+		updated.MemberEntityIDs = nil
+		updated.LastUpdateTime = time.Now().UTC()
+		if err := i.putGroup(&updated, group); err != nil {
+			return err
+		}
+	}
+
+	if err := i.view.Delete(groupAliasIndexKey(groupAlias.MountUUID, groupAlias.Name)); err != nil {
+		return err
+	}
+	return i.view.Delete(groupAliasPrefix + groupAlias.ID)
+}
+
+// deleteMountGroupAliases removes all group aliases of an auth backend.
+// The lock must be held.
+func (i *IdentityStore) deleteMountGroupAliases(mountUUID string) error {
+	prefix := groupAliasIndexPrefix + mountUUID + "/"
+	keys, err := i.view.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		raw, err := i.view.Get(prefix + key)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+		groupAlias, err := i.groupAlias(string(raw.Value))
+		if err != nil {
+			return err
+		}
+		if groupAlias == nil {
+			if err := i.view.Delete(prefix + key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := i.deleteGroupAlias(groupAlias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupAliasIndexKey returns the storage key indexing a group alias by
+// the auth backend and name.
+func groupAliasIndexKey(mountUUID, name string) string {
+	return groupAliasIndexPrefix + strings.TrimPrefix(aliasIndexKey(mountUUID, name), aliasIndexPrefix)
+}
+
+const identityGroupHelp = `
+Create a group. Internal groups have explicitly managed member entities
+and groups. The members of external groups are the entities that logged
+in through an auth backend reporting the name of the group alias.
+`
+
+const identityGroupListHelp = `
+List the IDs of all groups.
+`
+
+const identityGroupIDHelp = `
+Read, update or delete a group by ID. The members of a group, including
+those of its member groups, are granted the policies of the group.
+`
+
+const identityGroupNameHelp = `
+Read a group by name.
+`
+
+const identityGroupAliasHelp = `
+Create an alias mapping a group name reported by an auth backend, such
+as an LDAP group or a GitHub team, to an external group.
+`
+
+const identityGroupAliasListHelp = `
+List the IDs of all group aliases.
+`
+
+const identityGroupAliasIDHelp = `
+Read, update or delete a group alias by ID. Deleting the alias removes
+all members of its external group.
+`
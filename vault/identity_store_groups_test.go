@@ -0,0 +1,203 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testIdentityWrite performs a write to the identity store as root
+func testIdentityWrite(t *testing.T, c *Core, root, path string, data map[string]interface{}) (*logical.Response, error) {
+	req := logical.TestRequest(t, logical.WriteOperation, "identity/"+path)
+	req.ClientToken = root
+	req.Data = data
+	return c.HandleRequest(req)
+}
+
+func TestIdentityStore_GroupPolicies(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+	auth := login()
+	token, entityID := auth.ClientToken, auth.EntityID
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/secretread")
+	req.ClientToken = root
+	req.Data["rules"] = `path "secret/*" { policy = "read" }`
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	read := func() error {
+		req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = token
+		_, err := c.HandleRequest(req)
+		return err
+	}
+	if err := read(); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The policy is granted through a parent of the group of the entity
+	resp, err := testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"name":              "eng",
+		"member_entity_ids": entityID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	childID := resp.Data["id"].(string)
+
+	resp, err = testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"name":             "all",
+		"policies":         "secretread",
+		"member_group_ids": childID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	parentID := resp.Data["id"].(string)
+
+	if err := read(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The entity lists its direct groups
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity/id/"+entityID)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["group_ids"], []string{childID}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Membership cannot be cyclic
+	for _, members := range []string{childID, parentID} {
+		if _, err := testIdentityWrite(t, c, root, "group/id/"+childID, map[string]interface{}{
+			"member_group_ids": members,
+		}); err != logical.ErrInvalidRequest {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Members must exist
+	if _, err := testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"member_entity_ids": "nope",
+	}); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Deleting the child group removes it from the parent
+	req = logical.TestRequest(t, logical.DeleteOperation, "identity/group/id/"+childID)
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := read(); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/group/name/all")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if members := resp.Data["member_group_ids"].([]string); len(members) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestIdentityStore_ExternalGroups(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	auth := &logical.Auth{
+		Policies:     []string{"foo"},
+		Alias:        &logical.Alias{Name: "armon"},
+		GroupAliases: []*logical.Alias{{Name: "admins"}, {Name: "unknown"}},
+	}
+	login := testIdentityLoginAuth(t, c, root, auth)
+
+	resp, err := testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"name":     "admins",
+		"type":     "external",
+		"policies": "admin",
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	groupID := resp.Data["id"].(string)
+
+	// External groups cannot have members set explicitly
+	if _, err := testIdentityWrite(t, c, root, "group/id/"+groupID, map[string]interface{}{
+		"member_entity_ids": "foo",
+	}); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err = testIdentityWrite(t, c, root, "group-alias", map[string]interface{}{
+		"name":         "admins",
+		"mount_path":   "auth/foo/",
+		"canonical_id": groupID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	aliasID := resp.Data["id"].(string)
+
+	members := func() []string {
+		req := logical.TestRequest(t, logical.ReadOperation, "identity/group/id/"+groupID)
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp.Data["member_entity_ids"].([]string)
+	}
+
+	// Logging in with the group reported joins the external group
+	entityID := login().EntityID
+	if m := members(); !reflect.DeepEqual(m, []string{entityID}) {
+		t.Fatalf("bad: %#v", m)
+	}
+	policies, err := c.identityStore.EntityPolicies(entityID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(policies, []string{"admin"}) {
+		t.Fatalf("bad: %#v", policies)
+	}
+
+	// Logging in again does not duplicate the membership, and updating
+	// the group keeps its members
+	if _, err := testIdentityWrite(t, c, root, "group/id/"+groupID, map[string]interface{}{
+		"policies": "admin,ops",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	login()
+	if m := members(); !reflect.DeepEqual(m, []string{entityID}) {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	// Logging in without the group leaves the external group
+	auth.GroupAliases = nil
+	login()
+	if m := members(); len(m) != 0 {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	// Disabling the backend deletes the group alias
+	if err := c.disableCredential("foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req := logical.TestRequest(t, logical.ReadOperation, "identity/group-alias/id/"+aliasID)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -0,0 +1,1308 @@
+package vault
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// oidcConfigPath is the storage path of the provider configuration
+	oidcConfigPath = "oidc/config"
+
+	// oidcKeyPrefix is the storage prefix of the named signing keys
+	oidcKeyPrefix = "oidc/key/"
+
+	// oidcRolePrefix is the storage prefix of the roles tokens are
+	// issued for
+	oidcRolePrefix = "oidc/role/"
+
+	// oidcIssuerPath is appended to the base address to form the issuer.
+	// The discovery document is served relative to it.
+	oidcIssuerPath = "/v1/identity/oidc"
+
+	// The signing algorithms supported by the named keys
+	oidcAlgorithmRS256 = "RS256"
+	oidcAlgorithmES256 = "ES256"
+)
+
+// oidcReservedClaims are set by the provider and cannot be overridden
+// by a template.
+var oidcReservedClaims = []string{"iss", "sub", "aud", "exp", "iat", "nbf"}
+
+// oidcTemplateRegex matches the placeholders of a claim template, such
+// as {{identity.entity.name}}.
+var oidcTemplateRegex = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// oidcConfig is the configuration of the identity token provider
+type oidcConfig struct {
+	Issuer string `json:"issuer"`
+}
+
+// oidcNamedKey is a signing key that is rotated periodically. Retired
+// keys stay in the key ring, and are published, until tokens signed by
+// them have expired.
+type oidcNamedKey struct {
+	Name            string           `json:"name"`
+	Algorithm       string           `json:"algorithm"`
+	RotationPeriod  time.Duration    `json:"rotation_period"`
+	VerificationTTL time.Duration    `json:"verification_ttl"`
+	NextRotation    time.Time        `json:"next_rotation"`
+	SigningKeyID    string           `json:"signing_key_id"`
+	SigningKey      []byte           `json:"signing_key"`
+	KeyRing         []*oidcPublicKey `json:"key_ring"`
+}
+
+// oidcPublicKey is the public part of a signing key in the key ring
+type oidcPublicKey struct {
+	KeyID     string    `json:"key_id"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey []byte    `json:"public_key"`
+	ExpireAt  time.Time `json:"expire_at"`
+}
+
+// oidcRole determines the key and claims of the tokens issued for it
+type oidcRole struct {
+	Name     string        `json:"name"`
+	Key      string        `json:"key"`
+	Template string        `json:"template"`
+	TTL      time.Duration `json:"ttl"`
+	ClientID string        `json:"client_id"`
+}
+
+// oidcPaths returns the paths of the identity store issuing identity
+// tokens
+func oidcPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "oidc/config$",
+			Fields: map[string]*framework.FieldSchema{
+				"issuer": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Base address of the issuer, such as https://vault.example.com:8200. Defaults to the advertise address.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:  i.handleOIDCConfigRead,
+				logical.WriteOperation: i.handleOIDCConfigWrite,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCConfigHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCConfigHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/key/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.handleOIDCKeyList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCKeyListHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCKeyListHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/key/(?P<name>" + roleNameRegex + ")/rotate$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the key.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.WriteOperation: i.handleOIDCKeyRotate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCKeyRotateHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCKeyRotateHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/key/(?P<name>" + roleNameRegex + ")$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the key.",
+				},
+				"algorithm": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     oidcAlgorithmRS256,
+					Description: `Signing algorithm of the key, "RS256" or "ES256".`,
+				},
+				"rotation_period": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     86400,
+					Description: "How often the key is rotated.",
+				},
+				"verification_ttl": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     86400,
+					Description: "How long a rotated key is kept to verify the tokens it signed.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.handleOIDCKeyRead,
+				logical.WriteOperation:  i.handleOIDCKeyWrite,
+				logical.DeleteOperation: i.handleOIDCKeyDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCKeyHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCKeyHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/role/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.handleOIDCRoleList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCRoleListHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCRoleListHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/role/(?P<name>" + roleNameRegex + ")$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the key signing the tokens of the role.",
+				},
+				"template": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "JSON object of additional claims, which may contain placeholders such as {{identity.entity.name}}.",
+				},
+				"ttl": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     86400,
+					Description: "TTL of the tokens of the role.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.handleOIDCRoleRead,
+				logical.WriteOperation:  i.handleOIDCRoleWrite,
+				logical.DeleteOperation: i.handleOIDCRoleDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCRoleHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCRoleHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/token/(?P<name>" + roleNameRegex + ")$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.handleOIDCToken,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCTokenHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCTokenHelp),
+		},
+
+		&framework.Path{
+			Pattern: "oidc/introspect$",
+			Fields: map[string]*framework.FieldSchema{
+				"token": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Identity token to verify.",
+				},
+				"client_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "If given, the audience the token must have.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.WriteOperation: i.handleOIDCIntrospect,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(identityOIDCIntrospectHelp),
+			HelpDescription: strings.TrimSpace(identityOIDCIntrospectHelp),
+		},
+	}
+}
+
+// OIDCDiscovery returns the OpenID Connect discovery document of the
+// identity token provider. It does not require a client token.
+func (c *Core) OIDCDiscovery() (map[string]interface{}, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, ErrSealed
+	}
+	if c.standby {
+		return nil, ErrStandby
+	}
+
+	issuer, err := c.identityStore.oidcIssuer()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"jwks_uri":                              issuer + "/.well-known/keys",
+		"response_types_supported":              []string{"id_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{oidcAlgorithmRS256, oidcAlgorithmES256},
+	}, nil
+}
+
+// OIDCKeys returns the JSON web key set holding the public keys that
+// identity tokens can be verified with. It does not require a client
+// token.
+func (c *Core) OIDCKeys() (map[string]interface{}, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, ErrSealed
+	}
+	if c.standby {
+		return nil, ErrStandby
+	}
+
+	keys, err := c.identityStore.oidcPublicKeys()
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to read identity token keys: %v", err)
+		return nil, ErrInternalError
+	}
+	return map[string]interface{}{
+		"keys": keys,
+	}, nil
+}
+
+// oidcIssuer returns the issuer of identity tokens
+func (i *IdentityStore) oidcIssuer() (string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	config, err := i.oidcConfig()
+	if err != nil {
+		return "", err
+	}
+	base := config.Issuer
+	if base == "" {
+		base = i.core.advertiseAddr
+	}
+	return strings.TrimSuffix(base, "/") + oidcIssuerPath, nil
+}
+
+// oidcPublicKeys returns the JSON web keys of all named keys that have
+// not expired. The keys are served to unauthenticated clients, so they are
+// never rotated here: rotation happens when tokens are signed and on the
+// periodic rollback of the identity store.
+func (i *IdentityStore) oidcPublicKeys() ([]map[string]interface{}, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	names, err := i.view.List(oidcKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	keys := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		key, err := i.oidcKey(name)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			continue
+		}
+		for _, pub := range key.KeyRing {
+			if pub.expired(now) {
+				continue
+			}
+			jwk, err := pub.jwk()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, jwk)
+		}
+	}
+	return keys, nil
+}
+
+// rotateOIDCKeys rotates the named keys that are due and drops their
+// expired keys. The write lock is only held for one key at a time.
+func (i *IdentityStore) rotateOIDCKeys() error {
+	names, err := i.view.List(oidcKeyPrefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		i.lock.Lock()
+		_, err := i.oidcKeyRotated(name)
+		i.lock.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to rotate key '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (i *IdentityStore) handleOIDCConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	config, err := i.oidcConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer": config.Issuer,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleOIDCConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer := data.Get("issuer").(string)
+	if issuer != "" {
+		u, err := url.Parse(issuer)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return logical.ErrorResponse("issuer must be an address including scheme and host"),
+				logical.ErrInvalidRequest
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entry, err := logical.StorageEntryJSON(oidcConfigPath, &oidcConfig{Issuer: issuer})
+	if err != nil {
+		return nil, err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCKeyList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	keys, err := i.view.List(oidcKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) handleOIDCKeyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	key, err := i.oidcKey(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"algorithm":        key.Algorithm,
+			"rotation_period":  int64(key.RotationPeriod.Seconds()),
+			"verification_ttl": int64(key.VerificationTTL.Seconds()),
+			"next_rotation":    key.NextRotation,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleOIDCKeyWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, err := i.oidcKey(name)
+	if err != nil {
+		return nil, err
+	}
+	created := key == nil
+	if created {
+		key = &oidcNamedKey{Name: name}
+	}
+
+	// Only update the fields that are given
+	algorithm := key.Algorithm
+	if _, ok := data.GetOk("algorithm"); ok || created {
+		algorithm = data.Get("algorithm").(string)
+	}
+	switch algorithm {
+	case oidcAlgorithmRS256, oidcAlgorithmES256:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm '%s'", algorithm)),
+			logical.ErrInvalidRequest
+	}
+	_, rotationPeriodOk := data.GetOk("rotation_period")
+	if rotationPeriodOk || created {
+		key.RotationPeriod = time.Duration(data.Get("rotation_period").(int)) * time.Second
+	}
+	if _, ok := data.GetOk("verification_ttl"); ok || created {
+		key.VerificationTTL = time.Duration(data.Get("verification_ttl").(int)) * time.Second
+	}
+	if key.RotationPeriod <= 0 || key.VerificationTTL <= 0 {
+		return logical.ErrorResponse("rotation_period and verification_ttl must be positive"),
+			logical.ErrInvalidRequest
+	}
+
+	// Tokens must remain verifiable for their entire lifetime
+	roles, err := i.oidcRolesUsingKey(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.TTL > key.VerificationTTL {
+			msg := fmt.Sprintf("verification_ttl cannot be shorter than the ttl of role '%s'", role.Name)
+			return logical.ErrorResponse(msg), logical.ErrInvalidRequest
+		}
+	}
+
+	// A new algorithm takes effect with a new signing key
+	now := time.Now().UTC()
+	if created || algorithm != key.Algorithm {
+		key.Algorithm = algorithm
+		if err := key.rotate(now); err != nil {
+			return nil, err
+		}
+	} else if rotationPeriodOk {
+		key.NextRotation = now.Add(key.RotationPeriod)
+	}
+
+	if err := i.putOIDCKey(key); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCKeyDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	roles, err := i.oidcRolesUsingKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) > 0 {
+		msg := fmt.Sprintf("the key is used by role '%s'", roles[0].Name)
+		return logical.ErrorResponse(msg), logical.ErrInvalidRequest
+	}
+
+	if err := i.view.Delete(oidcKeyPrefix + name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCKeyRotate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, err := i.oidcKey(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+
+	if err := key.rotate(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := i.putOIDCKey(key); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCRoleList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	roles, err := i.view.List(oidcRolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (i *IdentityStore) handleOIDCRoleRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	role, err := i.oidcRole(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"key":       role.Key,
+			"template":  role.Template,
+			"ttl":       int64(role.TTL.Seconds()),
+			"client_id": role.ClientID,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleOIDCRoleWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	role, err := i.oidcRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &oidcRole{
+			Name:     name,
+			TTL:      time.Duration(data.Get("ttl").(int)) * time.Second,
+			ClientID: uuid.GenerateUUID(),
+		}
+	}
+
+	// Only update the fields that are given
+	if raw, ok := data.GetOk("key"); ok {
+		role.Key = raw.(string)
+	}
+	if raw, ok := data.GetOk("template"); ok {
+		role.Template = raw.(string)
+	}
+	if raw, ok := data.GetOk("ttl"); ok {
+		role.TTL = time.Duration(raw.(int)) * time.Second
+	}
+
+	if role.Key == "" {
+		return logical.ErrorResponse("missing key"), logical.ErrInvalidRequest
+	}
+	key, err := i.oidcKey(role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key '%s' not found", role.Key)),
+			logical.ErrInvalidRequest
+	}
+	if role.TTL <= 0 {
+		return logical.ErrorResponse("ttl must be positive"), logical.ErrInvalidRequest
+	}
+	if role.TTL > key.VerificationTTL {
+		return logical.ErrorResponse("ttl cannot be longer than the verification_ttl of the key"),
+			logical.ErrInvalidRequest
+	}
+	if err := validateOIDCTemplate(role.Template); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON(oidcRolePrefix+role.Name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCRoleDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if err := i.view.Delete(oidcRolePrefix + data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) handleOIDCToken(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("the token does not belong to an entity"),
+			logical.ErrInvalidRequest
+	}
+
+	issuer, err := i.oidcIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	role, err := i.oidcRole(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role not found"), logical.ErrInvalidRequest
+	}
+	key, err := i.oidcKeyRotated(role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key '%s' not found", role.Key)),
+			logical.ErrInvalidRequest
+	}
+	entity, err := i.entity(req.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("the entity of the token was not found"),
+			logical.ErrInvalidRequest
+	}
+	groups, err := i.entityGroups(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render the template first so that the reserved claims win
+	claims := make(map[string]interface{})
+	if role.Template != "" {
+		var template map[string]interface{}
+		if err := json.Unmarshal([]byte(role.Template), &template); err != nil {
+			return nil, err
+		}
+		for k, v := range template {
+			claims[k] = renderOIDCTemplate(v, entity, groups)
+		}
+	}
+
+	now := time.Now().UTC()
+	claims["iss"] = issuer
+	claims["sub"] = entity.ID
+	claims["aud"] = role.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(role.TTL).Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	token, err := key.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":     token,
+			"client_id": role.ClientID,
+			"ttl":       int64(role.TTL.Seconds()),
+		},
+	}, nil
+}
+
+func (i *IdentityStore) handleOIDCIntrospect(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	token := data.Get("token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token"), logical.ErrInvalidRequest
+	}
+
+	issuer, err := i.oidcIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	inactive := func(reason string) (*logical.Response, error) {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"active": false,
+				"error":  reason,
+			},
+		}, nil
+	}
+
+	claims, reason, err := i.verifyOIDCToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return inactive(reason)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != issuer {
+		return inactive("the token was issued by a different issuer")
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Unix() >= int64(exp) {
+		return inactive("the token has expired")
+	}
+	if clientID := data.Get("client_id").(string); clientID != "" {
+		if aud, _ := claims["aud"].(string); aud != clientID {
+			return inactive("the token was issued for a different audience")
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"active": true,
+		},
+	}, nil
+}
+
+// verifyOIDCToken checks the signature of a token against the key rings
+// of all named keys and returns its claims. If the token is invalid, the
+// reason is returned instead.
+func (i *IdentityStore) verifyOIDCToken(token string) (map[string]interface{}, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "malformed token", nil
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	rawHeader, err := jwtDecode(parts[0])
+	if err != nil || json.Unmarshal(rawHeader, &header) != nil {
+		return nil, "malformed token header", nil
+	}
+	sig, err := jwtDecode(parts[2])
+	if err != nil {
+		return nil, "malformed token signature", nil
+	}
+
+	// Find the public key the token was signed with
+	names, err := i.view.List(oidcKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	var pub *oidcPublicKey
+	for _, name := range names {
+		key, err := i.oidcKey(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if key == nil {
+			continue
+		}
+		for _, candidate := range key.KeyRing {
+			if candidate.KeyID == header.KeyID && !candidate.expired(time.Now()) {
+				pub = candidate
+			}
+		}
+	}
+	if pub == nil || pub.Algorithm != header.Algorithm {
+		return nil, "the token was not signed by a known key", nil
+	}
+	if !pub.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, "invalid token signature", nil
+	}
+
+	var claims map[string]interface{}
+	rawClaims, err := jwtDecode(parts[1])
+	if err != nil || json.Unmarshal(rawClaims, &claims) != nil {
+		return nil, "malformed token claims", nil
+	}
+	return claims, "", nil
+}
+
+// oidcConfig returns the provider configuration. The lock must be held.
+func (i *IdentityStore) oidcConfig() (*oidcConfig, error) {
+	var config oidcConfig
+	raw, err := i.view.Get(oidcConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		if err := raw.DecodeJSON(&config); err != nil {
+			return nil, err
+		}
+	}
+	return &config, nil
+}
+
+// oidcKey returns the named key, or nil if not found
+func (i *IdentityStore) oidcKey(name string) (*oidcNamedKey, error) {
+	raw, err := i.view.Get(oidcKeyPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var key oidcNamedKey
+	if err := raw.DecodeJSON(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// oidcKeyRotated returns the named key, rotating it first if it is due
+// and dropping the retired keys that have expired. The write lock must
+// be held.
+func (i *IdentityStore) oidcKeyRotated(name string) (*oidcNamedKey, error) {
+	key, err := i.oidcKey(name)
+	if err != nil || key == nil {
+		return key, err
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case now.After(key.NextRotation):
+		if err := key.rotate(now); err != nil {
+			return nil, err
+		}
+	case key.prune(now):
+	default:
+		return key, nil
+	}
+	if err := i.putOIDCKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// putOIDCKey persists a named key
+func (i *IdentityStore) putOIDCKey(key *oidcNamedKey) error {
+	entry, err := logical.StorageEntryJSON(oidcKeyPrefix+key.Name, key)
+	if err != nil {
+		return err
+	}
+	return i.view.Put(entry)
+}
+
+// oidcRole returns the named role, or nil if not found
+func (i *IdentityStore) oidcRole(name string) (*oidcRole, error) {
+	raw, err := i.view.Get(oidcRolePrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var role oidcRole
+	if err := raw.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// oidcRolesUsingKey returns the roles signing with the named key
+func (i *IdentityStore) oidcRolesUsingKey(key string) ([]*oidcRole, error) {
+	names, err := i.view.List(oidcRolePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*oidcRole
+	for _, name := range names {
+		role, err := i.oidcRole(name)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.Key == key {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// rotate replaces the signing key with a new one. The previous signing
+// key stays in the key ring until the verification TTL has passed.
+func (k *oidcNamedKey) rotate(now time.Time) error {
+	var signer crypto.Signer
+	var err error
+	switch k.Algorithm {
+	case oidcAlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case oidcAlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm '%s'", k.Algorithm)
+	}
+	if err != nil {
+		return err
+	}
+
+	var private []byte
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		private = x509.MarshalPKCS1PrivateKey(key)
+	case *ecdsa.PrivateKey:
+		private, err = x509.MarshalECPrivateKey(key)
+	}
+	if err != nil {
+		return err
+	}
+	public, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	for _, pub := range k.KeyRing {
+		if pub.KeyID == k.SigningKeyID {
+			pub.ExpireAt = now.Add(k.VerificationTTL)
+		}
+	}
+	k.SigningKeyID = uuid.GenerateUUID()
+	k.SigningKey = private
+	k.KeyRing = append(k.KeyRing, &oidcPublicKey{
+		KeyID:     k.SigningKeyID,
+		Algorithm: k.Algorithm,
+		PublicKey: public,
+	})
+	k.NextRotation = now.Add(k.RotationPeriod)
+	k.prune(now)
+	return nil
+}
+
+// prune drops the retired keys that have expired from the key ring,
+// returning whether any were dropped.
+func (k *oidcNamedKey) prune(now time.Time) bool {
+	var keyRing []*oidcPublicKey
+	for _, pub := range k.KeyRing {
+		if !pub.expired(now) {
+			keyRing = append(keyRing, pub)
+		}
+	}
+	pruned := len(keyRing) != len(k.KeyRing)
+	k.KeyRing = keyRing
+	return pruned
+}
+
+// sign returns a JSON web token with the given claims, signed with the
+// current signing key
+func (k *oidcNamedKey) sign(claims []byte) (string, error) {
+	parsed, err := parseOIDCSigningKey(k.SigningKey)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": k.Algorithm,
+		"kid": k.SigningKeyID,
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	input := jwtEncode(header) + "." + jwtEncode(claims)
+	hash := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, hash[:])
+		if err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, private, hash[:])
+		if err != nil {
+			return "", err
+		}
+		// The signature is the concatenation of the fixed size r and s
+		sig = append(paddedBytes(r, 32), paddedBytes(s, 32)...)
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", parsed)
+	}
+
+	return input + "." + jwtEncode(sig), nil
+}
+
+// expired checks if a retired key is past its verification TTL
+func (p *oidcPublicKey) expired(now time.Time) bool {
+	return !p.ExpireAt.IsZero() && !p.ExpireAt.After(now)
+}
+
+// verify checks the signature of the signed part of a token
+func (p *oidcPublicKey) verify(input, sig []byte) bool {
+	parsed, err := x509.ParsePKIXPublicKey(p.PublicKey)
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256(input)
+
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, hash[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(public, hash[:], r, s)
+	default:
+		return false
+	}
+}
+
+// jwk returns the key in the JSON web key format
+func (p *oidcPublicKey) jwk() (map[string]interface{}, error) {
+	parsed, err := x509.ParsePKIXPublicKey(p.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := map[string]interface{}{
+		"kid": p.KeyID,
+		"alg": p.Algorithm,
+		"use": "sig",
+	}
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = jwtEncode(public.N.Bytes())
+		jwk["e"] = jwtEncode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk["kty"] = "EC"
+		jwk["crv"] = "P-256"
+		jwk["x"] = jwtEncode(paddedBytes(public.X, 32))
+		jwk["y"] = jwtEncode(paddedBytes(public.Y, 32))
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", parsed)
+	}
+	return jwk, nil
+}
+
+// parseOIDCSigningKey parses a signing key, which is stored in the PKCS #1
+// form for RSA keys and in the SEC 1 form for EC keys
+func parseOIDCSigningKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+	return key, nil
+}
+
+// paddedBytes returns the big-endian bytes of an integer, zero-padded
+// on the left to the given size
+func paddedBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
+
+// jwtEncode encodes a part of a JSON web token, which is base64url
+// encoded without padding
+func jwtEncode(b []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "=")
+}
+
+// jwtDecode decodes a part of a JSON web token
+func jwtDecode(s string) ([]byte, error) {
+	if n := len(s) % 4; n != 0 {
+		s += strings.Repeat("=", 4-n)
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// validateOIDCTemplate checks that a claim template is a JSON object
+// that does not set reserved claims and only uses known placeholders.
+func validateOIDCTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(template), &claims); err != nil {
+		return fmt.Errorf("template must be a JSON object: %v", err)
+	}
+	for _, reserved := range oidcReservedClaims {
+		if _, ok := claims[reserved]; ok {
+			return fmt.Errorf("template cannot set the reserved claim '%s'", reserved)
+		}
+	}
+
+	var check func(v interface{}) error
+	check = func(v interface{}) error {
+		switch v := v.(type) {
+		case string:
+			for _, m := range oidcTemplateRegex.FindAllStringSubmatch(v, -1) {
+				if _, ok := oidcTemplateValue(m[1], &Entity{}, nil); !ok {
+					return fmt.Errorf("unknown template placeholder '%s'", m[1])
+				}
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, item := range v {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return check(claims)
+}
+
+// renderOIDCTemplate replaces the placeholders of a template value. A
+// string consisting of a single placeholder takes the type of its
+// value, so that lists such as group names remain JSON arrays.
+func renderOIDCTemplate(v interface{}, entity *Entity, groups []*Group) interface{} {
+	switch v := v.(type) {
+	case string:
+		if m := oidcTemplateRegex.FindStringSubmatch(v); m != nil && m[0] == v {
+			value, _ := oidcTemplateValue(m[1], entity, groups)
+			return value
+		}
+		return oidcTemplateRegex.ReplaceAllStringFunc(v, func(s string) string {
+			name := oidcTemplateRegex.FindStringSubmatch(s)[1]
+			switch value, _ := oidcTemplateValue(name, entity, groups); value := value.(type) {
+			case []string:
+				return strings.Join(value, ",")
+			default:
+				return fmt.Sprintf("%v", value)
+			}
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = renderOIDCTemplate(item, entity, groups)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for idx, item := range v {
+			out[idx] = renderOIDCTemplate(item, entity, groups)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// oidcTemplateValue returns the value of a template placeholder for an
+// entity and the groups it is a member of
+func oidcTemplateValue(name string, entity *Entity, groups []*Group) (interface{}, bool) {
+	switch name {
+	case "identity.entity.id":
+		return entity.ID, true
+	case "identity.entity.name":
+		return entity.Name, true
+	case "identity.entity.groups.ids":
+		ids := make([]string, 0, len(groups))
+		for _, group := range groups {
+			ids = append(ids, group.ID)
+		}
+		return ids, true
+	case "identity.entity.groups.names":
+		names := make([]string, 0, len(groups))
+		for _, group := range groups {
+			names = append(names, group.Name)
+		}
+		return names, true
+	}
+
+	const metadataPrefix = "identity.entity.metadata."
+	if strings.HasPrefix(name, metadataPrefix) && len(name) > len(metadataPrefix) {
+		return entity.Metadata[strings.TrimPrefix(name, metadataPrefix)], true
+	}
+	return nil, false
+}
+
+const identityOIDCConfigHelp = `
+Read or write the configuration of the identity token provider. The
+issuer of the tokens is the given base address followed by
+/v1/identity/oidc.
+`
+
+const identityOIDCKeyListHelp = `
+List the named keys signing identity tokens.
+`
+
+const identityOIDCKeyHelp = `
+Read, write or delete a named key signing identity tokens. The key is
+rotated every rotation_period, and rotated keys are published for
+verification_ttl so that the tokens they signed can still be verified.
+A key cannot be deleted while a role uses it.
+`
+
+const identityOIDCKeyRotateHelp = `
+Rotate a named key immediately.
+`
+
+const identityOIDCRoleListHelp = `
+List the roles identity tokens are issued for.
+`
+
+const identityOIDCRoleHelp = `
+Read, write or delete a role identity tokens are issued for. The role
+determines the signing key, the TTL and the additional claims of the
+tokens. Its generated client_id is the audience of the tokens.
+
+The template is a JSON object of additional claims. String values may
+contain the placeholders {{identity.entity.id}}, {{identity.entity.name}},
+{{identity.entity.metadata.<key>}}, {{identity.entity.groups.ids}} and
+{{identity.entity.groups.names}}.
+`
+
+const identityOIDCTokenHelp = `
+Generate a signed identity token of the role for the entity of the
+client token.
+`
+
+const identityOIDCIntrospectHelp = `
+Verify the signature, issuer and expiration of an identity token, and
+optionally its audience.
+`
@@ -0,0 +1,259 @@
+package vault
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testOIDCClaims decodes the claims of an identity token without
+// verifying it
+func testOIDCClaims(t *testing.T, token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("bad: %s", token)
+	}
+	raw, err := jwtDecode(parts[1])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return claims
+}
+
+func TestIdentityStore_OIDCToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+	auth := login()
+	token, entityID := auth.ClientToken, auth.EntityID
+
+	if _, err := testIdentityWrite(t, c, root, "entity/id/"+entityID, map[string]interface{}{
+		"metadata": map[string]interface{}{"team": "core"},
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err := testIdentityWrite(t, c, root, "group", map[string]interface{}{
+		"name":              "eng",
+		"member_entity_ids": entityID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	for _, algorithm := range []string{"RS256", "ES256"} {
+		if _, err := testIdentityWrite(t, c, root, "oidc/key/"+algorithm, map[string]interface{}{
+			"algorithm": algorithm,
+		}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		resp, err := testIdentityWrite(t, c, root, "oidc/role/"+algorithm, map[string]interface{}{
+			"key":      algorithm,
+			"ttl":      "1h",
+			"template": `{"team": "{{identity.entity.metadata.team}}", "groups": "{{identity.entity.groups.names}}", "who": "user {{identity.entity.name}}"}`,
+		})
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+
+		// Let the policy of the login read tokens of the role
+		req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/foo")
+		req.ClientToken = root
+		req.Data["rules"] = `path "identity/oidc/token/*" { policy = "read" }`
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		req = logical.TestRequest(t, logical.ReadOperation, "identity/oidc/token/"+algorithm)
+		req.ClientToken = token
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		jwt := resp.Data["token"].(string)
+		clientID := resp.Data["client_id"].(string)
+
+		claims := testOIDCClaims(t, jwt)
+		if claims["sub"] != entityID || claims["aud"] != clientID ||
+			claims["iss"] != "/v1/identity/oidc" || claims["team"] != "core" ||
+			claims["who"] != "user entity_"+entityID[:8] ||
+			!reflect.DeepEqual(claims["groups"], []interface{}{"eng"}) {
+			t.Fatalf("bad: %#v", claims)
+		}
+		if exp, iat := claims["exp"].(float64), claims["iat"].(float64); exp-iat != 3600 {
+			t.Fatalf("bad: %#v", claims)
+		}
+
+		introspect := func(data map[string]interface{}) bool {
+			resp, err := testIdentityWrite(t, c, root, "oidc/introspect", data)
+			if err != nil {
+				t.Fatalf("err: %v %v", err, resp)
+			}
+			return resp.Data["active"].(bool)
+		}
+		if !introspect(map[string]interface{}{"token": jwt, "client_id": clientID}) {
+			t.Fatalf("expected active token")
+		}
+		if introspect(map[string]interface{}{"token": jwt, "client_id": "other"}) {
+			t.Fatalf("expected inactive token")
+		}
+		if introspect(map[string]interface{}{"token": jwt[:len(jwt)-4] + "AAAA"}) {
+			t.Fatalf("expected inactive token")
+		}
+
+		// Tokens signed before a rotation remain valid
+		if _, err := testIdentityWrite(t, c, root, "oidc/key/"+algorithm+"/rotate", nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !introspect(map[string]interface{}{"token": jwt}) {
+			t.Fatalf("expected active token")
+		}
+	}
+
+	// Both keys have been rotated once, publishing two public keys each
+	jwks, err := c.OIDCKeys()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := jwks["keys"].([]map[string]interface{}); len(keys) != 4 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Keys in use cannot be deleted
+	req := logical.TestRequest(t, logical.DeleteOperation, "identity/oidc/key/RS256")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Tokens without an entity cannot get identity tokens
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/oidc/token/RS256")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestIdentityStore_OIDCKeyRotation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	if _, err := testIdentityWrite(t, c, root, "oidc/key/foo", map[string]interface{}{
+		"algorithm": "ES256",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Make the key due for rotation
+	i := c.identityStore
+	key, err := i.oidcKey("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	signingKeyID := key.SigningKeyID
+	key.NextRotation = time.Now().UTC().Add(-time.Minute)
+	if err := i.putOIDCKey(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Serving the public keys does not rotate them
+	jwks, err := c.OIDCKeys()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := jwks["keys"].([]map[string]interface{}); len(keys) != 1 {
+		t.Fatalf("bad: %#v", keys)
+	}
+	key, err = i.oidcKey("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key.SigningKeyID != signingKeyID {
+		t.Fatalf("bad: %#v", key)
+	}
+
+	// The periodic rollback does
+	if err := c.rollback.Rollback("identity/"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err = i.oidcKey("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key.SigningKeyID == signingKeyID || !key.NextRotation.After(time.Now()) {
+		t.Fatalf("bad: %#v", key)
+	}
+}
+
+func TestIdentityStore_OIDCRoleValidation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	if _, err := testIdentityWrite(t, c, root, "oidc/key/foo", map[string]interface{}{
+		"algorithm": "HS256",
+	}); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testIdentityWrite(t, c, root, "oidc/key/foo", map[string]interface{}{
+		"verification_ttl": "1h",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, data := range []map[string]interface{}{
+		// Unknown key
+		{"key": "bar"},
+		// Tokens must be verifiable for their lifetime
+		{"key": "foo", "ttl": "2h"},
+		// Reserved claims
+		{"key": "foo", "ttl": "1h", "template": `{"sub": "foo"}`},
+		// Unknown placeholders
+		{"key": "foo", "ttl": "1h", "template": `{"a": "{{identity.entity.nope}}"}`},
+		// Not a JSON object
+		{"key": "foo", "ttl": "1h", "template": `nope`},
+	} {
+		if _, err := testIdentityWrite(t, c, root, "oidc/role/foo", data); err != logical.ErrInvalidRequest {
+			t.Fatalf("err: %v %#v", err, data)
+		}
+	}
+
+	if _, err := testIdentityWrite(t, c, root, "oidc/role/foo", map[string]interface{}{
+		"key": "foo",
+		"ttl": "1h",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The verification TTL cannot drop below the TTL of a role
+	if _, err := testIdentityWrite(t, c, root, "oidc/key/foo", map[string]interface{}{
+		"verification_ttl": "30m",
+	}); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestOIDC_Encoding(t *testing.T) {
+	// Integers are padded to a fixed size
+	if out := paddedBytes(big.NewInt(0x0102), 4); !reflect.DeepEqual(out, []byte{0, 0, 1, 2}) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Parts of tokens are encoded without padding
+	for _, in := range []string{"", "a", "ab", "abc", "abcd"} {
+		encoded := jwtEncode([]byte(in))
+		if strings.Contains(encoded, "=") {
+			t.Fatalf("bad: %s", encoded)
+		}
+		out, err := jwtDecode(encoded)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(out) != in {
+			t.Fatalf("bad: %q", out)
+		}
+	}
+}
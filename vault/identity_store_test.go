@@ -0,0 +1,282 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testIdentityLogin enables a credential backend at auth/foo/ that logs
+// in with the given alias name and returns a function to log in.
+func testIdentityLogin(t *testing.T, c *Core, root, aliasName string) func() *logical.Auth {
+	return testIdentityLoginAuth(t, c, root, &logical.Auth{
+		Policies:    []string{"foo"},
+		DisplayName: aliasName,
+		Alias: &logical.Alias{
+			Name: aliasName,
+		},
+	})
+}
+
+// testIdentityLoginAuth is like testIdentityLogin, but the backend
+// responds with the given auth, which may be changed between logins.
+func testIdentityLoginAuth(t *testing.T, c *Core, root string, auth *logical.Auth) func() *logical.Auth {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: auth,
+		},
+	}
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	return func() *logical.Auth {
+		resp, err := c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+			t.Fatalf("bad: %#v", resp)
+		}
+		return resp.Auth
+	}
+}
+
+func TestIdentityStore_LoginCreatesEntity(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+
+	// Logging in twice maps to the same entity
+	auth1 := login()
+	auth2 := login()
+	if auth1.EntityID == "" || auth1.EntityID != auth2.EntityID {
+		t.Fatalf("bad: %q %q", auth1.EntityID, auth2.EntityID)
+	}
+
+	te, err := c.tokenStore.Lookup(auth1.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.EntityID != auth1.EntityID {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The entity lists the alias of the login
+	req := logical.TestRequest(t, logical.ReadOperation, "identity/entity/id/"+auth1.EntityID)
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	aliases := resp.Data["aliases"].([]map[string]interface{})
+	if len(aliases) != 1 {
+		t.Fatalf("bad: %#v", aliases)
+	}
+	if aliases[0]["name"] != "armon" || aliases[0]["mount_path"] != "auth/foo/" ||
+		aliases[0]["mount_type"] != "noop" {
+		t.Fatalf("bad: %#v", aliases[0])
+	}
+
+	// Child tokens belong to the same entity
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/policy/foo")
+	req.ClientToken = root
+	req.Data["rules"] = `path "auth/token/create" { policy = "write" }`
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = auth1.ClientToken
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	child, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if child.EntityID != auth1.EntityID {
+		t.Fatalf("bad: %#v", child)
+	}
+}
+
+func TestIdentityStore_EntityPolicies(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+	auth := login()
+
+	// Create a policy the token does not have
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/secretread")
+	req.ClientToken = root
+	req.Data["rules"] = `path "secret/*" { policy = "read" }`
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	read := func() error {
+		req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = auth.ClientToken
+		_, err := c.HandleRequest(req)
+		return err
+	}
+	if err := read(); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Grant the policy to the entity, which applies to existing tokens
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/entity/id/"+auth.EntityID)
+	req.ClientToken = root
+	req.Data["policies"] = "secretread"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := read(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Entities cannot be granted root
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/entity/id/"+auth.EntityID)
+	req.ClientToken = root
+	req.Data["policies"] = "root"
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestIdentityStore_EntityCRUD(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+
+	req := logical.TestRequest(t, logical.WriteOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	req.Data["policies"] = "foo, bar"
+	req.Data["metadata"] = map[string]interface{}{"team": "core"}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	id := resp.Data["id"].(string)
+
+	// Names are unique
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity/name/armon")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["id"] != id ||
+		!reflect.DeepEqual(resp.Data["policies"], []string{"foo", "bar"}) ||
+		!reflect.DeepEqual(resp.Data["metadata"], map[string]string{"team": "core"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Map the login to the entity ahead of time
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/entity-alias")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	req.Data["mount_path"] = "auth/foo"
+	req.Data["entity_id"] = id
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	aliasID := resp.Data["id"].(string)
+	if auth := login(); auth.EntityID != id {
+		t.Fatalf("bad: %#v", auth)
+	}
+
+	// Renaming updates the name index
+	req = logical.TestRequest(t, logical.WriteOperation, "identity/entity/id/"+id)
+	req.ClientToken = root
+	req.Data["name"] = "mitchellh"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for name, exists := range map[string]bool{"armon": false, "mitchellh": true} {
+		req = logical.TestRequest(t, logical.ReadOperation, "identity/entity/name/"+name)
+		req.ClientToken = root
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if (resp != nil) != exists {
+			t.Fatalf("bad: %s %#v", name, resp)
+		}
+	}
+
+	// Deleting the entity deletes its aliases
+	req = logical.TestRequest(t, logical.DeleteOperation, "identity/entity/id/"+id)
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity-alias/id/"+aliasID)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// A new login creates a new entity
+	if auth := login(); auth.EntityID == "" || auth.EntityID == id {
+		t.Fatalf("bad: %#v", auth)
+	}
+}
+
+func TestIdentityStore_DisableCredential(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	login := testIdentityLogin(t, c, root, "armon")
+	login()
+
+	req := logical.TestRequest(t, logical.ListOperation, "identity/entity-alias/id/")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	if err := c.disableCredential("foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestIdentityStore_Protected(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	if err := c.unmount("identity"); err == nil {
+		t.Fatalf("expected unmount error")
+	}
+	if err := c.mount(&MountEntry{Path: "foo", Type: "identity"}); err == nil {
+		t.Fatalf("expected mount error")
+	}
+}
@@ -0,0 +1,190 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// cubbyholeMountPath is the path the cubbyhole backend is mounted at
+	cubbyholeMountPath = "cubbyhole/"
+)
+
+// NewCubbyholeBackend returns the backend providing every token with a
+// private storage area that is destroyed when the token is revoked.
+func NewCubbyholeBackend(core *Core) logical.Backend {
+	b := &CubbyholeBackend{
+		core: core,
+	}
+
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(cubbyholeHelp),
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: ".*",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRead,
+					logical.WriteOperation:  b.handleWrite,
+					logical.DeleteOperation: b.handleDelete,
+					logical.ListOperation:   b.handleList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(cubbyholeHelpSynopsis),
+				HelpDescription: strings.TrimSpace(cubbyholeHelpDescription),
+			},
+		},
+	}
+
+	return b
+}
+
+// CubbyholeBackend is used for storing secrets that are only visible to
+// the token that wrote them. The storage is namespaced by the salted
+// client token, so not even a root token can read the cubbyhole of
+// another token.
+type CubbyholeBackend struct {
+	*framework.Backend
+
+	core *Core
+}
+
+// storagePrefix returns the prefix under which the cubbyhole of the
+// client token of the request is stored.
+func (b *CubbyholeBackend) storagePrefix(req *logical.Request) (string, error) {
+	if req.ClientToken == "" {
+		return "", fmt.Errorf("client token empty")
+	}
+	if strings.HasPrefix(req.ClientToken, batchTokenPrefix) {
+		return "", fmt.Errorf("cubbyhole is not available to batch tokens")
+	}
+	return b.core.tokenStore.SaltID(req.ClientToken) + "/", nil
+}
+
+func (b *CubbyholeBackend) handleRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix, err := b.storagePrefix(req)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// Read the path
+	out, err := req.Storage.Get(prefix + req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %v", err)
+	}
+
+	// Fast-path the no data case
+	if out == nil {
+		return nil, nil
+	}
+
+	// Decode the data
+	var rawData map[string]interface{}
+	if err := json.Unmarshal(out.Value, &rawData); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+
+	return &logical.Response{
+		Data: rawData,
+	}, nil
+}
+
+func (b *CubbyholeBackend) handleWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix, err := b.storagePrefix(req)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// Check that some fields are given
+	if len(req.Data) == 0 {
+		return nil, fmt.Errorf("missing data fields")
+	}
+
+	// JSON encode the data
+	buf, err := json.Marshal(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("json encoding failed: %v", err)
+	}
+
+	// Write out a new key
+	entry := &logical.StorageEntry{
+		Key:   prefix + req.Path,
+		Value: buf,
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, fmt.Errorf("failed to write: %v", err)
+	}
+
+	return nil, nil
+}
+
+func (b *CubbyholeBackend) handleDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix, err := b.storagePrefix(req)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// Delete the key at the request path
+	if err := req.Storage.Delete(prefix + req.Path); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *CubbyholeBackend) handleList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix, err := b.storagePrefix(req)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// List the keys at the prefix given by the request
+	keys, err := req.Storage.List(prefix + req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the response
+	return logical.ListResponse(keys), nil
+}
+
+// destroyCubbyhole is used to remove the entire cubbyhole of the
+// given salted token. It is invoked when the token is revoked.
+func (c *Core) destroyCubbyhole(saltedID string) error {
+	view := c.router.MatchingView(cubbyholeMountPath)
+	if view == nil {
+		return nil
+	}
+	return ClearView(view.SubView(saltedID + "/"))
+}
+
+const cubbyholeHelp = `
+The cubbyhole backend reads and writes arbitrary secrets to the backend.
+The secrets are stored per token: only the token that wrote a secret can
+read it, and the entire cubbyhole of a token is destroyed when the token
+is revoked or expires.
+`
+
+const cubbyholeHelpSynopsis = `
+Pass-through secret storage to a token-specific cubbyhole in the storage
+backend, allowing you to read/write arbitrary data into secret storage.
+`
+
+const cubbyholeHelpDescription = `
+The cubbyhole backend reads and writes arbitrary data into secret storage,
+encrypting it along the way.
+
+The view into the cubbyhole storage space is different for each token; it
+is a per-token private storage area. Not even a root token can read the
+cubbyhole of another token. When the token is revoked, the contents of
+its cubbyhole are destroyed.
+`
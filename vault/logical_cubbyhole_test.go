@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCubbyhole_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreMakeToken(t, c, root, "client1", []string{"default"})
+	testCoreMakeToken(t, c, root, "client2", []string{"default"})
+
+	// Write to the cubbyhole of the first token
+	req := logical.TestRequest(t, logical.WriteOperation, "cubbyhole/foo")
+	req.ClientToken = "client1"
+	req.Data["value"] = "bar"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The owner can read it back
+	req = logical.TestRequest(t, logical.ReadOperation, "cubbyhole/foo")
+	req.ClientToken = "client1"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || !reflect.DeepEqual(resp.Data, map[string]interface{}{"value": "bar"}) {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Other tokens, including root, cannot see it
+	for _, token := range []string{"client2", root} {
+		req = logical.TestRequest(t, logical.ReadOperation, "cubbyhole/foo")
+		req.ClientToken = token
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp != nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		req = logical.TestRequest(t, logical.ListOperation, "cubbyhole/")
+		req.ClientToken = token
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if keys := resp.Data["keys"].([]string); len(keys) != 0 {
+			t.Fatalf("bad: %#v", keys)
+		}
+	}
+}
+
+func TestCubbyhole_DestroyedOnRevoke(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreMakeToken(t, c, root, "client", []string{"default"})
+
+	req := logical.TestRequest(t, logical.WriteOperation, "cubbyhole/foo/bar")
+	req.ClientToken = "client"
+	req.Data["value"] = "baz"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	view := c.router.MatchingView(cubbyholeMountPath)
+	keys, err := CollectKeys(view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	if err := c.tokenStore.Revoke("client"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	keys, err = CollectKeys(view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestCubbyhole_Protected(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	if err := c.unmount("cubbyhole"); err == nil {
+		t.Fatalf("expected unmount error")
+	}
+	if err := c.remount("cubbyhole", "foo"); err == nil {
+		t.Fatalf("expected remount error")
+	}
+
+	me := &MountEntry{
+		Path: "foo",
+		Type: "cubbyhole",
+	}
+	if err := c.mount(me); err == nil {
+		t.Fatalf("expected mount error")
+	}
+}
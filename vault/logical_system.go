@@ -1,7 +1,11 @@
 package vault
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +20,17 @@ var (
 		barrierInitPath,
 		keyringPath,
 	}
+
+	// rootNamespacePaths are the prefixes of the paths of the system
+	// backend which are not available within a namespace
+	rootNamespacePaths = []string{
+		"quotas/",
+		"plugins/",
+		"audit",
+		"raw/",
+		"key-status",
+		"rotate",
+	}
 )
 
 func NewSystemBackend(core *Core) logical.Backend {
@@ -29,8 +44,15 @@ func NewSystemBackend(core *Core) logical.Backend {
 				"auth/*",
 				"remount",
 				"revoke-prefix/*",
+				"revoke-force/*",
+				"leases/lookup/*",
+				"leases/irrevocable",
+				"quotas/*",
+				"plugins/catalog/*",
 				"policy",
 				"policy/*",
+				"namespaces",
+				"namespaces/*",
 				"audit",
 				"audit/*",
 				"seal", // Must be set for Core.Seal() logic
@@ -51,6 +73,49 @@ func NewSystemBackend(core *Core) logical.Backend {
 				HelpDescription: strings.TrimSpace(sysHelp["mounts"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/tune$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+					"default_lease_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["tune_default_lease_ttl"][0]),
+					},
+					"max_lease_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["tune_max_lease_ttl"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_description"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
+					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["tune_options"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:  b.handleMountTuneRead,
+					logical.WriteOperation: b.handleMountTuneWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_tune"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_tune"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "mounts/(?P<path>.+)",
 
@@ -68,6 +133,10 @@ func NewSystemBackend(core *Core) logical.Backend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["mount_desc"][0]),
 					},
+					"plugin_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_plugin_name"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -159,6 +228,71 @@ func NewSystemBackend(core *Core) logical.Backend {
 				HelpDescription: strings.TrimSpace(sysHelp["revoke-prefix"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "revoke-force/(?P<prefix>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"prefix": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["revoke-force-path"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleRevokeForce,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["revoke-force"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["revoke-force"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/lookup$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"lease_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["lease_id"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleLeaseLookup,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-lookup"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-lookup"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/lookup/(?P<prefix>.*)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"prefix": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-list-prefix"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleLeaseList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/irrevocable$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleLeaseIrrevocable,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "auth$",
 
@@ -170,6 +304,71 @@ func NewSystemBackend(core *Core) logical.Backend {
 				HelpDescription: strings.TrimSpace(sysHelp["auth-table"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "auth/remount$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"from": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["auth_remount_from"][0]),
+					},
+					"to": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["auth_remount_to"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleAuthRemount,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["auth_remount"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["auth_remount"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "auth/(?P<path>.+?)/tune$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["auth_path"][0]),
+					},
+					"default_lease_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["tune_default_lease_ttl"][0]),
+					},
+					"max_lease_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["tune_max_lease_ttl"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_description"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
+					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["tune_options"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:  b.handleAuthTuneRead,
+					logical.WriteOperation: b.handleAuthTuneWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["auth_tune"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["auth_tune"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "auth/(?P<path>.+)",
 
@@ -186,6 +385,10 @@ func NewSystemBackend(core *Core) logical.Backend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_desc"][0]),
 					},
+					"plugin_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["auth_plugin_name"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -233,307 +436,1296 @@ func NewSystemBackend(core *Core) logical.Backend {
 			},
 
 			&framework.Path{
-				Pattern: "audit$",
+				Pattern: "namespaces/?$",
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: b.handleAuditTable,
+					logical.ListOperation: b.handleNamespaceList,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-table"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["namespace-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["namespace-list"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "audit/(?P<path>.+)",
+				Pattern: "namespaces/(?P<name>.+)",
 
 				Fields: map[string]*framework.FieldSchema{
-					"path": &framework.FieldSchema{
+					"name": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+						Description: strings.TrimSpace(sysHelp["namespace-name"][0]),
 					},
-					"type": &framework.FieldSchema{
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleNamespaceRead,
+					logical.WriteOperation:  b.handleNamespaceCreate,
+					logical.DeleteOperation: b.handleNamespaceDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["namespace"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["namespace"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "control-group/lookup$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"request_id": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_type"][0]),
+						Description: strings.TrimSpace(sysHelp["control-group-request-id"][0]),
 					},
-					"description": &framework.FieldSchema{
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleControlGroupLookup,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-lookup"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group-lookup"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "control-group/authorize$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"request_id": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_desc"][0]),
-					},
-					"options": &framework.FieldSchema{
-						Type:        framework.TypeMap,
-						Description: strings.TrimSpace(sysHelp["audit_opts"][0]),
+						Description: strings.TrimSpace(sysHelp["control-group-request-id"][0]),
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.WriteOperation:  b.handleEnableAudit,
-					logical.DeleteOperation: b.handleDisableAudit,
+					logical.WriteOperation: b.handleControlGroupAuthorize,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audit"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audit"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-authorize"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group-authorize"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "raw/(?P<path>.+)",
+				Pattern: "control-group/request$",
 
 				Fields: map[string]*framework.FieldSchema{
-					"path": &framework.FieldSchema{
-						Type: framework.TypeString,
-					},
-					"value": &framework.FieldSchema{
-						Type: framework.TypeString,
+					"request_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["control-group-request-id"][0]),
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation:   b.handleRawRead,
-					logical.WriteOperation:  b.handleRawWrite,
-					logical.DeleteOperation: b.handleRawDelete,
+					logical.WriteOperation: b.handleControlGroupRequest,
 				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-request"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group-request"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "key-status$",
+				Pattern: "wrapping/unwrap$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["wrapping-token"][0]),
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: b.handleKeyStatus,
+					logical.WriteOperation: b.handleWrappingUnwrap,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["key-status"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["key-status"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-unwrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrapping-unwrap"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "rotate$",
+				Pattern: "wrapping/lookup$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["wrapping-token"][0]),
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.WriteOperation: b.handleRotate,
+					logical.WriteOperation: b.handleWrappingLookup,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-lookup"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrapping-lookup"][1]),
 			},
-		},
-	}
-	return b.Backend
-}
 
-// SystemBackend implements logical.Backend and is used to interact with
-// the core of the system. This backend is hardcoded to exist at the "sys"
-// prefix. Conceptually it is similar to procfs on Linux.
-type SystemBackend struct {
-	Core    *Core
-	Backend *framework.Backend
-}
+			&framework.Path{
+				Pattern: "wrapping/rewrap$",
 
-// handleMountTable handles the "mounts" endpoint to provide the mount table
-func (b *SystemBackend) handleMountTable(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.Core.mounts.Lock()
-	defer b.Core.mounts.Unlock()
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["wrapping-token"][0]),
+					},
+				},
 
-	resp := &logical.Response{
-		Data: make(map[string]interface{}),
-	}
-	for _, entry := range b.Core.mounts.Entries {
-		info := map[string]string{
-			"type":        entry.Type,
-			"description": entry.Description,
-		}
-		resp.Data[entry.Path] = info
-	}
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleWrappingRewrap,
+				},
 
-	return resp, nil
-}
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-rewrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrapping-rewrap"][1]),
+			},
 
-// handleMount is used to mount a new path
-func (b *SystemBackend) handleMount(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	path := data.Get("path").(string)
-	logicalType := data.Get("type").(string)
-	description := data.Get("description").(string)
+			&framework.Path{
+				Pattern: "quotas/lease-count/?$",
 
-	if logicalType == "" {
-		return logical.ErrorResponse(
-				"backend type must be specified as a string"),
-			logical.ErrInvalidRequest
-	}
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleLeaseCountQuotaList,
+				},
 
-	// Create the mount entry
-	me := &MountEntry{
-		Path:        path,
-		Type:        logicalType,
-		Description: description,
-	}
+				HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quota-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["lease-count-quota-list"][1]),
+			},
 
-	// Attempt mount
-	if err := b.Core.mount(me); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: mount %#v failed: %v", me, err)
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			&framework.Path{
+				Pattern: "quotas/lease-count/(?P<name>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["quota-name"][0]),
+					},
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["quota-path"][0]),
+					},
+					"max_leases": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["lease-count-quota-max-leases"][0]),
+					},
+					"per_token": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["lease-count-quota-per-token"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleLeaseCountQuotaRead,
+					logical.WriteOperation:  b.handleLeaseCountQuotaWrite,
+					logical.DeleteOperation: b.handleLeaseCountQuotaDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quota"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["lease-count-quota"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "quotas/rate-limit/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleRateLimitQuotaList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quota-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quota-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "quotas/rate-limit/(?P<name>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["quota-name"][0]),
+					},
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["quota-path"][0]),
+					},
+					"rate": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["rate-limit-quota-rate"][0]),
+					},
+					"burst": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["rate-limit-quota-burst"][0]),
+					},
+					"per_client": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["rate-limit-quota-per-client"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRateLimitQuotaRead,
+					logical.WriteOperation:  b.handleRateLimitQuotaWrite,
+					logical.DeleteOperation: b.handleRateLimitQuotaDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quota"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quota"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "plugins/catalog/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handlePluginCatalogList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "plugins/catalog/(?P<name>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog-name"][0]),
+					},
+					"command": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog-command"][0]),
+					},
+					"args": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog-args"][0]),
+					},
+					"sha256": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog-sha256"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handlePluginCatalogRead,
+					logical.WriteOperation:  b.handlePluginCatalogWrite,
+					logical.DeleteOperation: b.handlePluginCatalogDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "audit$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleAuditTable,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-table"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "audit/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+					},
+					"type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_type"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_desc"][0]),
+					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["audit_opts"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation:  b.handleEnableAudit,
+					logical.DeleteOperation: b.handleDisableAudit,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "raw/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+					"value": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRawRead,
+					logical.WriteOperation:  b.handleRawWrite,
+					logical.DeleteOperation: b.handleRawDelete,
+				},
+			},
+
+			&framework.Path{
+				Pattern: "key-status$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleKeyStatus,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["key-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["key-status"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "rotate$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: b.handleRotate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
+			},
+		},
+	}
+
+	// The endpoints managing the whole Vault are only available in the
+	// root namespace
+	if core.namespace != nil {
+		var paths []*framework.Path
+		for _, p := range b.Backend.Paths {
+			if !rootNamespacePath(p.Pattern) {
+				paths = append(paths, p)
+			}
+		}
+		b.Backend.Paths = paths
+	}
+	return b.Backend
+}
+
+// rootNamespacePath checks if the pattern of a path of the system backend
+// is only available in the root namespace
+func rootNamespacePath(pattern string) bool {
+	for _, prefix := range rootNamespacePaths {
+		if strings.HasPrefix(pattern, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SystemBackend implements logical.Backend and is used to interact with
+// the core of the system. This backend is hardcoded to exist at the "sys"
+// prefix. Conceptually it is similar to procfs on Linux.
+type SystemBackend struct {
+	Core    *Core
+	Backend *framework.Backend
+}
+
+// handleMountTable handles the "mounts" endpoint to provide the mount table
+func (b *SystemBackend) handleMountTable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.mounts.Lock()
+	defer b.Core.mounts.Unlock()
+
+	resp := &logical.Response{
+		Data: make(map[string]interface{}),
+	}
+	for _, entry := range b.Core.mounts.Entries {
+		info := map[string]string{
+			"type":        entry.Type,
+			"description": entry.Description,
+		}
+		if entry.PluginName != "" {
+			info["plugin_name"] = entry.PluginName
+		}
+		resp.Data[entry.Path] = info
+	}
+
+	return resp, nil
+}
+
+// handleMount is used to mount a new path
+func (b *SystemBackend) handleMount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	path := data.Get("path").(string)
+	logicalType := data.Get("type").(string)
+	description := data.Get("description").(string)
+
+	if logicalType == "" {
+		return logical.ErrorResponse(
+				"backend type must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	pluginName, err := pluginMountName(logicalType, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// Create the mount entry
+	me := &MountEntry{
+		Path:        path,
+		Type:        logicalType,
+		Description: description,
+		PluginName:  pluginName,
+	}
+
+	// Attempt mount
+	if err := b.Core.mount(me); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: mount %#v failed: %v", me, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// pluginMountName returns the plugin_name of a mount, which is required
+// for the plugin backends and not valid for the others
+func pluginMountName(logicalType string, data *framework.FieldData) (string, error) {
+	name := data.Get("plugin_name").(string)
+	switch {
+	case logicalType == "plugin" && name == "":
+		return "", fmt.Errorf("plugin_name must be specified for plugin backends")
+	case logicalType != "plugin" && name != "":
+		return "", fmt.Errorf("plugin_name is only valid for plugin backends")
+	}
+	return name, nil
+}
+
+// handleMountTuneRead is used to get the settings of a mount
+func (b *SystemBackend) handleMountTuneRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return b.handleTuneReadCommon(b.Core.mounts, path, path)
+}
+
+// handleAuthTuneRead is used to get the settings of a credential backend
+func (b *SystemBackend) handleAuthTuneRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return b.handleTuneReadCommon(b.Core.auth, path, credentialRoutePrefix+path)
+}
+
+// handleTuneReadCommon returns the settings of an entry of a table. The
+// lease TTLs are the effective ones of the mount.
+func (b *SystemBackend) handleTuneReadCommon(
+	table *MountTable, path, routePath string) (*logical.Response, error) {
+	table.RLock()
+	entry := table.Find(path)
+	if entry != nil {
+		entry = entry.Clone()
+	}
+	table.RUnlock()
+	if entry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no matching mount at '%s'", path)),
+			logical.ErrInvalidRequest
+	}
+
+	requestKeys := entry.Config.AuditNonHMACRequestKeys
+	if requestKeys == nil {
+		requestKeys = []string{}
+	}
+	responseKeys := entry.Config.AuditNonHMACResponseKeys
+	if responseKeys == nil {
+		responseKeys = []string{}
+	}
+
+	defaultTTL, maxTTL := b.Core.leaseTTLs(routePath)
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"default_lease_ttl":            int64(defaultTTL.Seconds()),
+			"max_lease_ttl":                int64(maxTTL.Seconds()),
+			"description":                  entry.Description,
+			"audit_non_hmac_request_keys":  requestKeys,
+			"audit_non_hmac_response_keys": responseKeys,
+			"options":                      entry.Options,
+		},
+	}
+	return resp, nil
+}
+
+// handleMountTuneWrite is used to set the settings of a mount. Only the
+// given values are changed, and a lease TTL of zero restores the system
+// default.
+func (b *SystemBackend) handleMountTuneWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return b.handleTuneWriteCommon(b.Core.mounts, path, data, b.Core.tuneMount)
+}
+
+// handleAuthTuneWrite is used to set the settings of a credential backend
+func (b *SystemBackend) handleAuthTuneWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return b.handleTuneWriteCommon(b.Core.auth, path, data, b.Core.tuneCredential)
+}
+
+// handleTuneWriteCommon applies the given settings to a copy of an entry
+// of a table, and tunes the entry with it
+func (b *SystemBackend) handleTuneWriteCommon(table *MountTable, path string,
+	data *framework.FieldData, tune func(string, *MountEntry) error) (*logical.Response, error) {
+	table.RLock()
+	entry := table.Find(path)
+	if entry != nil {
+		entry = entry.Clone()
+	}
+	table.RUnlock()
+	if entry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no matching mount at '%s'", path)),
+			logical.ErrInvalidRequest
+	}
+
+	if raw, ok := data.GetOk("default_lease_ttl"); ok {
+		entry.Config.DefaultLeaseTTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk("max_lease_ttl"); ok {
+		entry.Config.MaxLeaseTTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := data.GetOk("description"); ok {
+		entry.Description = raw.(string)
+	}
+	if raw, ok := data.GetOk("audit_non_hmac_request_keys"); ok {
+		entry.Config.AuditNonHMACRequestKeys = parseKeyList(raw.(string))
+	}
+	if raw, ok := data.GetOk("audit_non_hmac_response_keys"); ok {
+		entry.Config.AuditNonHMACResponseKeys = parseKeyList(raw.(string))
+	}
+	if raw, ok := data.GetOk("options"); ok {
+		options := make(map[string]string)
+		for k, v := range raw.(map[string]interface{}) {
+			options[k] = fmt.Sprint(v)
+		}
+		entry.Options = options
+	}
+
+	// Attempt tune
+	if err := tune(path, entry); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: tune '%s' failed: %v", path, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// parseKeyList parses a comma-separated list of keys
+func parseKeyList(raw string) []string {
+	var keys []string
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// handleUnmount is used to unmount a path
+func (b *SystemBackend) handleUnmount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	suffix := strings.TrimPrefix(req.Path, "mounts/")
+	if len(suffix) == 0 {
+		return logical.ErrorResponse("path cannot be blank"), logical.ErrInvalidRequest
+	}
+
+	// Attempt unmount
+	if err := b.Core.unmount(suffix); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: unmount '%s' failed: %v", suffix, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+// handleRemount is used to remount a path
+func (b *SystemBackend) handleRemount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get the paths
+	fromPath := data.Get("from").(string)
+	toPath := data.Get("to").(string)
+	if fromPath == "" || toPath == "" {
+		return logical.ErrorResponse(
+				"both 'from' and 'to' path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+
+	// Attempt remount
+	if err := b.Core.remount(fromPath, toPath); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: remount '%s' to '%s' failed: %v", fromPath, toPath, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+// handleAuthRemount is used to move a credential backend to a new path
+func (b *SystemBackend) handleAuthRemount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get the paths
+	fromPath := data.Get("from").(string)
+	toPath := data.Get("to").(string)
+	if fromPath == "" || toPath == "" {
+		return logical.ErrorResponse(
+				"both 'from' and 'to' path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+
+	// Attempt remount
+	if err := b.Core.remountCredential(fromPath, toPath); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: auth remount '%s' to '%s' failed: %v", fromPath, toPath, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+// handleRenew is used to renew a lease with a given LeaseID
+func (b *SystemBackend) handleRenew(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	leaseID := data.Get("lease_id").(string)
+	incrementRaw := data.Get("increment").(int)
+
+	// Convert the increment
+	increment := time.Duration(incrementRaw) * time.Second
+
+	// Invoke the expiration manager directly
+	resp, err := b.Core.expiration.Renew(leaseID, increment)
+	if err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: renew '%s' failed: %v", leaseID, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return resp, err
+}
+
+// handleRevoke is used to revoke a given LeaseID
+func (b *SystemBackend) handleRevoke(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	leaseID := data.Get("lease_id").(string)
+
+	// Invoke the expiration manager directly
+	if err := b.Core.expiration.Revoke(leaseID); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: revoke '%s' failed: %v", leaseID, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// handleRevokePrefix is used to revoke a prefix with many LeaseIDs
+func (b *SystemBackend) handleRevokePrefix(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	prefix := data.Get("prefix").(string)
+
+	// Invoke the expiration manager directly
+	if err := b.Core.expiration.RevokePrefix(prefix); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: revoke prefix '%s' failed: %v", prefix, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+// handleRevokeForce is used to delete the leases under a prefix without
+// revoking them through their backends
+func (b *SystemBackend) handleRevokeForce(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	prefix := data.Get("prefix").(string)
+
+	// Invoke the expiration manager directly
+	if err := b.Core.expiration.RevokeForce(prefix); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: revoke force '%s' failed: %v", prefix, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 	return nil, nil
 }
 
-// handleUnmount is used to unmount a path
-func (b *SystemBackend) handleUnmount(
+// handleLeaseLookup is used to inspect the metadata of a lease
+func (b *SystemBackend) handleLeaseLookup(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	suffix := strings.TrimPrefix(req.Path, "mounts/")
-	if len(suffix) == 0 {
-		return logical.ErrorResponse("path cannot be blank"), logical.ErrInvalidRequest
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		return logical.ErrorResponse("missing lease_id"), logical.ErrInvalidRequest
 	}
 
-	// Attempt unmount
-	if err := b.Core.unmount(suffix); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: unmount '%s' failed: %v", suffix, err)
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	info, err := b.Core.expiration.Lookup(leaseID)
+	if err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: lease lookup '%s' failed: %v", leaseID, err)
+		return nil, err
+	}
+	if info == nil {
+		return logical.ErrorResponse("lease not found"), logical.ErrInvalidRequest
 	}
 
-	return nil, nil
+	return &logical.Response{
+		Data: leaseInfoData(info),
+	}, nil
 }
 
-// handleRemount is used to remount a path
-func (b *SystemBackend) handleRemount(
+// handleLeaseIrrevocable is used to list the leases that could not be
+// revoked automatically
+func (b *SystemBackend) handleLeaseIrrevocable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get the paths
-	fromPath := data.Get("from").(string)
-	toPath := data.Get("to").(string)
-	if fromPath == "" || toPath == "" {
+	infos, err := b.Core.expiration.ListIrrevocable()
+	if err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: irrevocable lease list failed: %v", err)
+		return nil, err
+	}
+
+	leases := make([]map[string]interface{}, 0, len(infos))
+	for _, info := range infos {
+		leases = append(leases, leaseInfoData(info))
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"leases": leases,
+		},
+	}, nil
+}
+
+// leaseInfoData returns the response data describing a lease
+func leaseInfoData(info *LeaseInfo) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":                info.LeaseID,
+		"path":              info.Path,
+		"issue_time":        info.IssueTime,
+		"expire_time":       nil,
+		"ttl":               0,
+		"renewable":         info.Renewable,
+		"token_accessor":    info.TokenAccessor,
+		"revoke_attempts":   info.RevokeAttempts,
+		"last_revoke_error": info.LastRevokeError,
+		"irrevocable":       info.Irrevocable,
+	}
+	if !info.ExpireTime.IsZero() {
+		data["expire_time"] = info.ExpireTime
+		if ttl := info.ExpireTime.Sub(time.Now()); ttl > 0 {
+			data["ttl"] = int64(ttl.Seconds())
+		}
+	}
+	return data
+}
+
+// handleLeaseList is used to list the lease IDs under a prefix
+func (b *SystemBackend) handleLeaseList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix := data.Get("prefix").(string)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	keys, err := b.Core.expiration.List(prefix)
+	if err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: lease list '%s' failed: %v", prefix, err)
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+// handleAuthTable handles the "auth" endpoint to provide the auth table
+func (b *SystemBackend) handleAuthTable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.auth.Lock()
+	defer b.Core.auth.Unlock()
+
+	resp := &logical.Response{
+		Data: make(map[string]interface{}),
+	}
+	for _, entry := range b.Core.auth.Entries {
+		info := map[string]string{
+			"type":        entry.Type,
+			"description": entry.Description,
+		}
+		if entry.PluginName != "" {
+			info["plugin_name"] = entry.PluginName
+		}
+		resp.Data[entry.Path] = info
+	}
+	return resp, nil
+}
+
+// handleEnableAuth is used to enable a new credential backend
+func (b *SystemBackend) handleEnableAuth(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	path := data.Get("path").(string)
+	logicalType := data.Get("type").(string)
+	description := data.Get("description").(string)
+
+	if logicalType == "" {
 		return logical.ErrorResponse(
-				"both 'from' and 'to' path must be specified as a string"),
+				"backend type must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
+	pluginName, err := pluginMountName(logicalType, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 
-	// Attempt remount
-	if err := b.Core.remount(fromPath, toPath); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: remount '%s' to '%s' failed: %v", fromPath, toPath, err)
+	// Create the mount entry
+	me := &MountEntry{
+		Path:        path,
+		Type:        logicalType,
+		Description: description,
+		PluginName:  pluginName,
+	}
+
+	// Attempt enabling
+	if err := b.Core.enableCredential(me); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: enable auth %#v failed: %v", me, err)
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
+	return nil, nil
+}
+
+// handleDisableAuth is used to disable a credential backend
+func (b *SystemBackend) handleDisableAuth(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	suffix := strings.TrimPrefix(req.Path, "auth/")
+	if len(suffix) == 0 {
+		return logical.ErrorResponse("path cannot be blank"), logical.ErrInvalidRequest
+	}
 
+	// Attempt disable
+	if err := b.Core.disableCredential(suffix); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: disable auth '%s' failed: %v", suffix, err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 	return nil, nil
 }
 
-// handleRenew is used to renew a lease with a given LeaseID
-func (b *SystemBackend) handleRenew(
+// handlePolicyList handles the "policy" endpoint to provide the enabled policies
+func (b *SystemBackend) handlePolicyList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	leaseID := data.Get("lease_id").(string)
-	incrementRaw := data.Get("increment").(int)
+	// Get all the configured policies
+	policies, err := b.Core.policy.ListPolicies()
 
-	// Convert the increment
-	increment := time.Duration(incrementRaw) * time.Second
+	// Add the special "root" policy
+	policies = append(policies, "root")
+	return logical.ListResponse(policies), err
+}
 
-	// Invoke the expiration manager directly
-	resp, err := b.Core.expiration.Renew(leaseID, increment)
+// handleNamespaceList handles the "namespaces" endpoint to list the
+// child namespaces
+func (b *SystemBackend) handleNamespaceList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return logical.ListResponse(b.Core.listNamespaces()), nil
+}
+
+// handleNamespaceRead handles the "namespaces/<name>" endpoint to read
+// a child namespace
+func (b *SystemBackend) handleNamespaceRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	nc := b.Core.childNamespace(data.Get("name").(string))
+	if nc == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   nc.namespace.ID,
+			"path": nc.namespace.Path,
+		},
+	}, nil
+}
+
+// handleNamespaceCreate handles the "namespaces/<name>" endpoint to
+// create a child namespace, returning the token to administer it
+func (b *SystemBackend) handleNamespaceCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns, te, err := b.Core.createNamespace(data.Get("name").(string))
 	if err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: renew '%s' failed: %v", leaseID, err)
+		b.Backend.Logger().Printf("[ERR] sys: create namespace failed: %v", err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":          ns.ID,
+			"path":        ns.Path,
+			"admin_token": te.ID,
+		},
+	}, nil
+}
+
+// handleNamespaceDelete handles the "namespaces/<name>" endpoint to
+// delete a child namespace
+func (b *SystemBackend) handleNamespaceDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.deleteNamespace(data.Get("name").(string)); err != nil {
+		b.Backend.Logger().Printf("[ERR] sys: delete namespace failed: %v", err)
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
+	return nil, nil
+}
+
+// handleControlGroupLookup handles the "control-group/lookup" endpoint
+// to read the status of a parked request
+func (b *SystemBackend) handleControlGroupLookup(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.Core.controlGroupLookup(req.ClientToken, data.Get("request_id").(string))
+	if err != nil {
+		return controlGroupErrorResponse(err)
+	}
+	return &logical.Response{Data: controlGroupStatus(r)}, nil
+}
+
+// handleControlGroupAuthorize handles the "control-group/authorize"
+// endpoint to approve a parked request
+func (b *SystemBackend) handleControlGroupAuthorize(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.Core.controlGroupAuthorize(req.ClientToken, data.Get("request_id").(string))
+	if err != nil {
+		return controlGroupErrorResponse(err)
+	}
+	return &logical.Response{Data: controlGroupStatus(r)}, nil
+}
+
+// handleControlGroupRequest handles the "control-group/request" endpoint
+// to redeem an approved request. The response is that of the replayed
+// request.
+func (b *SystemBackend) handleControlGroupRequest(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, resp, err := b.Core.controlGroupRedeem(req.ClientToken,
+		data.Get("request_id").(string), req.Connection)
+	if r == nil {
+		return controlGroupErrorResponse(err)
+	}
 	return resp, err
 }
 
-// handleRevoke is used to revoke a given LeaseID
-func (b *SystemBackend) handleRevoke(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	leaseID := data.Get("lease_id").(string)
+// controlGroupErrorResponse converts an error of the control group
+// endpoints into the response of the request
+func controlGroupErrorResponse(err error) (*logical.Response, error) {
+	switch err {
+	case logical.ErrPermissionDenied:
+		return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+	case ErrInternalError:
+		return nil, err
+	default:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+}
+
+// handleWrappingUnwrap handles the "wrapping/unwrap" endpoint to return
+// the response wrapped by a token, consuming the token
+func (b *SystemBackend) handleWrappingUnwrap(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	resp, err := b.Core.unwrap(wrappingRequestToken(req, data))
+	if err != nil {
+		return wrappingErrorResponse(err)
+	}
+	return resp, nil
+}
+
+// handleWrappingLookup handles the "wrapping/lookup" endpoint to inspect
+// a response wrapping token without consuming it
+func (b *SystemBackend) handleWrappingLookup(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	w, err := b.Core.lookupWrapped(wrappingRequestToken(req, data))
+	if err != nil {
+		return wrappingErrorResponse(err)
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"creation_ttl":  int(w.TTL.Seconds()),
+			"creation_time": w.CreationTime,
+		},
+	}, nil
+}
+
+// handleWrappingRewrap handles the "wrapping/rewrap" endpoint to move
+// a wrapped response to a new response wrapping token
+func (b *SystemBackend) handleWrappingRewrap(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	info, err := b.Core.rewrap(wrappingRequestToken(req, data))
+	if err != nil {
+		return wrappingErrorResponse(err)
+	}
+	return &logical.Response{WrapInfo: info}, nil
+}
+
+// wrappingRequestToken returns the wrapping token of a request to the
+// wrapping endpoints. It is taken from the data if given, otherwise the
+// client token is the wrapping token.
+func wrappingRequestToken(req *logical.Request, data *framework.FieldData) string {
+	if token := data.Get("token").(string); token != "" {
+		return token
+	}
+	return req.ClientToken
+}
+
+// wrappingErrorResponse converts an error of the wrapping endpoints into
+// the response of the request
+func wrappingErrorResponse(err error) (*logical.Response, error) {
+	if err == ErrInternalError {
+		return nil, err
+	}
+	return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+}
+
+// handleLeaseCountQuotaList handles the "quotas/lease-count" endpoint to
+// list the lease count quotas
+func (b *SystemBackend) handleLeaseCountQuotaList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return logical.ListResponse(b.Core.quotas.ListLeaseCount()), nil
+}
+
+// handleLeaseCountQuotaRead handles the "quotas/lease-count/<name>"
+// endpoint to read a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	q := b.Core.quotas.GetLeaseCount(data.Get("name").(string))
+	if q == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":       q.Name,
+			"path":       q.Path,
+			"max_leases": q.MaxLeases,
+			"per_token":  q.PerToken,
+		},
+	}, nil
+}
+
+// handleLeaseCountQuotaWrite handles the "quotas/lease-count/<name>"
+// endpoint to create or update a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	q := b.Core.quotas.GetLeaseCount(name)
+	if q == nil {
+		q = &LeaseCountQuota{Name: name}
+	} else {
+		updated := *q
+		q = &updated
+	}
+
+	if raw, ok := data.GetOk("path"); ok {
+		path, err := b.quotaPath(raw.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		q.Path = path
+	}
+	if raw, ok := data.GetOk("max_leases"); ok {
+		q.MaxLeases = raw.(int)
+	}
+	if raw, ok := data.GetOk("per_token"); ok {
+		q.PerToken = raw.(bool)
+	}
+	if q.MaxLeases <= 0 {
+		return logical.ErrorResponse("max_leases must be positive"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.quotas.SetLeaseCount(q); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleLeaseCountQuotaDelete handles the "quotas/lease-count/<name>"
+// endpoint to delete a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.quotas.DeleteLeaseCount(data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleRateLimitQuotaList handles the "quotas/rate-limit" endpoint to
+// list the rate limit quotas
+func (b *SystemBackend) handleRateLimitQuotaList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return logical.ListResponse(b.Core.quotas.ListRateLimit()), nil
+}
+
+// handleRateLimitQuotaRead handles the "quotas/rate-limit/<name>"
+// endpoint to read a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	q, blocked := b.Core.quotas.GetRateLimit(data.Get("name").(string))
+	if q == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":             q.Name,
+			"path":             q.Path,
+			"rate":             q.Rate,
+			"burst":            q.Burst,
+			"per_client":       q.PerClient,
+			"blocked_requests": blocked,
+		},
+	}, nil
+}
+
+// handleRateLimitQuotaWrite handles the "quotas/rate-limit/<name>"
+// endpoint to create or update a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	q, _ := b.Core.quotas.GetRateLimit(name)
+	if q == nil {
+		q = &RateLimitQuota{Name: name}
+	} else {
+		updated := *q
+		q = &updated
+	}
+
+	if raw, ok := data.GetOk("path"); ok {
+		path, err := b.quotaPath(raw.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		q.Path = path
+	}
+	if raw, ok := data.GetOk("rate"); ok {
+		rate, err := strconv.ParseFloat(raw.(string), 64)
+		if err != nil {
+			return logical.ErrorResponse("rate must be a number"), logical.ErrInvalidRequest
+		}
+		q.Rate = rate
+	}
+	if raw, ok := data.GetOk("burst"); ok {
+		q.Burst = raw.(int)
+	}
+	if raw, ok := data.GetOk("per_client"); ok {
+		q.PerClient = raw.(bool)
+	}
+	if q.Rate <= 0 {
+		return logical.ErrorResponse("rate must be positive"), logical.ErrInvalidRequest
+	}
+
+	// Allow bursts of a second of requests by default
+	if q.Burst <= 0 {
+		q.Burst = int(math.Ceil(q.Rate))
+	}
 
-	// Invoke the expiration manager directly
-	if err := b.Core.expiration.Revoke(leaseID); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: revoke '%s' failed: %v", leaseID, err)
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	if err := b.Core.quotas.SetRateLimit(q); err != nil {
+		return nil, err
 	}
 	return nil, nil
 }
 
-// handleRevokePrefix is used to revoke a prefix with many LeaseIDs
-func (b *SystemBackend) handleRevokePrefix(
+// handleRateLimitQuotaDelete handles the "quotas/rate-limit/<name>"
+// endpoint to delete a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	prefix := data.Get("prefix").(string)
-
-	// Invoke the expiration manager directly
-	if err := b.Core.expiration.RevokePrefix(prefix); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: revoke prefix '%s' failed: %v", prefix, err)
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	if err := b.Core.quotas.DeleteRateLimit(data.Get("name").(string)); err != nil {
+		return nil, err
 	}
 	return nil, nil
 }
 
-// handleAuthTable handles the "auth" endpoint to provide the auth table
-func (b *SystemBackend) handleAuthTable(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.Core.auth.Lock()
-	defer b.Core.auth.Unlock()
-
-	resp := &logical.Response{
-		Data: make(map[string]interface{}),
+// quotaPath normalizes the path of a quota, which must be empty, a
+// namespace, or under a mount of the root or of a namespace
+func (b *SystemBackend) quotaPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
 	}
-	for _, entry := range b.Core.auth.Entries {
-		info := map[string]string{
-			"type":        entry.Type,
-			"description": entry.Description,
-		}
-		resp.Data[entry.Path] = info
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
 	}
-	return resp, nil
+	nc, rest := b.Core.requestNamespace(path)
+	if rest != "" && nc.router.MatchingMount(rest) == "" {
+		return "", fmt.Errorf("no mount matches path '%s'", path)
+	}
+	return path, nil
 }
 
-// handleEnableAuth is used to enable a new credential backend
-func (b *SystemBackend) handleEnableAuth(
+// handlePluginCatalogList handles the "plugins/catalog" endpoint to list
+// the registered plugins
+func (b *SystemBackend) handlePluginCatalogList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	path := data.Get("path").(string)
-	logicalType := data.Get("type").(string)
-	description := data.Get("description").(string)
-
-	if logicalType == "" {
-		return logical.ErrorResponse(
-				"backend type must be specified as a string"),
-			logical.ErrInvalidRequest
+	names, err := b.Core.pluginCatalog.List()
+	if err != nil {
+		return nil, err
 	}
+	return logical.ListResponse(names), nil
+}
 
-	// Create the mount entry
-	me := &MountEntry{
-		Path:        path,
-		Type:        logicalType,
-		Description: description,
+// handlePluginCatalogRead handles the "plugins/catalog/<name>" endpoint
+// to read a plugin
+func (b *SystemBackend) handlePluginCatalogRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := b.Core.pluginCatalog.Get(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
 	}
 
-	// Attempt enabling
-	if err := b.Core.enableCredential(me); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: enable auth %#v failed: %v", me, err)
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	args := entry.Args
+	if args == nil {
+		args = []string{}
 	}
-	return nil, nil
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":    entry.Name,
+			"command": entry.Command,
+			"args":    args,
+			"sha256":  hex.EncodeToString(entry.Sha256),
+		},
+	}, nil
 }
 
-// handleDisableAuth is used to disable a credential backend
-func (b *SystemBackend) handleDisableAuth(
+// handlePluginCatalogWrite handles the "plugins/catalog/<name>" endpoint
+// to register a plugin
+func (b *SystemBackend) handlePluginCatalogWrite(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	suffix := strings.TrimPrefix(req.Path, "auth/")
-	if len(suffix) == 0 {
-		return logical.ErrorResponse("path cannot be blank"), logical.ErrInvalidRequest
+	command := data.Get("command").(string)
+	if command == "" {
+		return logical.ErrorResponse("missing command"), logical.ErrInvalidRequest
 	}
 
-	// Attempt disable
-	if err := b.Core.disableCredential(suffix); err != nil {
-		b.Backend.Logger().Printf("[ERR] sys: disable auth '%s' failed: %v", suffix, err)
+	sum, err := hex.DecodeString(data.Get("sha256").(string))
+	if err != nil || len(sum) != sha256.Size {
+		return logical.ErrorResponse("sha256 must be a hex-encoded SHA-256 checksum"),
+			logical.ErrInvalidRequest
+	}
+
+	var args []string
+	if raw := data.Get("args").(string); raw != "" {
+		args = strings.Split(raw, ",")
+	}
+
+	entry := &PluginEntry{
+		Name:    data.Get("name").(string),
+		Command: command,
+		Args:    args,
+		Sha256:  sum,
+	}
+	if err := b.Core.pluginCatalog.Set(entry); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 	return nil, nil
 }
 
-// handlePolicyList handles the "policy" endpoint to provide the enabled policies
-func (b *SystemBackend) handlePolicyList(
+// handlePluginCatalogDelete handles the "plugins/catalog/<name>" endpoint
+// to remove a plugin
+func (b *SystemBackend) handlePluginCatalogDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the configured policies
-	policies, err := b.Core.policy.ListPolicies()
-
-	// Add the special "root" policy
-	policies = append(policies, "root")
-	return logical.ListResponse(policies), err
+	if err := b.Core.pluginCatalog.Delete(data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
 }
 
 // handlePolicyRead handles the "policy/<name>" endpoint to read a policy
@@ -816,6 +2008,66 @@ west coast.
 		"",
 	},
 
+	"mount_plugin_name": {
+		`The name of the catalogued plugin to mount, for the "plugin" type.`,
+		"",
+	},
+
+	"mount_tune": {
+		"Tune the settings of a mounted backend.",
+		`
+Read or set the settings of a mounted backend: the default and maximum
+lease TTLs, the description, the keys left in the clear in the audit
+logs and the options passed to the backend. The lease TTLs override the
+system-wide values for the mount, but the maximum cannot exceed the
+system-wide maximum. Setting a TTL to zero restores the system-wide
+value. The settings apply without remounting the backend.
+		`,
+	},
+
+	"auth_tune": {
+		"Tune the settings of a credential backend.",
+		`
+Read or set the settings of an enabled credential backend: the default
+and maximum lease TTLs of its tokens, the description, the keys left in
+the clear in the audit logs and the options passed to the backend. The
+settings apply without disabling the backend.
+		`,
+	},
+
+	"tune_description": {
+		"The human-friendly description of the mount.",
+		"",
+	},
+
+	"tune_audit_non_hmac_request_keys": {
+		`Comma-separated list of the keys of the request data which are not
+HMAC'd in the audit logs of this mount.`,
+		"",
+	},
+
+	"tune_audit_non_hmac_response_keys": {
+		`Comma-separated list of the keys of the response data which are not
+HMAC'd in the audit logs of this mount.`,
+		"",
+	},
+
+	"tune_options": {
+		`The options passed to the backend. Changing them creates the backend
+again with the new options.`,
+		"",
+	},
+
+	"tune_default_lease_ttl": {
+		`The default lease TTL for this mount, in seconds or as a duration such as "1h".`,
+		"",
+	},
+
+	"tune_max_lease_ttl": {
+		`The maximum lease TTL for this mount, in seconds or as a duration such as "1h".`,
+		"",
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend.",
 		`
@@ -823,6 +2075,25 @@ Change the mount point of an already-mounted backend.
 		`,
 	},
 
+	"auth_remount": {
+		"Move the mount point of an enabled credential backend.",
+		`
+Change the mount point of an enabled credential backend. The data of the
+backend is kept, and the tokens issued by it and their leases are moved
+to the new path, so they remain valid and can be revoked by prefix.
+		`,
+	},
+
+	"auth_remount_from": {
+		"The current mount point of the credential backend, such as \"github-old\".",
+		"",
+	},
+
+	"auth_remount_to": {
+		"The new mount point of the credential backend, such as \"github\".",
+		"",
+	},
+
 	"remount_from": {
 		"",
 		"",
@@ -875,6 +2146,164 @@ all matching leases.
 		`,
 	},
 
+	"revoke-force": {
+		"Delete all secrets generated in a given prefix without revoking them",
+		`
+Deletes the leases of all the secrets generated under a given prefix,
+without asking their backends to revoke them. This is meant for leases
+that cannot be revoked, such as when the system holding the secrets is
+gone. The secrets themselves may remain valid. Tokens under the prefix
+are still revoked. This requires sudo capability.
+		`,
+	},
+
+	"revoke-force-path": {
+		`The path to delete leases under. Example: "prod/aws/ops"`,
+		"",
+	},
+
+	"leases-irrevocable": {
+		"List the leases that could not be revoked automatically",
+		`
+When the automatic revocation of an expired lease fails, it is retried
+with an exponential backoff. Once the retries are exhausted the lease is
+marked irrevocable, and it is kept until it is revoked manually or deleted
+with revoke-force. This lists those leases along with the error of the
+last revocation attempt. This requires sudo capability.
+		`,
+	},
+
+	"lease-count-quota-list": {
+		"List the lease count quotas",
+		"",
+	},
+
+	"lease-count-quota": {
+		"Limit the number of leases",
+		`
+A lease count quota rejects the requests under its path that may create
+a lease once the maximum number of leases is reached. Without a path the
+quota applies to all requests, except to the system, cubbyhole and
+identity backends and to the credential backends other than the creation
+of tokens, which do not create leases. By default the leases under the
+path are counted. With per_token, the leases held by the token making the
+request are counted instead, wherever they were issued. Rejected requests
+return a 429 status code. The leases are counted in the background after
+an unseal, and the quotas are not enforced until then.
+		`,
+	},
+
+	"rate-limit-quota-list": {
+		"List the rate limit quotas",
+		"",
+	},
+
+	"rate-limit-quota": {
+		"Limit the rate of requests",
+		`
+A rate limit quota limits the rate of the requests under its path. Without
+a path the quota applies to all requests, except to the system backend.
+The requests are limited with a token bucket, which allows bursts of up
+to burst requests and refills at rate requests per second. With
+per_client, each client address has its own bucket. Rejected requests
+return a 429 status code with a Retry-After header.
+		`,
+	},
+
+	"rate-limit-quota-rate": {
+		"The number of requests allowed per second. Example: 0.5",
+		"",
+	},
+
+	"rate-limit-quota-burst": {
+		"The number of requests allowed in a burst. Defaults to a second of requests.",
+		"",
+	},
+
+	"rate-limit-quota-per-client": {
+		"Limit the rate of each client address instead of the rate of all clients.",
+		"",
+	},
+
+	"plugin-catalog-list": {
+		"List the plugins in the catalog.",
+		"",
+	},
+
+	"plugin-catalog": {
+		"Register, read and remove the plugins of the catalog.",
+		`
+The plugins in the catalog can be mounted with the "plugin" backend type
+and their name as the plugin_name option, both as secret and credential
+backends. The command of a plugin is relative to the plugin directory of
+the server, and is only run if its SHA-256 checksum matches the catalog.
+		`,
+	},
+
+	"plugin-catalog-name": {
+		"The name of the plugin.",
+		"",
+	},
+
+	"plugin-catalog-command": {
+		"The command of the plugin, relative to the plugin directory.",
+		"",
+	},
+
+	"plugin-catalog-args": {
+		"Comma-separated list of arguments of the command.",
+		"",
+	},
+
+	"plugin-catalog-sha256": {
+		"The hex-encoded SHA-256 checksum of the command.",
+		"",
+	},
+
+	"quota-name": {
+		"The name of the quota.",
+		"",
+	},
+
+	"quota-path": {
+		`The path the quota applies to, such as a mount path or a namespace. Example: "postgres/". Empty for all paths.`,
+		"",
+	},
+
+	"lease-count-quota-max-leases": {
+		"The maximum number of leases.",
+		"",
+	},
+
+	"lease-count-quota-per-token": {
+		"Count the leases of each token instead of the leases under the path.",
+		"",
+	},
+
+	"leases-lookup": {
+		"Inspect the metadata of a lease",
+		`
+Returns when the lease was issued and expires, whether it can be
+renewed, the accessor of the token that owns it, and whether its
+automatic revocation has failed. The data of the secret itself is not
+returned.
+		`,
+	},
+
+	"leases-list": {
+		"List the lease IDs under a prefix",
+		`
+Lists the lease IDs directly under the given prefix, such as
+"aws/creds/deploy/". Nested prefixes are returned with a trailing
+slash. This requires sudo capability.
+		`,
+	},
+
+	"leases-list-prefix": {
+		"The prefix of the lease IDs to list.",
+		"",
+	},
+
 	"revoke-prefix-path": {
 		`The path to revoke keys under. Example: "prod/aws/ops"`,
 		"",
@@ -912,6 +2341,11 @@ Example: you might have an OAuth backend for GitHub, and one for Google Apps.
 		"",
 	},
 
+	"auth_plugin_name": {
+		`The name of the catalogued plugin to enable, for the "plugin" type.`,
+		"",
+	},
+
 	"policy-list": {
 		`List the configured access control policies.`,
 		`
@@ -980,6 +2414,96 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"namespace-list": {
+		"List the child namespaces.",
+		`
+List the names of the namespaces created in this namespace.
+		`,
+	},
+
+	"namespace": {
+		"Create, read or delete a child namespace.",
+		`
+A namespace is an isolated tree with its own mounts, credential backends,
+policies and tokens. Its endpoints are selected by prefixing their paths
+with the path of the namespace, or with the X-Vault-Namespace header.
+
+Creating a namespace returns an admin token, which has the root policy
+within the namespace only. Deleting a namespace revokes all its leases and
+deletes all its data, including its nested namespaces.
+		`,
+	},
+
+	"namespace-name": {
+		"The name of the namespace, which is a single path segment.",
+		"",
+	},
+
+	"control-group-lookup": {
+		"Read the status of a request parked by a control group.",
+		`
+The request may be looked up by the requester and by the holders of one
+of its approver policies.
+		`,
+	},
+
+	"control-group-authorize": {
+		"Approve a request parked by a control group.",
+		`
+The token must hold one of the approver policies of the request. Approvals
+are counted per identity: the entity of the token, or otherwise the topmost
+token it was created from below a root token. Approvals made by the
+requester or with several tokens of the same identity are not counted.
+		`,
+	},
+
+	"control-group-request": {
+		"Perform an approved request parked by a control group.",
+		`
+Once a request has collected its required approvals, the requester performs
+it by redeeming it. The request is replayed with the token and connection of
+the redeeming request, and its response is returned. A request can only be
+redeemed once.
+		`,
+	},
+
+	"control-group-request-id": {
+		"The ID of the parked request.",
+		"",
+	},
+
+	"wrapping-unwrap": {
+		"Return the response wrapped by a response wrapping token.",
+		`
+The wrapping token is given as the "token" parameter, or as the client token
+of the request. The token is revoked, so a response can only be unwrapped
+once.
+		`,
+	},
+
+	"wrapping-lookup": {
+		"Look up the properties of a response wrapping token.",
+		`
+Returns the TTL and the creation time of the wrapping token given as the
+"token" parameter, or as the client token of the request. The token is not
+consumed.
+		`,
+	},
+
+	"wrapping-rewrap": {
+		"Move a wrapped response to a new response wrapping token.",
+		`
+The wrapping token given as the "token" parameter, or as the client token of
+the request, is revoked and a new one is returned. The response keeps its
+original expiration, so this cannot be used to extend its lifetime.
+		`,
+	},
+
+	"wrapping-token": {
+		"The response wrapping token. Defaults to the client token.",
+		"",
+	},
+
 	"rotate": {
 		"Rotates the backend encryption key used to persist data.",
 		`
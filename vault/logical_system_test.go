@@ -2,6 +2,7 @@ package vault
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/audit"
@@ -14,8 +15,15 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"auth/*",
 		"remount",
 		"revoke-prefix/*",
+		"revoke-force/*",
+		"leases/lookup/*",
+		"leases/irrevocable",
+		"quotas/*",
+		"plugins/catalog/*",
 		"policy",
 		"policy/*",
+		"namespaces",
+		"namespaces/*",
 		"audit",
 		"audit/*",
 		"seal",
@@ -43,6 +51,14 @@ func TestSystemBackend_mounts(t *testing.T) {
 			"type":        "generic",
 			"description": "generic secret storage",
 		},
+		"cubbyhole/": map[string]string{
+			"type":        "cubbyhole",
+			"description": "per-token private secret storage",
+		},
+		"identity/": map[string]string{
+			"type":        "identity",
+			"description": "identity store",
+		},
 		"sys/": map[string]string{
 			"type":        "system",
 			"description": "system endpoints used for control, policy and debugging",
@@ -82,6 +98,111 @@ func TestSystemBackend_mount_invalid(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_mountTune(t *testing.T) {
+	b := testSystemBackend(t)
+
+	// The system defaults apply until tuned
+	req := logical.TestRequest(t, logical.ReadOperation, "mounts/secret/tune")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"default_lease_ttl":            int64(maxLeaseDuration.Seconds()),
+		"max_lease_ttl":                int64(maxLeaseDuration.Seconds()),
+		"description":                  "generic secret storage",
+		"audit_non_hmac_request_keys":  []string{},
+		"audit_non_hmac_response_keys": []string{},
+		"options":                      map[string]string{},
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "mounts/secret/tune")
+	req.Data["max_lease_ttl"] = "2h"
+	req.Data["description"] = "tuned"
+	req.Data["audit_non_hmac_request_keys"] = "name, role"
+	req.Data["options"] = map[string]interface{}{"foo": "bar"}
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The default is limited by the max of the mount
+	req = logical.TestRequest(t, logical.ReadOperation, "mounts/secret/tune")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp = map[string]interface{}{
+		"default_lease_ttl":            int64(7200),
+		"max_lease_ttl":                int64(7200),
+		"description":                  "tuned",
+		"audit_non_hmac_request_keys":  []string{"name", "role"},
+		"audit_non_hmac_response_keys": []string{},
+		"options":                      map[string]string{"foo": "bar"},
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+}
+
+func TestSystemBackend_authTune(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/tune")
+	req.Data["default_lease_ttl"] = "1h"
+	req.Data["description"] = "tokens"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/tune")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["default_lease_ttl"] != int64(3600) || resp.Data["description"] != "tokens" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The token store is a singleton which can't be created again
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/tune")
+	req.Data["options"] = map[string]interface{}{"foo": "bar"}
+	if _, err := b.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/nope/tune")
+	if _, err := b.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestSystemBackend_mountTune_invalid(t *testing.T) {
+	b := testSystemBackend(t)
+
+	cases := []map[string]interface{}{
+		{"max_lease_ttl": int(maxLeaseDuration.Seconds()) + 1},
+		{"max_lease_ttl": "1h", "default_lease_ttl": "2h"},
+		{"default_lease_ttl": -1},
+	}
+	for _, data := range cases {
+		req := logical.TestRequest(t, logical.WriteOperation, "mounts/secret/tune")
+		req.Data = data
+		if _, err := b.HandleRequest(req); err != logical.ErrInvalidRequest {
+			t.Fatalf("err: %v %#v", err, data)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "mounts/nope/tune")
+	if _, err := b.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestSystemBackend_unmount(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -317,6 +438,75 @@ func TestSystemBackend_revokePrefix(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_leases(t *testing.T) {
+	core, b, root := testCoreSystemBackend(t)
+
+	// Create a key with a lease
+	req := logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	req.ClientToken = root
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Read a key with a LeaseID
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	leaseID := resp.Secret.LeaseID
+
+	// Lookup the lease
+	req2 := logical.TestRequest(t, logical.WriteOperation, "leases/lookup")
+	req2.Data["lease_id"] = leaseID
+	resp2, err := b.HandleRequest(req2)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp2)
+	}
+	te, err := core.tokenStore.Lookup(root)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Data["id"] != leaseID || resp2.Data["path"] != "secret/foo" ||
+		resp2.Data["renewable"] != true || resp2.Data["token_accessor"] != te.Accessor {
+		t.Fatalf("bad: %#v", resp2.Data)
+	}
+	if ttl := resp2.Data["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad: %#v", resp2.Data)
+	}
+	if _, ok := resp2.Data["data"]; ok {
+		t.Fatalf("bad: %#v", resp2.Data)
+	}
+
+	// List the leases level by level
+	for prefix, expected := range map[string][]string{
+		"":            []string{"secret/"},
+		"secret":      []string{"foo/"},
+		"secret/foo/": []string{strings.TrimPrefix(leaseID, "secret/foo/")},
+	} {
+		req3 := logical.TestRequest(t, logical.ListOperation, "leases/lookup/"+prefix)
+		resp3, err := b.HandleRequest(req3)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if keys := resp3.Data["keys"]; !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("bad: %s %#v", prefix, keys)
+		}
+	}
+
+	// Unknown leases are an error
+	req2.Data["lease_id"] = "secret/foo/nope"
+	if _, err := b.HandleRequest(req2); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestSystemBackend_authTable(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.ReadOperation, "auth")
@@ -411,7 +601,7 @@ func TestSystemBackend_policyList(t *testing.T) {
 	}
 
 	exp := map[string]interface{}{
-		"keys": []string{"root"},
+		"keys": []string{"default", "root"},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
@@ -456,7 +646,7 @@ func TestSystemBackend_policyCRUD(t *testing.T) {
 	}
 
 	exp = map[string]interface{}{
-		"keys": []string{"foo", "root"},
+		"keys": []string{"default", "foo", "root"},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
@@ -490,7 +680,7 @@ func TestSystemBackend_policyCRUD(t *testing.T) {
 	}
 
 	exp = map[string]interface{}{
-		"keys": []string{"root"},
+		"keys": []string{"default", "root"},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/uuid"
 	"github.com/hashicorp/vault/logical"
 )
@@ -36,6 +38,8 @@ var (
 		"audit/",
 		"auth/",
 		"sys/",
+		"cubbyhole/",
+		"identity/",
 	}
 )
 
@@ -106,12 +110,26 @@ func (t *MountTable) Remove(path string) bool {
 
 // MountEntry is used to represent a mount table entry
 type MountEntry struct {
-	Path        string            `json:"path"`              // Mount Path
-	Type        string            `json:"type"`              // Logical backend Type
-	Description string            `json:"description"`       // User-provided description
-	UUID        string            `json:"uuid"`              // Barrier view UUID
-	Options     map[string]string `json:"options"`           // Backend configuration
-	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+	Path        string            `json:"path"`                  // Mount Path
+	Type        string            `json:"type"`                  // Logical backend Type
+	Description string            `json:"description"`           // User-provided description
+	UUID        string            `json:"uuid"`                  // Barrier view UUID
+	Options     map[string]string `json:"options"`               // Backend configuration
+	Config      MountConfig       `json:"config"`                // Configuration related to this mount (but not backend-derived)
+	Tainted     bool              `json:"tainted,omitempty"`     // Set as a Write-Ahead flag for unmount/remount
+	PluginName  string            `json:"plugin_name,omitempty"` // Catalogued plugin of a plugin backend
+}
+
+// MountConfig is used to hold settable options. A zero value means the
+// system-wide setting applies.
+type MountConfig struct {
+	DefaultLeaseTTL time.Duration `json:"default_lease_ttl"` // Override for global default
+	MaxLeaseTTL     time.Duration `json:"max_lease_ttl"`     // Override for global default
+
+	// Keys of the request data and response data left in the clear in the
+	// audit logs of the requests to this mount
+	AuditNonHMACRequestKeys  []string `json:"audit_non_hmac_request_keys,omitempty"`
+	AuditNonHMACResponseKeys []string `json:"audit_non_hmac_response_keys,omitempty"`
 }
 
 // Returns a deep copy of the mount entry
@@ -126,9 +144,27 @@ func (e *MountEntry) Clone() *MountEntry {
 		Description: e.Description,
 		UUID:        e.UUID,
 		Options:     optClone,
+		Config:      e.Config,
+		PluginName:  e.PluginName,
 	}
 }
 
+// backendConfig returns the configuration passed to the backend factory,
+// which is made of the tuned options of the mount
+func (e *MountEntry) backendConfig() map[string]string {
+	if e.Type != "plugin" && len(e.Options) == 0 {
+		return nil
+	}
+	conf := make(map[string]string, len(e.Options)+1)
+	for k, v := range e.Options {
+		conf[k] = v
+	}
+	if e.Type == "plugin" {
+		conf["plugin_name"] = e.PluginName
+	}
+	return conf
+}
+
 // Mount is used to mount a new backend to the mount table.
 func (c *Core) mount(me *MountEntry) error {
 	c.mounts.Lock()
@@ -146,17 +182,25 @@ func (c *Core) mount(me *MountEntry) error {
 		}
 	}
 
+	// The cubbyhole and identity backends are singletons mounted by default
+	if me.Type == "cubbyhole" || me.Type == "identity" {
+		return fmt.Errorf("cannot mount additional %s backends", me.Type)
+	}
+
 	// Verify there is no conflicting mount
 	if match := c.router.MatchingMount(me.Path); match != "" {
 		return fmt.Errorf("existing mount at '%s'", match)
 	}
+	if err := c.namespaceConflict(me.Path); err != nil {
+		return err
+	}
 
 	// Generate a new UUID and view
 	me.UUID = uuid.GenerateUUID()
 	view := NewBarrierView(c.barrier, backendBarrierPrefix+me.UUID+"/")
 
 	// Create the new backend
-	backend, err := c.newLogicalBackend(me.Type, view, nil)
+	backend, err := c.newLogicalBackend(me.Type, view, me.backendConfig())
 	if err != nil {
 		return err
 	}
@@ -165,12 +209,14 @@ func (c *Core) mount(me *MountEntry) error {
 	newTable := c.mounts.Clone()
 	newTable.Entries = append(newTable.Entries, me)
 	if err := c.persistMounts(newTable); err != nil {
+		cleanupBackend(backend)
 		return errors.New("failed to update mount table")
 	}
 	c.mounts = newTable
 
 	// Mount the backend
 	if err := c.router.Mount(backend, me.Path, me.UUID, view); err != nil {
+		cleanupBackend(backend)
 		return err
 	}
 	c.logger.Printf("[INFO] core: mounted '%s' type: %s", me.Path, me.Type)
@@ -269,6 +315,166 @@ func (c *Core) taintMountEntry(path string) error {
 	return nil
 }
 
+// tuneMount is used to update the settings of a mounted backend
+func (c *Core) tuneMount(path string, tuned *MountEntry) error {
+	c.mounts.Lock()
+	defer c.mounts.Unlock()
+
+	// Ensure we end the path in a slash
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	// Verify exact match of the route
+	entry := c.mounts.Find(path)
+	if entry == nil {
+		return fmt.Errorf("no matching mount at '%s'", path)
+	}
+
+	if err := c.tuneEntry(c.mounts, entry, tuned, path,
+		c.persistMounts, c.newLogicalBackend); err != nil {
+		return err
+	}
+	c.logger.Printf("[INFO] core: tuned '%s'", path)
+	return nil
+}
+
+// tuneEntry updates the description, config and options of an entry of
+// the table, and persists the table. The entry must be locked. If the
+// options change, the backend is created again with them and replaces
+// the mounted one, without remounting it.
+func (c *Core) tuneEntry(table *MountTable, entry, tuned *MountEntry, routePath string,
+	persist func(*MountTable) error,
+	factory func(string, logical.Storage, map[string]string) (logical.Backend, error)) error {
+	// Validate the config
+	if err := c.validateMountConfig(tuned.Config); err != nil {
+		return err
+	}
+
+	// Create the backend with the new options
+	var backend logical.Backend
+	if !optionsEqual(entry.Options, tuned.Options) {
+		switch entry.Type {
+		case "system", "identity", "cubbyhole", "token":
+			return fmt.Errorf("cannot set the options of the %s backend", entry.Type)
+		}
+
+		updated := entry.Clone()
+		updated.Options = tuned.Options
+		var err error
+		backend, err = factory(entry.Type, c.router.MatchingView(routePath), updated.backendConfig())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the mount table
+	oldDescription, oldConfig, oldOptions := entry.Description, entry.Config, entry.Options
+	entry.Description = tuned.Description
+	entry.Config = tuned.Config
+	entry.Options = tuned.Options
+	if err := persist(table); err != nil {
+		entry.Description, entry.Config, entry.Options = oldDescription, oldConfig, oldOptions
+		if backend != nil {
+			cleanupBackend(backend)
+		}
+		return errors.New("failed to update mount table")
+	}
+
+	if backend != nil {
+		return c.router.ReplaceBackend(routePath, backend)
+	}
+	return nil
+}
+
+// optionsEqual checks if two sets of backend options are the same
+func optionsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// validateMountConfig checks that a mount config does not exceed the
+// system-wide lease limits and is internally consistent
+func (c *Core) validateMountConfig(conf MountConfig) error {
+	if conf.DefaultLeaseTTL < 0 || conf.MaxLeaseTTL < 0 {
+		return fmt.Errorf("lease TTLs cannot be negative")
+	}
+	if conf.MaxLeaseTTL > c.maxLeaseTTL {
+		return fmt.Errorf("max_lease_ttl cannot be greater than the system max of %s", c.maxLeaseTTL)
+	}
+
+	maxTTL := c.maxLeaseTTL
+	if conf.MaxLeaseTTL > 0 {
+		maxTTL = conf.MaxLeaseTTL
+	}
+	if conf.DefaultLeaseTTL > maxTTL {
+		return fmt.Errorf("default_lease_ttl cannot be greater than the max_lease_ttl of %s", maxTTL)
+	}
+	return nil
+}
+
+// mountConfig returns the config of the mount serving the given path
+func (c *Core) mountConfig(path string) MountConfig {
+	var conf MountConfig
+
+	// Find the mount table entry of the path
+	table := c.mounts
+	mount := c.router.MatchingMount(path)
+	if strings.HasPrefix(mount, credentialRoutePrefix) {
+		table = c.auth
+		mount = strings.TrimPrefix(mount, credentialRoutePrefix)
+	}
+	if table == nil || mount == "" {
+		return conf
+	}
+
+	table.RLock()
+	if entry := table.Find(mount); entry != nil {
+		conf = entry.Config
+	}
+	table.RUnlock()
+	return conf
+}
+
+// leaseTTLs returns the effective default and maximum lease TTLs for
+// the mount serving the given path. Settings that are not overridden
+// by the mount fall back to the system-wide values.
+func (c *Core) leaseTTLs(path string) (time.Duration, time.Duration) {
+	defaultTTL, maxTTL := c.defaultLeaseTTL, c.maxLeaseTTL
+
+	conf := c.mountConfig(path)
+	if conf.MaxLeaseTTL > 0 {
+		maxTTL = conf.MaxLeaseTTL
+	}
+	if conf.DefaultLeaseTTL > 0 {
+		defaultTTL = conf.DefaultLeaseTTL
+	}
+	if defaultTTL > maxTTL {
+		defaultTTL = maxTTL
+	}
+	return defaultTTL, maxTTL
+}
+
+// auditOptions returns the options of the audit logs of the requests to
+// the mount serving the given path
+func (c *Core) auditOptions(path string) *audit.LogOptions {
+	conf := c.mountConfig(path)
+	if len(conf.AuditNonHMACRequestKeys) == 0 && len(conf.AuditNonHMACResponseKeys) == 0 {
+		return nil
+	}
+	return &audit.LogOptions{
+		NonHMACRequestKeys:  conf.AuditNonHMACRequestKeys,
+		NonHMACResponseKeys: conf.AuditNonHMACResponseKeys,
+	}
+}
+
 // Remount is used to remount a path at a new mount point.
 func (c *Core) remount(src, dst string) error {
 	c.mounts.Lock()
@@ -299,6 +505,9 @@ func (c *Core) remount(src, dst string) error {
 	if match := c.router.MatchingMount(dst); match != "" {
 		return fmt.Errorf("existing mount at '%s'", match)
 	}
+	if err := c.namespaceConflict(dst); err != nil {
+		return err
+	}
 
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(src); err != nil {
@@ -366,8 +575,22 @@ func (c *Core) loadMounts() error {
 		}
 	}
 
-	// Done if we have restored the mount table
+	// Done if we have restored the mount table, adding the builtin
+	// mounts to tables persisted before they existed
 	if c.mounts != nil {
+		var upgraded bool
+		for _, me := range []*MountEntry{cubbyholeMountEntry(), identityMountEntry()} {
+			if c.mounts.Find(me.Path) == nil {
+				c.mounts.Entries = append(c.mounts.Entries, me)
+				upgraded = true
+			}
+		}
+		if !upgraded {
+			return nil
+		}
+		if err := c.persistMounts(c.mounts); err != nil {
+			return loadMountsFailed
+		}
 		return nil
 	}
 
@@ -419,7 +642,7 @@ func (c *Core) setupMounts() error {
 		view = NewBarrierView(c.barrier, barrierPath)
 
 		// Initialize the backend
-		backend, err = c.newLogicalBackend(entry.Type, view, nil)
+		backend, err = c.newLogicalBackend(entry.Type, view, entry.backendConfig())
 		if err != nil {
 			c.logger.Printf(
 				"[ERR] core: failed to create mount entry %#v: %v",
@@ -430,6 +653,9 @@ func (c *Core) setupMounts() error {
 		if entry.Type == "system" {
 			c.systemView = view
 		}
+		if entry.Type == "identity" {
+			c.identityStore = backend.(*IdentityStore)
+		}
 
 		// Mount the backend
 		err = c.router.Mount(backend, entry.Path, entry.UUID, view)
@@ -449,9 +675,11 @@ func (c *Core) setupMounts() error {
 // unloadMounts is used before we seal the vault to reset the mounts to
 // their unloaded state. This is reversed by load and setup mounts.
 func (c *Core) unloadMounts() error {
+	c.router.Cleanup()
 	c.mounts = nil
 	c.router = NewRouter()
 	c.systemView = nil
+	c.identityStore = nil
 	return nil
 }
 
@@ -491,6 +719,28 @@ func defaultMountTable() *MountTable {
 		UUID:        uuid.GenerateUUID(),
 	}
 	table.Entries = append(table.Entries, genericMount)
+	table.Entries = append(table.Entries, cubbyholeMountEntry())
+	table.Entries = append(table.Entries, identityMountEntry())
 	table.Entries = append(table.Entries, sysMount)
 	return table
 }
+
+// cubbyholeMountEntry creates the mount entry of the cubbyhole backend
+func cubbyholeMountEntry() *MountEntry {
+	return &MountEntry{
+		Path:        cubbyholeMountPath,
+		Type:        "cubbyhole",
+		Description: "per-token private secret storage",
+		UUID:        uuid.GenerateUUID(),
+	}
+}
+
+// identityMountEntry creates the mount entry of the identity store
+func identityMountEntry() *MountEntry {
+	return &MountEntry{
+		Path:        identityMountPath,
+		Type:        "identity",
+		Description: "identity store",
+		UUID:        uuid.GenerateUUID(),
+	}
+}
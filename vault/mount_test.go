@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -307,6 +308,78 @@ func TestCore_Remount_Cleanup(t *testing.T) {
 	}
 }
 
+func TestCore_TuneMount(t *testing.T) {
+	c, key, root := TestCoreUnsealed(t)
+	var configs []map[string]string
+	c.logicalBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		configs = append(configs, conf.Config)
+		return &NoopBackend{}, nil
+	}
+	noopAudit := &NoopAudit{}
+	c.auditBackends["noop"] = func(map[string]string) (audit.Backend, error) {
+		return noopAudit, nil
+	}
+	if err := c.enableAudit(&MountEntry{Path: "noop", Type: "noop"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	me := &MountEntry{
+		Path: "test/",
+		Type: "noop",
+	}
+	if err := c.mount(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tuned := me.Clone()
+	tuned.Description = "tuned"
+	tuned.Options = map[string]string{"foo": "bar"}
+	tuned.Config.AuditNonHMACRequestKeys = []string{"foo"}
+	if err := c.tuneMount("test", tuned); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The backend is created again with the options
+	if len(configs) != 2 || configs[1]["foo"] != "bar" {
+		t.Fatalf("bad: %#v", configs)
+	}
+	if entry := c.mounts.Find("test/"); entry.Description != "tuned" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	// The audit options of the mount are passed to the audit backends
+	req := logical.TestRequest(t, logical.ReadOperation, "test/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	opts := noopAudit.ReqOpts[len(noopAudit.ReqOpts)-1]
+	if !reflect.DeepEqual(opts.RequestKeys(), []string{"foo"}) {
+		t.Fatalf("bad: %#v", opts)
+	}
+
+	// Tuning the settings only doesn't create the backend again
+	tuned = tuned.Clone()
+	tuned.Config.MaxLeaseTTL = time.Hour
+	if err := c.tuneMount("test", tuned); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("bad: %#v", configs)
+	}
+
+	// The options are persisted
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.Unseal(TestKeyCopy(key)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(configs) != 3 || configs[2]["foo"] != "bar" {
+		t.Fatalf("bad: %#v", configs)
+	}
+}
+
 func TestCore_Remount_Protected(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
 	err := c.remount("sys", "foo")
@@ -321,7 +394,7 @@ func TestDefaultMountTable(t *testing.T) {
 }
 
 func verifyDefaultTable(t *testing.T, table *MountTable) {
-	if len(table.Entries) != 2 {
+	if len(table.Entries) != 4 {
 		t.Fatalf("bad: %v", table.Entries)
 	}
 	for idx, entry := range table.Entries {
@@ -334,6 +407,20 @@ func verifyDefaultTable(t *testing.T, table *MountTable) {
 				t.Fatalf("bad: %v", entry)
 			}
 		case 1:
+			if entry.Path != "cubbyhole/" {
+				t.Fatalf("bad: %v", entry)
+			}
+			if entry.Type != "cubbyhole" {
+				t.Fatalf("bad: %v", entry)
+			}
+		case 2:
+			if entry.Path != "identity/" {
+				t.Fatalf("bad: %v", entry)
+			}
+			if entry.Type != "identity" {
+				t.Fatalf("bad: %v", entry)
+			}
+		case 3:
 			if entry.Path != "sys/" {
 				t.Fatalf("bad: %v", entry)
 			}
@@ -0,0 +1,477 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/uuid"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// coreNamespaceConfigPath is used to store the child namespaces of
+	// a namespace. Like the mount table, it is only available after an
+	// unseal.
+	coreNamespaceConfigPath = "core/namespaces"
+
+	// namespaceBarrierPrefix is the prefix to the ID used in the barrier
+	// for the storage of the namespaces.
+	namespaceBarrierPrefix = "namespaces/"
+)
+
+var (
+	// loadNamespacesFailed if loadNamespaces encounters an error
+	loadNamespacesFailed = errors.New("failed to setup namespaces")
+
+	// namespaceNameRegex is the format of the name of a namespace, which
+	// is a single path segment
+	namespaceNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+)
+
+// Namespace describes the namespace served by a core
+type Namespace struct {
+	// ID identifies the storage of the namespace
+	ID string
+
+	// Path is the full path of the namespace with a trailing slash,
+	// including the paths of the namespaces it is nested in
+	Path string
+}
+
+// namespaceEntry is the persisted record of a child namespace
+type namespaceEntry struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// namespaceBarrier wraps the barrier of the parent namespace so that all
+// the storage of a namespace is kept under its own prefix. Since every
+// other view is created from the barrier, this isolates the mount tables,
+// policies, tokens and backends of the namespace.
+type namespaceBarrier struct {
+	SecurityBarrier
+	prefix string
+}
+
+func (b *namespaceBarrier) Put(entry *Entry) error {
+	return b.SecurityBarrier.Put(&Entry{
+		Key:   b.prefix + entry.Key,
+		Value: entry.Value,
+	})
+}
+
+func (b *namespaceBarrier) Get(key string) (*Entry, error) {
+	entry, err := b.SecurityBarrier.Get(b.prefix + key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	entry.Key = strings.TrimPrefix(entry.Key, b.prefix)
+	return entry, nil
+}
+
+func (b *namespaceBarrier) Delete(key string) error {
+	return b.SecurityBarrier.Delete(b.prefix + key)
+}
+
+func (b *namespaceBarrier) List(prefix string) ([]string, error) {
+	return b.SecurityBarrier.List(b.prefix + prefix)
+}
+
+// newNamespaceCore creates the core serving a child namespace. It shares
+// the storage, audit and plugins of its parent, but has its own router
+// and backends. The core must still be setup by setupNamespace.
+func (c *Core) newNamespaceCore(entry *namespaceEntry) *Core {
+	path := entry.Name + "/"
+	if c.namespace != nil {
+		path = c.namespace.Path + path
+	}
+
+	nc := &Core{
+		physical: c.physical,
+		barrier: &namespaceBarrier{
+			SecurityBarrier: c.barrier,
+			prefix:          namespaceBarrierPrefix + entry.ID + "/",
+		},
+		router:        NewRouter(),
+		auditBackends: c.auditBackends,
+		auditBroker:   c.auditBroker,
+		pluginCatalog: c.pluginCatalog,
+		logger:        c.logger,
+		namespace: &Namespace{
+			ID:   entry.ID,
+			Path: path,
+		},
+		parent: c,
+
+		defaultLeaseTTL: c.defaultLeaseTTL,
+		maxLeaseTTL:     c.maxLeaseTTL,
+	}
+
+	// Use the backends of the parent, with the builtin ones bound to
+	// the new core
+	nc.logicalBackends = make(map[string]logical.Factory)
+	for k, f := range c.logicalBackends {
+		nc.logicalBackends[k] = f
+	}
+	nc.credentialBackends = make(map[string]logical.Factory)
+	for k, f := range c.credentialBackends {
+		nc.credentialBackends[k] = f
+	}
+	nc.setupBuiltinBackends()
+	return nc
+}
+
+// setupNamespace is used to load the tables of a namespace and to setup
+// its backends, much like postUnseal does for the root namespace
+func (c *Core) setupNamespace() error {
+	if err := c.loadMounts(); err != nil {
+		return err
+	}
+	if err := c.setupMounts(); err != nil {
+		return err
+	}
+	if err := c.startRollback(); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(); err != nil {
+		return err
+	}
+	if err := c.setupControlGroups(); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(); err != nil {
+		return err
+	}
+	if err := c.setupExpiration(); err != nil {
+		return err
+	}
+	return c.loadNamespaces()
+}
+
+// teardownNamespace is used to reverse setupNamespace. The core is marked
+// as sealed so that it rejects any request still holding onto it.
+func (c *Core) teardownNamespace() error {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	c.sealed = true
+
+	if err := c.unloadNamespaces(); err != nil {
+		return err
+	}
+	if err := c.stopExpiration(); err != nil {
+		return err
+	}
+	if err := c.teardownCredentials(); err != nil {
+		return err
+	}
+	if err := c.teardownQuotas(); err != nil {
+		return err
+	}
+	if err := c.teardownControlGroups(); err != nil {
+		return err
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		return err
+	}
+	if err := c.stopRollback(); err != nil {
+		return err
+	}
+	return c.unloadMounts()
+}
+
+// loadNamespaces is used to load and setup the child namespaces
+func (c *Core) loadNamespaces() error {
+	entries, err := c.namespaceEntries()
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to read namespaces: %v", err)
+		return loadNamespacesFailed
+	}
+
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	c.namespaces = make(map[string]*Core)
+	for _, entry := range entries {
+		nc := c.newNamespaceCore(entry)
+		if err := nc.setupNamespace(); err != nil {
+			c.logger.Printf("[ERR] core: failed to setup namespace '%s': %v",
+				nc.namespace.Path, err)
+			return loadNamespacesFailed
+		}
+		c.namespaces[entry.Name] = nc
+	}
+	return nil
+}
+
+// unloadNamespaces is used to teardown the child namespaces
+func (c *Core) unloadNamespaces() error {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	for _, nc := range c.namespaces {
+		if err := nc.teardownNamespace(); err != nil {
+			return err
+		}
+	}
+	c.namespaces = nil
+	return nil
+}
+
+// namespaceEntries reads the persisted child namespaces
+func (c *Core) namespaceEntries() ([]*namespaceEntry, error) {
+	raw, err := c.barrier.Get(coreNamespaceConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entries []*namespaceEntry
+	if err := json.Unmarshal(raw.Value, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode namespaces: %v", err)
+	}
+	return entries, nil
+}
+
+// persistNamespaces is used to persist the child namespaces after
+// modification. The namespace lock must be held.
+func (c *Core) persistNamespaces() error {
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]*namespaceEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, &namespaceEntry{
+			Name: name,
+			ID:   c.namespaces[name].namespace.ID,
+		})
+	}
+
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to encode namespaces: %v", err)
+		return err
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   coreNamespaceConfigPath,
+		Value: raw,
+	}); err != nil {
+		c.logger.Printf("[ERR] core: failed to persist namespaces: %v", err)
+		return err
+	}
+	return nil
+}
+
+// listNamespaces returns the names of the child namespaces
+func (c *Core) listNamespaces() []string {
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// childNamespace returns the core of a child namespace, or nil
+func (c *Core) childNamespace(name string) *Core {
+	c.namespaceLock.RLock()
+	defer c.namespaceLock.RUnlock()
+	return c.namespaces[name]
+}
+
+// createNamespace creates a child namespace and returns an admin token
+// for it. The token has the root policy within the new namespace only.
+func (c *Core) createNamespace(name string) (*Namespace, *TokenEntry, error) {
+	if !namespaceNameRegex.MatchString(name) {
+		return nil, nil, fmt.Errorf("invalid namespace name '%s'", name)
+	}
+	for _, p := range protectedMounts {
+		if name+"/" == p {
+			return nil, nil, fmt.Errorf("namespace name '%s' is reserved", name)
+		}
+	}
+
+	// The mount table is locked first, as when mounting
+	c.mounts.RLock()
+	defer c.mounts.RUnlock()
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	if _, ok := c.namespaces[name]; ok {
+		return nil, nil, fmt.Errorf("existing namespace '%s'", name)
+	}
+
+	// The namespace would shadow the mounts under its path
+	for _, me := range c.mounts.Entries {
+		if strings.HasPrefix(me.Path, name+"/") {
+			return nil, nil, fmt.Errorf("existing mount at '%s'", me.Path)
+		}
+	}
+
+	nc := c.newNamespaceCore(&namespaceEntry{
+		Name: name,
+		ID:   uuid.GenerateUUID(),
+	})
+	if err := nc.setupNamespace(); err != nil {
+		c.logger.Printf("[ERR] core: failed to setup namespace '%s': %v",
+			nc.namespace.Path, err)
+		return nil, nil, ErrInternalError
+	}
+	te, err := nc.tokenStore.RootToken()
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to create admin token of namespace '%s': %v",
+			nc.namespace.Path, err)
+		nc.destroyNamespace()
+		return nil, nil, ErrInternalError
+	}
+
+	if c.namespaces == nil {
+		c.namespaces = make(map[string]*Core)
+	}
+	c.namespaces[name] = nc
+	if err := c.persistNamespaces(); err != nil {
+		delete(c.namespaces, name)
+		nc.destroyNamespace()
+		return nil, nil, errors.New("failed to update namespaces")
+	}
+
+	c.logger.Printf("[INFO] core: created namespace '%s'", nc.namespace.Path)
+	return nc.namespace, te, nil
+}
+
+// deleteNamespace deletes a child namespace, including the namespaces
+// nested in it. All its leases are revoked and its storage is destroyed.
+func (c *Core) deleteNamespace(name string) error {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	nc, ok := c.namespaces[name]
+	if !ok {
+		return nil
+	}
+
+	// Delete the nested namespaces first
+	for _, child := range nc.listNamespaces() {
+		if err := nc.deleteNamespace(child); err != nil {
+			return err
+		}
+	}
+
+	// Revoke the leases of all the backends
+	if err := nc.revokeNamespaceLeases(); err != nil {
+		return err
+	}
+
+	delete(c.namespaces, name)
+	if err := c.persistNamespaces(); err != nil {
+		c.namespaces[name] = nc
+		return errors.New("failed to update namespaces")
+	}
+	if err := nc.destroyNamespace(); err != nil {
+		return err
+	}
+
+	c.logger.Printf("[INFO] core: deleted namespace '%s'", nc.namespace.Path)
+	return nil
+}
+
+// revokeNamespaceLeases revokes the leases of every backend of the namespace
+func (c *Core) revokeNamespaceLeases() error {
+	c.mounts.RLock()
+	paths := []string{credentialRoutePrefix}
+	for _, me := range c.mounts.Entries {
+		if me.Type != "system" {
+			paths = append(paths, me.Path)
+		}
+	}
+	c.mounts.RUnlock()
+
+	for _, path := range paths {
+		if err := c.expiration.RevokePrefix(path); err != nil {
+			return fmt.Errorf("failed to revoke leases of '%s%s': %v",
+				c.namespace.Path, path, err)
+		}
+	}
+	return nil
+}
+
+// destroyNamespace tears down the namespace and clears its storage
+func (c *Core) destroyNamespace() error {
+	if err := c.teardownNamespace(); err != nil {
+		return err
+	}
+	nb := c.barrier.(*namespaceBarrier)
+	if err := ClearView(NewBarrierView(nb.SecurityBarrier, nb.prefix)); err != nil {
+		c.logger.Printf("[ERR] core: failed to clear namespace '%s': %v", c.namespace.Path, err)
+		return ErrInternalError
+	}
+	return nil
+}
+
+// requestNamespace resolves the namespace a request path is in. The core
+// of the namespace is returned along with the path within the namespace.
+// The first segments of the path select the nested namespaces, and the
+// root namespace is used when none match.
+func (c *Core) requestNamespace(path string) (*Core, string) {
+	nc := c
+	for {
+		idx := strings.Index(path, "/")
+		if idx <= 0 {
+			return nc, path
+		}
+		child := nc.childNamespace(path[:idx])
+		if child == nil {
+			return nc, path
+		}
+		nc, path = child, path[idx+1:]
+	}
+}
+
+// namespaceConflict returns an error if the given mount path is under a
+// child namespace, since requests on it would be routed to the namespace
+func (c *Core) namespaceConflict(path string) error {
+	idx := strings.Index(path, "/")
+	if idx <= 0 {
+		return nil
+	}
+	if c.childNamespace(path[:idx]) != nil {
+		return fmt.Errorf("existing namespace at '%s'", path[:idx+1])
+	}
+	return nil
+}
+
+// NamespaceCore returns the core of the namespace at the given path, for
+// the operations of a namespace that are not made through HandleRequest.
+// The root core is returned for an empty path.
+func (c *Core) NamespaceCore(path string) (*Core, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, ErrSealed
+	}
+	if c.standby {
+		return nil, ErrStandby
+	}
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return c, nil
+	}
+	nc, rest := c.requestNamespace(path + "/")
+	if nc == c || rest != "" {
+		return nil, fmt.Errorf("namespace '%s' not found", path)
+	}
+	return nc, nil
+}
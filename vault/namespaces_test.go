@@ -0,0 +1,276 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testCreateNamespace creates a namespace at the given path, which may
+// be nested, and returns its admin token
+func testCreateNamespace(t *testing.T, c *Core, token, parent, name string) string {
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        parent + "sys/namespaces/" + name,
+		ClientToken: token,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	if resp.Data["path"] != parent+name+"/" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	return resp.Data["admin_token"].(string)
+}
+
+func TestCore_Namespace(t *testing.T) {
+	c, key, root := TestCoreUnsealed(t)
+	admin := testCreateNamespace(t, c, root, "", "ns1")
+
+	// Write a secret within the namespace
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "ns1/secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: admin,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if req.Path != "secret/foo" || req.Namespace != "ns1/" {
+		t.Fatalf("bad: %#v", req)
+	}
+
+	// The root namespace does not see it
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+
+	// The tokens only act within their namespace
+	for token, path := range map[string]string{
+		root:  "ns1/secret/foo",
+		admin: "secret/foo",
+	} {
+		req = &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: token,
+		}
+		if _, err := c.HandleRequest(req); err != logical.ErrPermissionDenied {
+			t.Fatalf("%s: err: %v", path, err)
+		}
+	}
+
+	// The namespace survives a seal
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if unseal, err := c.Unseal(key); err != nil || !unseal {
+		t.Fatalf("err: %v", err)
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "ns1/secret/foo",
+		ClientToken: admin,
+	}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The namespace has its own system backend
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "ns1/sys/mounts",
+		ClientToken: admin,
+	}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["sys/"]; !ok {
+		t.Fatalf("bad: %#v", resp)
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "ns1/sys/audit",
+		ClientToken: admin,
+	}
+	if _, err := c.HandleRequest(req); err != logical.ErrUnsupportedPath {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_Namespace_Nested(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	admin := testCreateNamespace(t, c, root, "", "ns1")
+	nestedAdmin := testCreateNamespace(t, c, admin, "ns1/", "ns2")
+
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "ns1/ns2/secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: nestedAdmin,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if req.Namespace != "ns1/ns2/" {
+		t.Fatalf("bad: %#v", req)
+	}
+
+	// The admin of the parent namespace is not valid in the nested one
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "ns1/ns2/secret/foo",
+		ClientToken: admin,
+	}
+	if _, err := c.HandleRequest(req); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = &logical.Request{
+		Operation:   logical.ListOperation,
+		Path:        "ns1/sys/namespaces",
+		ClientToken: admin,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ns2"}) {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Deleting a namespace deletes the nested namespaces and their data
+	req = &logical.Request{
+		Operation:   logical.DeleteOperation,
+		Path:        "sys/namespaces/ns1",
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := c.listNamespaces(); len(names) != 0 {
+		t.Fatalf("bad: %#v", names)
+	}
+	keys, err := c.barrier.List(namespaceBarrierPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "ns1/ns2/secret/foo",
+		ClientToken: nestedAdmin,
+	}
+	if _, err := c.HandleRequest(req); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_Namespace_Conflicts(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCreateNamespace(t, c, root, "", "ns1")
+
+	// Mounts cannot be shadowed by a namespace, nor the reverse
+	if err := c.mount(&MountEntry{Path: "ns1/foo", Type: "generic"}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if err := c.remount("secret", "ns1/secret"); err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, name := range []string{"ns1", "secret", "sys", "auth", "a/b", ""} {
+		if _, _, err := c.createNamespace(name); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCore_NamespaceCore(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	admin := testCreateNamespace(t, c, root, "", "ns1")
+	testCreateNamespace(t, c, admin, "ns1/", "ns2")
+
+	nc, err := c.NamespaceCore("")
+	if err != nil || nc != c {
+		t.Fatalf("bad: %v %v", nc, err)
+	}
+	nc, err = c.NamespaceCore("ns1/ns2/")
+	if err != nil || nc.namespace.Path != "ns1/ns2/" {
+		t.Fatalf("bad: %v %v", nc, err)
+	}
+	if _, err := c.NamespaceCore("ns1/ns3"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestCore_Namespace_BatchToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	admin1 := testCreateNamespace(t, c, root, "", "ns1")
+	admin2 := testCreateNamespace(t, c, root, "", "ns2")
+
+	for ns, admin := range map[string]string{"ns1/": admin1, "ns2/": admin2} {
+		req := &logical.Request{
+			Operation:   logical.WriteOperation,
+			Path:        ns + "sys/policy/foo",
+			Data:        map[string]interface{}{"rules": `path "secret/*" { policy = "write" }`},
+			ClientToken: admin,
+		}
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "ns1/auth/token/create",
+		Data:        map[string]interface{}{"type": "batch", "policies": []string{"foo"}, "no_parent": true},
+		ClientToken: admin1,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+
+	// The batch token has no parent to tie it to its namespace, and is
+	// still only valid within it
+	for path, expect := range map[string]error{
+		"ns1/secret/foo": nil,
+		"ns2/secret/foo": logical.ErrPermissionDenied,
+		"secret/foo":     logical.ErrPermissionDenied,
+	} {
+		req = &logical.Request{
+			Operation:   logical.WriteOperation,
+			Path:        path,
+			Data:        map[string]interface{}{"value": "bar"},
+			ClientToken: token,
+		}
+		if _, err := c.HandleRequest(req); err != expect {
+			t.Fatalf("%s: err: %v", path, err)
+		}
+	}
+	for _, path := range []string{"", "ns2/"} {
+		nc, err := c.NamespaceCore(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if te, err := nc.tokenStore.Lookup(token); err != nil || te != nil {
+			t.Fatalf("%q: bad: %#v %v", path, te, err)
+		}
+	}
+}
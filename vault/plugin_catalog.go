@@ -0,0 +1,140 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin"
+)
+
+const (
+	// pluginCatalogPath is the barrier path of the plugin catalog
+	pluginCatalogPath = "core/plugin-catalog/"
+)
+
+// PluginEntry is a plugin registered in the catalog
+type PluginEntry struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Sha256  []byte   `json:"sha256"`
+}
+
+// PluginCatalog records the plugins which can be mounted. The command of
+// a plugin is relative to the plugin directory of the server, and is only
+// run if its SHA-256 checksum matches the catalog.
+type PluginCatalog struct {
+	view      *BarrierView
+	directory string
+}
+
+// NewPluginCatalog creates a catalog stored in the view
+func NewPluginCatalog(view *BarrierView, directory string) *PluginCatalog {
+	return &PluginCatalog{
+		view:      view,
+		directory: directory,
+	}
+}
+
+// List returns the names of the plugins
+func (pc *PluginCatalog) List() ([]string, error) {
+	return pc.view.List("")
+}
+
+// Get returns a plugin, or nil if it is not registered
+func (pc *PluginCatalog) Get(name string) (*PluginEntry, error) {
+	out, err := pc.view.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin: %v", err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	var entry PluginEntry
+	if err := out.DecodeJSON(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin: %v", err)
+	}
+	return &entry, nil
+}
+
+// Set registers a plugin. The command must exist in the plugin directory.
+func (pc *PluginCatalog) Set(entry *PluginEntry) error {
+	command, err := pc.commandPath(entry.Command)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(command); err != nil {
+		return fmt.Errorf("invalid plugin command: %v", err)
+	}
+
+	buf, err := logical.StorageEntryJSON(entry.Name, entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin: %v", err)
+	}
+	if err := pc.view.Put(buf); err != nil {
+		return fmt.Errorf("failed to persist plugin: %v", err)
+	}
+	return nil
+}
+
+// Delete removes a plugin from the catalog. The mounts of the plugin keep
+// running until they are unmounted or the vault is sealed.
+func (pc *PluginCatalog) Delete(name string) error {
+	if err := pc.view.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete plugin: %v", err)
+	}
+	return nil
+}
+
+// Runner returns how to launch a registered plugin
+func (pc *PluginCatalog) Runner(name string) (*plugin.Runner, error) {
+	entry, err := pc.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("unknown plugin: %s", name)
+	}
+
+	command, err := pc.commandPath(entry.Command)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.Runner{
+		Name:    entry.Name,
+		Command: command,
+		Args:    entry.Args,
+		Sha256:  entry.Sha256,
+	}, nil
+}
+
+// commandPath returns the path of a command in the plugin directory
+func (pc *PluginCatalog) commandPath(command string) (string, error) {
+	if pc.directory == "" {
+		return "", fmt.Errorf("no plugin directory is configured")
+	}
+	if command == "" || filepath.IsAbs(command) {
+		return "", fmt.Errorf("plugin command must be relative to the plugin directory")
+	}
+
+	dir := filepath.Clean(pc.directory)
+	path := filepath.Join(dir, command)
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("plugin command must be in the plugin directory")
+	}
+	return path, nil
+}
+
+// newPluginBackend launches the plugin named by the plugin_name option
+// and returns a backend running in it
+func (c *Core) newPluginBackend(config *logical.BackendConfig) (logical.Backend, error) {
+	runner, err := c.pluginCatalog.Runner(config.Config["plugin_name"])
+	if err != nil {
+		return nil, err
+	}
+	return plugin.NewBackend(runner, config)
+}
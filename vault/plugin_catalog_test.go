@@ -0,0 +1,213 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin"
+)
+
+// testPluginHelperArg makes the test binary run as a plugin
+const testPluginHelperArg = "plugin-helper"
+
+// testPluginDir links the test binary into a plugin directory, and
+// returns the directory and the checksum of the binary
+func testPluginDir(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "vault-plugins")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := os.Symlink(os.Args[0], filepath.Join(dir, "vault-test")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	raw, err := ioutil.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sum := sha256.Sum256(raw)
+	return dir, hex.EncodeToString(sum[:])
+}
+
+func TestPluginCatalog(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	dir, _ := testPluginDir(t)
+	defer os.RemoveAll(dir)
+
+	pc := c.pluginCatalog
+	entry := &PluginEntry{
+		Name:    "test",
+		Command: "vault-test",
+		Args:    []string{"-foo"},
+		Sha256:  []byte{1, 2, 3},
+	}
+
+	// A plugin directory is required
+	if err := pc.Set(entry); err == nil {
+		t.Fatalf("should fail")
+	}
+	pc.directory = dir
+
+	// The command must be in the directory
+	for _, command := range []string{"", "/bin/sh", "../vault-test", "missing"} {
+		bad := *entry
+		bad.Command = command
+		if err := pc.Set(&bad); err == nil {
+			t.Fatalf("should fail: %s", command)
+		}
+	}
+
+	if err := pc.Set(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := pc.Get("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, entry) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	runner, err := pc.Runner("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if runner.Command != filepath.Join(dir, "vault-test") {
+		t.Fatalf("bad: %#v", runner)
+	}
+
+	names, err := pc.List()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"test"}) {
+		t.Fatalf("bad: %v", names)
+	}
+
+	if err := pc.Delete("test"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, _ := pc.Get("test"); out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+	if _, err := pc.Runner("test"); err == nil {
+		t.Fatalf("should fail")
+	}
+}
+
+func TestCore_MountPlugin(t *testing.T) {
+	c, key, root := TestCoreUnsealed(t)
+	dir, sum := testPluginDir(t)
+	defer os.RemoveAll(dir)
+	c.pluginCatalog.directory = dir
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/plugins/catalog/kv")
+	req.ClientToken = root
+	req.Data["command"] = "vault-test"
+	req.Data["args"] = "-test.run=TestPlugin_helper,--," + testPluginHelperArg
+	req.Data["sha256"] = sum
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/plugins/catalog/kv")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["sha256"] != sum || resp.Data["command"] != "vault-test" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The plugin name is required for plugin backends only
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/mounts/kv")
+	req.ClientToken = root
+	req.Data["type"] = "plugin"
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	req.Data["type"] = "generic"
+	req.Data["plugin_name"] = "kv"
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	req.Data["type"] = "plugin"
+	req.Data["plugin_name"] = "unknown"
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	req.Data["plugin_name"] = "kv"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "kv/foo")
+	req.ClientToken = root
+	req.Data["value"] = "bar"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The data is stored in the view of the mount
+	out, err := c.router.MatchingView("kv/").Get("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("missing entry")
+	}
+
+	// The plugin is launched again when the vault is unsealed
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.Unseal(TestKeyCopy(key)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "kv/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info := resp.Data["kv/"].(map[string]string); info["plugin_name"] != "kv" {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/mounts/kv")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+// TestPlugin_helper is run as the plugin by the tests above
+func TestPlugin_helper(t *testing.T) {
+	if os.Args[len(os.Args)-1] != testPluginHelperArg {
+		return
+	}
+
+	if err := plugin.Serve(PassthroughBackendFactory); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	os.Exit(0)
+}
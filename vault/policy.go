@@ -2,9 +2,12 @@ package vault
 
 import (
 	"fmt"
+	"net"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/vault/logical"
 )
 
 const (
@@ -27,46 +30,172 @@ type PathPolicy struct {
 	Prefix string `hcl:",key"`
 	Policy string
 	Glob   bool
+
+	// AllowedCIDRs, AllowedDays and AllowedHours are optional conditions
+	// on the request context. If any are set, the policy only grants access
+	// when the request satisfies all of them. Days and hours are evaluated
+	// in UTC. Conditions have no effect on a deny policy.
+	AllowedCIDRs []string `hcl:"allowed_cidrs"`
+	AllowedDays  []string `hcl:"allowed_days"`
+	AllowedHours string   `hcl:"allowed_hours"`
+
+	// RequiredApprovals and ApproverPolicies place the path behind a
+	// control group. Requests that are otherwise permitted are parked
+	// until RequiredApprovals distinct tokens holding one of the
+	// ApproverPolicies have authorized them.
+	RequiredApprovals int      `hcl:"required_approvals"`
+	ApproverPolicies  []string `hcl:"approver_policies"`
+
+	// The conditions above, parsed into a form that is cheap to evaluate
+	cidrs     []*net.IPNet
+	days      map[time.Weekday]bool
+	hourStart int
+	hourEnd   int
 }
 
-// TakesPrecedence is used when multiple policies
-// collide on a path to determine which policy takes
-// precendence.
-func (p *PathPolicy) TakesPrecedence(other *PathPolicy) bool {
-	// Handle the full merge matrix
-	switch p.Policy {
-	case PathPolicyDeny:
-		// Deny always takes precendence
-		return true
-
-	case PathPolicyRead:
-		// Read never takes precedence
-		return false
-
-	case PathPolicyWrite:
-		switch other.Policy {
-		case PathPolicyRead:
-			return true
-		case PathPolicyDeny, PathPolicyWrite, PathPolicySudo:
-			return false
-		default:
-			panic("missing case")
+// HasConditions checks if the path policy carries any conditions
+// that must be met by the request.
+func (p *PathPolicy) HasConditions() bool {
+	return len(p.AllowedCIDRs) > 0 || len(p.AllowedDays) > 0 || p.AllowedHours != ""
+}
+
+// HasControlGroup checks if requests permitted by the path policy
+// must be authorized by a control group.
+func (p *PathPolicy) HasControlGroup() bool {
+	return p.RequiredApprovals > 0
+}
+
+// checkConditions is used to verify that the request connection and the
+// given time satisfy the conditions of the policy. A non-empty reason is
+// returned if a condition is not met.
+func (p *PathPolicy) checkConditions(conn *logical.Connection, now time.Time) string {
+	if len(p.cidrs) > 0 {
+		var ip net.IP
+		if conn != nil {
+			ip = net.ParseIP(conn.RemoteAddr)
+		}
+		if ip == nil {
+			return "source address unknown"
+		}
+		found := false
+		for _, cidr := range p.cidrs {
+			if cidr.Contains(ip) {
+				found = true
+				break
+			}
 		}
+		if !found {
+			return fmt.Sprintf("source address %s not in allowed CIDRs", ip)
+		}
+	}
 
-	case PathPolicySudo:
-		switch other.Policy {
-		case PathPolicyRead, PathPolicyWrite:
-			return true
-		case PathPolicyDeny, PathPolicySudo:
-			return false
-		default:
-			panic("missing case")
+	now = now.UTC()
+	if len(p.days) > 0 && !p.days[now.Weekday()] {
+		return fmt.Sprintf("%s is not an allowed day", now.Weekday())
+	}
+
+	if p.AllowedHours != "" {
+		minute := now.Hour()*60 + now.Minute()
+		var inWindow bool
+		if p.hourStart <= p.hourEnd {
+			inWindow = minute >= p.hourStart && minute < p.hourEnd
+		} else {
+			// The window wraps around midnight
+			inWindow = minute >= p.hourStart || minute < p.hourEnd
+		}
+		if !inWindow {
+			return fmt.Sprintf("%s UTC is outside the allowed hours %s",
+				now.Format("15:04"), p.AllowedHours)
+		}
+	}
+	return ""
+}
+
+// parseConditions is used to validate and parse the conditions
+// of a path policy.
+func (p *PathPolicy) parseConditions() error {
+	p.cidrs = nil
+	for _, raw := range p.AllowedCIDRs {
+		_, cidr, err := net.ParseCIDR(raw)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR '%s' for path '%s': %v", raw, p.Prefix, err)
 		}
+		p.cidrs = append(p.cidrs, cidr)
+	}
+
+	p.days = nil
+	if len(p.AllowedDays) > 0 {
+		p.days = make(map[time.Weekday]bool, len(p.AllowedDays))
+		for _, raw := range p.AllowedDays {
+			day, ok := policyWeekdays[strings.ToLower(strings.TrimSpace(raw))]
+			if !ok {
+				return fmt.Errorf("invalid day '%s' for path '%s'", raw, p.Prefix)
+			}
+			p.days[day] = true
+		}
+	}
+
+	p.hourStart, p.hourEnd = 0, 0
+	if p.AllowedHours != "" {
+		parts := strings.Split(p.AllowedHours, "-")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid hours '%s' for path '%s', expected 'HH:MM-HH:MM'",
+				p.AllowedHours, p.Prefix)
+		}
+		start, err := parseTimeOfDay(parts[0])
+		if err != nil {
+			return fmt.Errorf("invalid hours '%s' for path '%s': %v", p.AllowedHours, p.Prefix, err)
+		}
+		end, err := parseTimeOfDay(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid hours '%s' for path '%s': %v", p.AllowedHours, p.Prefix, err)
+		}
+		if start == end {
+			return fmt.Errorf("invalid hours '%s' for path '%s', window is empty",
+				p.AllowedHours, p.Prefix)
+		}
+		p.hourStart, p.hourEnd = start, end
+	}
+	return nil
+}
 
-	default:
-		panic("missing case")
+// validateControlGroup is used to validate the control group
+// settings of a path policy.
+func (p *PathPolicy) validateControlGroup() error {
+	switch {
+	case p.RequiredApprovals < 0:
+		return fmt.Errorf("required_approvals for path '%s' cannot be negative", p.Prefix)
+	case p.RequiredApprovals == 0 && len(p.ApproverPolicies) > 0:
+		return fmt.Errorf("approver_policies for path '%s' set without required_approvals", p.Prefix)
+	case p.RequiredApprovals > 0 && len(p.ApproverPolicies) == 0:
+		return fmt.Errorf("required_approvals for path '%s' set without approver_policies", p.Prefix)
 	}
-	return false
+	return nil
+}
+
+// parseTimeOfDay parses a "HH:MM" value into minutes since midnight.
+// The special value "24:00" is accepted to denote the end of the day.
+func parseTimeOfDay(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s'", raw)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// policyWeekdays maps the accepted day names to their weekday
+var policyWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
 }
 
 // Parse is used to parse the specified ACL rules into an
@@ -96,6 +225,16 @@ func Parse(rules string) (*Policy, error) {
 		default:
 			return nil, fmt.Errorf("Invalid path policy: %#v", pp)
 		}
+
+		// Check the conditions are valid
+		if err := pp.parseConditions(); err != nil {
+			return nil, fmt.Errorf("Invalid path policy: %v", err)
+		}
+
+		// Check the control group is valid
+		if err := pp.validateControlGroup(); err != nil {
+			return nil, fmt.Errorf("Invalid path policy: %v", err)
+		}
 	}
 	return p, nil
 }
@@ -16,8 +16,78 @@ const (
 
 	// policyCacheSize is the number of policies that are kept cached
 	policyCacheSize = 1024
+
+	// defaultPolicyName is the name of the built-in policy that is
+	// attached to every non-root token unless explicitly excluded
+	defaultPolicyName = "default"
 )
 
+// defaultPolicyRules are the rules the default policy is created with.
+// The policy may be modified by operators after it has been created.
+const defaultPolicyRules = `
+# Allow tokens to look up their own properties
+path "auth/token/lookup-self" {
+    policy = "read"
+}
+
+# Allow tokens to renew themselves
+path "auth/token/renew-self" {
+    policy = "write"
+}
+` + defaultPolicyCubbyholeRules + defaultPolicyCapabilitiesRules +
+	defaultPolicyControlGroupRules + defaultPolicyWrappingRules
+
+const defaultPolicyCubbyholeRules = `
+# Allow tokens to manage their own cubbyhole
+path "cubbyhole/*" {
+    policy = "write"
+}
+`
+
+const defaultPolicyCapabilitiesRules = `
+# Allow tokens to look up their own capabilities on a path
+path "auth/token/capabilities-self" {
+    policy = "write"
+}
+`
+
+const defaultPolicyControlGroupRules = `
+# Allow tokens to look up and redeem the requests they made that
+# were parked by a control group
+path "sys/control-group/lookup" {
+    policy = "write"
+}
+
+path "sys/control-group/request" {
+    policy = "write"
+}
+`
+
+const defaultPolicyWrappingRules = `
+# Allow tokens to unwrap, look up and rewrap the responses wrapped for them
+path "sys/wrapping/unwrap" {
+    policy = "write"
+}
+
+path "sys/wrapping/lookup" {
+    policy = "write"
+}
+
+path "sys/wrapping/rewrap" {
+    policy = "write"
+}
+`
+
+// defaultPolicyUpgrades are the rules that were added to the default policy
+// after it was first introduced, in the order they were added. They are
+// merged once into a default policy that was created before them.
+var defaultPolicyUpgrades = []string{
+	defaultPolicyCubbyholeRules,
+	defaultPolicyCapabilitiesRules,
+	defaultPolicyControlGroupRules,
+	defaultPolicyWrappingRules,
+}
+
 // PolicyStore is used to provide durable storage of policy, and to
 // manage ACLs associated with them.
 type PolicyStore struct {
@@ -29,6 +99,10 @@ type PolicyStore struct {
 type PolicyEntry struct {
 	Version int
 	Raw     string
+
+	// Revision is the number of defaultPolicyUpgrades that have been
+	// merged into the default policy. It is unused for other policies.
+	Revision int
 }
 
 // NewPolicyStore creates a new PolicyStore that is backed
@@ -50,6 +124,11 @@ func (c *Core) setupPolicyStore() error {
 
 	// Create the policy store
 	c.policy = NewPolicyStore(view)
+
+	// Ensure that the default policy exists
+	if err := c.policy.createDefaultPolicy(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -71,10 +150,14 @@ func (ps *PolicyStore) SetPolicy(p *Policy) error {
 	}
 
 	// Create the entry
-	entry, err := logical.StorageEntryJSON(p.Name, &PolicyEntry{
+	policyEntry := &PolicyEntry{
 		Version: 2,
 		Raw:     p.Raw,
-	})
+	}
+	if p.Name == defaultPolicyName {
+		policyEntry.Revision = len(defaultPolicyUpgrades)
+	}
+	entry, err := logical.StorageEntryJSON(p.Name, policyEntry)
 	if err != nil {
 		return fmt.Errorf("failed to create entry: %v", err)
 	}
@@ -158,6 +241,9 @@ func (ps *PolicyStore) DeletePolicy(name string) error {
 	if name == "root" {
 		return fmt.Errorf("cannot delete root policy")
 	}
+	if name == defaultPolicyName {
+		return fmt.Errorf("cannot delete default policy")
+	}
 	if err := ps.view.Delete(name); err != nil {
 		return fmt.Errorf("failed to delete policy: %v", err)
 	}
@@ -167,6 +253,77 @@ func (ps *PolicyStore) DeletePolicy(name string) error {
 	return nil
 }
 
+// createDefaultPolicy is used to create the default policy if it
+// does not yet exist. An existing default policy is left untouched
+// so that any modifications made by an operator are preserved, except
+// that the rules added to the default policy since it was created are
+// merged into it.
+func (ps *PolicyStore) createDefaultPolicy() error {
+	out, err := ps.view.Get(defaultPolicyName)
+	if err != nil {
+		return fmt.Errorf("failed to read default policy: %v", err)
+	}
+	if out == nil {
+		policy, err := Parse(defaultPolicyRules)
+		if err != nil {
+			return fmt.Errorf("failed to parse default policy: %v", err)
+		}
+		policy.Name = defaultPolicyName
+		return ps.SetPolicy(policy)
+	}
+
+	policyEntry := new(PolicyEntry)
+	if err := out.DecodeJSON(policyEntry); err != nil {
+		return fmt.Errorf("failed to decode default policy: %v", err)
+	}
+	if policyEntry.Revision >= len(defaultPolicyUpgrades) {
+		return nil
+	}
+
+	// Merge the rules the policy does not have yet. Rules for a path
+	// the policy already defines are skipped, as the operator may have
+	// chosen a different level for it.
+	policy, err := ps.GetPolicy(defaultPolicyName)
+	if err != nil {
+		return fmt.Errorf("failed to read default policy: %v", err)
+	}
+	raw := policy.Raw
+	for _, rules := range defaultPolicyUpgrades[policyEntry.Revision:] {
+		upgrade, err := Parse(rules)
+		if err != nil {
+			return fmt.Errorf("failed to parse default policy: %v", err)
+		}
+		if !policyDefinesPaths(policy, upgrade) {
+			raw += rules
+		}
+	}
+
+	policy, err = Parse(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse default policy: %v", err)
+	}
+	policy.Name = defaultPolicyName
+	return ps.SetPolicy(policy)
+}
+
+// policyDefinesPaths checks if the policy has a rule for every
+// path that the other policy has a rule for.
+func policyDefinesPaths(policy, other *Policy) bool {
+	for _, op := range other.Paths {
+		found := false
+		for _, pp := range policy.Paths {
+			if pp.Prefix == op.Prefix && pp.Glob == op.Glob {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // ACL is used to return an ACL which is built using the
 // named policies.
 func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
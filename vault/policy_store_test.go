@@ -42,6 +42,100 @@ func TestPolicyStore_Root(t *testing.T) {
 	}
 }
 
+func TestPolicyStore_Default(t *testing.T) {
+	ps := mockPolicyStore(t)
+	if err := ps.createDefaultPolicy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Get should return the default policy
+	p, err := ps.GetPolicy("default")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if p == nil || p.Name != "default" || len(p.Paths) == 0 {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	// Modifications should be preserved
+	p, err = Parse(`path "secret/*" { policy = "read" }`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p.Name = "default"
+	if err := ps.SetPolicy(p); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ps.createDefaultPolicy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ps.GetPolicy("default")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Raw != p.Raw {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Delete should fail
+	err = ps.DeletePolicy("default")
+	if err == nil || err.Error() != "cannot delete default policy" {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestPolicyStore_Default_Upgrade(t *testing.T) {
+	ps := mockPolicyStore(t)
+
+	// Put a default policy that predates the upgrades, with a modified
+	// rule for one of the paths that was added later
+	raw := `path "auth/token/lookup-self" { policy = "read" }
+path "cubbyhole/*" { policy = "deny" }
+`
+	entry, err := logical.StorageEntryJSON("default", &PolicyEntry{
+		Version: 2,
+		Raw:     raw,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ps.view.Put(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The missing rules are merged, the modified rule is preserved
+	if err := ps.createDefaultPolicy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p, err := ps.GetPolicy("default")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if p.Raw != raw+defaultPolicyCapabilitiesRules+defaultPolicyControlGroupRules+defaultPolicyWrappingRules {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	// The rules are merged only once, even if removed by an operator
+	p, err = Parse(raw)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p.Name = "default"
+	if err := ps.SetPolicy(p); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ps.createDefaultPolicy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ps.GetPolicy("default")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Raw != raw {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
 func TestPolicyStore_CRUD(t *testing.T) {
 	ps := mockPolicyStore(t)
 
@@ -3,43 +3,10 @@ package vault
 import (
 	"reflect"
 	"testing"
-)
+	"time"
 
-func TestPolicy_TakesPrecedence(t *testing.T) {
-	type tcase struct {
-		a, b       string
-		precedence bool
-	}
-	tests := []tcase{
-		tcase{PathPolicyDeny, PathPolicyDeny, true},
-		tcase{PathPolicyDeny, PathPolicyRead, true},
-		tcase{PathPolicyDeny, PathPolicyWrite, true},
-		tcase{PathPolicyDeny, PathPolicySudo, true},
-
-		tcase{PathPolicyRead, PathPolicyDeny, false},
-		tcase{PathPolicyRead, PathPolicyRead, false},
-		tcase{PathPolicyRead, PathPolicyWrite, false},
-		tcase{PathPolicyRead, PathPolicySudo, false},
-
-		tcase{PathPolicyWrite, PathPolicyDeny, false},
-		tcase{PathPolicyWrite, PathPolicyRead, true},
-		tcase{PathPolicyWrite, PathPolicyWrite, false},
-		tcase{PathPolicyWrite, PathPolicySudo, false},
-
-		tcase{PathPolicySudo, PathPolicyDeny, false},
-		tcase{PathPolicySudo, PathPolicyRead, true},
-		tcase{PathPolicySudo, PathPolicyWrite, true},
-		tcase{PathPolicySudo, PathPolicySudo, false},
-	}
-	for idx, test := range tests {
-		a := &PathPolicy{Policy: test.a}
-		b := &PathPolicy{Policy: test.b}
-		if out := a.TakesPrecedence(b); out != test.precedence {
-			t.Fatalf("bad: idx %d expect: %v out: %v",
-				idx, test.precedence, out)
-		}
-	}
-}
+	"github.com/hashicorp/vault/logical"
+)
 
 func TestPolicy_Parse(t *testing.T) {
 	p, err := Parse(rawPolicy)
@@ -52,9 +19,9 @@ func TestPolicy_Parse(t *testing.T) {
 	}
 
 	expect := []*PathPolicy{
-		&PathPolicy{"", "deny", true},
-		&PathPolicy{"stage/", "sudo", true},
-		&PathPolicy{"prod/version", "read", false},
+		&PathPolicy{Prefix: "", Policy: "deny", Glob: true},
+		&PathPolicy{Prefix: "stage/", Policy: "sudo", Glob: true},
+		&PathPolicy{Prefix: "prod/version", Policy: "read", Glob: false},
 	}
 	if !reflect.DeepEqual(p.Paths, expect) {
 		t.Fatalf("bad: %#v", p)
@@ -80,3 +47,138 @@ path "prod/version" {
 	policy = "read"
 }
 `
+
+func TestPolicy_ParseConditions(t *testing.T) {
+	p, err := Parse(conditionalPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(p.Paths) != 2 {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	pp := p.Paths[0]
+	if !pp.HasConditions() {
+		t.Fatalf("expected conditions: %#v", pp)
+	}
+	if len(pp.cidrs) != 2 {
+		t.Fatalf("bad: %#v", pp.cidrs)
+	}
+	if len(pp.days) != 5 || pp.days[time.Saturday] || !pp.days[time.Monday] {
+		t.Fatalf("bad: %#v", pp.days)
+	}
+	if pp.hourStart != 9*60 || pp.hourEnd != 17*60+30 {
+		t.Fatalf("bad: %d %d", pp.hourStart, pp.hourEnd)
+	}
+	if p.Paths[1].HasConditions() {
+		t.Fatalf("unexpected conditions: %#v", p.Paths[1])
+	}
+}
+
+func TestPolicy_ParseConditions_Invalid(t *testing.T) {
+	for _, rules := range []string{
+		`path "foo" { policy = "read" allowed_cidrs = ["10.0.0.1"] }`,
+		`path "foo" { policy = "read" allowed_days = ["funday"] }`,
+		`path "foo" { policy = "read" allowed_hours = "09:00" }`,
+		`path "foo" { policy = "read" allowed_hours = "09:00-25:00" }`,
+		`path "foo" { policy = "read" allowed_hours = "09:00-09:00" }`,
+	} {
+		if _, err := Parse(rules); err == nil {
+			t.Fatalf("expected error: %s", rules)
+		}
+	}
+}
+
+func TestPolicy_ParseControlGroup(t *testing.T) {
+	p, err := Parse(`
+path "sys/raw/*" {
+	policy = "sudo"
+	required_approvals = 2
+	approver_policies = ["security", "ops"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pp := p.Paths[0]
+	if !pp.HasControlGroup() || pp.RequiredApprovals != 2 {
+		t.Fatalf("bad: %#v", pp)
+	}
+	if !reflect.DeepEqual(pp.ApproverPolicies, []string{"security", "ops"}) {
+		t.Fatalf("bad: %#v", pp.ApproverPolicies)
+	}
+
+	for _, rules := range []string{
+		`path "foo" { policy = "read" required_approvals = -1 }`,
+		`path "foo" { policy = "read" required_approvals = 1 }`,
+		`path "foo" { policy = "read" approver_policies = ["ops"] }`,
+	} {
+		if _, err := Parse(rules); err == nil {
+			t.Fatalf("expected error: %s", rules)
+		}
+	}
+}
+
+func TestPathPolicy_CheckConditions(t *testing.T) {
+	p, err := Parse(conditionalPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pp := p.Paths[0]
+
+	// Monday, 10:15 UTC
+	monday := time.Date(2015, 8, 3, 10, 15, 0, 0, time.UTC)
+	saturday := time.Date(2015, 8, 8, 10, 15, 0, 0, time.UTC)
+	evening := time.Date(2015, 8, 3, 18, 0, 0, 0, time.UTC)
+
+	type tcase struct {
+		conn *logical.Connection
+		now  time.Time
+		ok   bool
+	}
+	tcases := []tcase{
+		{&logical.Connection{RemoteAddr: "10.0.1.5"}, monday, true},
+		{&logical.Connection{RemoteAddr: "192.168.1.1"}, monday, true},
+		{&logical.Connection{RemoteAddr: "10.0.2.5"}, monday, false},
+		{&logical.Connection{RemoteAddr: ""}, monday, false},
+		{nil, monday, false},
+		{&logical.Connection{RemoteAddr: "10.0.1.5"}, saturday, false},
+		{&logical.Connection{RemoteAddr: "10.0.1.5"}, evening, false},
+	}
+	for idx, tc := range tcases {
+		reason := pp.checkConditions(tc.conn, tc.now)
+		if (reason == "") != tc.ok {
+			t.Fatalf("bad: idx %d expect: %v reason: %s", idx, tc.ok, reason)
+		}
+	}
+
+	// Windows wrapping midnight
+	night := &PathPolicy{Policy: PathPolicyRead, AllowedHours: "22:00-06:00"}
+	if err := night.parseConditions(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reason := night.checkConditions(nil, time.Date(2015, 8, 3, 23, 0, 0, 0, time.UTC)); reason != "" {
+		t.Fatalf("bad: %s", reason)
+	}
+	if reason := night.checkConditions(nil, time.Date(2015, 8, 3, 5, 59, 0, 0, time.UTC)); reason != "" {
+		t.Fatalf("bad: %s", reason)
+	}
+	if reason := night.checkConditions(nil, time.Date(2015, 8, 3, 12, 0, 0, 0, time.UTC)); reason == "" {
+		t.Fatalf("expected failure")
+	}
+}
+
+var conditionalPolicy = `
+name = "break-glass"
+
+path "sys/raw/*" {
+	policy = "sudo"
+	allowed_cidrs = ["10.0.1.0/24", "192.168.1.1/32"]
+	allowed_days = ["mon", "tue", "Wednesday", "thu", "fri"]
+	allowed_hours = "09:00-17:30"
+}
+
+path "secret/*" {
+	policy = "read"
+}
+`
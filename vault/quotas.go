@@ -0,0 +1,440 @@
+package vault
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// quotaSubPath is the sub-path used for the quota store view.
+	// This is nested under the system view.
+	quotaSubPath = "quotas/"
+
+	// leaseCountQuotaPrefix is the prefix of the lease count quotas
+	// within the quota store view
+	leaseCountQuotaPrefix = "lease-count/"
+
+	// rateLimitQuotaPrefix is the prefix of the rate limit quotas within
+	// the quota store view
+	rateLimitQuotaPrefix = "rate-limit/"
+
+	// rateLimitPurgeSize is the number of client buckets of a rate limit
+	// quota above which the idle buckets are purged
+	rateLimitPurgeSize = 1024
+)
+
+// LeaseCountQuota limits the number of leases that can exist. The quota
+// applies to the requests under its path, or to all requests if the path
+// is empty. Without PerToken, the leases under the path are counted. With
+// PerToken, the leases held by the token making the request are counted,
+// regardless of where they were issued.
+type LeaseCountQuota struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	MaxLeases int    `json:"max_leases"`
+	PerToken  bool   `json:"per_token"`
+}
+
+// RateLimitQuota limits the rate of the requests under its path, or of
+// all requests if the path is empty, using a token bucket that allows
+// bursts of up to Burst requests and refills at Rate requests per second.
+// With PerClient, each client address has its own bucket.
+type RateLimitQuota struct {
+	Name      string  `json:"name"`
+	Path      string  `json:"path"`
+	Rate      float64 `json:"rate"`
+	Burst     int     `json:"burst"`
+	PerClient bool    `json:"per_client"`
+}
+
+// QuotaStore is used to durably store the quotas. The quotas are cached
+// in memory, since they are checked on every request.
+type QuotaStore struct {
+	view *BarrierView
+
+	leaseCount map[string]*LeaseCountQuota
+	rateLimit  map[string]*rateLimiter
+	l          sync.RWMutex
+}
+
+// NewQuotaStore creates a new QuotaStore backed by the given view, and
+// loads the existing quotas
+func NewQuotaStore(view *BarrierView) (*QuotaStore, error) {
+	qs := &QuotaStore{
+		view:       view,
+		leaseCount: make(map[string]*LeaseCountQuota),
+		rateLimit:  make(map[string]*rateLimiter),
+	}
+
+	leaseView := view.SubView(leaseCountQuotaPrefix)
+	names, err := leaseView.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lease count quotas: %v", err)
+	}
+	for _, name := range names {
+		out, err := leaseView.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lease count quota: %v", err)
+		}
+		if out == nil {
+			continue
+		}
+		q := new(LeaseCountQuota)
+		if err := out.DecodeJSON(q); err != nil {
+			return nil, fmt.Errorf("failed to decode lease count quota: %v", err)
+		}
+		qs.leaseCount[q.Name] = q
+	}
+
+	rateView := view.SubView(rateLimitQuotaPrefix)
+	names, err = rateView.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate limit quotas: %v", err)
+	}
+	for _, name := range names {
+		out, err := rateView.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rate limit quota: %v", err)
+		}
+		if out == nil {
+			continue
+		}
+		q := new(RateLimitQuota)
+		if err := out.DecodeJSON(q); err != nil {
+			return nil, fmt.Errorf("failed to decode rate limit quota: %v", err)
+		}
+		qs.rateLimit[q.Name] = newRateLimiter(q)
+	}
+	return qs, nil
+}
+
+// setupQuotas is used to initialize the quota store
+// when the vault is being unsealed.
+func (c *Core) setupQuotas() error {
+	// The quotas are managed in the root namespace, and the namespaces
+	// are checked against them with their full paths
+	if c.parent != nil {
+		c.quotas = c.parent.quotas
+		return nil
+	}
+
+	view := c.systemView.SubView(quotaSubPath)
+	qs, err := NewQuotaStore(view)
+	if err != nil {
+		return err
+	}
+	c.quotas = qs
+	return nil
+}
+
+// teardownQuotas is used to reverse setupQuotas
+// when the vault is being sealed.
+func (c *Core) teardownQuotas() error {
+	c.quotas = nil
+	return nil
+}
+
+// ListLeaseCount returns the names of the lease count quotas
+func (qs *QuotaStore) ListLeaseCount() []string {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+
+	names := make([]string, 0, len(qs.leaseCount))
+	for name := range qs.leaseCount {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetLeaseCount returns a lease count quota, or nil if there is none
+func (qs *QuotaStore) GetLeaseCount(name string) *LeaseCountQuota {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+	return qs.leaseCount[name]
+}
+
+// SetLeaseCount creates or updates a lease count quota
+func (qs *QuotaStore) SetLeaseCount(q *LeaseCountQuota) error {
+	qs.l.Lock()
+	defer qs.l.Unlock()
+
+	entry, err := logical.StorageEntryJSON(leaseCountQuotaPrefix+q.Name, q)
+	if err != nil {
+		return fmt.Errorf("failed to create entry: %v", err)
+	}
+	if err := qs.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist lease count quota: %v", err)
+	}
+	qs.leaseCount[q.Name] = q
+	return nil
+}
+
+// DeleteLeaseCount deletes a lease count quota
+func (qs *QuotaStore) DeleteLeaseCount(name string) error {
+	qs.l.Lock()
+	defer qs.l.Unlock()
+
+	if err := qs.view.Delete(leaseCountQuotaPrefix + name); err != nil {
+		return fmt.Errorf("failed to delete lease count quota: %v", err)
+	}
+	delete(qs.leaseCount, name)
+	return nil
+}
+
+// leaseCountQuotas returns the lease count quotas that apply to a path
+func (qs *QuotaStore) leaseCountQuotas(path string) []*LeaseCountQuota {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+
+	var quotas []*LeaseCountQuota
+	for _, q := range qs.leaseCount {
+		if strings.HasPrefix(path, q.Path) {
+			quotas = append(quotas, q)
+		}
+	}
+	return quotas
+}
+
+// ListRateLimit returns the names of the rate limit quotas
+func (qs *QuotaStore) ListRateLimit() []string {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+
+	names := make([]string, 0, len(qs.rateLimit))
+	for name := range qs.rateLimit {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetRateLimit returns a rate limit quota along with the number of
+// requests it blocked since it was loaded, or nil if there is none
+func (qs *QuotaStore) GetRateLimit(name string) (*RateLimitQuota, uint64) {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+
+	rl, ok := qs.rateLimit[name]
+	if !ok {
+		return nil, 0
+	}
+	rl.l.Lock()
+	defer rl.l.Unlock()
+	return rl.quota, rl.blocked
+}
+
+// SetRateLimit creates or updates a rate limit quota. This resets the
+// state of its buckets.
+func (qs *QuotaStore) SetRateLimit(q *RateLimitQuota) error {
+	qs.l.Lock()
+	defer qs.l.Unlock()
+
+	entry, err := logical.StorageEntryJSON(rateLimitQuotaPrefix+q.Name, q)
+	if err != nil {
+		return fmt.Errorf("failed to create entry: %v", err)
+	}
+	if err := qs.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist rate limit quota: %v", err)
+	}
+	qs.rateLimit[q.Name] = newRateLimiter(q)
+	return nil
+}
+
+// DeleteRateLimit deletes a rate limit quota
+func (qs *QuotaStore) DeleteRateLimit(name string) error {
+	qs.l.Lock()
+	defer qs.l.Unlock()
+
+	if err := qs.view.Delete(rateLimitQuotaPrefix + name); err != nil {
+		return fmt.Errorf("failed to delete rate limit quota: %v", err)
+	}
+	delete(qs.rateLimit, name)
+	return nil
+}
+
+// allow checks a request against the rate limit quotas applying to its
+// path, and returns how long to wait before retrying if it is rejected
+func (qs *QuotaStore) allow(path, remoteAddr string, now time.Time) (bool, time.Duration) {
+	qs.l.RLock()
+	defer qs.l.RUnlock()
+
+	for _, rl := range qs.rateLimit {
+		if !strings.HasPrefix(path, rl.quota.Path) {
+			continue
+		}
+		if ok, wait := rl.allow(remoteAddr, now); !ok {
+			return false, wait
+		}
+	}
+	return true, 0
+}
+
+// AllowRequest checks a request to the given path from the given client
+// address against the rate limit quotas. If the request is rejected, it
+// returns how long to wait before retrying. The paths within namespaces
+// are prefixed with the namespace. Requests to the system backends are
+// exempt, so that the quotas can always be managed.
+func (c *Core) AllowRequest(path, remoteAddr string) (bool, time.Duration) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed || c.standby || c.quotas == nil {
+		return true, 0
+	}
+	if _, rest := c.requestNamespace(path); strings.HasPrefix(rest, "sys/") {
+		return true, 0
+	}
+	return c.quotas.allow(path, remoteAddr, time.Now())
+}
+
+// rateLimiter holds the token buckets of a rate limit quota
+type rateLimiter struct {
+	quota     *RateLimitQuota
+	buckets   map[string]*tokenBucket
+	blocked   uint64
+	lastPurge time.Time
+	l         sync.Mutex
+}
+
+func newRateLimiter(q *RateLimitQuota) *rateLimiter {
+	return &rateLimiter{
+		quota:   q,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the bucket of the client, or from the shared
+// bucket unless the quota is per client
+func (rl *rateLimiter) allow(remoteAddr string, now time.Time) (bool, time.Duration) {
+	rl.l.Lock()
+	defer rl.l.Unlock()
+
+	key := ""
+	if rl.quota.PerClient {
+		key = remoteAddr
+	}
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.quota.Burst), last: now}
+		rl.buckets[key] = b
+	}
+
+	ok, wait := b.take(rl.quota.Rate, rl.quota.Burst, now)
+	if !ok {
+		rl.blocked++
+		metrics.IncrCounter([]string{"quota", "rate_limit", "violation"}, 1)
+		metrics.IncrCounter([]string{"quota", "rate_limit", rl.quota.Name, "violation"}, 1)
+	}
+
+	// Purge the buckets of the idle clients, which are full again
+	if len(rl.buckets) > rateLimitPurgeSize && now.Sub(rl.lastPurge) > refillTime(rl.quota.Rate, rl.quota.Burst) {
+		for key, b := range rl.buckets {
+			if b.refill(rl.quota.Rate, rl.quota.Burst, now) >= float64(rl.quota.Burst) {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.lastPurge = now
+	}
+	return ok, wait
+}
+
+// tokenBucket is a bucket of request tokens that refills over time
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last refill, up to the
+// burst size, and returns the resulting number of tokens
+func (b *tokenBucket) refill(rate float64, burst int, now time.Time) float64 {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+	return b.tokens
+}
+
+// refillTime returns how long it takes to fill an empty bucket
+func refillTime(rate float64, burst int) time.Duration {
+	return time.Duration(float64(burst) / rate * float64(time.Second))
+}
+
+// take takes a token from the bucket if there is one, and otherwise
+// returns how long it takes until there is one
+func (b *tokenBucket) take(rate float64, burst int, now time.Time) (bool, time.Duration) {
+	if b.refill(rate, burst, now) >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	missing := 1 - b.tokens
+	return false, time.Duration(missing / rate * float64(time.Second))
+}
+
+// checkLeaseCountQuotas is used to reject a request that may create a
+// lease if a lease count quota applying to it is reached. Otherwise a slot
+// is reserved under the quotas, so that concurrent requests cannot exceed
+// them, and the reservation must be released once the request has been
+// handled. Requests that cannot create a lease are not checked, and nil
+// is returned for them.
+func (c *Core) checkLeaseCountQuotas(req *logical.Request) (*leaseReservation, error) {
+	if !mayCreateLease(req) {
+		return nil, nil
+	}
+	path := req.Path
+	if c.namespace != nil {
+		path = c.namespace.Path + path
+	}
+	quotas := c.quotas.leaseCountQuotas(path)
+	if len(quotas) == 0 {
+		return nil, nil
+	}
+
+	r, q := c.expiration.reserveLease(quotas, req.ClientToken)
+	if q != nil {
+		metrics.IncrCounter([]string{"quota", "lease_count", "violation"}, 1)
+		return nil, &LeaseCountQuotaError{Quota: q}
+	}
+	return r, nil
+}
+
+// mayCreateLease returns whether a request may create a lease. Only reads
+// and writes return secrets, and the system backend is exempt so that the
+// quotas can always be managed. Within the credential backends, only the
+// creation of tokens is leased, and the cubbyhole and identity backends
+// never return leases.
+func mayCreateLease(req *logical.Request) bool {
+	if req.Operation != logical.ReadOperation && req.Operation != logical.WriteOperation {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(req.Path, "sys/"),
+		strings.HasPrefix(req.Path, "cubbyhole/"),
+		strings.HasPrefix(req.Path, "identity/"):
+		return false
+	case strings.HasPrefix(req.Path, "auth/"):
+		return strings.HasPrefix(req.Path, "auth/token/create")
+	}
+	return true
+}
+
+// LeaseCountQuotaError is returned when a request is rejected by a lease
+// count quota
+type LeaseCountQuotaError struct {
+	Quota *LeaseCountQuota
+}
+
+func (e *LeaseCountQuotaError) Error() string {
+	if e.Quota.PerToken {
+		return fmt.Sprintf("lease count quota '%s' exceeded: the token holds the maximum of %d leases",
+			e.Quota.Name, e.Quota.MaxLeases)
+	}
+	return fmt.Sprintf("lease count quota '%s' exceeded: the maximum of %d leases exist under '%s'",
+		e.Quota.Name, e.Quota.MaxLeases, e.Quota.Path)
+}
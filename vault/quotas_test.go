@@ -0,0 +1,328 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestQuotaStore_LeaseCount(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	qs := c.quotas
+
+	q := &LeaseCountQuota{Name: "db", Path: "secret/", MaxLeases: 10}
+	if err := qs.SetLeaseCount(q); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := qs.ListLeaseCount(); !reflect.DeepEqual(names, []string{"db"}) {
+		t.Fatalf("bad: %v", names)
+	}
+
+	// The quotas are loaded by a new store
+	qs2, err := NewQuotaStore(qs.view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out := qs2.GetLeaseCount("db"); !reflect.DeepEqual(out, q) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	if quotas := qs.leaseCountQuotas("secret/foo"); len(quotas) != 1 {
+		t.Fatalf("bad: %#v", quotas)
+	}
+	if quotas := qs.leaseCountQuotas("other/foo"); len(quotas) != 0 {
+		t.Fatalf("bad: %#v", quotas)
+	}
+
+	if err := qs.DeleteLeaseCount("db"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out := qs.GetLeaseCount("db"); out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+	qs2, err = NewQuotaStore(qs.view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := qs2.ListLeaseCount(); len(names) != 0 {
+		t.Fatalf("bad: %v", names)
+	}
+}
+
+func TestCore_LeaseCountQuota(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testWaitLeaseCounts(t, c.expiration)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	read := func(token string) error {
+		req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = token
+		_, err := c.HandleRequest(req)
+		return err
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/secret")
+	req.Data["path"] = "secret"
+	req.Data["max_leases"] = 2
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The quota is reached after two leases
+	for i := 0; i < 2; i++ {
+		if err := read(root); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := read(root); err != logical.ErrQuotaExceeded {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Writes to the system backend are exempt
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/secret")
+	req.Data["max_leases"] = 3
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := read(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A per token quota counts the leases of the token only
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/lease-count/secret")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/token")
+	req.Data["max_leases"] = 3
+	req.Data["per_token"] = true
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := read(root); err != logical.ErrQuotaExceeded {
+		t.Fatalf("err: %v", err)
+	}
+	if err := read(resp.Auth.ClientToken); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Quotas must apply to a mount and allow leases
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/bad")
+	req.Data["path"] = "nope/"
+	req.Data["max_leases"] = 1
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+	req.Data["path"] = "secret/"
+	req.Data["max_leases"] = 0
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_LeaseCountQuota_Global(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testWaitLeaseCounts(t, c.expiration)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	child := resp.Auth.ClientToken
+
+	// A global quota reached by the existing leases
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/global")
+	req.Data["max_leases"] = c.expiration.Count("")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrQuotaExceeded {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests that cannot create a lease are not blocked
+	req = logical.TestRequest(t, logical.WriteOperation, "cubbyhole/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = child
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/revoke/"+child)
+	req.ClientToken = child
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_LeaseCountQuota_Namespace(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	admin := testCreateNamespace(t, c, root, "", "ns1")
+	testWaitLeaseCounts(t, c.expiration)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "ns1/auth/token/create")
+	req.ClientToken = admin
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The leases of the namespace are counted by the root
+	if n := c.expiration.Count("ns1/"); n != 1 || c.expiration.Count("ns1/auth/token/") != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// A global quota of the root applies to the namespace
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/global")
+	req.Data["max_leases"] = c.expiration.Count("")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "ns1/auth/token/create")
+	req.ClientToken = admin
+	if _, err := c.HandleRequest(req); err != logical.ErrQuotaExceeded {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/lease-count/global")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A quota on a mount of the namespace only applies to it
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/lease-count/ns1")
+	req.Data["path"] = "ns1/auth/token"
+	req.Data["max_leases"] = c.expiration.Count("ns1/auth/token/")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "ns1/auth/token/create")
+	req.ClientToken = admin
+	if _, err := c.HandleRequest(req); err != logical.ErrQuotaExceeded {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestQuotaStore_RateLimit(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	qs := c.quotas
+
+	q := &RateLimitQuota{Name: "login", Path: "auth/", Rate: 2, Burst: 3, PerClient: true}
+	if err := qs.SetRateLimit(q); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A burst is allowed, after which requests wait for a token
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := qs.allow("auth/userpass/login/foo", "1.2.3.4", now); !ok {
+			t.Fatalf("rejected %d", i)
+		}
+	}
+	ok, wait := qs.allow("auth/userpass/login/foo", "1.2.3.4", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("bad: %v %v", ok, wait)
+	}
+
+	// Other clients and paths are not limited
+	if ok, _ := qs.allow("auth/userpass/login/foo", "5.6.7.8", now); !ok {
+		t.Fatalf("rejected")
+	}
+	if ok, _ := qs.allow("secret/foo", "1.2.3.4", now); !ok {
+		t.Fatalf("rejected")
+	}
+
+	// The bucket refills over time
+	if ok, _ := qs.allow("auth/userpass/login/foo", "1.2.3.4", now.Add(wait)); !ok {
+		t.Fatalf("rejected")
+	}
+	if _, blocked := qs.GetRateLimit("login"); blocked != 1 {
+		t.Fatalf("bad: %d", blocked)
+	}
+
+	// The quotas are loaded by a new store
+	qs2, err := NewQuotaStore(qs.view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, _ := qs2.GetRateLimit("login"); !reflect.DeepEqual(out, q) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	if err := qs.DeleteRateLimit("login"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := qs.ListRateLimit(); len(names) != 0 {
+		t.Fatalf("bad: %v", names)
+	}
+}
+
+func TestCore_AllowRequest(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/quotas/rate-limit/global")
+	req.Data["rate"] = 0.001
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The burst defaults to a second of requests
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/quotas/rate-limit/global")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["burst"] != 1 || resp.Data["path"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if ok, _ := c.AllowRequest("secret/foo", "1.2.3.4"); !ok {
+		t.Fatalf("rejected")
+	}
+	if ok, wait := c.AllowRequest("secret/foo", "5.6.7.8"); ok || wait <= 0 {
+		t.Fatalf("bad: %v %v", ok, wait)
+	}
+
+	// The system backend is exempt
+	if ok, _ := c.AllowRequest("sys/quotas/rate-limit/global", "1.2.3.4"); !ok {
+		t.Fatalf("rejected")
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/quotas/rate-limit/bad")
+	req.Data["rate"] = 0
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
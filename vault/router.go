@@ -74,10 +74,63 @@ func (r *Router) Mount(backend logical.Backend, prefix, salt string, view *Barri
 func (r *Router) Unmount(prefix string) error {
 	r.l.Lock()
 	defer r.l.Unlock()
-	r.root.Delete(prefix)
+	if raw, ok := r.root.Delete(prefix); ok {
+		cleanupBackend(raw.(*mountEntry).backend)
+	}
 	return nil
 }
 
+// ReplaceBackend swaps the backend mounted at a prefix, keeping its
+// view. The previous backend is cleaned up.
+func (r *Router) ReplaceBackend(prefix string, backend logical.Backend) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	raw, ok := r.root.Get(prefix)
+	if !ok {
+		return fmt.Errorf("no mount at '%s'", prefix)
+	}
+	me := raw.(*mountEntry)
+
+	paths := backend.SpecialPaths()
+	if paths == nil {
+		paths = new(logical.Paths)
+	}
+
+	old := me.backend
+	r.root.Insert(prefix, &mountEntry{
+		tainted:    me.tainted,
+		salt:       me.salt,
+		backend:    backend,
+		view:       me.view,
+		rootPaths:  pathsToRadix(paths.Root),
+		loginPaths: pathsToRadix(paths.Unauthenticated),
+	})
+	cleanupBackend(old)
+	return nil
+}
+
+// Cleanup releases the resources of all the mounted backends. It is
+// used before the router is discarded when the vault is sealed.
+func (r *Router) Cleanup() {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.root.Walk(func(k string, raw interface{}) bool {
+		cleanupBackend(raw.(*mountEntry).backend)
+		return false
+	})
+}
+
+// cleanupBackend releases the resources held by a backend, such as the
+// process of a plugin, if it has any
+func cleanupBackend(backend logical.Backend) {
+	if b, ok := backend.(interface {
+		Cleanup()
+	}); ok {
+		b.Cleanup()
+	}
+}
+
 // Remount is used to change the mount location of a logical backend
 func (r *Router) Remount(src, dst string) error {
 	r.l.Lock()
@@ -183,15 +236,20 @@ func (r *Router) Route(req *logical.Request) (*logical.Response, error) {
 	// Attach the storage view for the request
 	req.Storage = me.view
 
-	// Hash the request token unless this is the token backend
+	// Hash the request token unless this is the token backend, the
+	// cubbyhole backend, which salts the token like the token store does,
+	// or the system backend, which is part of the core
+	systemPath := strings.HasPrefix(original, "sys/")
 	clientToken := req.ClientToken
-	if !strings.HasPrefix(original, "auth/token/") {
+	if !strings.HasPrefix(original, "auth/token/") &&
+		!strings.HasPrefix(original, cubbyholeMountPath) && !systemPath {
 		req.ClientToken = me.SaltID(req.ClientToken)
 	}
 
-	// If the request is not a login path, then clear the connection
+	// If the request is not a login path or a system path, then clear
+	// the connection
 	originalConn := req.Connection
-	if !loginPath {
+	if !loginPath && !systemPath {
 		req.Connection = nil
 	}
 
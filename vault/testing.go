@@ -85,11 +85,11 @@ func TestKeyCopy(key []byte) []byte {
 
 type noopAudit struct{}
 
-func (n *noopAudit) LogRequest(a *logical.Auth, r *logical.Request, e error) error {
+func (n *noopAudit) LogRequest(a *logical.Auth, r *logical.Request, e error, o *audit.LogOptions) error {
 	return nil
 }
 
-func (n *noopAudit) LogResponse(a *logical.Auth, r *logical.Request, re *logical.Response, err error) error {
+func (n *noopAudit) LogResponse(a *logical.Auth, r *logical.Request, re *logical.Response, err error, o *audit.LogOptions) error {
 	return nil
 }
 
@@ -1,10 +1,16 @@
 package vault
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -27,23 +33,54 @@ const (
 	// tokenSubPath is the sub-path used for the token store
 	// view. This is nested under the system view.
 	tokenSubPath = "token/"
+
+	// rolesPrefix is the prefix used to store token roles
+	rolesPrefix = "roles/"
+
+	// accessorPrefix is the prefix used to store tokens for their
+	// secondary accessor based index
+	accessorPrefix = "accessor/"
+
+	// wrappedPrefix is the prefix used to store the responses wrapped
+	// by response wrapping tokens
+	wrappedPrefix = "wrapped/"
+
+	// batchTokenPrefix is prepended to batch tokens so that they can
+	// be told apart from service tokens without decrypting them
+	batchTokenPrefix = "b."
+
+	// batchTokenKeyContext is the context used to derive the key
+	// protecting batch tokens from the barrier keyring
+	batchTokenKeyContext = "token-store-batch"
 )
 
 var (
 	// displayNameSanitize is used to sanitize a display name given to a token.
 	displayNameSanitize = regexp.MustCompile("[^a-zA-Z0-9-]")
+
+	// pathSuffixSanitize is used to ensure a role's path suffix is valid.
+	pathSuffixSanitize = regexp.MustCompile("^[a-zA-Z0-9-+_./]*$")
 )
 
+// roleNameRegex matches the valid names of a token role
+const roleNameRegex = `\w(([\w-.]+)?\w)?`
+
 // TokenStore is used to manage client tokens. Tokens are used for
 // clients to authenticate, and each token is mapped to an applicable
 // set of policy which is used for authorization.
 type TokenStore struct {
 	*framework.Backend
 
+	core *Core
 	view *BarrierView
 	salt *salt.Salt
 
 	expiration *ExpirationManager
+
+	// tidyLock prevents a tidy from removing the indexes of a token
+	// that is being created. Creation holds it for reading, while a tidy
+	// only holds it for writing to delete a single entry.
+	tidyLock sync.RWMutex
 }
 
 // NewTokenStore is used to construct a token store that is
@@ -54,6 +91,7 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 
 	// Initialize the store
 	t := &TokenStore{
+		core: c,
 		view: view,
 	}
 
@@ -69,11 +107,15 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 		// Allow a token lease to be extended indefinitely, but each time for only
 		// as much as the original lease allowed for. If the lease has a 1 hour expiration,
 		// it can only be extended up to another hour each time this means.
-		AuthRenew: framework.LeaseExtend(0, 0, true),
+		// Tokens created against a role are renewed according to the role.
+		AuthRenew: t.authRenew,
 
 		PathsSpecial: &logical.Paths{
 			Root: []string{
 				"revoke-prefix/*",
+				"roles/*",
+				"accessors/*",
+				"tidy",
 			},
 
 			Unauthenticated: []string{
@@ -93,6 +135,90 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 				HelpDescription: strings.TrimSpace(tokenCreateHelp),
 			},
 
+			&framework.Path{
+				Pattern: "create/(?P<role_name>" + roleNameRegex + ")",
+
+				Fields: map[string]*framework.FieldSchema{
+					"role_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the role to create the token against",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: t.handleCreateAgainstRole,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenCreateRoleHelp),
+				HelpDescription: strings.TrimSpace(tokenCreateRoleHelp),
+			},
+
+			&framework.Path{
+				Pattern: "roles/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: t.handleRoleList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenListRolesHelp),
+				HelpDescription: strings.TrimSpace(tokenListRolesHelp),
+			},
+
+			&framework.Path{
+				Pattern: "roles/(?P<role_name>" + roleNameRegex + ")",
+
+				Fields: map[string]*framework.FieldSchema{
+					"role_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the role",
+					},
+
+					"allowed_policies": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Comma-separated list of policies tokens may be created with",
+					},
+
+					"orphan": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If true, tokens created against this role will be orphan tokens",
+					},
+
+					"default_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "The lease of tokens that do not request one",
+					},
+
+					"max_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "The maximum lifetime of tokens, including renewals",
+					},
+
+					"period": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "If set, tokens are renewed for this period on each renewal and have no maximum lifetime",
+					},
+
+					"path_suffix": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Suffix appended to the path of created tokens, usable with revoke-prefix",
+					},
+
+					"bound_cidrs": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Comma-separated list of CIDR blocks tokens may be used from",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   t.handleRoleRead,
+					logical.WriteOperation:  t.handleRoleWrite,
+					logical.DeleteOperation: t.handleRoleDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenRolesHelp),
+				HelpDescription: strings.TrimSpace(tokenRolesHelp),
+			},
+
 			&framework.Path{
 				Pattern: "lookup/(?P<token>.+)",
 
@@ -111,6 +237,46 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 				HelpDescription: strings.TrimSpace(tokenLookupHelp),
 			},
 
+			&framework.Path{
+				Pattern: "lookup-accessor/(?P<accessor>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"accessor": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Accessor of the token to lookup",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: t.handleLookupAccessor,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenLookupAccessorHelp),
+				HelpDescription: strings.TrimSpace(tokenLookupAccessorHelp),
+			},
+
+			&framework.Path{
+				Pattern: "accessors/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: t.handleAccessorList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenListAccessorsHelp),
+				HelpDescription: strings.TrimSpace(tokenListAccessorsHelp),
+			},
+
+			&framework.Path{
+				Pattern: "tidy$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: t.handleTidy,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenTidyHelp),
+				HelpDescription: strings.TrimSpace(tokenTidyHelp),
+			},
+
 			&framework.Path{
 				Pattern: "lookup-self$",
 
@@ -129,6 +295,24 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 				HelpDescription: strings.TrimSpace(tokenLookupHelp),
 			},
 
+			&framework.Path{
+				Pattern: "capabilities-self$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Path to check the capabilities on",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: t.handleCapabilitiesSelf,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenCapabilitiesSelfHelp),
+				HelpDescription: strings.TrimSpace(tokenCapabilitiesSelfHelp),
+			},
+
 			&framework.Path{
 				Pattern: "revoke/(?P<token>.+)",
 
@@ -147,6 +331,24 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 				HelpDescription: strings.TrimSpace(tokenRevokeHelp),
 			},
 
+			&framework.Path{
+				Pattern: "revoke-accessor/(?P<accessor>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"accessor": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Accessor of the token to revoke",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: t.handleRevokeAccessor,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenRevokeAccessorHelp),
+				HelpDescription: strings.TrimSpace(tokenRevokeAccessorHelp),
+			},
+
 			&framework.Path{
 				Pattern: "revoke-orphan/(?P<token>.+)",
 
@@ -183,6 +385,28 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 				HelpDescription: strings.TrimSpace(tokenRevokePrefixHelp),
 			},
 
+			&framework.Path{
+				Pattern: "renew-self$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Token to renew",
+					},
+					"increment": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "The desired increment in seconds to the token expiration",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.WriteOperation: t.handleRenew,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenRenewSelfHelp),
+				HelpDescription: strings.TrimSpace(tokenRenewSelfHelp),
+			},
+
 			&framework.Path{
 				Pattern: "renew/(?P<token>.+)",
 
@@ -212,13 +436,85 @@ func NewTokenStore(c *Core) (*TokenStore, error) {
 
 // TokenEntry is used to represent a given token
 type TokenEntry struct {
-	ID          string            // ID of this entry, generally a random UUID
-	Parent      string            // Parent token, used for revocation trees
-	Policies    []string          // Which named policies should be used
-	Path        string            // Used for audit trails, this is something like "auth/user/login"
-	Meta        map[string]string // Used for auditing. This could include things like "source", "user", "ip"
-	DisplayName string            // Used for operators to be able to associate with the source
-	NumUses     int               // Used to restrict the number of uses (zero is unlimited). This is to support one-time-tokens (generalized).
+	ID             string            // ID of this entry, generally a random UUID
+	Accessor       string            // Non-secret identifier of this entry, used for lookup and revocation without the ID
+	Parent         string            // Parent token, used for revocation trees
+	Policies       []string          // Which named policies should be used
+	Path           string            // Used for audit trails, this is something like "auth/user/login"
+	Meta           map[string]string // Used for auditing. This could include things like "source", "user", "ip"
+	DisplayName    string            // Used for operators to be able to associate with the source
+	NumUses        int               // Used to restrict the number of uses (zero is unlimited). This is to support one-time-tokens (generalized).
+	Period         time.Duration     // If set, the token is periodic and each renewal sets its lease to exactly the period
+	ExplicitMaxTTL time.Duration     // If set, the token cannot be renewed beyond this lifetime, regardless of the mount limits
+	ExpireTime     time.Time         // Only set for batch tokens, which expire at this time instead of through a lease
+	BoundCIDRs     []string          // If set, the token may only be used by clients within these CIDR blocks
+	EntityID       string            // If set, the identity entity the token belongs to, whose policies are granted as well
+}
+
+// IsBatch returns if the entry is a batch token. Batch tokens are never
+// persisted; the entry is encrypted into the token itself.
+func (te *TokenEntry) IsBatch() bool {
+	return strings.HasPrefix(te.ID, batchTokenPrefix)
+}
+
+// batchTokenEntry is the content of a batch token that is encrypted
+// into the token itself
+type batchTokenEntry struct {
+	// SaltedParent is the salted ID of the parent token, so that the
+	// parent cannot be recovered from the batch token
+	SaltedParent string `json:"salted_parent,omitempty"`
+
+	// Parent is the ID of the parent token in batch tokens created
+	// before the parent was salted. It is only read.
+	Parent string `json:"parent,omitempty"`
+
+	Policies    []string          `json:"policies"`
+	Path        string            `json:"path"`
+	Meta        map[string]string `json:"meta"`
+	DisplayName string            `json:"display_name"`
+	ExpireTime  time.Time         `json:"expire_time"`
+	BoundCIDRs  []string          `json:"bound_cidrs"`
+	EntityID    string            `json:"entity_id"`
+
+	// NamespaceID is the ID of the namespace the token was created in,
+	// empty for the root namespace. The token is only valid within it.
+	NamespaceID string `json:"namespace_id,omitempty"`
+}
+
+// accessorEntry is stored under the accessor index and maps an
+// accessor back to its token
+type accessorEntry struct {
+	TokenID    string `json:"token_id"`
+	AccessorID string `json:"accessor_id"`
+}
+
+// tsRoleEntry contains token store role information
+type tsRoleEntry struct {
+	// The name of the role. Embedded so it can be used for pathing
+	Name string `json:"name"`
+
+	// The policies that tokens created against this role may have. If
+	// empty, the usual subset-of-parent rule applies.
+	AllowedPolicies []string `json:"allowed_policies"`
+
+	// If true, tokens created against this role will be orphan tokens
+	Orphan bool `json:"orphan"`
+
+	// The lease of tokens created against this role if none is requested
+	DefaultTTL time.Duration `json:"default_ttl"`
+
+	// The maximum lifetime of tokens created against this role
+	MaxTTL time.Duration `json:"max_ttl"`
+
+	// If set, tokens created against this role are periodic
+	Period time.Duration `json:"period"`
+
+	// If set, a suffix appended to the path of created tokens
+	PathSuffix string `json:"path_suffix"`
+
+	// If set, tokens created against this role may only be used by
+	// clients within these CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs"`
 }
 
 // SetExpirationManager is used to provide the token store with
@@ -254,8 +550,19 @@ func (ts *TokenStore) Create(entry *TokenEntry) error {
 	if entry.ID == "" {
 		entry.ID = uuid.GenerateUUID()
 	}
+	if entry.IsBatch() {
+		return fmt.Errorf("token ID cannot begin with '%s'", batchTokenPrefix)
+	}
 	saltedId := ts.SaltID(entry.ID)
 
+	// The indexes are written before the primary entry, so they must
+	// not be tidied until it exists
+	ts.tidyLock.RLock()
+	defer ts.tidyLock.RUnlock()
+
+	// Generate the accessor
+	entry.Accessor = uuid.GenerateUUID()
+
 	// Marshal the entry
 	enc, err := json.Marshal(entry)
 	if err != nil {
@@ -284,15 +591,179 @@ func (ts *TokenStore) Create(entry *TokenEntry) error {
 		}
 	}
 
+	// Write the accessor index, for the same reason before the primary index
+	le, err := logical.StorageEntryJSON(accessorPrefix+ts.SaltID(entry.Accessor), &accessorEntry{
+		TokenID:    entry.ID,
+		AccessorID: entry.Accessor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode accessor entry: %v", err)
+	}
+	if err := ts.view.Put(le); err != nil {
+		return fmt.Errorf("failed to persist accessor entry: %v", err)
+	}
+
 	// Write the primary ID
 	path := lookupPrefix + saltedId
-	le := &logical.StorageEntry{Key: path, Value: enc}
+	le = &logical.StorageEntry{Key: path, Value: enc}
 	if err := ts.view.Put(le); err != nil {
 		return fmt.Errorf("failed to persist entry: %v", err)
 	}
 	return nil
 }
 
+// createBatch is used to create a new batch token. Instead of being
+// persisted, the entry is encrypted with a key derived from the barrier
+// keyring and the ciphertext becomes the ID of the token.
+func (ts *TokenStore) createBatch(entry *TokenEntry) error {
+	defer metrics.MeasureSince([]string{"token", "create_batch"}, time.Now())
+	var saltedParent string
+	if entry.Parent != "" {
+		saltedParent = ts.SaltID(entry.Parent)
+	}
+	plain, err := json.Marshal(&batchTokenEntry{
+		SaltedParent: saltedParent,
+		Policies:     entry.Policies,
+		Path:         entry.Path,
+		Meta:         entry.Meta,
+		DisplayName:  entry.DisplayName,
+		ExpireTime:   entry.ExpireTime,
+		BoundCIDRs:   entry.BoundCIDRs,
+		EntityID:     entry.EntityID,
+		NamespaceID:  ts.namespaceID(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
+	// Encrypt using the key derived from the active term
+	info, err := ts.core.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+	term := uint32(info.Term)
+	gcm, err := ts.batchTokenAEAD(term)
+	if err != nil {
+		return err
+	}
+
+	// The output is the key term followed by the nonce and the sealed
+	// entry. The term is authenticated as additional data.
+	size := termSize + gcm.NonceSize()
+	out := make([]byte, size, size+len(plain)+gcm.Overhead())
+	binary.BigEndian.PutUint32(out[:termSize], term)
+	nonce := out[termSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	out = gcm.Seal(out, nonce, plain, out[:termSize])
+
+	entry.ID = batchTokenPrefix + base64.URLEncoding.EncodeToString(out)
+	entry.Accessor = ""
+	return nil
+}
+
+// lookupBatch is used to decrypt a batch token. Tokens that cannot be
+// decrypted or that have expired are treated as not found. A batch token
+// with a parent is only valid as long as the parent is.
+func (ts *TokenStore) lookupBatch(id string) (*TokenEntry, error) {
+	raw, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(id, batchTokenPrefix))
+	if err != nil || len(raw) < termSize {
+		return nil, nil
+	}
+
+	term := binary.BigEndian.Uint32(raw[:termSize])
+	gcm, err := ts.batchTokenAEAD(term)
+	if err != nil {
+		return nil, nil
+	}
+	if len(raw) < termSize+gcm.NonceSize() {
+		return nil, nil
+	}
+	nonce := raw[termSize : termSize+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, raw[termSize+gcm.NonceSize():], raw[:termSize])
+	if err != nil {
+		return nil, nil
+	}
+
+	var out batchTokenEntry
+	if err := json.Unmarshal(plain, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode batch token: %v", err)
+	}
+
+	// Batch tokens expire purely from their embedded TTL, and are not
+	// valid outside of the namespace they were created in
+	if !time.Now().UTC().Before(out.ExpireTime) {
+		return nil, nil
+	}
+	if out.NamespaceID != ts.namespaceID() {
+		return nil, nil
+	}
+
+	var parent *TokenEntry
+	switch {
+	case out.SaltedParent != "":
+		parent, err = ts.lookupSalted(out.SaltedParent)
+	case out.Parent != "":
+		parent, err = ts.Lookup(out.Parent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var parentID string
+	if out.SaltedParent != "" || out.Parent != "" {
+		if parent == nil {
+			return nil, nil
+		}
+		parentID = parent.ID
+	}
+
+	return &TokenEntry{
+		ID:          id,
+		Parent:      parentID,
+		Policies:    out.Policies,
+		Path:        out.Path,
+		Meta:        out.Meta,
+		DisplayName: out.DisplayName,
+		ExpireTime:  out.ExpireTime,
+		BoundCIDRs:  out.BoundCIDRs,
+		EntityID:    out.EntityID,
+	}, nil
+}
+
+// batchTokenAEAD returns the AEAD protecting batch tokens of the given
+// term. The barrier of a namespace derives its keys from the root keyring,
+// so the ID of the namespace is part of the context to keep the keys of
+// the namespaces apart.
+func (ts *TokenStore) batchTokenAEAD(term uint32) (cipher.AEAD, error) {
+	context := batchTokenKeyContext
+	if id := ts.namespaceID(); id != "" {
+		context += "/" + id
+	}
+	key, err := ts.core.barrier.DeriveKey(term, []byte(context))
+	if err != nil {
+		return nil, err
+	}
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(aesCipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize GCM mode")
+	}
+	return gcm, nil
+}
+
+// namespaceID returns the ID of the namespace of the token store, which
+// is empty for the root namespace
+func (ts *TokenStore) namespaceID() string {
+	if ts.core.namespace == nil {
+		return ""
+	}
+	return ts.core.namespace.ID
+}
+
 // UseToken is used to manage restricted use tokens and decrement
 // their available uses.
 func (ts *TokenStore) UseToken(te *TokenEntry) error {
@@ -330,12 +801,46 @@ func (ts *TokenStore) UseToken(te *TokenEntry) error {
 	return nil
 }
 
+// setPath is used to change the path a token was issued at, when its
+// credential backend is remounted. Batch tokens are left unchanged since
+// their entry is not persisted.
+func (ts *TokenStore) setPath(id, path string) error {
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return nil
+	}
+	saltedId := ts.SaltID(id)
+	te, err := ts.lookupSalted(saltedId)
+	if err != nil {
+		return err
+	}
+	if te == nil {
+		return nil
+	}
+	te.Path = path
+
+	// Marshal the entry
+	enc, err := json.Marshal(te)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
+	// Write under the primary ID
+	le := &logical.StorageEntry{Key: lookupPrefix + saltedId, Value: enc}
+	if err := ts.view.Put(le); err != nil {
+		return fmt.Errorf("failed to persist entry: %v", err)
+	}
+	return nil
+}
+
 // Lookup is used to find a token given its ID
 func (ts *TokenStore) Lookup(id string) (*TokenEntry, error) {
 	defer metrics.MeasureSince([]string{"token", "lookup"}, time.Now())
 	if id == "" {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return ts.lookupBatch(id)
+	}
 	return ts.lookupSalted(ts.SaltID(id))
 }
 
@@ -368,6 +873,9 @@ func (ts *TokenStore) Revoke(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 	return ts.revokeSalted(ts.SaltID(id))
 }
 
@@ -394,6 +902,24 @@ func (ts *TokenStore) revokeSalted(saltedId string) error {
 		}
 	}
 
+	// Clear the accessor index if any
+	if entry != nil && entry.Accessor != "" {
+		path := accessorPrefix + ts.SaltID(entry.Accessor)
+		if err := ts.view.Delete(path); err != nil {
+			return fmt.Errorf("failed to delete accessor entry: %v", err)
+		}
+	}
+
+	// Clear the wrapped response if any
+	if err := ts.view.Delete(wrappedPrefix + saltedId); err != nil {
+		return fmt.Errorf("failed to delete wrapped response: %v", err)
+	}
+
+	// Destroy the cubbyhole of the token
+	if err := ts.core.destroyCubbyhole(saltedId); err != nil {
+		return fmt.Errorf("failed to destroy cubbyhole: %v", err)
+	}
+
 	// Revoke all secrets under this token
 	if entry != nil {
 		if err := ts.expiration.RevokeByToken(entry.ID); err != nil {
@@ -403,6 +929,24 @@ func (ts *TokenStore) revokeSalted(saltedId string) error {
 	return nil
 }
 
+// lookupByAccessor is used to find the ID of a token given its accessor.
+// An empty ID is returned if the accessor is not found.
+func (ts *TokenStore) lookupByAccessor(accessor string) (string, error) {
+	entry, err := ts.view.Get(accessorPrefix + ts.SaltID(accessor))
+	if err != nil {
+		return "", fmt.Errorf("failed to read accessor entry: %v", err)
+	}
+	if entry == nil {
+		return "", nil
+	}
+
+	var out accessorEntry
+	if err := entry.DecodeJSON(&out); err != nil {
+		return "", fmt.Errorf("failed to decode accessor entry: %v", err)
+	}
+	return out.TokenID, nil
+}
+
 // RevokeTree is used to invalide a given token and all
 // child tokens.
 func (ts *TokenStore) RevokeTree(id string) error {
@@ -411,6 +955,9 @@ func (ts *TokenStore) RevokeTree(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	// Get the salted ID
 	saltedId := ts.SaltID(id)
@@ -448,11 +995,162 @@ func (ts *TokenStore) revokeTreeSalted(saltedId string) error {
 	return nil
 }
 
-// handleCreate handles the auth/token/create path for creation of new tokens
-func (ts *TokenStore) handleCreate(
-	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	// Read the parent policy
-	parent, err := ts.Lookup(req.ClientToken)
+// tidyParentIndex removes the entries of the parent index that refer
+// to a token that no longer exists, either the parent or the child.
+// It returns the number of removed entries.
+func (ts *TokenStore) tidyParentIndex() (int, error) {
+	parents, err := ts.view.List(parentPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan for parents: %v", err)
+	}
+
+	var removed int
+	for _, parent := range parents {
+		children, err := ts.view.List(parentPrefix + parent)
+		if err != nil {
+			return removed, fmt.Errorf("failed to scan for children: %v", err)
+		}
+
+		for _, child := range children {
+			saltedParent := strings.TrimSuffix(parent, "/")
+			saltedChild := child
+			ok, err := ts.tidyEntry(parentPrefix+parent+child, func() (bool, error) {
+				pte, err := ts.lookupSalted(saltedParent)
+				if err != nil || pte == nil {
+					return pte == nil, err
+				}
+				cte, err := ts.lookupSalted(saltedChild)
+				return cte == nil, err
+			})
+			if err != nil {
+				return removed, err
+			}
+			if ok {
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
+
+// tidyAccessorIndex removes the entries of the accessor index that refer
+// to a token that no longer exists. It returns the number of removed entries.
+func (ts *TokenStore) tidyAccessorIndex() (int, error) {
+	salted, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan for accessors: %v", err)
+	}
+
+	var removed int
+	for _, key := range salted {
+		path := accessorPrefix + key
+		ok, err := ts.tidyEntry(path, func() (bool, error) {
+			entry, err := ts.view.Get(path)
+			if err != nil {
+				return false, fmt.Errorf("failed to read accessor entry: %v", err)
+			}
+			if entry == nil {
+				return false, nil
+			}
+
+			var out accessorEntry
+			if err := entry.DecodeJSON(&out); err != nil {
+				return false, fmt.Errorf("failed to decode accessor entry: %v", err)
+			}
+			te, err := ts.lookupSalted(ts.SaltID(out.TokenID))
+			return te == nil, err
+		})
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// tidyEntry deletes an index entry if the given check reports it as
+// dangling. The check is done once without locking, so that the scan does
+// not block token creation, and again under the tidy lock before deleting
+// since the token may have been created in the meantime. It returns
+// whether the entry was deleted.
+func (ts *TokenStore) tidyEntry(path string, dangling func() (bool, error)) (bool, error) {
+	if ok, err := dangling(); err != nil || !ok {
+		return false, err
+	}
+
+	ts.tidyLock.Lock()
+	defer ts.tidyLock.Unlock()
+
+	if ok, err := dangling(); err != nil || !ok {
+		return false, err
+	}
+	if err := ts.view.Delete(path); err != nil {
+		return false, fmt.Errorf("failed to delete entry: %v", err)
+	}
+	return true, nil
+}
+
+// handleTidy handles the auth/token/tidy path for cleaning up the indexes
+// left behind by tokens that were only partially created or revoked.
+// Leases issued to tokens that no longer exist are revoked.
+func (ts *TokenStore) handleTidy(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	defer metrics.MeasureSince([]string{"token", "tidy"}, time.Now())
+
+	parents, err := ts.tidyParentIndex()
+	if err != nil {
+		return nil, err
+	}
+	accessors, err := ts.tidyAccessorIndex()
+	if err != nil {
+		return nil, err
+	}
+	leaseIndexes, revoked, err := ts.expiration.TidyTokenIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"parent_index_removed":      parents,
+			"accessor_index_removed":    accessors,
+			"lease_token_index_removed": leaseIndexes,
+			"leases_revoked":            revoked,
+		},
+	}, nil
+}
+
+// handleCreateAgainstRole handles the auth/token/create/<role> path for
+// creation of new tokens constrained by a role
+func (ts *TokenStore) handleCreateAgainstRole(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("role_name").(string)
+	role, err := ts.tokenStoreRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role %s", name)),
+			logical.ErrInvalidRequest
+	}
+
+	return ts.handleCreateCommon(req, role)
+}
+
+// handleCreate handles the auth/token/create path for creation of new tokens
+func (ts *TokenStore) handleCreate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return ts.handleCreateCommon(req, nil)
+}
+
+// handleCreateCommon handles the creation of new tokens, optionally
+// constrained by the given role
+func (ts *TokenStore) handleCreateCommon(
+	req *logical.Request, role *tsRoleEntry) (*logical.Response, error) {
+	// Read the parent policy
+	parent, err := ts.Lookup(req.ClientToken)
 	if err != nil || parent == nil {
 		return logical.ErrorResponse("parent token lookup failed"), logical.ErrInvalidRequest
 	}
@@ -464,18 +1162,29 @@ func (ts *TokenStore) handleCreate(
 			logical.ErrInvalidRequest
 	}
 
+	// A batch token is not tracked, so children could not be revoked with it
+	if parent.IsBatch() {
+		return logical.ErrorResponse("batch tokens cannot generate child tokens"),
+			logical.ErrInvalidRequest
+	}
+
 	// Check if the parent policy is root
 	isRoot := strListContains(parent.Policies, "root")
 
 	// Read and parse the fields
 	var data struct {
-		ID          string
-		Policies    []string
-		Metadata    map[string]string `mapstructure:"meta"`
-		NoParent    bool              `mapstructure:"no_parent"`
-		Lease       string
-		DisplayName string `mapstructure:"display_name"`
-		NumUses     int    `mapstructure:"num_uses"`
+		ID              string
+		Policies        []string
+		Metadata        map[string]string `mapstructure:"meta"`
+		NoParent        bool              `mapstructure:"no_parent"`
+		NoDefaultPolicy bool              `mapstructure:"no_default_policy"`
+		Lease           string
+		Period          string
+		ExplicitMaxTTL  string   `mapstructure:"explicit_max_ttl"`
+		DisplayName     string   `mapstructure:"display_name"`
+		NumUses         int      `mapstructure:"num_uses"`
+		BoundCIDRs      []string `mapstructure:"bound_cidrs"`
+		Type            string
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -488,13 +1197,26 @@ func (ts *TokenStore) handleCreate(
 			logical.ErrInvalidRequest
 	}
 
-	// Setup the token entry
+	// Determine the type of the token
+	var batch bool
+	switch data.Type {
+	case "", "service":
+	case "batch":
+		batch = true
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid token type '%s'", data.Type)),
+			logical.ErrInvalidRequest
+	}
+
+	// Setup the token entry. Child tokens belong to the entity of the
+	// parent, so that they are granted the same entity policies.
 	te := TokenEntry{
 		Parent:      req.ClientToken,
 		Path:        "auth/token/create",
 		Meta:        data.Metadata,
 		DisplayName: "token",
 		NumUses:     data.NumUses,
+		EntityID:    parent.EntityID,
 	}
 
 	// Attach the given display name if any
@@ -505,6 +1227,28 @@ func (ts *TokenStore) handleCreate(
 		te.DisplayName = full
 	}
 
+	// Restrict the addresses the token may be used from. A role with bound
+	// CIDR blocks overrides the requested ones. The blocks must be within
+	// those of the parent, and a child inherits the blocks of the parent
+	// when none are given.
+	if role != nil && len(role.BoundCIDRs) > 0 {
+		te.BoundCIDRs = role.BoundCIDRs
+	} else {
+		cidrs, err := parseCIDRList(data.BoundCIDRs)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		te.BoundCIDRs = cidrs
+	}
+	if len(parent.BoundCIDRs) > 0 {
+		if len(te.BoundCIDRs) == 0 {
+			te.BoundCIDRs = parent.BoundCIDRs
+		} else if !cidrListSubset(parent.BoundCIDRs, te.BoundCIDRs) {
+			return logical.ErrorResponse("bound CIDR blocks must be within those of the parent"),
+				logical.ErrInvalidRequest
+		}
+	}
+
 	// Allow specifying the ID of the token if the client is root
 	if data.ID != "" {
 		if !isRoot {
@@ -514,17 +1258,40 @@ func (ts *TokenStore) handleCreate(
 		te.ID = data.ID
 	}
 
-	// Only permit policies to be a subset unless the client is root
-	if len(data.Policies) == 0 {
-		data.Policies = parent.Policies
-	}
-	if !isRoot && !strListSubset(parent.Policies, data.Policies) {
-		return logical.ErrorResponse("child policies must be subset of parent"), logical.ErrInvalidRequest
+	// A role with allowed policies replaces the subset-of-parent rule,
+	// otherwise only permit policies to be a subset unless the client is root
+	switch {
+	case role != nil && len(role.AllowedPolicies) > 0:
+		if len(data.Policies) == 0 {
+			data.Policies = role.AllowedPolicies
+		}
+		if !strListSubset(role.AllowedPolicies, data.Policies) {
+			return logical.ErrorResponse("token policies must be subset of the role's allowed policies"),
+				logical.ErrInvalidRequest
+		}
+
+	default:
+		if len(data.Policies) == 0 {
+			data.Policies = parent.Policies
+		}
+		if !isRoot && !strListSubset(parent.Policies, data.Policies) {
+			return logical.ErrorResponse("child policies must be subset of parent"), logical.ErrInvalidRequest
+		}
 	}
 	te.Policies = data.Policies
 
-	// Only allow an orphan token if the client is root
-	if data.NoParent {
+	// Attach the default policy unless explicitly excluded
+	if !data.NoDefaultPolicy {
+		te.Policies = addDefaultPolicy(te.Policies)
+	}
+
+	// Only allow an orphan token if the client is root, unless the role
+	// creates orphans
+	switch {
+	case role != nil && role.Orphan:
+		te.Parent = ""
+
+	case data.NoParent:
 		if !isRoot {
 			return logical.ErrorResponse("root required to create orphan token"),
 				logical.ErrInvalidRequest
@@ -533,6 +1300,15 @@ func (ts *TokenStore) handleCreate(
 		te.Parent = ""
 	}
 
+	// Record the role in the path of the token so that its tokens
+	// can be renewed according to the role and revoked by prefix
+	if role != nil {
+		te.Path = "auth/token/create/" + role.Name
+		if role.PathSuffix != "" {
+			te.Path = te.Path + "/" + role.PathSuffix
+		}
+	}
+
 	// Parse the lease if any
 	var leaseDuration time.Duration
 	if data.Lease != "" {
@@ -546,6 +1322,103 @@ func (ts *TokenStore) handleCreate(
 		leaseDuration = dur
 	}
 
+	// Parse the period if any, only root may create periodic tokens
+	// outside of a role
+	if data.Period != "" {
+		if !isRoot {
+			return logical.ErrorResponse("root required to create periodic token"),
+				logical.ErrInvalidRequest
+		}
+		dur, err := time.ParseDuration(data.Period)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if dur < 0 {
+			return logical.ErrorResponse("period must be positive"), logical.ErrInvalidRequest
+		}
+		te.Period = dur
+	}
+
+	// Parse the explicit max TTL if any
+	if data.ExplicitMaxTTL != "" {
+		dur, err := time.ParseDuration(data.ExplicitMaxTTL)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if dur < 0 {
+			return logical.ErrorResponse("explicit_max_ttl must be positive"), logical.ErrInvalidRequest
+		}
+		te.ExplicitMaxTTL = dur
+	}
+
+	// Apply the lease constraints of the role
+	if role != nil {
+		if leaseDuration == 0 {
+			leaseDuration = role.DefaultTTL
+		}
+		if role.MaxTTL > 0 && (leaseDuration == 0 || leaseDuration > role.MaxTTL) {
+			leaseDuration = role.MaxTTL
+		}
+		if role.Period > 0 {
+			te.Period = role.Period
+		}
+	}
+
+	// The lease of a periodic token is always its period
+	if te.Period > 0 {
+		leaseDuration = te.Period
+	}
+
+	// The lease can never exceed the explicit max TTL
+	if te.ExplicitMaxTTL > 0 && (leaseDuration == 0 || leaseDuration > te.ExplicitMaxTTL) {
+		leaseDuration = te.ExplicitMaxTTL
+	}
+
+	// Batch tokens are never persisted, so they cannot have anything that
+	// requires tracking, and always expire from their embedded TTL
+	if batch {
+		switch {
+		case te.ID != "":
+			return logical.ErrorResponse("batch tokens cannot have a custom ID"),
+				logical.ErrInvalidRequest
+		case te.NumUses > 0:
+			return logical.ErrorResponse("batch tokens cannot have a use limit"),
+				logical.ErrInvalidRequest
+		case te.Period > 0:
+			return logical.ErrorResponse("batch tokens cannot be periodic"),
+				logical.ErrInvalidRequest
+		case strListContains(te.Policies, "root"):
+			return logical.ErrorResponse("batch tokens cannot be root tokens"),
+				logical.ErrInvalidRequest
+		}
+
+		defaultTTL, maxTTL := ts.core.leaseTTLs(req.MountPoint + req.Path)
+		if leaseDuration == 0 {
+			leaseDuration = defaultTTL
+		}
+		if leaseDuration > maxTTL {
+			leaseDuration = maxTTL
+		}
+		te.ExpireTime = time.Now().UTC().Add(leaseDuration)
+
+		if err := ts.createBatch(&te); err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Auth: &logical.Auth{
+				DisplayName: te.DisplayName,
+				Policies:    te.Policies,
+				Metadata:    te.Meta,
+				LeaseOptions: logical.LeaseOptions{
+					Lease: leaseDuration,
+				},
+				ClientToken: te.ID,
+				EntityID:    te.EntityID,
+			},
+		}, nil
+	}
+
 	// Create the token
 	if err := ts.Create(&te); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -562,7 +1435,10 @@ func (ts *TokenStore) handleCreate(
 				LeaseGracePeriod: leaseDuration / 10,
 				Renewable:        leaseDuration > 0,
 			},
+			Period:      te.Period,
 			ClientToken: te.ID,
+			Accessor:    te.Accessor,
+			EntityID:    te.EntityID,
 		},
 	}
 
@@ -586,6 +1462,30 @@ func (ts *TokenStore) handleRevokeTree(
 	return nil, nil
 }
 
+// handleRevokeAccessor handles the auth/token/revoke-accessor/accessor path
+// for revocation of a token and all its child tokens by accessor.
+func (ts *TokenStore) handleRevokeAccessor(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessor := data.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+
+	id, err := ts.lookupByAccessor(accessor)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
+	}
+
+	// Revoke the token and its children
+	if err := ts.RevokeTree(id); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
 // handleRevokeOrphan handles the auth/token/revoke-orphan/id path for revocation of tokens
 // in a way that leaves child tokens orphaned. Normally, using sys/revoke/leaseID will revoke
 // the token and all children.
@@ -644,26 +1544,135 @@ func (ts *TokenStore) handleLookup(
 		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 	}
 
-	// Generate a response. We purposely omit the parent reference otherwise
-	// you could escalade your privileges.
-	resp := &logical.Response{
+	return tokenLookupResponse(out), nil
+}
+
+// handleCapabilitiesSelf handles the auth/token/capabilities-self path
+// to return the policy levels the client token is granted on a path
+func (ts *TokenStore) handleCapabilitiesSelf(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := strings.TrimPrefix(data.Get("path").(string), "/")
+	if path == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	te, err := ts.Lookup(req.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	if te == nil {
+		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
+	}
+
+	acl, err := ts.core.tokenACL(te)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
 		Data: map[string]interface{}{
-			"id":           out.ID,
-			"policies":     out.Policies,
-			"path":         out.Path,
-			"meta":         out.Meta,
-			"display_name": out.DisplayName,
-			"num_uses":     out.NumUses,
+			"capabilities": acl.Capabilities(path, req.Connection),
 		},
+	}, nil
+}
+
+// handleLookupAccessor handles the auth/token/lookup-accessor/accessor path
+// for querying information about a token without knowing its ID. The ID is
+// not included in the response.
+func (ts *TokenStore) handleLookupAccessor(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessor := data.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+
+	id, err := ts.lookupByAccessor(accessor)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
+	}
+
+	out, err := ts.Lookup(id)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if out == nil {
+		return logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
 	}
+
+	resp := tokenLookupResponse(out)
+	resp.Data["id"] = ""
 	return resp, nil
 }
 
-// handleRenew handles the auth/token/renew/id path for renewal of tokens.
-// This is used to prevent token expiration and revocation.
+// handleAccessorList handles the auth/token/accessors/ path for listing
+// the accessors of all tokens
+func (ts *TokenStore) handleAccessorList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index is keyed by the salted accessor, so read the entries
+	// to recover the accessors themselves
+	keys := make([]string, 0, len(entries))
+	for _, salted := range entries {
+		entry, err := ts.view.Get(accessorPrefix + salted)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		var out accessorEntry
+		if err := entry.DecodeJSON(&out); err != nil {
+			return nil, err
+		}
+		keys = append(keys, out.AccessorID)
+	}
+	return logical.ListResponse(keys), nil
+}
+
+// tokenLookupResponse generates the response of a token lookup. We
+// purposely omit the parent reference otherwise you could escalade
+// your privileges.
+func tokenLookupResponse(te *TokenEntry) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":               te.ID,
+			"accessor":         te.Accessor,
+			"policies":         te.Policies,
+			"path":             te.Path,
+			"meta":             te.Meta,
+			"display_name":     te.DisplayName,
+			"num_uses":         te.NumUses,
+			"period":           int64(te.Period.Seconds()),
+			"explicit_max_ttl": int64(te.ExplicitMaxTTL.Seconds()),
+			"bound_cidrs":      te.BoundCIDRs,
+			"type":             tokenType(te),
+			"entity_id":        te.EntityID,
+		},
+	}
+}
+
+// tokenType returns the type of the token as reported by lookups
+func tokenType(te *TokenEntry) string {
+	if te.IsBatch() {
+		return "batch"
+	}
+	return "service"
+}
+
+// handleRenew handles the auth/token/renew/id and auth/token/renew-self paths
+// for renewal of tokens. This is used to prevent token expiration and revocation.
 func (ts *TokenStore) handleRenew(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	id := data.Get("token").(string)
+	if id == "" {
+		id = req.ClientToken
+	}
 	if id == "" {
 		return logical.ErrorResponse("missing token ID"), logical.ErrInvalidRequest
 	}
@@ -683,6 +1692,11 @@ func (ts *TokenStore) handleRenew(
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
 
+	// Batch tokens expire purely from their embedded TTL
+	if out.IsBatch() {
+		return logical.ErrorResponse("batch tokens cannot be renewed"), logical.ErrInvalidRequest
+	}
+
 	// Revoke the token and its children
 	auth, err := ts.expiration.RenewToken(out.Path, out.ID, increment)
 	if err != nil {
@@ -696,15 +1710,190 @@ func (ts *TokenStore) handleRenew(
 	return resp, nil
 }
 
+// authRenew is used to renew tokens. Periodic tokens are renewed for
+// exactly their period. Tokens created against a role are renewed according
+// to the lease constraints of the role, all other tokens may be extended
+// indefinitely, each time by at most their original lease.
+func (ts *TokenStore) authRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.Auth == nil {
+		return nil, fmt.Errorf("request auth was nil")
+	}
+
+	// The path of the request is the path the token was created on. A
+	// token created against a role is constrained by the role, so it can
+	// no longer be renewed once the role has been deleted.
+	var role *tsRoleEntry
+	if strings.HasPrefix(req.Path, "create/") {
+		name := strings.SplitN(strings.TrimPrefix(req.Path, "create/"), "/", 2)[0]
+		var err error
+		role, err = ts.tokenStoreRole(name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("token role '%s' no longer exists", name)
+		}
+	}
+
+	// Periodic tokens have no maximum lifetime
+	if req.Auth.Period > 0 {
+		req.Auth.Lease = req.Auth.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+
+	if role != nil {
+		return framework.LeaseExtend(0, role.MaxTTL, true)(req, d)
+	}
+	return framework.LeaseExtend(0, 0, true)(req, d)
+}
+
+// tokenStoreRole is used to fetch the named role
+func (ts *TokenStore) tokenStoreRole(name string) (*tsRoleEntry, error) {
+	entry, err := ts.view.Get(rolesPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result tsRoleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// handleRoleList handles the auth/token/roles/ path for listing roles
+func (ts *TokenStore) handleRoleList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := ts.view.List(rolesPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+// handleRoleRead handles the auth/token/roles/<name> path for reading a role
+func (ts *TokenStore) handleRoleRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := ts.tokenStoreRole(data.Get("role_name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":             role.Name,
+			"allowed_policies": role.AllowedPolicies,
+			"orphan":           role.Orphan,
+			"default_ttl":      int64(role.DefaultTTL.Seconds()),
+			"max_ttl":          int64(role.MaxTTL.Seconds()),
+			"period":           int64(role.Period.Seconds()),
+			"path_suffix":      role.PathSuffix,
+			"bound_cidrs":      role.BoundCIDRs,
+		},
+	}, nil
+}
+
+// handleRoleWrite handles the auth/token/roles/<name> path for creating
+// or updating a role
+func (ts *TokenStore) handleRoleWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("role_name").(string)
+	if name == "" {
+		return logical.ErrorResponse("role name cannot be empty"), logical.ErrInvalidRequest
+	}
+
+	role := &tsRoleEntry{
+		Name:       name,
+		Orphan:     data.Get("orphan").(bool),
+		DefaultTTL: time.Duration(data.Get("default_ttl").(int)) * time.Second,
+		MaxTTL:     time.Duration(data.Get("max_ttl").(int)) * time.Second,
+		Period:     time.Duration(data.Get("period").(int)) * time.Second,
+		PathSuffix: strings.Trim(data.Get("path_suffix").(string), "/"),
+	}
+	for _, p := range strings.Split(data.Get("allowed_policies").(string), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			role.AllowedPolicies = append(role.AllowedPolicies, p)
+		}
+	}
+	cidrs, err := parseCIDRList(strings.Split(data.Get("bound_cidrs").(string), ","))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	role.BoundCIDRs = cidrs
+
+	// Validate the role
+	switch {
+	case strListContains(role.AllowedPolicies, "root"):
+		return logical.ErrorResponse("roles cannot allow the root policy"),
+			logical.ErrInvalidRequest
+	case role.DefaultTTL < 0 || role.MaxTTL < 0 || role.Period < 0:
+		return logical.ErrorResponse("durations cannot be negative"),
+			logical.ErrInvalidRequest
+	case role.MaxTTL > 0 && role.DefaultTTL > role.MaxTTL:
+		return logical.ErrorResponse("default_ttl cannot be greater than max_ttl"),
+			logical.ErrInvalidRequest
+	case !pathSuffixSanitize.MatchString(role.PathSuffix),
+		strings.Contains(role.PathSuffix, ".."):
+		return logical.ErrorResponse("path_suffix contains invalid characters"),
+			logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON(rolesPrefix+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.view.Put(entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleRoleDelete handles the auth/token/roles/<name> path for deleting a role
+func (ts *TokenStore) handleRoleDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := ts.view.Delete(rolesPrefix + data.Get("role_name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 const (
 	tokenBackendHelp = `The token credential backend is always enabled and builtin to Vault.
 Client tokens are used to identify a client and to allow Vault to associate policies and ACLs
 which are enforced on every request. This backend also allows for generating sub-tokens as well
 as revocation of tokens.`
-	tokenCreateHelp       = `The token create path is used to create new tokens.`
-	tokenLookupHelp       = `This endpoint will lookup a token and its properties.`
-	tokenRevokeHelp       = `This endpoint will delete the token and all of its child tokens.`
-	tokenRevokeOrphanHelp = `This endpoint will delete the token and orphan its child tokens.`
-	tokenRevokePrefixHelp = `This endpoint will delete all tokens generated under a prefix with their child tokens.`
-	tokenRenewHelp        = `This endpoint will renew the token and prevent expiration.`
+	tokenCreateHelp           = `The token create path is used to create new tokens.`
+	tokenCreateRoleHelp       = `This token create path is used to create new tokens adhering to the given role.`
+	tokenListRolesHelp        = `This endpoint lists configured roles.`
+	tokenRolesHelp            = `This endpoint allows creating, reading, and deleting roles.`
+	tokenLookupHelp           = `This endpoint will lookup a token and its properties.`
+	tokenCapabilitiesSelfHelp = `This endpoint returns the capabilities of the client token on a path.`
+	tokenLookupAccessorHelp   = `This endpoint will lookup a token by its accessor and return its properties, omitting the token ID.`
+	tokenListAccessorsHelp    = `This endpoint lists the accessors of all tokens.`
+	tokenRevokeAccessorHelp   = `This endpoint will delete the token referenced by an accessor and all of its child tokens.`
+	tokenRevokeHelp           = `This endpoint will delete the token and all of its child tokens.`
+	tokenRevokeOrphanHelp     = `This endpoint will delete the token and orphan its child tokens.`
+	tokenRevokePrefixHelp     = `This endpoint will delete all tokens generated under a prefix with their child tokens.`
+	tokenRenewHelp            = `This endpoint will renew the token and prevent expiration.`
+	tokenRenewSelfHelp        = `This endpoint will renew the token used to call it and prevent expiration.`
+	tokenTidyHelp             = `This endpoint removes dangling token indexes and revokes leases whose token no longer exists.`
 )
+
+// addDefaultPolicy returns the policies with the default policy attached.
+// Root tokens are exempt, and the policies are returned unmodified if the
+// default policy is already present.
+func addDefaultPolicy(policies []string) []string {
+	if strListContains(policies, "root") || strListContains(policies, defaultPolicyName) {
+		return policies
+	}
+	out := make([]string, 0, len(policies)+1)
+	out = append(out, policies...)
+	return append(out, defaultPolicyName)
+}
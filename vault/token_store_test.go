@@ -1,9 +1,14 @@
 package vault
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"log"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +27,12 @@ func mockTokenStore(t *testing.T) (*Core, *TokenStore, string) {
 	router := NewRouter()
 	router.Mount(ts, "auth/token/", "", ts.view)
 
+	// The mock manager shares its view with the manager of the core,
+	// which would otherwise attempt to expire the same leases
+	if err := c.expiration.Stop(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
 	view := c.systemView.SubView(expirationSubPath)
 	exp := NewExpirationManager(router, view, ts, logger)
 	ts.SetExpirationManager(exp)
@@ -349,6 +360,7 @@ func TestTokenStore_HandleRequest_CreateToken_DisplayName(t *testing.T) {
 
 	expected := &TokenEntry{
 		ID:          resp.Auth.ClientToken,
+		Accessor:    resp.Auth.Accessor,
 		Parent:      root,
 		Policies:    []string{"root"},
 		Path:        "auth/token/create",
@@ -377,6 +389,7 @@ func TestTokenStore_HandleRequest_CreateToken_NumUses(t *testing.T) {
 
 	expected := &TokenEntry{
 		ID:          resp.Auth.ClientToken,
+		Accessor:    resp.Auth.Accessor,
 		Parent:      root,
 		Policies:    []string{"root"},
 		Path:        "auth/token/create",
@@ -438,6 +451,7 @@ func TestTokenStore_HandleRequest_CreateToken_NoPolicy(t *testing.T) {
 
 	expected := &TokenEntry{
 		ID:          resp.Auth.ClientToken,
+		Accessor:    resp.Auth.Accessor,
 		Parent:      root,
 		Policies:    []string{"root"},
 		Path:        "auth/token/create",
@@ -535,6 +549,64 @@ func TestTokenStore_HandleRequest_CreateToken_NonRoot_Subset(t *testing.T) {
 	}
 }
 
+func TestTokenStore_HandleRequest_CreateToken_DefaultPolicy(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "client", []string{"foo"})
+
+	// The default policy is attached
+	out, err := ts.Lookup("client")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.Policies, []string{"foo", "default"}) {
+		t.Fatalf("bad: %#v", out.Policies)
+	}
+
+	// The default policy can be excluded
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"foo"}
+	req.Data["no_default_policy"] = true
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"foo"}) {
+		t.Fatalf("bad: %#v", resp.Auth.Policies)
+	}
+}
+
+func TestTokenStore_HandleRequest_CapabilitiesSelf(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	policy, _ := Parse(`path "secret/*" { policy = "read" }`)
+	policy.Name = "foo"
+	if err := c.policy.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testCoreMakeToken(t, c, root, "client", []string{"foo"})
+
+	// The default policy grants access to the endpoint
+	for path, expected := range map[string][]string{
+		"secret/foo":  []string{"read"},
+		"/secret/foo": []string{"read"},
+		"sys/raw/foo": []string{"deny"},
+	} {
+		req := &logical.Request{
+			Operation:   logical.WriteOperation,
+			Path:        "auth/token/capabilities-self",
+			Data:        map[string]interface{}{"path": path},
+			ClientToken: "client",
+		}
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		if !reflect.DeepEqual(resp.Data["capabilities"], expected) {
+			t.Fatalf("%s: bad: %#v", path, resp)
+		}
+	}
+}
+
 func TestTokenStore_HandleRequest_CreateToken_NonRoot_InvalidSubset(t *testing.T) {
 	_, ts, root := mockTokenStore(t)
 	testMakeToken(t, ts, root, "client", []string{"foo", "bar"})
@@ -716,13 +788,24 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 
+	te, err := ts.Lookup(root)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
 	exp := map[string]interface{}{
-		"id":           root,
-		"policies":     []string{"root"},
-		"path":         "auth/token/root",
-		"meta":         map[string]string(nil),
-		"display_name": "root",
-		"num_uses":     0,
+		"id":               root,
+		"accessor":         te.Accessor,
+		"policies":         []string{"root"},
+		"path":             "auth/token/root",
+		"meta":             map[string]string(nil),
+		"display_name":     "root",
+		"num_uses":         0,
+		"period":           int64(0),
+		"explicit_max_ttl": int64(0),
+		"bound_cidrs":      []string(nil),
+		"type":             "service",
+		"entity_id":        "",
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("bad: %#v exp: %#v", resp.Data, exp)
@@ -781,17 +864,150 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 
+	te, err := ts.Lookup(root)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	exp := map[string]interface{}{
+		"id":               root,
+		"accessor":         te.Accessor,
+		"policies":         []string{"root"},
+		"path":             "auth/token/root",
+		"meta":             map[string]string(nil),
+		"display_name":     "root",
+		"num_uses":         0,
+		"period":           int64(0),
+		"explicit_max_ttl": int64(0),
+		"bound_cidrs":      []string(nil),
+		"type":             "service",
+		"entity_id":        "",
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("bad: %#v exp: %#v", resp.Data, exp)
+	}
+}
+
+func TestTokenStore_HandleRequest_LookupAccessor(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "tokenid", []string{"foo"})
+	te, err := ts.Lookup("tokenid")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.Accessor == "" {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "lookup-accessor/"+te.Accessor)
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The token ID is not disclosed
 	exp := map[string]interface{}{
-		"id":           root,
-		"policies":     []string{"root"},
-		"path":         "auth/token/root",
-		"meta":         map[string]string(nil),
-		"display_name": "root",
-		"num_uses":     0,
+		"id":               "",
+		"accessor":         te.Accessor,
+		"policies":         []string{"foo", "default"},
+		"path":             "auth/token/create",
+		"meta":             map[string]string(nil),
+		"display_name":     "token",
+		"num_uses":         0,
+		"period":           int64(0),
+		"explicit_max_ttl": int64(0),
+		"bound_cidrs":      []string(nil),
+		"type":             "service",
+		"entity_id":        "",
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("bad: %#v exp: %#v", resp.Data, exp)
 	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "lookup-accessor/invalid")
+	if _, err := ts.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTokenStore_HandleRequest_RevokeAccessor(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "child", []string{"root", "foo"})
+	testMakeToken(t, ts, "child", "sub-child", []string{"foo"})
+	te, err := ts.Lookup("child")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.WriteOperation, "revoke-accessor/"+te.Accessor)
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The token and its children are revoked
+	for _, id := range []string{"child", "sub-child"} {
+		out, err := ts.Lookup(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out != nil {
+			t.Fatalf("bad: %v", out)
+		}
+	}
+
+	// The accessor is no longer valid
+	req = logical.TestRequest(t, logical.ReadOperation, "lookup-accessor/"+te.Accessor)
+	if _, err := ts.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTokenStore_HandleRequest_AccessorList(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "tokenid", []string{"foo"})
+
+	var exp []string
+	for _, id := range []string{root, "tokenid"} {
+		te, err := ts.Lookup(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		exp = append(exp, te.Accessor)
+	}
+
+	req := logical.TestRequest(t, logical.ListOperation, "accessors/")
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	keys := resp.Data["keys"].([]string)
+	sort.Strings(keys)
+	sort.Strings(exp)
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("bad: %#v exp: %#v", keys, exp)
+	}
+}
+
+func TestCore_AccessorList_Sudo(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreMakeToken(t, c, root, "client", []string{"default"})
+
+	// Listing accessors requires sudo
+	req := logical.TestRequest(t, logical.ListOperation, "auth/token/accessors/")
+	req.ClientToken = "client"
+	if _, err := c.HandleRequest(req); err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v", err)
+	}
+
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("bad: %#v", resp)
+	}
 }
 
 func TestTokenStore_HandleRequest_Renew(t *testing.T) {
@@ -838,6 +1054,45 @@ func TestTokenStore_HandleRequest_Renew(t *testing.T) {
 	}
 }
 
+func TestTokenStore_HandleRequest_RenewSelf(t *testing.T) {
+	exp := mockExpiration(t)
+	ts := exp.tokenStore
+
+	// Create new token
+	root, err := ts.RootToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a new token
+	auth := &logical.Auth{
+		ClientToken: root.ID,
+		LeaseOptions: logical.LeaseOptions{
+			Lease:     time.Hour,
+			Renewable: true,
+		},
+	}
+	err = exp.RegisterAuth("auth/token/root", auth)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	beforeRenew := time.Now().UTC()
+	req := logical.TestRequest(t, logical.WriteOperation, "renew-self")
+	req.ClientToken = root.ID
+	req.Data["increment"] = "3600s"
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Get the new expire time
+	newExpire := resp.Auth.ExpirationTime()
+	if newExpire.Before(beforeRenew.Add(time.Hour)) {
+		t.Fatalf("should have at least an hour: %s %s", newExpire, beforeRenew)
+	}
+}
+
 func testMakeToken(t *testing.T, ts *TokenStore, root, client string, policy []string) {
 	req := logical.TestRequest(t, logical.WriteOperation, "create")
 	req.ClientToken = root
@@ -867,3 +1122,951 @@ func testCoreMakeToken(t *testing.T, c *Core, root, client string, policy []stri
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestTokenStore_RoleCRUD(t *testing.T) {
+	_, ts, _ := mockTokenStore(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "roles/test")
+	req.Data = map[string]interface{}{
+		"allowed_policies": "foo, bar",
+		"orphan":           true,
+		"default_ttl":      "1h",
+		"max_ttl":          "2h",
+		"path_suffix":      "happening/",
+		"bound_cidrs":      "10.0.0.0/8, 192.168.1.5/24",
+	}
+	resp, err := ts.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "roles/test")
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	expected := map[string]interface{}{
+		"name":             "test",
+		"allowed_policies": []string{"foo", "bar"},
+		"orphan":           true,
+		"default_ttl":      int64(3600),
+		"max_ttl":          int64(7200),
+		"period":           int64(0),
+		"path_suffix":      "happening",
+		"bound_cidrs":      []string{"10.0.0.0/8", "192.168.1.0/24"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "roles/")
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"test"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "roles/test")
+	if resp, err := ts.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "roles/test")
+	resp, err = ts.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestTokenStore_RoleCRUD_Invalid(t *testing.T) {
+	_, ts, _ := mockTokenStore(t)
+
+	for _, data := range []map[string]interface{}{
+		{"allowed_policies": "foo,root"},
+		{"default_ttl": "2h", "max_ttl": "1h"},
+		{"path_suffix": "../foo"},
+		{"path_suffix": "foo bar"},
+		{"bound_cidrs": "10.0.0.0/33"},
+	} {
+		req := logical.TestRequest(t, logical.WriteOperation, "roles/test")
+		req.Data = data
+		resp, err := ts.HandleRequest(req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("err: %v %v %v", data, err, resp)
+		}
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateAgainstRole(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "client", []string{"ci"})
+
+	req := logical.TestRequest(t, logical.WriteOperation, "roles/deploy")
+	req.Data = map[string]interface{}{
+		"allowed_policies": "app,db",
+		"orphan":           true,
+		"default_ttl":      "1h",
+		"max_ttl":          "2h",
+		"path_suffix":      "prod",
+	}
+	if resp, err := ts.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Unknown roles are rejected
+	req = logical.TestRequest(t, logical.WriteOperation, "create/unknown")
+	req.ClientToken = "client"
+	if resp, err := ts.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Policies outside of the role are rejected, even for root
+	for _, token := range []string{"client", root} {
+		req = logical.TestRequest(t, logical.WriteOperation, "create/deploy")
+		req.ClientToken = token
+		req.Data["policies"] = []string{"app", "ci"}
+		resp, err := ts.HandleRequest(req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+	}
+
+	// A non-root caller can create an orphan token with policies it
+	// does not hold, with the lease limited by the role
+	req = logical.TestRequest(t, logical.WriteOperation, "create/deploy")
+	req.ClientToken = "client"
+	req.Data["lease"] = "10h"
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != 2*time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	out, err := ts.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.Policies, []string{"app", "db", "default"}) {
+		t.Fatalf("bad: %#v", out.Policies)
+	}
+	if out.Parent != "" || out.Path != "auth/token/create/deploy/prod" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The default lease of the role is used if none is requested
+	req = logical.TestRequest(t, logical.WriteOperation, "create/deploy")
+	req.ClientToken = "client"
+	req.Data["policies"] = []string{"db"}
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour || !reflect.DeepEqual(resp.Auth.Policies, []string{"db", "default"}) {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+}
+
+func TestCore_TokenRole_RenewRevoke(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/roles/deploy")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"allowed_policies": "app",
+		"max_ttl":          "1h",
+		"path_suffix":      "prod",
+	}
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create/deploy")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	client := resp.Auth.ClientToken
+
+	// Renewal cannot extend past the maximum lifetime of the role
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = client
+	req.Data["increment"] = "10h"
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease > time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Renewal is denied once the role has been deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "auth/token/roles/deploy")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = client
+	if resp, err := c.HandleRequest(req); err == nil {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	// Tokens can be revoked by the path suffix of the role
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/revoke-prefix/auth/token/create/deploy/prod")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	te, err := c.tokenStore.Lookup(client)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te != nil {
+		t.Fatalf("bad: %#v", te)
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateToken_Period(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "client", []string{"foo"})
+
+	// Only root can create periodic tokens outside of a role
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = "client"
+	req.Data["period"] = "1h"
+	resp, err := ts.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The lease of a periodic token is its period
+	req.ClientToken = root
+	req.Data["lease"] = "10h"
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour || resp.Auth.Period != time.Hour || !resp.Auth.Renewable {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Lookup shows the period
+	req = logical.TestRequest(t, logical.ReadOperation, "lookup/"+resp.Auth.ClientToken)
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["period"] != int64(3600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tokens created against a role with a period are periodic
+	req = logical.TestRequest(t, logical.WriteOperation, "roles/daemon")
+	req.Data["period"] = "2h"
+	req.Data["max_ttl"] = "3h"
+	if resp, err := ts.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "create/daemon")
+	req.ClientToken = "client"
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != 2*time.Hour || resp.Auth.Period != 2*time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	out, err := ts.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Period != 2*time.Hour {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestCore_PeriodicToken_Renew(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["period"] = "1h"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	client := resp.Auth.ClientToken
+
+	// Every renewal sets the lease to exactly the period
+	for _, increment := range []string{"10h", "1m"} {
+		req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+		req.ClientToken = client
+		req.Data["increment"] = increment
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		if resp.Auth.Lease != time.Hour {
+			t.Fatalf("bad: %s %#v", increment, resp.Auth)
+		}
+	}
+}
+
+func TestCore_PeriodicToken_MaxTTL(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/auth/token/tune")
+	req.Data["max_lease_ttl"] = "30m"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The period is not limited by the mount, initially or on renewal
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["period"] = "1h"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = resp.Auth.ClientToken
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Other tokens are
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["lease"] = "1h"
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != 30*time.Minute {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+}
+
+func TestCore_PeriodicToken_Login(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				Period:   time.Hour,
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The period is not limited by the mount
+	req = logical.TestRequest(t, logical.WriteOperation, "sys/auth/foo/tune")
+	req.Data["max_lease_ttl"] = "30m"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	lresp, err := c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if lresp.Auth.Lease != time.Hour || !lresp.Auth.Renewable {
+		t.Fatalf("bad: %#v", lresp.Auth)
+	}
+	te, err := c.tokenStore.Lookup(lresp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.Period != time.Hour {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// Renewal through the credential backend keeps the period
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+	req.ClientToken = lresp.Auth.ClientToken
+	req.Data["increment"] = "10h"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+}
+
+func TestCore_ExplicitMaxTTL(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["lease"] = "2h"
+	req.Data["explicit_max_ttl"] = "1h"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	token := resp.Auth.ClientToken
+
+	// Renewal does not extend the token beyond its explicit max TTL
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew/"+token)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease > time.Hour || resp.Auth.Lease < 59*time.Minute {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup/"+token)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["explicit_max_ttl"] != int64(3600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateToken_Batch(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "parent", []string{"foo"})
+
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = "parent"
+	req.Data["type"] = "batch"
+	req.Data["lease"] = "1h"
+	req.Data["meta"] = map[string]string{"user": "armon"}
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+	if !strings.HasPrefix(token, batchTokenPrefix) {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	if resp.Auth.Lease != time.Hour || resp.Auth.Renewable || resp.Auth.Accessor != "" {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Nothing should be persisted for the token
+	keys, err := ts.view.List(lookupPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// The token only carries the salted ID of its parent
+	raw, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(token, batchTokenPrefix))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	gcm, err := ts.batchTokenAEAD(binary.BigEndian.Uint32(raw[:termSize]))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	nonce := raw[termSize : termSize+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, raw[termSize+gcm.NonceSize():], raw[:termSize])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var blob batchTokenEntry
+	if err := json.Unmarshal(plain, &blob); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if blob.Parent != "" || blob.SaltedParent != ts.SaltID("parent") {
+		t.Fatalf("bad: %#v", blob)
+	}
+
+	out, err := ts.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &TokenEntry{
+		ID:          token,
+		Parent:      "parent",
+		Policies:    []string{"foo", "default"},
+		Path:        "auth/token/create",
+		Meta:        map[string]string{"user": "armon"},
+		DisplayName: "token",
+		ExpireTime:  out.ExpireTime,
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.ExpireTime.Sub(time.Now()) > time.Hour || out.ExpireTime.Sub(time.Now()) < 59*time.Minute {
+		t.Fatalf("bad: %v", out.ExpireTime)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "lookup-self")
+	req.ClientToken = token
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["type"] != "batch" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Batch tokens cannot create children, be renewed or be revoked
+	req = logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = token
+	resp, err = ts.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "renew-self")
+	req.ClientToken = token
+	resp, err = ts.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	if err := ts.Revoke(token); err == nil {
+		t.Fatalf("should fail")
+	}
+
+	// Revoking the parent invalidates the batch token
+	if err := ts.RevokeTree("parent"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = ts.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateToken_Batch_Invalid(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+
+	cases := []map[string]interface{}{
+		{"type": "bogus"},
+		{"type": "batch", "policies": []string{"root"}},
+		{"type": "batch", "policies": []string{"foo"}, "id": "foo"},
+		{"type": "batch", "policies": []string{"foo"}, "num_uses": 1},
+		{"type": "batch", "policies": []string{"foo"}, "period": "1h"},
+	}
+	for _, data := range cases {
+		req := logical.TestRequest(t, logical.WriteOperation, "create")
+		req.ClientToken = root
+		req.Data = data
+		resp, err := ts.HandleRequest(req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("data: %#v err: %v %v", data, err, resp)
+		}
+	}
+
+	// Service tokens cannot take an ID that looks like a batch token
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = root
+	req.Data["id"] = batchTokenPrefix + "foo"
+	resp, err := ts.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestTokenStore_BatchToken_Invalid(t *testing.T) {
+	_, ts, _ := mockTokenStore(t)
+
+	// Expired tokens are not found
+	te := &TokenEntry{
+		Policies:   []string{"foo"},
+		Path:       "auth/token/create",
+		ExpireTime: time.Now().UTC().Add(-time.Second),
+	}
+	if err := ts.createBatch(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ts.Lookup(te.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Tampered tokens are not found
+	te.ExpireTime = time.Now().UTC().Add(time.Hour)
+	if err := ts.createBatch(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	raw, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(te.ID, batchTokenPrefix))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	raw[len(raw)-1] ^= 1
+	tampered := batchTokenPrefix + base64.URLEncoding.EncodeToString(raw)
+	for _, id := range []string{tampered, batchTokenPrefix + "foo", batchTokenPrefix} {
+		out, err = ts.Lookup(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out != nil {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+
+	// The original is still valid
+	out, err = ts.Lookup(te.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("missing token")
+	}
+}
+
+func TestCore_BatchToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/policy/foo")
+	req.ClientToken = root
+	req.Data["rules"] = `path "secret/*" { policy = "read" }`
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["type"] = "batch"
+	req.Data["policies"] = []string{"foo"}
+	req.Data["lease"] = "10m"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+	if resp.Auth.Lease != 10*time.Minute {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// The token is usable without having been registered, and leases
+	// issued to it cannot outlive it
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = token
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Secret.Lease > 10*time.Minute || resp.Secret.Lease < 9*time.Minute {
+		t.Fatalf("bad: %#v", resp.Secret)
+	}
+
+	// Other paths are still subject to its policies
+	req = logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.ClientToken = token
+	resp, err = c.HandleRequest(req)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Without a lease the default lease of the token backend applies
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["type"] = "batch"
+	req.Data["policies"] = []string{"foo"}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth.Lease != c.defaultLeaseTTL {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+}
+
+func TestCore_BoundCIDRs(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"default"}
+	req.Data["lease"] = "1h"
+	req.Data["bound_cidrs"] = []string{"10.0.0.0/8", "192.168.1.5/32"}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+
+	for addr, allowed := range map[string]bool{
+		"10.1.2.3":    true,
+		"192.168.1.5": true,
+		"192.168.1.6": false,
+		"":            false,
+	} {
+		req = logical.TestRequest(t, logical.WriteOperation, "auth/token/renew-self")
+		req.ClientToken = token
+		if addr != "" {
+			req.Connection = &logical.Connection{RemoteAddr: addr}
+		}
+		resp, err = c.HandleRequest(req)
+		if allowed && err != nil {
+			t.Fatalf("addr: %s err: %v %v", addr, err, resp)
+		}
+		if !allowed && err != logical.ErrPermissionDenied {
+			t.Fatalf("addr: %s err: %v %v", addr, err, resp)
+		}
+	}
+
+	// Invalid blocks are rejected
+	req = logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["bound_cidrs"] = []string{"10.0.0.0/33"}
+	resp, err = c.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateToken_BoundCIDRs_Parent(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"foo"}
+	req.Data["bound_cidrs"] = []string{"10.0.0.0/8"}
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	parent := resp.Auth.ClientToken
+
+	// A child inherits the blocks of the parent
+	req = logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = parent
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	out, err := ts.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.BoundCIDRs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// A child may narrow the blocks of the parent
+	req = logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = parent
+	req.Data["bound_cidrs"] = []string{"10.1.0.0/16"}
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	out, err = ts.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.BoundCIDRs, []string{"10.1.0.0/16"}) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// A child cannot widen the blocks of the parent
+	req = logical.TestRequest(t, logical.WriteOperation, "create")
+	req.ClientToken = parent
+	req.Data["bound_cidrs"] = []string{"0.0.0.0/0"}
+	resp, err = ts.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestTokenStore_HandleRequest_CreateAgainstRole_BoundCIDRs(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "roles/test")
+	req.Data["bound_cidrs"] = "10.0.0.0/8"
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The role overrides the requested blocks
+	req = logical.TestRequest(t, logical.WriteOperation, "create/test")
+	req.ClientToken = root
+	req.Data["bound_cidrs"] = []string{"0.0.0.0/0"}
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	out, err := ts.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.BoundCIDRs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestTokenStore_HandleRequest_Tidy(t *testing.T) {
+	_, ts, root := mockTokenStore(t)
+	testMakeToken(t, ts, root, "parent", []string{"foo"})
+	for _, id := range []string{"child", "other"} {
+		if err := ts.Create(&TokenEntry{ID: id, Parent: "parent", Policies: []string{"foo"}}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Simulate a create that failed after writing the indexes and a revoke
+	// that failed after deleting the primary entry
+	le := &logical.StorageEntry{Key: parentPrefix + ts.SaltID("parent") + "/" + ts.SaltID("missing")}
+	if err := ts.view.Put(le); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	le, err := logical.StorageEntryJSON(accessorPrefix+ts.SaltID("accessor"), &accessorEntry{
+		TokenID:    "missing",
+		AccessorID: "accessor",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ts.view.Put(le); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ts.view.Delete(lookupPrefix + ts.SaltID("other")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.WriteOperation, "tidy")
+	req.ClientToken = root
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	expected := map[string]interface{}{
+		"parent_index_removed":      2,
+		"accessor_index_removed":    2,
+		"lease_token_index_removed": 0,
+		"leases_revoked":            0,
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The remaining indexes are intact
+	children, err := ts.view.List(parentPrefix + ts.SaltID("parent") + "/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(children, []string{ts.SaltID("child")}) {
+		t.Fatalf("bad: %#v", children)
+	}
+	accessors, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(accessors) != 3 {
+		t.Fatalf("bad: %#v", accessors)
+	}
+}
+
+func TestTokenStore_TidyEntry_Recheck(t *testing.T) {
+	_, ts, _ := mockTokenStore(t)
+	if err := ts.view.Put(&logical.StorageEntry{Key: "foo"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// An entry that stops being dangling before the lock is taken, such
+	// as the index of a token being created, is kept
+	var checks int
+	ok, err := ts.tidyEntry("foo", func() (bool, error) {
+		checks++
+		return checks == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok || checks != 2 {
+		t.Fatalf("bad: %v %d", ok, checks)
+	}
+	out, err := ts.view.Get("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("entry should be kept")
+	}
+
+	ok, err = ts.tidyEntry("foo", func() (bool, error) {
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("entry should be deleted")
+	}
+	out, err = ts.view.Get("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestCore_Tidy_Sudo(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testCoreMakeToken(t, c, root, "client", []string{"foo"})
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/tidy")
+	req.ClientToken = "client"
+	resp, err := c.HandleRequest(req)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
@@ -3,6 +3,8 @@ package vault
 import (
 	"crypto/rand"
 	"fmt"
+	"net"
+	"strings"
 )
 
 // memzero is used to zero out a byte buffer. This specific format is optimized
@@ -33,6 +35,44 @@ func strListContains(haystack []string, needle string) bool {
 	return false
 }
 
+// strListRemove returns a copy of a list of strings without the
+// given string.
+func strListRemove(list []string, item string) []string {
+	var out []string
+	for _, v := range list {
+		if v != item {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseCommaList splits a comma-separated list, trimming whitespace and
+// skipping blank entries.
+func parseCommaList(raw string) []string {
+	var out []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// strMapEqual checks if two string maps hold the same entries. A nil
+// map is equal to an empty one.
+func strMapEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
 // strListSubset checks if a given list is a subset
 // of another set
 func strListSubset(super, sub []string) bool {
@@ -43,3 +83,69 @@ func strListSubset(super, sub []string) bool {
 	}
 	return true
 }
+
+// parseCIDRList validates a list of CIDR blocks, returning them in
+// their canonical form. Blank entries are skipped.
+func parseCIDRList(cidrs []string) ([]string, error) {
+	var out []string
+	for _, raw := range cidrs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR block '%s': %v", raw, err)
+		}
+		out = append(out, cidr.String())
+	}
+	return out, nil
+}
+
+// cidrListContains checks if an address is within any of the given
+// CIDR blocks. A missing or invalid address is never contained.
+func cidrListContains(cidrs []string, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, raw := range cidrs {
+		_, cidr, err := net.ParseCIDR(raw)
+		if err != nil {
+			continue
+		}
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// cidrListSubset checks if every CIDR block of a list is within one of
+// the blocks of another list. Invalid blocks are never within.
+func cidrListSubset(super, sub []string) bool {
+	for _, raw := range sub {
+		_, inner, err := net.ParseCIDR(raw)
+		if err != nil {
+			return false
+		}
+		innerOnes, _ := inner.Mask.Size()
+
+		found := false
+		for _, rawSuper := range super {
+			_, outer, err := net.ParseCIDR(rawSuper)
+			if err != nil {
+				continue
+			}
+			outerOnes, _ := outer.Mask.Size()
+			if outer.Contains(inner.IP) && outerOnes <= innerOnes {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
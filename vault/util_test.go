@@ -62,3 +62,28 @@ func TestStrListSubset(t *testing.T) {
 		t.Fatalf("Bad")
 	}
 }
+
+func TestCIDRListSubset(t *testing.T) {
+	parent := []string{
+		"10.0.0.0/8",
+		"192.168.1.5/32",
+	}
+	if !cidrListSubset(parent, []string{"10.1.0.0/16", "192.168.1.5/32"}) {
+		t.Fatalf("Bad")
+	}
+	if !cidrListSubset(parent, parent) {
+		t.Fatalf("Bad")
+	}
+	if !cidrListSubset(parent, nil) {
+		t.Fatalf("Bad")
+	}
+	if cidrListSubset(parent, []string{"0.0.0.0/0"}) {
+		t.Fatalf("Bad")
+	}
+	if cidrListSubset(parent, []string{"192.168.1.0/24"}) {
+		t.Fatalf("Bad")
+	}
+	if cidrListSubset(nil, parent) {
+		t.Fatalf("Bad")
+	}
+}
@@ -0,0 +1,224 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// wrappingTokenPath is the path of response wrapping tokens, which
+	// distinguishes them from any other token
+	wrappingTokenPath = "sys/wrapping/wrap"
+
+	// wrappingPolicyName is the policy of response wrapping tokens. It is
+	// not stored in the policy store, instead the tokens are granted the
+	// wrappingPolicy built-in policy.
+	wrappingPolicyName = "response-wrapping"
+
+	// wrappingUnwrapPath and wrappingRewrapPath are the paths of the
+	// system backend consuming response wrapping tokens
+	wrappingUnwrapPath = "sys/wrapping/unwrap"
+	wrappingRewrapPath = "sys/wrapping/rewrap"
+)
+
+// wrappingPolicy are the rules of response wrapping tokens, which can
+// only be used with the wrapping endpoints
+const wrappingPolicy = `
+path "sys/wrapping/unwrap" {
+    policy = "write"
+}
+
+path "sys/wrapping/lookup" {
+    policy = "write"
+}
+
+path "sys/wrapping/rewrap" {
+    policy = "write"
+}
+`
+
+// wrappedResponse is a response stored for a response wrapping token
+type wrappedResponse struct {
+	Response     *logical.Response `json:"response"`
+	TTL          time.Duration     `json:"ttl"`
+	CreationTime time.Time         `json:"creation_time"`
+}
+
+// wrappingACL returns the ACL of response wrapping tokens
+func wrappingACL() (*ACL, error) {
+	p, err := Parse(wrappingPolicy)
+	if err != nil {
+		return nil, err
+	}
+	p.Name = wrappingPolicyName
+	return NewACL([]*Policy{p})
+}
+
+// wrappingConsumes returns whether a request on the given path consumes
+// the response wrapping token it is made with. The wrapping endpoints
+// manage the token themselves, any other use of the token consumes it.
+func wrappingConsumes(path string) bool {
+	switch path {
+	case wrappingUnwrapPath, wrappingRewrapPath, "sys/wrapping/lookup":
+		return false
+	}
+	return true
+}
+
+// wrapResponse stores a response for a new single-use token and returns
+// the information to unwrap it. Raw and redirect responses are not
+// wrapped, in which case no information is returned.
+func (c *Core) wrapResponse(req *logical.Request, resp *logical.Response) (*logical.WrapInfo, error) {
+	if resp == nil || resp.IsError() || resp.Redirect != "" {
+		return nil, nil
+	}
+	if _, ok := resp.Data[logical.HTTPContentType]; ok {
+		return nil, nil
+	}
+
+	ttl := req.WrapTTL
+	if ttl > c.maxLeaseTTL {
+		ttl = c.maxLeaseTTL
+	}
+	return c.wrap(&wrappedResponse{
+		Response:     resp,
+		TTL:          ttl,
+		CreationTime: time.Now().UTC(),
+	})
+}
+
+// wrap creates a new response wrapping token for the given response.
+// The token expires at the end of the TTL of the response, taking its
+// response with it.
+func (c *Core) wrap(w *wrappedResponse) (*logical.WrapInfo, error) {
+	defer metrics.MeasureSince([]string{"core", "wrap"}, time.Now())
+	te := TokenEntry{
+		Path:        wrappingTokenPath,
+		Policies:    []string{wrappingPolicyName},
+		DisplayName: "response-wrapping",
+		NumUses:     1,
+	}
+	if err := c.tokenStore.Create(&te); err != nil {
+		c.logger.Printf("[ERR] core: failed to create wrapping token: %v", err)
+		return nil, ErrInternalError
+	}
+
+	entry, err := logical.StorageEntryJSON(wrappedPrefix+c.tokenStore.SaltID(te.ID), w)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to encode wrapped response: %v", err)
+		return nil, ErrInternalError
+	}
+	if err := c.tokenStore.view.Put(entry); err != nil {
+		c.logger.Printf("[ERR] core: failed to persist wrapped response: %v", err)
+		return nil, ErrInternalError
+	}
+
+	// The remaining TTL is used as the lease so that rewrapping
+	// does not extend the lifetime of the response
+	lease := w.CreationTime.Add(w.TTL).Sub(time.Now().UTC())
+	if lease <= 0 {
+		return nil, fmt.Errorf("wrapped response has expired")
+	}
+	auth := &logical.Auth{
+		ClientToken: te.ID,
+		Accessor:    te.Accessor,
+		Policies:    te.Policies,
+		DisplayName: te.DisplayName,
+		LeaseOptions: logical.LeaseOptions{
+			Lease: lease,
+		},
+	}
+	if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
+		c.logger.Printf("[ERR] core: failed to register wrapping token lease: %v", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.WrapInfo{
+		Token:        te.ID,
+		Accessor:     te.Accessor,
+		TTL:          w.TTL,
+		CreationTime: w.CreationTime,
+	}, nil
+}
+
+// unwrap is used to return the response wrapped by the given token.
+// The token is revoked, so a response can only be unwrapped once.
+func (c *Core) unwrap(token string) (*logical.Response, error) {
+	defer metrics.MeasureSince([]string{"core", "unwrap"}, time.Now())
+	c.wrappingLock.Lock()
+	defer c.wrappingLock.Unlock()
+
+	w, err := c.lookupWrapped(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.tokenStore.Revoke(token); err != nil {
+		c.logger.Printf("[ERR] core: failed to revoke wrapping token: %v", err)
+		return nil, ErrInternalError
+	}
+	return w.Response, nil
+}
+
+// rewrap is used to move a wrapped response to a new response wrapping
+// token, revoking the given one. The response keeps its original
+// expiration, so this cannot be used to extend its lifetime.
+func (c *Core) rewrap(token string) (*logical.WrapInfo, error) {
+	defer metrics.MeasureSince([]string{"core", "rewrap"}, time.Now())
+	c.wrappingLock.Lock()
+	defer c.wrappingLock.Unlock()
+
+	w, err := c.lookupWrapped(token)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.wrap(w)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.tokenStore.Revoke(token); err != nil {
+		c.logger.Printf("[ERR] core: failed to revoke wrapping token: %v", err)
+		return nil, ErrInternalError
+	}
+	return info, nil
+}
+
+// lookupWrapped resolves a response wrapping token to its wrapped response
+func (c *Core) lookupWrapped(token string) (*wrappedResponse, error) {
+	if token == "" {
+		return nil, fmt.Errorf("missing wrapping token")
+	}
+
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to lookup token: %v", err)
+		return nil, ErrInternalError
+	}
+	if te == nil || te.Path != wrappingTokenPath {
+		return nil, fmt.Errorf("wrapping token is not valid or does not exist")
+	}
+
+	out, err := c.tokenStore.view.Get(wrappedPrefix + c.tokenStore.SaltID(token))
+	if err != nil {
+		c.logger.Printf("[ERR] core: failed to read wrapped response: %v", err)
+		return nil, ErrInternalError
+	}
+	if out == nil {
+		return nil, fmt.Errorf("wrapping token is not valid or does not exist")
+	}
+
+	w := new(wrappedResponse)
+	if err := out.DecodeJSON(w); err != nil {
+		c.logger.Printf("[ERR] core: failed to decode wrapped response: %v", err)
+		return nil, ErrInternalError
+	}
+
+	// The token is revoked when its lease expires, but the expiration may
+	// not have fired yet
+	if time.Now().UTC().After(w.CreationTime.Add(w.TTL)) {
+		return nil, fmt.Errorf("wrapping token is not valid or does not exist")
+	}
+	return w, nil
+}
@@ -0,0 +1,214 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testWrappingRequest makes a request to a wrapping endpoint with the
+// given client token, passing the wrapping token in the data if given
+func testWrappingRequest(c *Core, endpoint, clientToken, token string) (*logical.Response, error) {
+	req := &logical.Request{
+		Operation:   logical.WriteOperation,
+		Path:        "sys/wrapping/" + endpoint,
+		ClientToken: clientToken,
+		Data:        map[string]interface{}{},
+	}
+	if token != "" {
+		req.Data["token"] = token
+	}
+	return c.HandleRequest(req)
+}
+
+func TestCore_Wrapping(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"foo"}
+	req.WrapTTL = 5 * time.Minute
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth != nil || resp.Data != nil || resp.WrapInfo == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.WrapInfo.TTL != 5*time.Minute || resp.WrapInfo.Token == "" || resp.WrapInfo.Accessor == "" {
+		t.Fatalf("bad: %#v", resp.WrapInfo)
+	}
+	token := resp.WrapInfo.Token
+
+	resp, err = testWrappingRequest(c, "lookup", root, token)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["creation_ttl"] != 300 || resp.Data["creation_time"].(time.Time).IsZero() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Unwrapping returns the original response
+	resp, err = testWrappingRequest(c, "unwrap", root, token)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || !strListContains(te.Policies, "foo") {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The token can only be used once
+	if resp, err := testWrappingRequest(c, "unwrap", root, token); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp, err := testWrappingRequest(c, "lookup", root, token); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestCore_Wrapping_ClientToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["foo"] = "bar"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	req.WrapTTL = time.Minute
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.WrapInfo.Token
+
+	// The wrapping token can be used as the client token, and is not
+	// consumed by a lookup
+	resp, err = testWrappingRequest(c, "lookup", token, "")
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	resp, err = testWrappingRequest(c, "unwrap", token, "")
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_Wrapping_Rewrap(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.WriteOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["foo"] = "bar"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	req.WrapTTL = time.Minute
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.WrapInfo.Token
+	creation := resp.WrapInfo.CreationTime
+
+	resp, err = testWrappingRequest(c, "rewrap", root, token)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	info := resp.WrapInfo
+	if info == nil || info.Token == token || info.TTL != time.Minute || !info.CreationTime.Equal(creation) {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The old token is revoked
+	if resp, err := testWrappingRequest(c, "unwrap", root, token); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	resp, err = testWrappingRequest(c, "unwrap", root, info.Token)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_Wrapping_InvalidToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// Regular tokens do not wrap anything
+	if resp, err := testWrappingRequest(c, "unwrap", root, ""); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Using a wrapping token for anything else consumes it
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	req.WrapTTL = time.Minute
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.WrapInfo.Token
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = token
+	resp, err = c.HandleRequest(req)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp, err := testWrappingRequest(c, "unwrap", root, token); err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+}
+
+func TestCore_Wrapping_Audit(t *testing.T) {
+	noop := &NoopAudit{}
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(map[string]string) (audit.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.WriteOperation, "sys/audit/noop")
+	req.ClientToken = root
+	req.Data["type"] = "noop"
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	req.WrapTTL = time.Minute
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The response is audited along with the wrapping token
+	audited := noop.Resp[len(noop.Resp)-1]
+	if audited.WrapInfo == nil || audited.WrapInfo.Token != resp.WrapInfo.Token {
+		t.Fatalf("bad: %#v", audited)
+	}
+	if audited.Data == nil {
+		t.Fatalf("bad: %#v", audited)
+	}
+}